@@ -0,0 +1,737 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/prometheus/common/version"
+)
+
+var (
+	errOnlyGET  = errors.New("only GET allowed")
+	errOnlyPOST = errors.New("only POST allowed")
+)
+
+// apiResponse is the envelope of all `/api/v1` responses, following the Prometheus HTTP API conventions.
+type apiResponse struct {
+	Status string      `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+func apiRespond(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(apiResponse{Status: "success", Data: data})
+}
+
+func apiError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiResponse{Status: "error", Error: err.Error()})
+}
+
+// MappingsHandlerFunc is the HTTP handler for `/api/v1/mappings`. It lists the known alert group to issue mappings,
+// optionally filtered by the `receiver` query parameter.
+func MappingsHandlerFunc(state *notify.State) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyGET)
+			return
+		}
+
+		receiver := r.URL.Query().Get("receiver")
+		mappings := []notify.Mapping{}
+		for _, m := range state.Mappings() {
+			if receiver == "" || m.Receiver == receiver {
+				mappings = append(mappings, m)
+			}
+		}
+		apiRespond(w, mappings)
+	}
+}
+
+// buildStatus is the response of `/api/v1/status`.
+type buildStatus struct {
+	Version        string    `json:"version"`
+	Revision       string    `json:"revision"`
+	Branch         string    `json:"branch"`
+	BuildDate      string    `json:"buildDate"`
+	GoVersion      string    `json:"goVersion"`
+	StartTime      time.Time `json:"startTime"`
+	ConfigLoadTime time.Time `json:"configLoadTime"`
+	Uptime         string    `json:"uptime"`
+}
+
+// BuildStatusHandlerFunc is the HTTP handler for `/api/v1/status`. It returns build information, the time the
+// configuration was loaded and the uptime.
+func BuildStatusHandlerFunc(startTime, configLoadTime time.Time) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyGET)
+			return
+		}
+		apiRespond(w, buildStatus{
+			Version:        version.Version,
+			Revision:       version.Revision,
+			Branch:         version.Branch,
+			BuildDate:      version.BuildDate,
+			GoVersion:      version.GoVersion,
+			StartTime:      startTime,
+			ConfigLoadTime: configLoadTime,
+			Uptime:         time.Since(startTime).Round(time.Second).String(),
+		})
+	}
+}
+
+// ReceiversHandlerFunc is the HTTP handler for `/api/v1/receivers` and `/api/v1/receivers/<name>/effective`. It lists
+// the configured receivers, or returns a single one, with defaults applied, environment variables expanded and secrets
+// redacted, i.e. the values in force.
+func ReceiversHandlerFunc(cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyGET)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/api/v1/receivers/")
+		if path == r.URL.Path || path == "" {
+			apiRespond(w, cfg.Receivers)
+			return
+		}
+		name := strings.TrimSuffix(path, "/effective")
+		if name == path || name == "" {
+			apiError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %s", r.URL.Path))
+			return
+		}
+		conf := cfg.ReceiverByName(name)
+		if conf == nil {
+			apiError(w, http.StatusNotFound, fmt.Errorf("receiver missing: %s", name))
+			return
+		}
+		apiRespond(w, conf)
+	}
+}
+
+// mappingRequest is the body of the `/api/v1/mappings/detach` and `/api/v1/mappings/relink` requests.
+type mappingRequest struct {
+	Receiver   string `json:"receiver"`
+	IssueLabel string `json:"issueLabel"`
+	// Project defaults to the project of the known mapping, or to the receiver's project if it is neither templated nor
+	// mapped.
+	Project string `json:"project,omitempty"`
+	// IssueKey is the issue to link the alert group to. Only used by relink.
+	IssueKey string `json:"issueKey,omitempty"`
+}
+
+// MappingActionHandlerFunc is the HTTP handler for `/api/v1/mappings/detach` and, if relink is true,
+// `/api/v1/mappings/relink`. Detach makes the next notification of the alert group create a fresh issue, relink
+// points the alert group at an existing issue, e.g. after issues were merged or moved by hand.
+func MappingActionHandlerFunc(cfg *config.Config, tmpl *template.Template, state *notify.State, relink bool, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyPOST)
+			return
+		}
+		defer func() { _ = r.Body.Close() }()
+
+		var req mappingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err))
+			return
+		}
+		if req.IssueLabel == "" {
+			apiError(w, http.StatusBadRequest, errors.New("issueLabel is required"))
+			return
+		}
+		if relink && req.IssueKey == "" {
+			apiError(w, http.StatusBadRequest, errors.New("issueKey is required"))
+			return
+		}
+		conf := cfg.ReceiverByName(req.Receiver)
+		if conf == nil {
+			apiError(w, http.StatusNotFound, fmt.Errorf("receiver missing: %s", req.Receiver))
+			return
+		}
+		if req.Project == "" {
+			var known []string
+			for _, m := range state.Mappings() {
+				if m.Receiver == conf.Name && m.IssueLabel == req.IssueLabel {
+					known = append(known, m.Project)
+				}
+			}
+			if len(known) == 1 {
+				req.Project = known[0]
+			} else if len(known) > 1 {
+				apiError(w, http.StatusBadRequest, fmt.Errorf("project is required, the alert group has issues in %s", strings.Join(known, ", ")))
+				return
+			} else if projects := config.SplitProjects(conf.Project); len(projects) == 1 && !strings.Contains(conf.Project, "{{") && conf.ProjectMapping == nil {
+				req.Project = projects[0]
+			} else {
+				apiError(w, http.StatusBadRequest, errors.New("project is required for receivers with several, templated or mapped projects"))
+				return
+			}
+		}
+
+		logger := log.With(logger, "receiver", conf.Name)
+		ticketer, err := newTicketer(r.Context(), conf, logger)
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err)
+			return
+		}
+		receiver := notify.NewReceiver(logger, conf, tmpl, ticketer, state)
+		if relink {
+			err = receiver.Relink(r.Context(), req.Project, req.IssueLabel, req.IssueKey)
+		} else {
+			err = receiver.Detach(r.Context(), req.Project, req.IssueLabel)
+		}
+		if err != nil {
+			apiError(w, http.StatusBadGateway, err)
+			return
+		}
+		m, _ := state.Mapping(conf.Name, req.Project, req.IssueLabel)
+		apiRespond(w, m)
+	}
+}
+
+// incident is an item of `/api/v1/incident` responses and the body of its requests.
+type incident struct {
+	Receiver string `json:"receiver"`
+	// IssueKey is the current incident issue new issues are linked to. Empty if there is none.
+	IssueKey string `json:"issueKey"`
+}
+
+// IncidentHandlerFunc is the HTTP handler for `/api/v1/incident`. GET lists the current incident issue of every
+// receiver with current_incident, POST sets (or, with an empty issueKey, clears) the one of a receiver until restart.
+func IncidentHandlerFunc(cfg *config.Config, state *notify.State) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			incidents := []incident{}
+			for _, rc := range cfg.Receivers {
+				if rc.CurrentIncident != nil {
+					incidents = append(incidents, incident{Receiver: rc.Name, IssueKey: state.Incident(rc)})
+				}
+			}
+			apiRespond(w, incidents)
+		case http.MethodPost:
+			defer func() { _ = r.Body.Close() }()
+
+			var req incident
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err))
+				return
+			}
+			conf := cfg.ReceiverByName(req.Receiver)
+			if conf == nil {
+				apiError(w, http.StatusNotFound, fmt.Errorf("receiver missing: %s", req.Receiver))
+				return
+			}
+			if conf.CurrentIncident == nil {
+				apiError(w, http.StatusBadRequest, fmt.Errorf("receiver %s has no current_incident", conf.Name))
+				return
+			}
+			state.SetIncident(conf.Name, req.IssueKey)
+			apiRespond(w, incident{Receiver: conf.Name, IssueKey: state.Incident(conf)})
+		default:
+			apiError(w, http.StatusMethodNotAllowed, errors.New("only GET and POST allowed"))
+		}
+	}
+}
+
+// pauseStatus is the response of `/api/v1/pause` and `/api/v1/resume`.
+type pauseStatus struct {
+	// All is whether all receivers are paused, Receivers the receivers paused one by one.
+	All       bool     `json:"all"`
+	Receivers []string `json:"receivers"`
+	// Queued is the number of alert groups with a queued notification, by receiver.
+	Queued map[string]int `json:"queued"`
+	// Drained is the number of queued notifications processed on resume, Failed the error of receivers whose queue
+	// could not be drained, by receiver.
+	Drained map[string]int    `json:"drained,omitempty"`
+	Failed  map[string]string `json:"failed,omitempty"`
+}
+
+func newPauseStatus(cfg *config.Config, state *notify.State) pauseStatus {
+	all, receivers := state.PausedReceivers()
+	res := pauseStatus{All: all, Receivers: receivers, Queued: map[string]int{}}
+	for _, rc := range cfg.Receivers {
+		if n := state.Queued(rc.Name); n > 0 {
+			res.Queued[rc.Name] = n
+		}
+	}
+	return res
+}
+
+// pausedReceiver returns the receiver of the `receiver` query parameter, empty for all receivers.
+func pausedReceiver(cfg *config.Config, r *http.Request) (string, error) {
+	name := r.URL.Query().Get("receiver")
+	if name != "" && cfg.ReceiverByName(name) == nil {
+		return "", fmt.Errorf("receiver missing: %s", name)
+	}
+	return name, nil
+}
+
+// PauseHandlerFunc is the HTTP handler for `/api/v1/pause`. POST pauses the receiver of the `receiver` query parameter,
+// or all receivers: their notifications are accepted and queued without contacting Jira. GET returns what is paused.
+func PauseHandlerFunc(cfg *config.Config, state *notify.State, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			receiver, err := pausedReceiver(cfg, r)
+			if err != nil {
+				apiError(w, http.StatusNotFound, err)
+				return
+			}
+			if !state.Persisted() {
+				apiError(w, http.StatusUnprocessableEntity, errors.New("pausing requires -state.file, to keep the queued notifications across restarts"))
+				return
+			}
+			if err := state.Pause(receiver); err != nil {
+				apiError(w, http.StatusInternalServerError, err)
+				return
+			}
+			level.Warn(logger).Log("msg", "paused notifications", "receiver", receiver)
+		default:
+			apiError(w, http.StatusMethodNotAllowed, errors.New("only GET and POST allowed"))
+			return
+		}
+		apiRespond(w, newPauseStatus(cfg, state))
+	}
+}
+
+// ResumeHandlerFunc is the HTTP handler for `/api/v1/resume`. It resumes the receiver of the `receiver` query
+// parameter, or all receivers, and processes the notifications queued meanwhile before responding.
+func ResumeHandlerFunc(cfg *config.Config, tmpl *template.Template, state *notify.State, hashJiraLabel bool, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyPOST)
+			return
+		}
+		receiver, err := pausedReceiver(cfg, r)
+		if err != nil {
+			apiError(w, http.StatusNotFound, err)
+			return
+		}
+		if err := state.Resume(receiver); err != nil {
+			apiError(w, http.StatusInternalServerError, err)
+			return
+		}
+		level.Info(logger).Log("msg", "resumed notifications", "receiver", receiver)
+
+		drained, failed := map[string]int{}, map[string]string{}
+		for _, conf := range cfg.Receivers {
+			if (receiver != "" && conf.Name != receiver) || state.Paused(conf.Name) || state.Queued(conf.Name) == 0 {
+				continue
+			}
+			logger := log.With(logger, "receiver", conf.Name)
+			ticketer, err := newTicketer(r.Context(), conf, logger)
+			if err != nil {
+				failed[conf.Name] = err.Error()
+				continue
+			}
+			n, _, err := notify.NewReceiver(logger, conf, tmpl, ticketer, state).NotifyQueued(r.Context(), hashJiraLabel)
+			drained[conf.Name] = n
+			if err != nil {
+				failed[conf.Name] = err.Error()
+			}
+		}
+		res := newPauseStatus(cfg, state)
+		res.Drained, res.Failed = drained, failed
+		apiRespond(w, res)
+	}
+}
+
+// testResult is the response of `/api/v1/test`.
+type testResult struct {
+	Issues []*jira.Issue `json:"issues"`
+	// Mappings are the issues tracking the alert group after the notification, unless it was a dry run.
+	Mappings []notify.Mapping `json:"mappings,omitempty"`
+}
+
+// TestHandlerFunc is the HTTP handler for `/api/v1/test`. It accepts an Alertmanager webhook payload and returns the
+// issues the receiver (the `receiver` query parameter, or the payload's receiver) would create for it. Unless
+// `dry_run=false` is given, Jira is not contacted.
+func TestHandlerFunc(cfg *config.Config, tmpl *template.Template, state *notify.State, hashJiraLabel bool, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyPOST)
+			return
+		}
+		defer func() { _ = r.Body.Close() }()
+
+		dryRun := true
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				apiError(w, http.StatusBadRequest, fmt.Errorf("invalid dry_run: %s", err))
+				return
+			}
+		}
+
+		data := alertmanager.Data{}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			apiError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %s", err))
+			return
+		}
+		if name := r.URL.Query().Get("receiver"); name != "" {
+			data.Receiver = name
+		}
+		conf := cfg.ReceiverByName(data.Receiver)
+		if conf == nil {
+			apiError(w, http.StatusNotFound, fmt.Errorf("receiver missing: %s", data.Receiver))
+			return
+		}
+		logger := log.With(logger, "receiver", conf.Name)
+
+		issues, err := notify.NewReceiver(logger, conf, tmpl, nil, nil).Render(&data, hashJiraLabel)
+		if err != nil {
+			apiError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		res := testResult{Issues: issues}
+		if dryRun {
+			apiRespond(w, res)
+			return
+		}
+
+		ticketer, err := newTicketer(r.Context(), conf, logger)
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if _, err := notify.NewReceiver(logger, conf, tmpl, ticketer, state).Notify(r.Context(), &data, hashJiraLabel); err != nil {
+			apiError(w, http.StatusBadGateway, err)
+			return
+		}
+		for _, m := range state.Mappings() {
+			if m.Receiver == conf.Name && m.GroupKey == data.GroupKey {
+				res.Mappings = append(res.Mappings, m)
+			}
+		}
+		apiRespond(w, res)
+	}
+}
+
+var errDeadLettersDisabled = errors.New("dead letter store is disabled, see -dead-letter.dir")
+
+// DeadLettersHandlerFunc is the HTTP handler for `/api/v1/dead-letters` and `/api/v1/dead-letters/<id>`. GET lists
+// permanently failed notifications (optionally filtered by the `receiver` query parameter) or returns a single one
+// including its payload, DELETE purges them.
+func DeadLettersHandlerFunc(deadLetters *deadLetterStore) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+			apiError(w, http.StatusMethodNotAllowed, errors.New("only GET and DELETE allowed"))
+			return
+		}
+		if deadLetters == nil {
+			apiError(w, http.StatusNotFound, errDeadLettersDisabled)
+			return
+		}
+
+		if id := strings.TrimPrefix(r.URL.Path, "/api/v1/dead-letters/"); id != r.URL.Path && id != "" {
+			dl, err := deadLetters.Get(id)
+			// Corrupt dead letters can be purged.
+			if (err == nil || errors.Is(err, errDeadLetterCorrupt)) && r.Method == http.MethodDelete {
+				err = deadLetters.Remove(id)
+			}
+			if err != nil {
+				apiError(w, deadLetterErrorStatus(err), err)
+				return
+			}
+			apiRespond(w, dl)
+			return
+		}
+
+		dls, err := deadLetters.List(r.URL.Query().Get("receiver"))
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			for _, dl := range dls {
+				if err := deadLetters.Remove(dl.ID); err != nil && !errors.Is(err, errDeadLetterNotFound) {
+					apiError(w, http.StatusInternalServerError, err)
+					return
+				}
+			}
+		}
+		apiRespond(w, dls)
+	}
+}
+
+// replayResult is the response of a bulk `/api/v1/replay`.
+type replayResult struct {
+	Replayed []string          `json:"replayed"`
+	Failed   map[string]string `json:"failed"`
+}
+
+// ReplayHandlerFunc is the HTTP handler for `/api/v1/replay/<id>` and `/api/v1/replay`. It handles the notification
+// of the given dead letter, or of all dead letters (optionally filtered by the `receiver` query parameter), again and
+// removes the dead letters that succeed.
+func ReplayHandlerFunc(cfg *config.Config, tmpl *template.Template, state *notify.State, deadLetters *deadLetterStore, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+	replay := func(ctx context.Context, dl deadLetter) error {
+		if dl.Receiver == "" {
+			// A corrupt dead letter, see deadLetterStore.List.
+			return errors.New(dl.Error)
+		}
+		conf := cfg.ReceiverByName(dl.Receiver)
+		if conf == nil {
+			return fmt.Errorf("receiver missing: %s", dl.Receiver)
+		}
+		if _, _, err := notifyReceiver(ctx, conf, tmpl, state, &dl.Data, logger); err != nil {
+			return err
+		}
+		return deadLetters.Remove(dl.ID)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyPOST)
+			return
+		}
+		if deadLetters == nil {
+			apiError(w, http.StatusNotFound, errDeadLettersDisabled)
+			return
+		}
+
+		if id := strings.TrimPrefix(r.URL.Path, "/api/v1/replay/"); id != r.URL.Path && id != "" {
+			dl, err := deadLetters.Get(id)
+			if err != nil {
+				apiError(w, deadLetterErrorStatus(err), err)
+				return
+			}
+			if err := replay(r.Context(), dl); err != nil {
+				apiError(w, http.StatusBadGateway, err)
+				return
+			}
+			apiRespond(w, dl)
+			return
+		}
+
+		dls, err := deadLetters.List(r.URL.Query().Get("receiver"))
+		if err != nil {
+			apiError(w, http.StatusInternalServerError, err)
+			return
+		}
+		res := replayResult{Replayed: []string{}, Failed: map[string]string{}}
+		for _, dl := range dls {
+			if err := replay(r.Context(), dl); err != nil {
+				res.Failed[dl.ID] = err.Error()
+				continue
+			}
+			res.Replayed = append(res.Replayed, dl.ID)
+		}
+		apiRespond(w, res)
+	}
+}
+
+func deadLetterErrorStatus(err error) int {
+	if errors.Is(err, errDeadLetterNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// ConfigSchemaHandlerFunc is the HTTP handler for `/api/v1/config-schema`. It returns the JSON Schema of this
+// version's configuration format as is, i.e. without the API envelope, so it can be referenced by editors and tools.
+func ConfigSchemaHandlerFunc() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyGET)
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		_ = json.NewEncoder(w).Encode(config.JSONSchema())
+	}
+}
+
+// maxValidateSize limits the size of the configuration and template accepted by `/api/v1/validate-config`.
+const maxValidateSize = 4 << 20
+
+// validateResult is the response of `/api/v1/validate-config`.
+type validateResult struct {
+	Receivers []string `json:"receivers"`
+}
+
+// ValidateConfigHandlerFunc is the HTTP handler for `/api/v1/validate-config`. It checks a candidate configuration,
+// sent as the body or as the `config` part of a multipart form, including its templates: those of the `template` part
+// if sent, the running template tmpl otherwise. Nothing the candidate references is read, see config.ParseUntrusted.
+func ValidateConfigHandlerFunc(tmpl *template.Template, logger log.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyPOST)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxValidateSize)
+
+		var content, tmplText []byte
+		var err error
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := r.ParseMultipartForm(maxValidateSize); err != nil {
+				apiError(w, http.StatusBadRequest, err)
+				return
+			}
+			if content, err = formFile(r, "config"); err != nil {
+				apiError(w, http.StatusBadRequest, err)
+				return
+			}
+			if hasFormFile(r, "template") {
+				if tmplText, err = formFile(r, "template"); err != nil {
+					apiError(w, http.StatusBadRequest, err)
+					return
+				}
+			}
+		} else if content, err = io.ReadAll(r.Body); err != nil {
+			apiError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		cfg, err := config.ParseUntrusted(content)
+		if err != nil {
+			apiError(w, http.StatusUnprocessableEntity, fmt.Errorf("invalid configuration: %w", err))
+			return
+		}
+		tmpl := tmpl
+		if tmplText != nil {
+			if tmpl, err = template.ParseTemplate(string(tmplText), logger); err != nil {
+				apiError(w, http.StatusUnprocessableEntity, fmt.Errorf("invalid template: %w", err))
+				return
+			}
+		}
+
+		res := validateResult{Receivers: []string{}}
+		for _, rc := range cfg.Receivers {
+			if err := checkReceiverTemplates(tmpl, rc); err != nil {
+				apiError(w, http.StatusUnprocessableEntity, fmt.Errorf("invalid template in receiver %q: %w", rc.Name, err))
+				return
+			}
+			res.Receivers = append(res.Receivers, rc.Name)
+		}
+		apiRespond(w, res)
+	}
+}
+
+// hasFormFile reports whether the parsed multipart form has the named file or value.
+func hasFormFile(r *http.Request, name string) bool {
+	_, file := r.MultipartForm.File[name]
+	_, value := r.MultipartForm.Value[name]
+	return file || value
+}
+
+// formFile returns the content of the named file or value of a parsed multipart form.
+func formFile(r *http.Request, name string) ([]byte, error) {
+	if v, ok := r.MultipartForm.Value[name]; ok && len(v) > 0 {
+		return []byte(v[0]), nil
+	}
+	f, _, err := r.FormFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// checkReceiverTemplates parses the templated settings of a receiver, so syntax errors are found before the first
+// notification.
+func checkReceiverTemplates(tmpl *template.Template, rc *config.ReceiverConfig) error {
+	texts := []string{rc.Project, rc.IssueType, rc.Summary, rc.Description, rc.DescriptionHeader, rc.DescriptionFooter, rc.Priority, rc.Assignee, rc.Reporter, rc.IssueIdentifierLabel, rc.PartialResolutionComment}
+	texts = append(texts, rc.Components...)
+	for _, v := range rc.AdditionalIssueLabels {
+		texts = append(texts, v)
+	}
+	for _, m := range []*config.LabelMapping{rc.ProjectMapping, rc.IssueTypeMapping, rc.AssigneeMapping} {
+		if m == nil {
+			continue
+		}
+		for _, v := range m.Values {
+			texts = append(texts, v)
+		}
+	}
+	if rc.CreationLimit != nil {
+		texts = append(texts, rc.CreationLimit.StormSummary)
+	}
+	if rc.IncidentMode != nil {
+		texts = append(texts, rc.IncidentMode.Summary)
+	}
+	if rc.AutoResolve != nil {
+		texts = append(texts, rc.AutoResolve.Comment)
+	}
+	for _, f := range rc.AssetsFields {
+		texts = append(texts, f.Query)
+	}
+	for _, f := range rc.CascadingFields {
+		texts = append(texts, f.Parent, f.Child)
+	}
+	if rc.Epics != nil {
+		texts = append(texts, rc.Epics.Summary)
+	}
+	if rc.Subtasks != nil {
+		texts = append(texts, rc.Subtasks.Summary)
+	}
+	if rc.ServiceDesk != nil {
+		texts = append(texts, rc.ServiceDesk.Organizations...)
+		texts = append(texts, rc.ServiceDesk.Participants...)
+		if rc.ServiceDesk.SLAWarning != nil {
+			texts = append(texts, rc.ServiceDesk.SLAWarning.Comment)
+		}
+	}
+	texts = append(texts, templateStrings(rc.Fields)...)
+
+	for _, text := range texts {
+		if err := tmpl.Check(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateStrings returns the strings of a decoded YAML value, which may all be templates.
+func templateStrings(value interface{}) []string {
+	var texts []string
+	switch v := value.(type) {
+	case string:
+		texts = append(texts, v)
+	case []interface{}:
+		for _, e := range v {
+			texts = append(texts, templateStrings(e)...)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			texts = append(texts, k)
+			texts = append(texts, templateStrings(e)...)
+		}
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			texts = append(texts, templateStrings(k)...)
+			texts = append(texts, templateStrings(e)...)
+		}
+	}
+	return texts
+}
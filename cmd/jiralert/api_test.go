@@ -0,0 +1,321 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/stretchr/testify/require"
+)
+
+// newAPITestConfig returns a configuration with a single Jira receiver, backed by a fake Jira for the duration of the
+// test.
+func newAPITestConfig(t *testing.T) (*config.Config, *fakejira.Server) {
+	fake := fakejira.New()
+	testModeJira = fake
+	t.Cleanup(func() { testModeJira = nil })
+
+	reopen := config.Duration(0)
+	return &config.Config{Receivers: []*config.ReceiverConfig{{
+		Name:            "jira-ab",
+		Backend:         config.BackendJira,
+		Project:         "AB",
+		IssueType:       "Bug",
+		Summary:         `{{ .GroupLabels.alertname }}`,
+		ReopenState:     "To Do",
+		ReopenDuration:  &reopen,
+		CurrentIncident: &config.CurrentIncident{},
+	}}}, fake
+}
+
+func firing(alertname string) *alertmanager.Data {
+	return &alertmanager.Data{
+		Receiver:    "jira-ab",
+		Status:      alertmanager.AlertFiring,
+		GroupKey:    alertname,
+		GroupLabels: alertmanager.KV{"alertname": alertname},
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+	}
+}
+
+func serve(handler func(http.ResponseWriter, *http.Request), method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return w
+}
+
+func TestMappingsHandler(t *testing.T) {
+	cfg, fake := newAPITestConfig(t)
+	state := notify.NewState()
+	_, err := notify.NewReceiver(log.NewNopLogger(), cfg.Receivers[0], template.SimpleTemplate(), fake, state).Notify(context.Background(), firing("Disk"), false)
+	require.NoError(t, err)
+	handler := MappingsHandlerFunc(state)
+
+	w := serve(handler, http.MethodGet, "/api/v1/mappings?receiver=jira-ab", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Status string           `json:"status"`
+		Data   []notify.Mapping `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "success", resp.Status)
+	require.Len(t, resp.Data, 1)
+	require.Equal(t, `ALERT{alertname="Disk"}`, resp.Data[0].IssueLabel)
+	require.Equal(t, "AB-1", resp.Data[0].IssueKey)
+
+	w = serve(handler, http.MethodGet, "/api/v1/mappings?receiver=other", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"status":"success","data":[]}`, w.Body.String())
+
+	w = serve(handler, http.MethodPost, "/api/v1/mappings", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.JSONEq(t, `{"status":"error","error":"only GET allowed"}`, w.Body.String())
+}
+
+func TestBuildStatusHandler(t *testing.T) {
+	loaded := time.Date(2022, 11, 5, 22, 0, 0, 0, time.UTC)
+	handler := BuildStatusHandlerFunc(time.Now().Add(-time.Hour), loaded)
+
+	w := serve(handler, http.MethodGet, "/api/v1/status", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data buildStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, loaded, resp.Data.ConfigLoadTime)
+	require.Equal(t, "1h0m0s", resp.Data.Uptime)
+	require.NotEmpty(t, resp.Data.GoVersion)
+
+	w = serve(handler, http.MethodPost, "/api/v1/status", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestMappingActionHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		relink   bool
+		method   string
+		body     string
+		want     int
+		wantBody string
+	}{
+		{name: "detach", body: `{"receiver":"jira-ab","issueLabel":"ALERT{alertname=\"Disk\"}"}`, want: http.StatusOK, wantBody: `"issueKey":""`},
+		{name: "relink", relink: true, body: `{"receiver":"jira-ab","issueLabel":"ALERT{alertname=\"Disk\"}","issueKey":"AB-2"}`, want: http.StatusOK, wantBody: `"issueKey":"AB-2"`},
+		{name: "GET", method: http.MethodGet, want: http.StatusMethodNotAllowed, wantBody: "only POST allowed"},
+		{name: "invalid body", body: `{`, want: http.StatusBadRequest, wantBody: "invalid request body"},
+		{name: "no issue label", body: `{"receiver":"jira-ab"}`, want: http.StatusBadRequest, wantBody: "issueLabel is required"},
+		{name: "no issue key", relink: true, body: `{"receiver":"jira-ab","issueLabel":"ALERT{alertname=\"Disk\"}"}`, want: http.StatusBadRequest, wantBody: "issueKey is required"},
+		{name: "unknown receiver", body: `{"receiver":"other","issueLabel":"ALERT{alertname=\"Disk\"}"}`, want: http.StatusNotFound, wantBody: "receiver missing: other"},
+		{name: "unknown issue", relink: true, body: `{"receiver":"jira-ab","issueLabel":"ALERT{alertname=\"Disk\"}","issueKey":"AB-9"}`, want: http.StatusBadGateway, wantBody: `"status":"error"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, fake := newAPITestConfig(t)
+			state := notify.NewState()
+			receiver := notify.NewReceiver(log.NewNopLogger(), cfg.Receivers[0], template.SimpleTemplate(), fake, state)
+			for _, alertname := range []string{"Disk", "Memory"} {
+				_, err := receiver.Notify(context.Background(), firing(alertname), false)
+				require.NoError(t, err)
+			}
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			w := serve(MappingActionHandlerFunc(cfg, template.SimpleTemplate(), state, tc.relink, log.NewNopLogger()), method, "/api/v1/mappings/detach", tc.body)
+			require.Equal(t, tc.want, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), tc.wantBody)
+		})
+	}
+}
+
+func TestIncidentHandler(t *testing.T) {
+	cfg, _ := newAPITestConfig(t)
+	cfg.Receivers = append(cfg.Receivers, &config.ReceiverConfig{Name: "no-incident"})
+	handler := IncidentHandlerFunc(cfg, notify.NewState())
+
+	w := serve(handler, http.MethodPost, "/api/v1/incident", `{"receiver":"jira-ab","issueKey":"AB-1"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.JSONEq(t, `{"status":"success","data":{"receiver":"jira-ab","issueKey":"AB-1"}}`, w.Body.String())
+
+	w = serve(handler, http.MethodGet, "/api/v1/incident", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.JSONEq(t, `{"status":"success","data":[{"receiver":"jira-ab","issueKey":"AB-1"}]}`, w.Body.String())
+
+	for _, tc := range []struct {
+		method, body string
+		want         int
+		wantBody     string
+	}{
+		{method: http.MethodPost, body: `[]`, want: http.StatusBadRequest, wantBody: "invalid request body"},
+		{method: http.MethodPost, body: `{"receiver":"other"}`, want: http.StatusNotFound, wantBody: "receiver missing: other"},
+		{method: http.MethodPost, body: `{"receiver":"no-incident"}`, want: http.StatusBadRequest, wantBody: "receiver no-incident has no current_incident"},
+		{method: http.MethodDelete, want: http.StatusMethodNotAllowed, wantBody: "only GET and POST allowed"},
+	} {
+		w := serve(handler, tc.method, "/api/v1/incident", tc.body)
+		require.Equal(t, tc.want, w.Code, w.Body.String())
+		require.Contains(t, w.Body.String(), tc.wantBody)
+	}
+}
+
+func TestPauseHandlers(t *testing.T) {
+	cfg, _ := newAPITestConfig(t)
+
+	// Queued notifications must survive restarts.
+	w := serve(PauseHandlerFunc(cfg, notify.NewState(), log.NewNopLogger()), http.MethodPost, "/api/v1/pause", "")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), "pausing requires -state.file")
+
+	state, err := notify.LoadState(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	pause := PauseHandlerFunc(cfg, state, log.NewNopLogger())
+	resume := ResumeHandlerFunc(cfg, template.SimpleTemplate(), state, false, log.NewNopLogger())
+
+	w = serve(pause, http.MethodPost, "/api/v1/pause?receiver=jira-ab", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.JSONEq(t, `{"status":"success","data":{"all":false,"receivers":["jira-ab"],"queued":{}}}`, w.Body.String())
+
+	w = serve(pause, http.MethodGet, "/api/v1/pause", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"receivers":["jira-ab"]`)
+
+	w = serve(resume, http.MethodPost, "/api/v1/resume?receiver=jira-ab", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"receivers":[]`)
+
+	for _, tc := range []struct {
+		handler func(http.ResponseWriter, *http.Request)
+		method  string
+		target  string
+		want    int
+	}{
+		{handler: pause, method: http.MethodPost, target: "/api/v1/pause?receiver=other", want: http.StatusNotFound},
+		{handler: pause, method: http.MethodDelete, target: "/api/v1/pause", want: http.StatusMethodNotAllowed},
+		{handler: resume, method: http.MethodPost, target: "/api/v1/resume?receiver=other", want: http.StatusNotFound},
+		{handler: resume, method: http.MethodGet, target: "/api/v1/resume", want: http.StatusMethodNotAllowed},
+	} {
+		w := serve(tc.handler, tc.method, tc.target, "")
+		require.Equal(t, tc.want, w.Code, tc.target)
+		require.Contains(t, w.Body.String(), `"status":"error"`, tc.target)
+	}
+}
+
+func TestTestHandler(t *testing.T) {
+	cfg, fake := newAPITestConfig(t)
+	state := notify.NewState()
+	handler := TestHandlerFunc(cfg, template.SimpleTemplate(), state, false, log.NewNopLogger())
+	payload, err := json.Marshal(firing("Disk"))
+	require.NoError(t, err)
+
+	// A dry run renders the issue without creating it.
+	w := serve(handler, http.MethodPost, "/api/v1/test", string(payload))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data testResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Issues, 1)
+	require.Equal(t, "Disk", resp.Data.Issues[0].Fields.Summary)
+	require.Empty(t, resp.Data.Mappings)
+	issues, _, err := fake.SearchWithContext(context.Background(), notify.Query{Project: "AB"}, nil)
+	require.NoError(t, err)
+	require.Empty(t, issues)
+
+	w = serve(handler, http.MethodPost, "/api/v1/test?dry_run=false", string(payload))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp.Data = testResult{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Mappings, 1)
+	require.Equal(t, "AB-1", resp.Data.Mappings[0].IssueKey)
+
+	for _, tc := range []struct {
+		method, target, body string
+		want                 int
+		wantBody             string
+	}{
+		{method: http.MethodGet, target: "/api/v1/test", want: http.StatusMethodNotAllowed, wantBody: "only POST allowed"},
+		{method: http.MethodPost, target: "/api/v1/test?dry_run=maybe", body: string(payload), want: http.StatusBadRequest, wantBody: "invalid dry_run"},
+		{method: http.MethodPost, target: "/api/v1/test", body: `{`, want: http.StatusBadRequest, wantBody: "invalid request body"},
+		{method: http.MethodPost, target: "/api/v1/test?receiver=other", body: string(payload), want: http.StatusNotFound, wantBody: "receiver missing: other"},
+	} {
+		w := serve(handler, tc.method, tc.target, tc.body)
+		require.Equal(t, tc.want, w.Code, tc.target)
+		require.Contains(t, w.Body.String(), tc.wantBody, tc.target)
+	}
+}
+
+func TestValidateConfigHandler(t *testing.T) {
+	// The candidate references files that do not exist: they must not be read.
+	const candidate = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password_file: /nonexistent/password
+  project: $(JIRALERT_UNSET_PROJECT)
+  issue_type: Bug
+  summary: '{{ template "jira.summary" . }}'
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: /nonexistent/jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+`
+	running, err := template.ParseTemplate(`{{ define "jira.summary" }}running{{ end }}`, log.NewNopLogger())
+	require.NoError(t, err)
+	handler := ValidateConfigHandlerFunc(running, log.NewNopLogger())
+
+	for _, tc := range []struct {
+		name     string
+		template string
+		want     int
+		wantBody string
+	}{
+		{name: "running template", want: http.StatusOK, wantBody: `{"status":"success","data":{"receivers":["jira-ab"]}}`},
+		{name: "template part", template: `{{ define "jira.summary" }}candidate{{ end }}`, want: http.StatusOK},
+		{name: "invalid template part", template: `{{ define "jira.summary" }}`, want: http.StatusUnprocessableEntity, wantBody: "invalid template"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var req *http.Request
+			if tc.template == "" {
+				req = httptest.NewRequest(http.MethodPost, "/api/v1/validate-config", strings.NewReader(candidate))
+			} else {
+				var body bytes.Buffer
+				mw := multipart.NewWriter(&body)
+				require.NoError(t, mw.WriteField("config", candidate))
+				require.NoError(t, mw.WriteField("template", tc.template))
+				require.NoError(t, mw.Close())
+				req = httptest.NewRequest(http.MethodPost, "/api/v1/validate-config", &body)
+				req.Header.Set("Content-Type", mw.FormDataContentType())
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			require.Equal(t, tc.want, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), tc.wantBody)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// cloudAssetsURL is the API of Jira Cloud's Assets, shared by all instances.
+const cloudAssetsURL = "https://api.atlassian.com/jsm/assets/workspace/"
+
+// assetsObjects caches the results of Assets queries, by API URL and query, and the Assets workspaces of Jira Cloud
+// instances, by API URL.
+var assetsObjects = &assetsCache{ttl: time.Hour, objects: map[string]assetsCacheEntry{}, workspaces: map[string]string{}}
+
+type assetsCacheEntry struct {
+	values  []interface{}
+	expires time.Time
+}
+
+// assetsCache remembers the field values found by Assets queries for ttl, so alerts about the same objects do not
+// query Assets over and over. Failed queries are not remembered.
+type assetsCache struct {
+	ttl time.Duration
+
+	mtx        sync.Mutex
+	objects    map[string]assetsCacheEntry
+	workspaces map[string]string
+}
+
+func (c *assetsCache) get(key string) ([]interface{}, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.objects[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.objects, key)
+		return nil, false
+	}
+	return e.values, true
+}
+
+func (c *assetsCache) set(key string, values []interface{}) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.objects[key] = assetsCacheEntry{values: values, expires: time.Now().Add(c.ttl)}
+}
+
+// serviceManagementIssueService adds the lookups of Jira Service Management features, e.g. Assets (formerly Insight)
+// objects and organizations, to the issue service of a Jira instance.
+type serviceManagementIssueService struct {
+	notify.Ticketer
+	client *jira.Client
+	apiURL string
+	cloud  bool
+}
+
+// GetCreateMetaWithOptionsWithContext implements notify.CreateMetaGetter, which the embedded Ticketer does not expose.
+func (s *serviceManagementIssueService) GetCreateMetaWithOptionsWithContext(ctx context.Context, options *jira.GetQueryOptions) (*jira.CreateMetaInfo, *jira.Response, error) {
+	return s.client.Issue.GetCreateMetaWithOptionsWithContext(ctx, options)
+}
+
+// SearchAssetsWithContext implements notify.AssetsSearcher. Jira Cloud references objects by workspace and object
+// ID, Server and Data Center by object key.
+func (s *serviceManagementIssueService) SearchAssetsWithContext(ctx context.Context, workspaceID, aql string, limit int) ([]interface{}, error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", s.apiURL, workspaceID, aql, limit)
+	if values, ok := assetsObjects.get(key); ok {
+		return values, nil
+	}
+
+	var (
+		values []interface{}
+		err    error
+	)
+	if s.cloud {
+		values, err = s.searchCloud(ctx, workspaceID, aql, limit)
+	} else {
+		values, err = s.searchServer(ctx, aql, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	assetsObjects.set(key, values)
+	return values, nil
+}
+
+func (s *serviceManagementIssueService) searchServer(ctx context.Context, aql string, limit int) ([]interface{}, error) {
+	q := url.Values{"qlQuery": {aql}, "resultPerPage": {strconv.Itoa(limit)}}
+	req, err := s.client.NewRequestWithContext(ctx, "GET", "rest/assets/1.0/aql/objects?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		ObjectEntries []struct {
+			ObjectKey string `json:"objectKey"`
+		} `json:"objectEntries"`
+	}
+	if _, err := s.client.Do(req, &res); err != nil {
+		return nil, errors.Wrap(err, "search Assets objects")
+	}
+	values := []interface{}{}
+	for i, o := range res.ObjectEntries {
+		if i == limit {
+			break
+		}
+		values = append(values, map[string]interface{}{"key": o.ObjectKey})
+	}
+	return values, nil
+}
+
+func (s *serviceManagementIssueService) searchCloud(ctx context.Context, workspaceID, aql string, limit int) ([]interface{}, error) {
+	if workspaceID == "" {
+		var err error
+		if workspaceID, err = s.workspace(ctx); err != nil {
+			return nil, err
+		}
+	}
+	q := url.Values{"startAt": {"0"}, "maxResults": {strconv.Itoa(limit)}}
+	req, err := s.client.NewRequestWithContext(ctx, "POST", cloudAssetsURL+url.PathEscape(workspaceID)+"/v1/object/aql?"+q.Encode(), map[string]string{"qlQuery": aql})
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Values []struct {
+			ID string `json:"id"`
+		} `json:"values"`
+	}
+	if _, err := s.client.Do(req, &res); err != nil {
+		return nil, errors.Wrap(err, "search Assets objects")
+	}
+	values := []interface{}{}
+	for i, o := range res.Values {
+		if i == limit {
+			break
+		}
+		values = append(values, map[string]interface{}{"workspaceId": workspaceID, "id": workspaceID + ":" + o.ID, "objectId": o.ID})
+	}
+	return values, nil
+}
+
+// workspace returns the Assets workspace of a Jira Cloud instance.
+func (s *serviceManagementIssueService) workspace(ctx context.Context) (string, error) {
+	assetsObjects.mtx.Lock()
+	workspaceID, ok := assetsObjects.workspaces[s.apiURL]
+	assetsObjects.mtx.Unlock()
+	if ok {
+		return workspaceID, nil
+	}
+
+	req, err := s.client.NewRequestWithContext(ctx, "GET", "rest/servicedeskapi/assets/workspace", nil)
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		Values []struct {
+			WorkspaceID string `json:"workspaceId"`
+		} `json:"values"`
+	}
+	if _, err := s.client.Do(req, &res); err != nil {
+		return "", errors.Wrap(err, "get Assets workspace")
+	}
+	if len(res.Values) == 0 {
+		return "", errors.New("no Assets workspace found")
+	}
+	workspaceID = res.Values[0].WorkspaceID
+
+	assetsObjects.mtx.Lock()
+	assetsObjects.workspaces[s.apiURL] = workspaceID
+	assetsObjects.mtx.Unlock()
+	return workspaceID, nil
+}
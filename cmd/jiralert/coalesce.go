@@ -0,0 +1,188 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+const (
+	// coalesceMaxRetries is how often a held notification failing with an error that may go away on retry is retried
+	// before it is given up on.
+	coalesceMaxRetries = 5
+	// coalesceMaxBackoff caps the delay before the retries of a held notification, which doubles from the window.
+	coalesceMaxBackoff = 10 * time.Minute
+)
+
+// coalescer holds notifications for a window and replaces them by further deliveries for the same alert group, so
+// rapid-fire updates from Alertmanager result in a single Jira operation. Held notifications are persisted to the
+// state, so they survive restarts, and handled in the background once the window passed: deliveries do not wait for
+// them.
+type coalescer struct {
+	window time.Duration
+	state  *notify.State
+	// notify handles a held notification, final telling it is not retried on failure.
+	notify func(ctx context.Context, data *alertmanager.Data, final bool) (bool, error)
+	logger log.Logger
+
+	mtx     sync.Mutex
+	pending map[string]*heldNotification
+}
+
+// heldNotification is a notification held by the coalescer.
+type heldNotification struct {
+	ctx     context.Context
+	data    alertmanager.Data
+	retries int
+}
+
+func newCoalescer(window time.Duration, state *notify.State, notify func(context.Context, *alertmanager.Data, bool) (bool, error), logger log.Logger) *coalescer {
+	return &coalescer{window: window, state: state, notify: notify, logger: logger, pending: map[string]*heldNotification{}}
+}
+
+// Enabled reports whether notifications are held at all.
+func (c *coalescer) Enabled() bool {
+	return c.window > 0
+}
+
+// Hold holds the notification for the window and reports whether it replaced the held notification of the same
+// receiver and alert group: Alertmanager sends all alerts of a group with each delivery, so the newest one is
+// current. The error tells the notification could not be persisted, so the delivery must be retried.
+func (c *coalescer) Hold(ctx context.Context, data *alertmanager.Data) (bool, error) {
+	key := data.Receiver + "/" + data.GroupKey
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.state.Coalesce(key, *data); err != nil {
+		return false, err
+	}
+	if held, ok := c.pending[key]; ok {
+		held.ctx, held.data = ctx, *data
+		return true, nil
+	}
+	c.holdLocked(key, &heldNotification{ctx: ctx, data: *data}, c.window)
+	return false, nil
+}
+
+// Restore holds the notifications persisted to the state before a restart again.
+func (c *coalescer) Restore() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for key, data := range c.state.Coalesced() {
+		c.holdLocked(key, &heldNotification{ctx: context.Background(), data: data}, c.window)
+	}
+}
+
+func (c *coalescer) holdLocked(key string, held *heldNotification, delay time.Duration) {
+	c.pending[key] = held
+	time.AfterFunc(delay, func() { c.flush(key) })
+}
+
+// flush handles the held notification of the given key. If it fails with an error that may go away on retry, it is
+// held again with a growing delay, up to coalesceMaxRetries times, unless a newer delivery replaced it meanwhile.
+func (c *coalescer) flush(key string) {
+	c.mtx.Lock()
+	held := c.pending[key]
+	delete(c.pending, key)
+	c.mtx.Unlock()
+
+	final := held.retries >= coalesceMaxRetries
+	retry, err := c.notify(held.ctx, &held.data, final)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	newer, replaced := c.pending[key]
+	if err == nil || !retry || final {
+		if !replaced {
+			c.state.ReleaseCoalesced(key, c.logger)
+		}
+		return
+	}
+	held.retries++
+	if replaced {
+		// The newer delivery is retried instead, within the same bound.
+		newer.retries = held.retries
+		return
+	}
+	c.holdLocked(key, held, c.backoff(held.retries))
+}
+
+// backoff returns the delay before the given retry of a held notification.
+func (c *coalescer) backoff(retries int) time.Duration {
+	d := c.window
+	for i := 0; i < retries && d < coalesceMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > coalesceMaxBackoff {
+		return coalesceMaxBackoff
+	}
+	return d
+}
+
+// notifyCoalesced handles a notification held by the coalescer like a webhook request. Failures are reported unless
+// they are retried by the coalescer, i.e. unless they may go away on retry and the attempt is not final.
+func (h *payloadHandler) notifyCoalesced(ctx context.Context, data *alertmanager.Data, final bool) (bool, error) {
+	pendingNotifications.Inc()
+	defer pendingNotifications.Dec()
+
+	confs := h.cfg.ReceiversFor(data.Receiver)
+	if len(confs) == 0 {
+		err := errors.Errorf("receiver missing: %s", data.Receiver)
+		h.failCoalesced(http.StatusNotFound, err, unknownReceiver, data)
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	var (
+		failed    string
+		failRetry bool
+		failErr   error
+	)
+	for _, conf := range confs {
+		_, retry, err := notifyWithFallback(ctx, h.cfg, conf, h.tmpl, h.state, h.retries, data, h.logger)
+		if err != nil && failErr == nil {
+			failed, failRetry, failErr = conf.Name, retry, err
+		}
+	}
+	if failErr != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case failRetry && !final:
+			// Held again and retried.
+			status = http.StatusServiceUnavailable
+		case failRetry:
+			failErr = errors.Wrapf(failErr, "giving up after %d retries", coalesceMaxRetries)
+		}
+		h.failCoalesced(status, failErr, failed, data)
+		return failRetry, failErr
+	}
+	if !anyPaused(h.state, confs) {
+		h.payloads.Add(payloadHash(data), time.Now())
+	}
+	return false, nil
+}
+
+// failCoalesced reports a failed coalesced notification. Unlike for payloads, the request was counted when it was held.
+func (h *payloadHandler) failCoalesced(status int, err error, receiver string, data *alertmanager.Data) {
+	reportFailure(status, err, receiver, data, h.failures, h.deadLetters, h.logger)
+	level.Error(h.logger).Log("msg", "error handling coalesced notification", "statusCode", status, "err", err, "receiver", receiver, "groupKeyHash", notify.GroupKeyHash(data.GroupKey))
+}
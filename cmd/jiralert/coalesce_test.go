@@ -0,0 +1,146 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/stretchr/testify/require"
+)
+
+// coalesceRecorder records the notifications handled by a coalescer, failing as told by fail.
+type coalesceRecorder struct {
+	mtx      sync.Mutex
+	notified []alertmanager.Data
+	final    []bool
+	fail     func(n int) bool
+}
+
+func (r *coalesceRecorder) notify(_ context.Context, data *alertmanager.Data, final bool) (bool, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.notified = append(r.notified, *data)
+	r.final = append(r.final, final)
+	if r.fail != nil && r.fail(len(r.notified)) {
+		return true, errors.New("jira is down")
+	}
+	return false, nil
+}
+
+func (r *coalesceRecorder) notifications() []alertmanager.Data {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]alertmanager.Data{}, r.notified...)
+}
+
+func TestCoalescer(t *testing.T) {
+	state := notify.NewState()
+	// The first attempt fails with an error that may go away on retry.
+	rec := &coalesceRecorder{fail: func(n int) bool { return n == 1 }}
+	c := newCoalescer(50*time.Millisecond, state, rec.notify, log.NewNopLogger())
+
+	// Deliveries are held without waiting for the window, the newest one replacing the held one.
+	start := time.Now()
+	merged, err := c.Hold(context.Background(), &alertmanager.Data{Receiver: "jira", GroupKey: "a", Status: alertmanager.AlertFiring, Alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring, Fingerprint: "1"}}})
+	require.NoError(t, err)
+	require.False(t, merged)
+	newest := alertmanager.Data{Receiver: "jira", GroupKey: "a", Status: alertmanager.AlertFiring, Alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring, Fingerprint: "2"}}}
+	merged, err = c.Hold(context.Background(), &newest)
+	require.NoError(t, err)
+	require.True(t, merged)
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+	require.Empty(t, rec.notifications())
+	require.Equal(t, map[string]alertmanager.Data{"jira/a": newest}, state.Coalesced())
+
+	// The newest delivery is handled, and retried after the retryable failure. Alerts only in older deliveries are not.
+	require.Eventually(t, func() bool { return len(rec.notifications()) == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []alertmanager.Data{newest, newest}, rec.notifications())
+	require.Eventually(t, func() bool { return len(state.Coalesced()) == 0 }, time.Second, 10*time.Millisecond)
+
+	// Deliveries of other receivers are held separately.
+	_, err = c.Hold(context.Background(), &alertmanager.Data{Receiver: "other", GroupKey: "a", Status: alertmanager.AlertFiring})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(rec.notifications()) == 3 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "other", rec.notifications()[2].Receiver)
+	time.Sleep(100 * time.Millisecond)
+	require.Len(t, rec.notifications(), 3)
+}
+
+func TestCoalescerRetries(t *testing.T) {
+	state := notify.NewState()
+	rec := &coalesceRecorder{fail: func(int) bool { return true }}
+	c := newCoalescer(time.Millisecond, state, rec.notify, log.NewNopLogger())
+
+	_, err := c.Hold(context.Background(), &alertmanager.Data{Receiver: "jira", GroupKey: "a", Status: alertmanager.AlertFiring})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(state.Coalesced()) == 0 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	// The notification is given up on after the last retry, which is told to be final.
+	rec.mtx.Lock()
+	defer rec.mtx.Unlock()
+	require.Len(t, rec.notified, coalesceMaxRetries+1)
+	want := make([]bool, coalesceMaxRetries+1)
+	want[coalesceMaxRetries] = true
+	require.Equal(t, want, rec.final)
+}
+
+func TestCoalescerBackoff(t *testing.T) {
+	c := newCoalescer(time.Minute, notify.NewState(), nil, log.NewNopLogger())
+	require.Equal(t, 2*time.Minute, c.backoff(1))
+	require.Equal(t, 8*time.Minute, c.backoff(3))
+	require.Equal(t, coalesceMaxBackoff, c.backoff(4))
+	require.Equal(t, coalesceMaxBackoff, c.backoff(100))
+}
+
+func TestCoalescerRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := notify.LoadState(path)
+	require.NoError(t, err)
+	data := alertmanager.Data{Receiver: "jira", GroupKey: "a", Status: alertmanager.AlertFiring, Alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring, Fingerprint: "1"}}}
+	_, err = newCoalescer(time.Hour, state, nil, log.NewNopLogger()).Hold(context.Background(), &data)
+	require.NoError(t, err)
+
+	// The held notification is handled after a restart.
+	restarted, err := notify.LoadState(path)
+	require.NoError(t, err)
+	rec := &coalesceRecorder{}
+	newCoalescer(time.Millisecond, restarted, rec.notify, log.NewNopLogger()).Restore()
+	require.Eventually(t, func() bool { return len(rec.notifications()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, data, rec.notifications()[0])
+	require.Eventually(t, func() bool { return len(restarted.Coalesced()) == 0 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, restarted.Flush())
+	restarted, err = notify.LoadState(path)
+	require.NoError(t, err)
+	require.Empty(t, restarted.Coalesced())
+
+	// Deliveries that cannot be held durably fail, so Alertmanager retries them.
+	state, err = notify.LoadState(filepath.Join(t.TempDir(), "missing", "state.json"))
+	require.NoError(t, err)
+	_, err = newCoalescer(time.Hour, state, nil, log.NewNopLogger()).Hold(context.Background(), &data)
+	require.Error(t, err)
+}
+
+func TestCoalescerDisabled(t *testing.T) {
+	require.False(t, newCoalescer(0, notify.NewState(), nil, log.NewNopLogger()).Enabled())
+	require.True(t, newCoalescer(time.Second, notify.NewState(), nil, log.NewNopLogger()).Enabled())
+}
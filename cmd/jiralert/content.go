@@ -39,6 +39,9 @@ const (
           body > * { margin: 15px; padding: 0; }
           pre { padding: 10px; font-size: 13px; background-color: #f5f5f5; border: 1px solid #ccc; }
           h1, h2 { font-weight: 500; }
+          table { border-collapse: collapse; }
+          th, td { padding: 5px 10px; text-align: left; border-bottom: 1px solid #ddd; }
+          .error { color: #a94442; }
           a { color: #337ab7; }
           a:hover, a:focus { color: #23527c; }
         </style>
@@ -46,7 +49,9 @@ const (
       <body>
         <div class="navbar">
           <div class="navbar-header"><a href="/">JIRAlert</a></div>
+          <div><a href="/status">Status</a></div>
           <div><a href="/config">Configuration</a></div>
+          <div><a href="/playground">Playground</a></div>
           <div><a href="/metrics">Metrics</a></div>
           <div><a href="/debug/pprof">Profiling</a></div>
           <div><a href="{{ .DocsURL }}">Help</a></div>
@@ -62,11 +67,71 @@ const (
         <a href="https://prometheus.io/docs/alerting/alertmanager/">Prometheus Alertmanager</a>.
     {{- end }}
 
+    {{ define "content.status" -}}
+      <h2>Receivers</h2>
+      <table>
+        <tr><th>Name</th><th>Jira</th><th>Project</th><th>Errors</th></tr>
+        {{- range .Receivers }}
+        <tr><td>{{ .Name }}</td><td>{{ .APIURL }}</td><td>{{ .Project }}</td><td>{{ .Errors }}</td></tr>
+        {{- end }}
+      </table>
+      <h2>Recent notifications</h2>
+      <table>
+        <tr><th>Time</th><th>Receiver</th><th>Group</th><th>Outcome</th><th>Issues</th><th>Duration</th></tr>
+        {{- range .Notifications }}
+        <tr>
+          <td>{{ .Time.UTC.Format "2006-01-02 15:04:05" }}</td>
+          <td>{{ .Receiver }}</td>
+          <td>{{ range $i, $p := .GroupLabels.SortedPairs }}{{ if $i }}, {{ end }}{{ $p.Name }}="{{ $p.Value }}"{{ end }}</td>
+          <td{{ if not .OK }} class="error"{{ end }}>{{ .Status }}</td>
+          <td>{{ range $i, $k := .IssueKeys }}{{ if $i }}, {{ end }}{{ $k }}{{ end }}</td>
+          <td>{{ .Duration }}</td>
+        </tr>
+        {{- else }}
+        <tr><td colspan="6">No notifications received since startup.</td></tr>
+        {{- end }}
+      </table>
+    {{- end }}
+
     {{ define "content.config" -}}
       <h2>Configuration</h2>
       <pre>{{ .Config }}</pre>
     {{- end }}
 
+    {{ define "content.playground" -}}
+      {{- with .Playground }}
+      <h2>Template playground</h2>
+      <p>Renders the issues a receiver would create for an Alertmanager webhook payload, without contacting Jira.
+        Leave summary and description empty to use the receiver's templates.</p>
+      <form method="post" action="/playground">
+        <p>Receiver <select name="receiver">
+          {{- range .Receivers }}<option{{ if eq . $.Playground.Receiver }} selected{{ end }}>{{ . }}</option>{{ end -}}
+        </select></p>
+        <p>Payload<br><textarea name="payload" rows="20" cols="120">{{ .Payload }}</textarea></p>
+        <p>Summary<br><textarea name="summary" rows="2" cols="120">{{ .Summary }}</textarea></p>
+        <p>Description<br><textarea name="description" rows="6" cols="120">{{ .Description }}</textarea></p>
+        <p><input type="submit" value="Render"></p>
+      </form>
+      {{- if .Err }}
+      <h2>Error</h2>
+      <pre class="error">{{ .Err }}</pre>
+      {{- end }}
+      {{- range .Issues }}
+      <h2>{{ .Project }}: {{ .Summary }}</h2>
+      <h3>Description</h3>
+      <pre>{{ .Description }}</pre>
+      <h3>Fields</h3>
+      <pre>{{ .Fields }}</pre>
+      {{- end }}
+      {{- if .Queries }}
+      <h2>JQL</h2>
+      {{- range .Queries }}
+      <pre>{{ . }}</pre>
+      {{- end }}
+      {{- end }}
+      {{- end }}
+    {{- end }}
+
     {{ define "content.error" -}}
       <h2>Error</h2>
       <pre>{{ .Err }}</pre>
@@ -82,12 +147,26 @@ type tdata struct {
 
 	// `/error` only
 	Err error
+
+	// `/status` only
+	Receivers     []receiverStatus
+	Notifications []notificationRecord
+
+	// `/playground` only
+	Playground *playground
+}
+
+type receiverStatus struct {
+	Name, APIURL, Project string
+	Errors                int
 }
 
 var (
-	allTemplates   = template.Must(template.New("").Parse(templates))
-	homeTemplate   = pageTemplate("home")
-	configTemplate = pageTemplate("config")
+	allTemplates       = template.Must(template.New("").Parse(templates))
+	homeTemplate       = pageTemplate("home")
+	configTemplate     = pageTemplate("config")
+	statusTemplate     = pageTemplate("status")
+	playgroundTemplate = pageTemplate("playground")
 	// errorTemplate  = pageTemplate("error")
 )
 
@@ -130,3 +209,32 @@ func ConfigHandlerFunc(config *config.Config) func(http.ResponseWriter, *http.Re
 		}
 	}
 }
+
+// StatusHandlerFunc is the HTTP handler for the `/status` page. It lists the configured receivers and the most recent
+// notifications.
+func StatusHandlerFunc(config *config.Config, notifications *notificationLog) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("only GET allowed"))
+			return
+		}
+
+		receivers := make([]receiverStatus, 0, len(config.Receivers))
+		for _, rc := range config.Receivers {
+			receivers = append(receivers, receiverStatus{
+				Name:    rc.Name,
+				APIURL:  rc.APIURL,
+				Project: rc.Project,
+				Errors:  notifications.Errors(rc.Name),
+			})
+		}
+		if err := statusTemplate.Execute(w, &tdata{
+			DocsURL:       docsURL,
+			Receivers:     receivers,
+			Notifications: notifications.Recent(),
+		}); err != nil {
+			w.WriteHeader(500)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// credentialFiles remembers the content of credential files re-read after Jira rejected the credentials, by path, so
+// clients created after a rotation use the new credentials rather than those read on startup.
+var credentialFiles = &credentialCache{secrets: map[string]config.Secret{}}
+
+type credentialCache struct {
+	mtx     sync.Mutex
+	secrets map[string]config.Secret
+}
+
+// get returns the last content read from the file at path, or loaded if it was not re-read yet.
+func (c *credentialCache) get(path string, loaded config.Secret) config.Secret {
+	if path == "" {
+		return loaded
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if s, ok := c.secrets[path]; ok {
+		return s
+	}
+	return loaded
+}
+
+// refresh re-reads the file at path and returns its content.
+func (c *credentialCache) refresh(path string) (config.Secret, error) {
+	s, err := config.ReadSecretFile(path)
+	if err != nil {
+		return "", err
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.secrets[path] = s
+	return s, nil
+}
+
+// authTransport authenticates Jira requests with basic auth or, without a user, a personal access token. If Jira
+// responds 401 Unauthorized and the password or token is read from a file, the file is re-read and the request is
+// retried once with the new credentials, so rotating them does not fail notifications until JIRAlert is restarted.
+type authTransport struct {
+	user                    string
+	password, token         config.Secret
+	passwordFile, tokenFile string
+	base                    http.RoundTripper
+}
+
+func newAuthTransport(conf *config.ReceiverConfig, base http.RoundTripper) *authTransport {
+	t := &authTransport{base: base}
+	if conf.User != "" && conf.Password != "" {
+		t.user, t.password, t.passwordFile = conf.User, conf.Password, conf.PasswordFile
+	} else {
+		t.token, t.tokenFile = conf.PersonalAccessToken, conf.PersonalAccessTokenFile
+	}
+	return t
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	file, secret := t.tokenFile, t.token
+	if t.user != "" {
+		file, secret = t.passwordFile, t.password
+	}
+	secret = credentialFiles.get(file, secret)
+
+	resp, err := t.base.RoundTrip(t.authenticate(req, secret))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || file == "" {
+		return resp, err
+	}
+	// Requests with a body can only be retried if it can be read again.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	refreshed, rerr := credentialFiles.refresh(file)
+	if rerr != nil || refreshed == secret {
+		return resp, nil
+	}
+	retry := t.authenticate(req, refreshed)
+	if req.GetBody != nil {
+		body, berr := req.GetBody()
+		if berr != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	credentialRefreshesTotal.Inc()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// authenticate returns a copy of req carrying the credentials, as round trippers must not modify requests.
+func (t *authTransport) authenticate(req *http.Request, secret config.Secret) *http.Request {
+	r := req.Clone(req.Context())
+	if t.user != "" {
+		r.SetBasicAuth(t.user, string(secret))
+	} else {
+		r.Header.Set("Authorization", "Bearer "+string(secret))
+	}
+	return r
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthTransportRefresh(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		mtx.Lock()
+		requests = append(requests, password+":"+string(body))
+		mtx.Unlock()
+		if password != "new" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name         string
+		body         func() io.Reader
+		want         int
+		wantRequests []string
+	}{
+		{
+			name:         "rewindable body",
+			body:         func() io.Reader { return strings.NewReader("payload") },
+			want:         http.StatusOK,
+			wantRequests: []string{"old:payload", "new:payload"},
+		},
+		{
+			name:         "without body",
+			body:         func() io.Reader { return nil },
+			want:         http.StatusOK,
+			wantRequests: []string{"old:", "new:"},
+		},
+		{
+			// The body was consumed by the first attempt and cannot be sent again.
+			name:         "body that cannot be read again",
+			body:         func() io.Reader { return io.MultiReader(strings.NewReader("payload")) },
+			want:         http.StatusUnauthorized,
+			wantRequests: []string{"old:payload"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mtx.Lock()
+			requests = nil
+			mtx.Unlock()
+			passwordFile := filepath.Join(t.TempDir(), "password")
+			require.NoError(t, os.WriteFile(passwordFile, []byte("new"), 0o600))
+			conf := &config.ReceiverConfig{User: "jiralert", Password: "old", PasswordFile: passwordFile}
+			client := &http.Client{Transport: newAuthTransport(conf, http.DefaultTransport)}
+
+			req, err := http.NewRequest(http.MethodPost, srv.URL, tc.body())
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			require.Equal(t, tc.want, resp.StatusCode)
+			mtx.Lock()
+			require.Equal(t, tc.wantRequests, requests)
+			mtx.Unlock()
+		})
+	}
+}
+
+func TestAuthTransportUnchangedFile(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	// The file still holds the rejected token, so the request is not retried.
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0o600))
+	conf := &config.ReceiverConfig{PersonalAccessToken: "token", PersonalAccessTokenFile: tokenFile}
+	client := &http.Client{Transport: newAuthTransport(conf, http.DefaultTransport)}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, 1, requests)
+}
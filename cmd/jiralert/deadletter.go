@@ -0,0 +1,184 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+var (
+	errDeadLetterNotFound = errors.New("dead letter not found")
+	errDeadLetterCorrupt  = errors.New("corrupt dead letter")
+	deadLetterIDRe        = regexp.MustCompile(`^[0-9a-f]{16}$`)
+)
+
+// deadLetter is a permanently failed notification kept for inspection and replay.
+type deadLetter struct {
+	ID       string            `json:"id"`
+	Time     time.Time         `json:"time"`
+	Receiver string            `json:"receiver"`
+	Status   int               `json:"status"`
+	Error    string            `json:"error"`
+	Data     alertmanager.Data `json:"data"`
+}
+
+// deadLetterStore persists dead letters as one JSON file each in a directory, so they survive restarts. Once it holds
+// max dead letters, the oldest ones are dropped.
+type deadLetterStore struct {
+	dir string
+	max int
+
+	mtx sync.Mutex
+	// ids are the IDs of the stored dead letters, oldest first.
+	ids []string
+}
+
+func newDeadLetterStore(dir string, max int) (*deadLetterStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &deadLetterStore{dir: dir, max: max}
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if id != e.Name() && deadLetterIDRe.MatchString(id) {
+			s.ids = append(s.ids, id)
+		}
+	}
+	sort.Strings(s.ids)
+	deadLettersGauge.Set(float64(len(s.ids)))
+	return s, nil
+}
+
+func (s *deadLetterStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Add stores the dead letter, assigning it an ID.
+func (s *deadLetterStore) Add(dl deadLetter) error {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	dl.ID = fmt.Sprintf("%016x", dl.Time.UnixNano())
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path(dl.ID), b, 0o600); err != nil {
+		return err
+	}
+	s.ids = append(s.ids, dl.ID)
+
+	for s.max > 0 && len(s.ids) > s.max {
+		if err := s.remove(s.ids[0]); err != nil {
+			return err
+		}
+		deadLettersDroppedTotal.Inc()
+	}
+	deadLettersGauge.Set(float64(len(s.ids)))
+	return nil
+}
+
+// Get returns the dead letter with the given ID.
+func (s *deadLetterStore) Get(id string) (deadLetter, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.get(id)
+}
+
+func (s *deadLetterStore) get(id string) (deadLetter, error) {
+	var dl deadLetter
+	if s.index(id) < 0 {
+		return dl, errDeadLetterNotFound
+	}
+	b, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return dl, err
+	}
+	if err := json.Unmarshal(b, &dl); err != nil {
+		return deadLetter{ID: id}, fmt.Errorf("%w %s: %v", errDeadLetterCorrupt, id, err)
+	}
+	return dl, nil
+}
+
+// List returns all dead letters, oldest first. If receiver is not empty, only its dead letters are returned. Corrupt
+// dead letters are returned with only their ID and the error, so they can be inspected and purged like the others.
+func (s *deadLetterStore) List(receiver string) ([]deadLetter, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	res := make([]deadLetter, 0, len(s.ids))
+	for _, id := range s.ids {
+		dl, err := s.get(id)
+		if errors.Is(err, errDeadLetterCorrupt) {
+			dl.Error = err.Error()
+		} else if err != nil {
+			return nil, err
+		}
+		if receiver == "" || dl.Receiver == receiver {
+			res = append(res, dl)
+		}
+	}
+	return res, nil
+}
+
+// Remove deletes the dead letter with the given ID.
+func (s *deadLetterStore) Remove(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.remove(id); err != nil {
+		return err
+	}
+	deadLettersGauge.Set(float64(len(s.ids)))
+	return nil
+}
+
+func (s *deadLetterStore) remove(id string) error {
+	i := s.index(id)
+	if i < 0 {
+		return errDeadLetterNotFound
+	}
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.ids = append(s.ids[:i], s.ids[i+1:]...)
+	return nil
+}
+
+// index returns the position of id in s.ids, or -1.
+func (s *deadLetterStore) index(id string) int {
+	i := sort.SearchStrings(s.ids, id)
+	if i < len(s.ids) && s.ids[i] == id {
+		return i
+	}
+	return -1
+}
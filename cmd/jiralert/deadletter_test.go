@@ -0,0 +1,195 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// testDeadLetter returns a dead letter of a firing alert of the given receiver, failed at the given time.
+func testDeadLetter(receiver, alertname string, at time.Time) deadLetter {
+	return deadLetter{
+		Time:     at,
+		Receiver: receiver,
+		Status:   http.StatusInternalServerError,
+		Error:    "JIRA request failed",
+		Data: alertmanager.Data{
+			Receiver:    receiver,
+			Status:      alertmanager.AlertFiring,
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			GroupLabels: alertmanager.KV{"alertname": alertname},
+		},
+	}
+}
+
+func deadLetterIDs(t *testing.T, s *deadLetterStore, receiver string) []string {
+	dls, err := s.List(receiver)
+	require.NoError(t, err)
+	ids := []string{}
+	for _, dl := range dls {
+		ids = append(ids, dl.ID)
+	}
+	return ids
+}
+
+func TestDeadLetterStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newDeadLetterStore(dir, 2)
+	require.NoError(t, err)
+
+	dropped := testutil.ToFloat64(deadLettersDroppedTotal)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, receiver := range []string{"a", "b", "a"} {
+		require.NoError(t, s.Add(testDeadLetter(receiver, "Alert", start.Add(time.Duration(i)*time.Second))))
+	}
+	// IDs are the failure times in nanoseconds, in hex.
+	first, second, third := "17a668b730013200", "17a668b76b9bfc00", "17a668b7a736c600"
+
+	// Beyond the maximum, the oldest dead letter is dropped.
+	require.Equal(t, []string{second, third}, deadLetterIDs(t, s, ""))
+	require.Equal(t, []string{third}, deadLetterIDs(t, s, "a"))
+	require.Equal(t, 2.0, testutil.ToFloat64(deadLettersGauge))
+	require.Equal(t, dropped+1, testutil.ToFloat64(deadLettersDroppedTotal))
+	_, err = s.Get(first)
+	require.ErrorIs(t, err, errDeadLetterNotFound)
+
+	dl, err := s.Get(third)
+	require.NoError(t, err)
+	want := testDeadLetter("a", "Alert", start.Add(2*time.Second))
+	want.ID = third
+	require.Equal(t, want, dl)
+
+	// The dead letters survive restarts.
+	s, err = newDeadLetterStore(dir, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{second, third}, deadLetterIDs(t, s, ""))
+
+	require.NoError(t, s.Remove(second))
+	require.ErrorIs(t, s.Remove(second), errDeadLetterNotFound)
+	require.Equal(t, []string{third}, deadLetterIDs(t, s, ""))
+	require.Equal(t, 1.0, testutil.ToFloat64(deadLettersGauge))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestDeadLetterStoreCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newDeadLetterStore(dir, 0)
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.Add(testDeadLetter("a", "Alert", start)))
+	require.NoError(t, s.Add(testDeadLetter("a", "Alert", start.Add(time.Second))))
+	ids := deadLetterIDs(t, s, "")
+	require.NoError(t, os.WriteFile(s.path(ids[0]), []byte(`{"id":`), 0o600))
+
+	// The corrupt dead letter does not hide the others, and is listed with its error.
+	_, err = s.Get(ids[0])
+	require.ErrorIs(t, err, errDeadLetterCorrupt)
+	dls, err := s.List("")
+	require.NoError(t, err)
+	require.Len(t, dls, 2)
+	require.Equal(t, ids[0], dls[0].ID)
+	require.Contains(t, dls[0].Error, "corrupt dead letter "+ids[0])
+	require.Equal(t, "a", dls[1].Receiver)
+	require.Equal(t, []string{ids[1]}, deadLetterIDs(t, s, "a"))
+
+	// It fails to replay, but can be purged.
+	handler := ReplayHandlerFunc(&config.Config{}, template.SimpleTemplate(), notify.NewState(), s, log.NewNopLogger())
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/replay/"+ids[0], nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/replay", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"`+ids[0]+`":"corrupt dead letter `+ids[0])
+
+	w = httptest.NewRecorder()
+	DeadLettersHandlerFunc(s)(w, httptest.NewRequest(http.MethodDelete, "/api/v1/dead-letters/"+ids[0], nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, []string{ids[1]}, deadLetterIDs(t, s, ""))
+}
+
+func TestReplayHandler(t *testing.T) {
+	fake := fakejira.New()
+	testModeJira = fake
+	defer func() { testModeJira = nil }()
+
+	reopen := config.Duration(0)
+	cfg := &config.Config{Receivers: []*config.ReceiverConfig{{
+		Name:           "jira",
+		Backend:        config.BackendJira,
+		Project:        "AB",
+		IssueType:      "Bug",
+		Summary:        `{{ .GroupLabels.alertname }}`,
+		ReopenState:    "To Do",
+		ReopenDuration: &reopen,
+	}}}
+	s, err := newDeadLetterStore(t.TempDir(), 0)
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.Add(testDeadLetter("jira", "First", start)))
+	require.NoError(t, s.Add(testDeadLetter("jira", "Second", start.Add(time.Second))))
+	require.NoError(t, s.Add(testDeadLetter("removed", "Third", start.Add(2*time.Second))))
+	ids := deadLetterIDs(t, s, "")
+	handler := ReplayHandlerFunc(cfg, template.SimpleTemplate(), notify.NewState(), s, log.NewNopLogger())
+
+	summaries := func() []string {
+		issues, _, err := fake.SearchWithContext(context.Background(), notify.Query{Project: "AB"}, nil)
+		require.NoError(t, err)
+		res := []string{}
+		for _, i := range issues {
+			res = append(res, i.Fields.Summary)
+		}
+		return res
+	}
+
+	// A single dead letter is replayed and removed.
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/replay/"+ids[0], nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, []string{"First"}, summaries())
+	require.Equal(t, ids[1:], deadLetterIDs(t, s, ""))
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/replay/"+ids[0], nil))
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	// The dead letters of receivers which no longer exist fail to replay, and are kept.
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/replay", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res struct {
+		Data replayResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, replayResult{Replayed: []string{ids[1]}, Failed: map[string]string{ids[2]: "receiver missing: removed"}}, res.Data)
+	require.ElementsMatch(t, []string{"First", "Second"}, summaries())
+	require.Equal(t, ids[2:], deadLetterIDs(t, s, ""))
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// payloadCache remembers the hashes of successfully processed notifications for a fixed window, so identical
+// resends (e.g. due to Alertmanager's repeat_interval) can be skipped.
+type payloadCache struct {
+	window time.Duration
+
+	mtx  sync.Mutex
+	seen map[string]time.Time
+}
+
+func newPayloadCache(window time.Duration) *payloadCache {
+	return &payloadCache{window: window, seen: map[string]time.Time{}}
+}
+
+// payloadHash returns a hash of the notification content. Maps are marshaled with sorted keys, so the hash does not
+// depend on the order in which Alertmanager serialized them.
+func payloadHash(data *alertmanager.Data) string {
+	b, err := json.Marshal(data)
+	if err != nil {
+		// Data only contains strings, maps of strings and times, this cannot fail.
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+// Seen reports whether the given hash was added within the window.
+func (c *payloadCache) Seen(hash string, now time.Time) bool {
+	if c.window <= 0 || hash == "" {
+		return false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t, ok := c.seen[hash]
+	return ok && now.Sub(t) < c.window
+}
+
+// Add records the given hash, dropping expired ones.
+func (c *payloadCache) Add(hash string, now time.Time) {
+	if c.window <= 0 || hash == "" {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for h, t := range c.seen {
+		if now.Sub(t) >= c.window {
+			delete(c.seen, h)
+		}
+	}
+	c.seen[hash] = now
+}
+
+// anyPaused reports whether any of the receivers is paused. Notifications only queued by paused receivers are not
+// recorded as processed, so a redelivery is queued again rather than skipped.
+func anyPaused(state *notify.State, confs []*config.ReceiverConfig) bool {
+	for _, conf := range confs {
+		if state.Paused(conf.Name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadHash(t *testing.T) {
+	// The hash does not depend on the order of the serialized maps.
+	var a, b alertmanager.Data
+	require.NoError(t, json.Unmarshal([]byte(`{"receiver":"jira","groupLabels":{"a":"1","b":"2"},"alerts":[{"labels":{"x":"1","y":"2"}}]}`), &a))
+	require.NoError(t, json.Unmarshal([]byte(`{"alerts":[{"labels":{"y":"2","x":"1"}}],"groupLabels":{"b":"2","a":"1"},"receiver":"jira"}`), &b))
+	require.Equal(t, payloadHash(&a), payloadHash(&b))
+
+	b.Status = alertmanager.AlertResolved
+	require.NotEqual(t, payloadHash(&a), payloadHash(&b))
+}
+
+func TestPayloadCache(t *testing.T) {
+	now := time.Now()
+	c := newPayloadCache(time.Minute)
+	require.False(t, c.Seen("a", now))
+	c.Add("a", now)
+	require.True(t, c.Seen("a", now.Add(59*time.Second)))
+	require.False(t, c.Seen("a", now.Add(time.Minute)))
+	require.False(t, c.Seen("b", now))
+
+	// Expired hashes are dropped.
+	c.Add("b", now.Add(time.Minute))
+	require.Len(t, c.seen, 1)
+
+	// Disabled caches never report payloads as seen.
+	c = newPayloadCache(0)
+	c.Add("a", now)
+	require.False(t, c.Seen("a", now))
+}
@@ -0,0 +1,208 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+const (
+	deploymentCloud  = "Cloud"
+	deploymentServer = "Server"
+)
+
+// jiraDeployments caches the deployment type of each Jira instance, by API URL.
+var jiraDeployments = &deploymentCache{deployments: map[string]string{}}
+
+// deploymentCache remembers the deployment type reported by the serverInfo endpoint of Jira instances. Failed
+// detections are not remembered, so they are retried with the next notification.
+type deploymentCache struct {
+	mtx         sync.Mutex
+	deployments map[string]string
+}
+
+// Get returns the deployment type of the Jira instance the client talks to, i.e. Cloud, Server or DataCenter.
+func (c *deploymentCache) Get(ctx context.Context, apiURL string, client *jira.Client) (string, error) {
+	c.mtx.Lock()
+	deployment, ok := c.deployments[apiURL]
+	c.mtx.Unlock()
+	if ok {
+		return deployment, nil
+	}
+
+	req, err := client.NewRequestWithContext(ctx, "GET", "rest/api/2/serverInfo", nil)
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		DeploymentType string `json:"deploymentType"`
+	}
+	if _, err := client.Do(req, &info); err != nil {
+		return "", errors.Wrap(err, "get Jira server info")
+	}
+	deployment = info.DeploymentType
+	if deployment == "" {
+		// Servers older than 7.x don't report a deployment type.
+		deployment = deploymentServer
+	}
+
+	c.mtx.Lock()
+	c.deployments[apiURL] = deployment
+	c.mtx.Unlock()
+	return deployment, nil
+}
+
+// jiraIssueService is the notify.Ticketer of a Jira instance, the issue service running searches as JQL.
+type jiraIssueService struct {
+	*jira.IssueService
+}
+
+// SearchWithContext runs the query as JQL.
+func (s jiraIssueService) SearchWithContext(ctx context.Context, query notify.Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	return s.IssueService.SearchWithContext(ctx, query.JQL(), options)
+}
+
+// cloudIssueService adapts the issues created on Jira Cloud, which identifies users by account ID rather than user
+// name. Both Cloud and Server accept wiki markup through the v2 API, so summaries and descriptions need no changes.
+// The v3 API, which requires descriptions and comments in the Atlassian Document Format, is not supported.
+type cloudIssueService struct {
+	jiraIssueService
+	users *jira.UserService
+}
+
+// CreateWithContext replaces the names of the assignee, the reporter and of user fields by their account IDs. Names that do not match
+// any user, e.g. because they already are account IDs, are used as account IDs as they are.
+func (s *cloudIssueService) CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	// Copy the issue, callers keep the users and fields they set.
+	fields := *issue.Fields
+	copied := *issue
+	copied.Fields = &fields
+	if a := issue.Fields.Assignee; a != nil && a.Name != "" && a.AccountID == "" {
+		accountID, resp, err := s.accountID(ctx, a.Name)
+		if err != nil {
+			return nil, resp, err
+		}
+		fields.Assignee = &jira.User{AccountID: accountID}
+	}
+	if r := issue.Fields.Reporter; r != nil && r.Name != "" && r.AccountID == "" {
+		accountID, resp, err := s.accountID(ctx, r.Name)
+		if err != nil {
+			return nil, resp, err
+		}
+		fields.Reporter = &jira.User{AccountID: accountID}
+	}
+	if len(issue.Fields.Unknowns) > 0 {
+		fields.Unknowns = make(map[string]interface{}, len(issue.Fields.Unknowns))
+		for key, value := range issue.Fields.Unknowns {
+			value, resp, err := s.resolveUsers(ctx, value)
+			if err != nil {
+				return nil, resp, err
+			}
+			fields.Unknowns[key] = value
+		}
+	}
+	return s.IssueService.CreateWithContext(ctx, &copied)
+}
+
+// AddWatcherWithContext replaces the name of the watcher by its account ID.
+func (s *cloudIssueService) AddWatcherWithContext(ctx context.Context, issueID string, userName string) (*jira.Response, error) {
+	accountID, resp, err := s.accountID(ctx, userName)
+	if err != nil {
+		return resp, err
+	}
+	return s.IssueService.AddWatcherWithContext(ctx, issueID, accountID)
+}
+
+// UpdateIssueWithContext replaces the names of user fields set by their account IDs.
+func (s *cloudIssueService) UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	fields, ok := data["fields"].(map[string]interface{})
+	if !ok {
+		return s.IssueService.UpdateIssueWithContext(ctx, jiraID, data)
+	}
+	// Copy the data, callers keep the fields they set.
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		copied[key] = value
+	}
+	resolved := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		value, resp, err := s.resolveUsers(ctx, value)
+		if err != nil {
+			return resp, err
+		}
+		resolved[key] = value
+	}
+	copied["fields"] = resolved
+	return s.IssueService.UpdateIssueWithContext(ctx, jiraID, copied)
+}
+
+// resolveUsers replaces the names of user field values by their account IDs.
+func (s *cloudIssueService) resolveUsers(ctx context.Context, value interface{}) (interface{}, *jira.Response, error) {
+	switch v := value.(type) {
+	case *notify.FieldUser:
+		if v.Name == "" || v.AccountID != "" {
+			return v, nil, nil
+		}
+		accountID, resp, err := s.accountID(ctx, v.Name)
+		if err != nil {
+			return nil, resp, err
+		}
+		return &notify.FieldUser{AccountID: accountID}, resp, nil
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, u := range v {
+			u, resp, err := s.resolveUsers(ctx, u)
+			if err != nil {
+				return nil, resp, err
+			}
+			values = append(values, u)
+		}
+		return values, nil, nil
+	}
+	return value, nil, nil
+}
+
+// accountID returns the account ID of the only user whose email address, display name or account ID is name. User
+// searches also return users matching only partially, e.g. by a prefix of their name: those are never used, as the
+// issue would be assigned to the wrong person. Names no user matches at all are returned as they are.
+func (s *cloudIssueService) accountID(ctx context.Context, name string) (string, *jira.Response, error) {
+	users, resp, err := s.users.FindWithContext(ctx, url.QueryEscape(name))
+	if err != nil {
+		return "", resp, errors.Wrapf(err, "find Jira user %q", name)
+	}
+	if len(users) == 0 {
+		return name, resp, nil
+	}
+	var matches []string
+	for _, u := range users {
+		if strings.EqualFold(u.EmailAddress, name) || strings.EqualFold(u.DisplayName, name) || u.AccountID == name {
+			matches = append(matches, u.AccountID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", resp, errors.Errorf("no Jira user matches %q exactly (partial matches: %d)", name, len(users))
+	case 1:
+		return matches[0], resp, nil
+	default:
+		return "", resp, errors.Errorf("Jira user %q is ambiguous, it matches %d users", name, len(matches))
+	}
+}
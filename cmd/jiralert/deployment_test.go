@@ -0,0 +1,67 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudAccountID(t *testing.T) {
+	users := map[string][]jira.User{
+		"jane@example.com": {{AccountID: "1", EmailAddress: "Jane@example.com", DisplayName: "Jane Doe"}},
+		"Jane Doe":         {{AccountID: "1", DisplayName: "Jane Doe"}, {AccountID: "2", DisplayName: "Jane Doe-Smith"}},
+		"Jane":             {{AccountID: "1", DisplayName: "Jane Doe"}},
+		"John":             {{AccountID: "3", DisplayName: "John"}, {AccountID: "4", DisplayName: "John"}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := users[r.URL.Query().Get("query")]
+		if res == nil {
+			res = []jira.User{}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+	client, err := jira.NewClient(srv.Client(), srv.URL)
+	require.NoError(t, err)
+	s := &cloudIssueService{jiraIssueService: jiraIssueService{client.Issue}, users: client.User}
+
+	for _, tc := range []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "jane@example.com", want: "1"},
+		{name: "Jane Doe", want: "1"},
+		{name: "5b10ac8d82e05b22cc7d4ef5", want: "5b10ac8d82e05b22cc7d4ef5"},
+		{name: "Jane", wantErr: `no Jira user matches "Jane" exactly (partial matches: 1)`},
+		{name: "John", wantErr: `Jira user "John" is ambiguous, it matches 2 users`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			accountID, _, err := s.accountID(context.Background(), tc.name)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, accountID)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// Types of activity events.
+const (
+	eventReceived     = "received"
+	eventRendered     = "rendered"
+	eventCreated      = "created"
+	eventUpdated      = "updated"
+	eventTransitioned = "transitioned"
+	eventCommented    = "commented"
+	eventHandled      = "handled"
+	eventFailed       = "failed"
+)
+
+const (
+	// eventBuffer is the number of events buffered per subscriber. Slow subscribers miss further events.
+	eventBuffer = 256
+	// eventKeepAlive is how often a comment is sent to idle subscribers, so proxies keep the connection open.
+	eventKeepAlive = 30 * time.Second
+)
+
+// activityEvent is a step of handling a notification, as streamed by `/api/v1/events`.
+type activityEvent struct {
+	Time         time.Time `json:"time"`
+	Type         string    `json:"type"`
+	Receiver     string    `json:"receiver"`
+	GroupKeyHash string    `json:"groupKeyHash,omitempty"`
+	IssueKey     string    `json:"issueKey,omitempty"`
+	// Summary is the summary of rendered and created issues.
+	Summary string `json:"summary,omitempty"`
+	// Transition is the ID of the transition of transitioned issues.
+	Transition string `json:"transition,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// eventStream fans activity events out to the subscribers of `/api/v1/events`.
+type eventStream struct {
+	mtx         sync.Mutex
+	subscribers map[chan activityEvent]struct{}
+}
+
+// events is the activity event stream of this instance.
+var events = &eventStream{subscribers: map[chan activityEvent]struct{}{}}
+
+func (s *eventStream) subscribe() chan activityEvent {
+	ch := make(chan activityEvent, eventBuffer)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (s *eventStream) unsubscribe(ch chan activityEvent) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.subscribers, ch)
+}
+
+// publish sends the event to all subscribers without blocking.
+func (s *eventStream) publish(e activityEvent) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.subscribers) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+type eventGroupKey struct{}
+
+// withEventGroup returns a context carrying the group key of the notification being handled, for the events of the
+// API calls made for it.
+func withEventGroup(ctx context.Context, groupKey string) context.Context {
+	return context.WithValue(ctx, eventGroupKey{}, groupKey)
+}
+
+func eventGroupKeyHash(ctx context.Context) string {
+	if groupKey, ok := ctx.Value(eventGroupKey{}).(string); ok {
+		return notify.GroupKeyHash(groupKey)
+	}
+	return ""
+}
+
+// eventTicketer publishes an activity event for every mutation of a receiver's issues.
+type eventTicketer struct {
+	notify.Ticketer
+	receiver string
+}
+
+// Unwrap returns the wrapped Ticketer, so optional interfaces it implements can still be found.
+func (t *eventTicketer) Unwrap() notify.Ticketer {
+	return t.Ticketer
+}
+
+func (t *eventTicketer) publish(ctx context.Context, e activityEvent, err error) {
+	e.Receiver = t.receiver
+	e.GroupKeyHash = eventGroupKeyHash(ctx)
+	if err != nil {
+		e.Type = eventFailed
+		e.Error = err.Error()
+	}
+	events.publish(e)
+}
+
+func (t *eventTicketer) CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	var summary string
+	if issue.Fields != nil {
+		summary = issue.Fields.Summary
+	}
+	t.publish(ctx, activityEvent{Type: eventRendered, Summary: summary}, nil)
+	created, resp, err := t.Ticketer.CreateWithContext(ctx, issue)
+	e := activityEvent{Type: eventCreated, Summary: summary}
+	if created != nil {
+		e.IssueKey = created.Key
+	}
+	t.publish(ctx, e, err)
+	return created, resp, err
+}
+
+func (t *eventTicketer) UpdateWithOptionsWithContext(ctx context.Context, issue *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	updated, resp, err := t.Ticketer.UpdateWithOptionsWithContext(ctx, issue, opts)
+	t.publish(ctx, activityEvent{Type: eventUpdated, IssueKey: issue.Key}, err)
+	return updated, resp, err
+}
+
+func (t *eventTicketer) UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	resp, err := t.Ticketer.UpdateIssueWithContext(ctx, jiraID, data)
+	t.publish(ctx, activityEvent{Type: eventUpdated, IssueKey: jiraID}, err)
+	return resp, err
+}
+
+func (t *eventTicketer) DoTransitionWithContext(ctx context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	resp, err := t.Ticketer.DoTransitionWithContext(ctx, ticketID, transitionID)
+	t.publish(ctx, activityEvent{Type: eventTransitioned, IssueKey: ticketID, Transition: transitionID}, err)
+	return resp, err
+}
+
+func (t *eventTicketer) AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	added, resp, err := t.Ticketer.AddCommentWithContext(ctx, issueID, comment)
+	t.publish(ctx, activityEvent{Type: eventCommented, IssueKey: issueID}, err)
+	return added, resp, err
+}
+
+// EventsHandlerFunc is the HTTP handler for `/api/v1/events`. It streams activity events as server-sent events,
+// optionally only those of the receiver of the `receiver` query parameter, until the client disconnects.
+func EventsHandlerFunc() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			apiError(w, http.StatusMethodNotAllowed, errOnlyGET)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			apiError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+			return
+		}
+		receiver := r.URL.Query().Get("receiver")
+
+		ch := events.subscribe()
+		defer events.unsubscribe(ch)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case e := <-ch:
+				if receiver != "" && e.Receiver != receiver {
+					continue
+				}
+				b, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(EventsHandlerFunc()))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?receiver=jira-ab", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The headers are sent once subscribed: the events of the receiver's mutations are streamed, those of other
+	// receivers are not.
+	const groupKey = `{}:{alertname="DiskFull"}`
+	evCtx := withEventGroup(context.Background(), groupKey)
+	fake := fakejira.New()
+	other := &eventTicketer{Ticketer: fake, receiver: "jira-cd"}
+	_, _, err = other.CreateWithContext(evCtx, &jira.Issue{Fields: &jira.IssueFields{Project: jira.Project{Key: "CD"}, Type: jira.IssueType{Name: "Bug"}, Summary: "Other"}})
+	require.NoError(t, err)
+	ticketer := &eventTicketer{Ticketer: fake, receiver: "jira-ab"}
+	created, _, err := ticketer.CreateWithContext(evCtx, &jira.Issue{Fields: &jira.IssueFields{Project: jira.Project{Key: "AB"}, Type: jira.IssueType{Name: "Bug"}, Summary: "Disk full"}})
+	require.NoError(t, err)
+	_, err = ticketer.DoTransitionWithContext(evCtx, "AB-99", "1")
+	require.Error(t, err)
+
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() (string, activityEvent) {
+		var name string
+		var e activityEvent
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return name, e
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+				require.False(t, e.Time.IsZero())
+				e.Time = time.Time{}
+			}
+		}
+	}
+	hash := notify.GroupKeyHash(groupKey)
+	for _, want := range []activityEvent{
+		{Type: eventRendered, Receiver: "jira-ab", GroupKeyHash: hash, Summary: "Disk full"},
+		{Type: eventCreated, Receiver: "jira-ab", GroupKeyHash: hash, Summary: "Disk full", IssueKey: created.Key},
+		{Type: eventFailed, Receiver: "jira-ab", GroupKeyHash: hash, IssueKey: "AB-99", Transition: "1", Error: err.Error()},
+	} {
+		name, e := readEvent()
+		require.Equal(t, want.Type, name)
+		require.Equal(t, want, e)
+	}
+
+	// Disconnecting unsubscribes.
+	cancel()
+	require.Eventually(t, func() bool {
+		events.mtx.Lock()
+		defer events.mtx.Unlock()
+		return len(events.subscribers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
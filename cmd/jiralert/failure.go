@@ -0,0 +1,111 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// failureEvent describes a notification that failed permanently, or that keeps being retried.
+type failureEvent struct {
+	Receiver    string          `json:"receiver"`
+	GroupKey    string          `json:"groupKey"`
+	GroupLabels alertmanager.KV `json:"groupLabels"`
+	Status      int             `json:"status"`
+	Error       string          `json:"error"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// failureNotifier posts failure events to the configured failure webhook.
+type failureNotifier struct {
+	conf   *config.FailureWebhook
+	client *http.Client
+	logger log.Logger
+}
+
+func newFailureNotifier(conf *config.FailureWebhook, logger log.Logger) *failureNotifier {
+	if conf == nil {
+		return nil
+	}
+	return &failureNotifier{conf: conf, client: &http.Client{Timeout: 10 * time.Second}, logger: logger}
+}
+
+// Notify sends the event in the background, so the webhook response to Alertmanager is not delayed.
+func (n *failureNotifier) Notify(e failureEvent) {
+	if n == nil {
+		return
+	}
+	go func() {
+		if err := n.send(e); err != nil {
+			level.Error(n.logger).Log("msg", "error sending failure webhook", "receiver", e.Receiver, "err", err)
+		}
+	}()
+}
+
+func (n *failureNotifier) send(e failureEvent) error {
+	var payload interface{}
+	switch n.conf.Format {
+	case config.FailureWebhookSlack:
+		payload = map[string]string{
+			"text": fmt.Sprintf(":warning: JIRAlert failed to handle a notification for receiver *%s* (group %v): %s", e.Receiver, e.GroupLabels, e.Error),
+		}
+	case config.FailureWebhookAlertmanager:
+		payload = []map[string]interface{}{{
+			"labels": alertmanager.KV{
+				alertmanager.AlertNameLabel: "JiralertNotificationFailed",
+				"receiver":                  e.Receiver,
+			},
+			"annotations": alertmanager.KV{
+				"groupKey":    e.GroupKey,
+				"groupLabels": fmt.Sprintf("%v", e.GroupLabels),
+				"error":       e.Error,
+			},
+			"startsAt": e.Timestamp,
+		}}
+	default:
+		payload = e
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.Post(string(n.conf.URL), "application/json", bytes.NewReader(b))
+	if err != nil {
+		// Without the URL, which is secret.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return uerr.Err
+		}
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("returned status %s, body %q", resp.Status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// notifyWithFallback handles a notification for the given receiver and, if it fails permanently or has been failing
+// for longer than the receiver's fallback duration, with its fallback receiver. The results and retry flag of the
+// primary receiver are returned if the fallback fails too, with the error of the primary receiver extended by the one of
+// the fallback.
+func notifyWithFallback(ctx context.Context, cfg *config.Config, conf *config.ReceiverConfig, tmpl *template.Template, state *notify.State, retries *retryTracker, data *alertmanager.Data, logger log.Logger) ([]notify.GroupResult, bool, error) {
+	ctx, span := tracer.Start(ctx, "notify", trace.WithAttributes(
+		attribute.String("jiralert.receiver", conf.Name),
+		attribute.String("jiralert.group_key", data.GroupKey),
+	))
+	defer span.End()
+	ctx = withRetryCount(ctx, retries.Retries(conf.Name, data.GroupKey))
+
+	results, retry, err := notifyReceiver(ctx, conf, tmpl, state, data, logger)
+	var failingFor time.Duration
+	if retry {
+		failingFor = retries.Failed(conf.Name, data, err, time.Now())
+	}
+	if err != nil && conf.Fallback != nil && (!retry || failingFor >= time.Duration(*conf.Fallback.After)) {
+		span.AddEvent("fallback", trace.WithAttributes(attribute.String("jiralert.fallback", conf.Fallback.Receiver)))
+		fallback := cfg.ReceiverByName(conf.Fallback.Receiver)
+		level.Warn(logger).Log("msg", "notification failed, handling it with the fallback receiver", "receiver", conf.Name, "fallback", fallback.Name, "groupKey", data.GroupKey, "groupKeyHash", notify.GroupKeyHash(data.GroupKey), "err", err)
+		if fallbackResults, _, ferr := notifyReceiver(ctx, fallback, tmpl, state, data, logger); ferr != nil {
+			level.Error(logger).Log("msg", "fallback receiver failed", "receiver", conf.Name, "fallback", fallback.Name, "groupKey", data.GroupKey, "groupKeyHash", notify.GroupKeyHash(data.GroupKey), "err", ferr)
+			err = fmt.Errorf("%w; fallback receiver %s failed: %v", err, fallback.Name, ferr)
+		} else {
+			fallbackTotal.WithLabelValues(conf.Name, fallback.Name).Inc()
+			results, retry, err = fallbackResults, false, nil
+		}
+	}
+
+	if !retry {
+		retries.Succeeded(conf.Name, data.GroupKey)
+	}
+	if err != nil {
+		var apiErr *notify.APIError
+		if errors.As(err, &apiErr) {
+			span.SetAttributes(attribute.String("jiralert.api", apiErr.API), attribute.Int("http.status_code", apiErr.StatusCode))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return results, retry, err
+}
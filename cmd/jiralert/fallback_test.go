@@ -0,0 +1,118 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyWithFallback(t *testing.T) {
+	// GitHub answering every request with the given status.
+	failingGitHub := func(status int) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message":"failed"}`, status)
+		}))
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+	reopen := config.Duration(0)
+	receiver := func(name string) *config.ReceiverConfig {
+		return &config.ReceiverConfig{
+			Name:           name,
+			Project:        "AB",
+			IssueType:      "Bug",
+			Summary:        `{{ .GroupLabels.alertname }}`,
+			ReopenState:    "To Do",
+			ReopenDuration: &reopen,
+		}
+	}
+	github := func(name, url string) *config.ReceiverConfig {
+		rc := receiver(name)
+		rc.Backend, rc.APIURL, rc.Project, rc.ReopenState = config.BackendGitHub, url, "o/r", "open"
+		return rc
+	}
+
+	for _, tc := range []struct {
+		name     string
+		status   int
+		after    time.Duration
+		fallback *config.ReceiverConfig
+		// wantIssues is the number of issues created by the fallback.
+		wantIssues int
+		wantRetry  bool
+		wantErr    string
+	}{
+		{name: "permanent failure", status: http.StatusBadRequest, after: time.Hour, fallback: receiver("fallback"), wantIssues: 1},
+		{name: "retryable failure", status: http.StatusServiceUnavailable, after: 0, fallback: receiver("fallback"), wantIssues: 1},
+		{name: "retryable failure before after", status: http.StatusServiceUnavailable, after: time.Hour, fallback: receiver("fallback"), wantRetry: true, wantErr: "503"},
+		{
+			name:     "failing fallback",
+			status:   http.StatusBadRequest,
+			fallback: github("fallback", failingGitHub(http.StatusForbidden)),
+			wantErr:  "; fallback receiver fallback failed: ",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := fakejira.New()
+			testModeJira = fake
+			defer func() { testModeJira = nil }()
+
+			after := config.Duration(tc.after)
+			primary := github("primary", failingGitHub(tc.status))
+			primary.Fallback = &config.Fallback{Receiver: tc.fallback.Name, After: &after}
+			cfg := &config.Config{Receivers: []*config.ReceiverConfig{primary, tc.fallback}}
+
+			data := &alertmanager.Data{
+				Receiver:    "primary",
+				Status:      alertmanager.AlertFiring,
+				GroupKey:    "{}:{alertname=\"Disk\"}",
+				GroupLabels: alertmanager.KV{"alertname": "Disk"},
+				Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			}
+			retries := newRetryTracker(nil, time.Hour)
+			_, retry, err := notifyWithFallback(context.Background(), cfg, primary, template.SimpleTemplate(), notify.NewState(), retries, data, log.NewNopLogger())
+			require.Equal(t, tc.wantRetry, retry)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, 0, retries.Retries("primary", data.GroupKey))
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.wantErr)
+			}
+			if tc.fallback.Backend == config.BackendGitHub {
+				// The error of the primary receiver comes first, and is kept for the span attributes.
+				require.Regexp(t, `400.*; fallback receiver fallback failed: .*403`, err.Error())
+				var apiErr *notify.APIError
+				require.ErrorAs(t, err, &apiErr)
+				require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+			}
+
+			issues, _, err := fake.SearchWithContext(context.Background(), notify.Query{Project: "AB"}, nil)
+			require.NoError(t, err)
+			require.Len(t, issues, tc.wantIssues)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// payloadResult is the outcome of handling a payload read from a message queue.
+type payloadResult int
+
+const (
+	// payloadDone means all receivers were notified, or the payload was already handled.
+	payloadDone payloadResult = iota
+	// payloadRetry means the notification failed but can be retried by redelivering the payload.
+	payloadRetry
+	// payloadDrop means the payload cannot succeed on redelivery. The failure was reported.
+	payloadDrop
+)
+
+// payloadHandler handles Alertmanager webhook payloads read from message queues like requests to the /alert endpoint.
+type payloadHandler struct {
+	cfg         *config.Config
+	tmpl        *template.Template
+	state       *notify.State
+	retries     *retryTracker
+	payloads    *payloadCache
+	failures    *failureNotifier
+	deadLetters *deadLetterStore
+	logger      log.Logger
+}
+
+// handle notifies the receivers of the payload of a message of the given source, e.g. "NATS message".
+func (h *payloadHandler) handle(payload []byte, source string, logger log.Logger) payloadResult {
+	pendingNotifications.Inc()
+	defer pendingNotifications.Dec()
+
+	data := alertmanager.Data{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		h.fail(http.StatusBadRequest, err, unknownReceiver, &data, source, logger)
+		return payloadDrop
+	}
+	confs := h.cfg.ReceiversFor(data.Receiver)
+	if len(confs) == 0 {
+		h.fail(http.StatusNotFound, fmt.Errorf("receiver missing: %s", data.Receiver), unknownReceiver, &data, source, logger)
+		return payloadDrop
+	}
+
+	// With -dedup.window, redeliveries of payloads already handled are only acknowledged.
+	hash := payloadHash(&data)
+	if h.payloads.Seen(hash, time.Now()) {
+		for _, conf := range confs {
+			deduplicatedTotal.WithLabelValues(conf.Name).Inc()
+		}
+		return payloadDone
+	}
+
+	var (
+		failed    *config.ReceiverConfig
+		failRetry bool
+		failErr   error
+	)
+	for _, conf := range confs {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		_, retry, err := notifyWithFallback(ctx, h.cfg, conf, h.tmpl, h.state, h.retries, &data, logger)
+		cancel()
+		if err != nil && failErr == nil {
+			failed, failRetry, failErr = conf, retry, err
+		}
+	}
+	if failErr != nil {
+		if failRetry {
+			h.fail(http.StatusServiceUnavailable, failErr, failed.Name, &data, source, logger)
+			return payloadRetry
+		}
+		h.fail(http.StatusInternalServerError, failErr, failed.Name, &data, source, logger)
+		return payloadDrop
+	}
+	if !anyPaused(h.state, confs) {
+		h.payloads.Add(hash, time.Now())
+	}
+	for _, conf := range confs {
+		requestTotal.WithLabelValues(conf.Name, "200").Inc()
+	}
+	return payloadDone
+}
+
+// fail reports a failed payload the same way a failed webhook request is.
+func (h *payloadHandler) fail(status int, err error, receiver string, data *alertmanager.Data, source string, logger log.Logger) {
+	reportFailure(status, err, receiver, data, h.failures, h.deadLetters, logger)
+	level.Error(logger).Log("msg", "error handling "+source, "statusCode", status, "err", err, "receiver", receiver, "groupKeyHash", notify.GroupKeyHash(data.GroupKey))
+	requestTotal.WithLabelValues(receiver, strconv.Itoa(status)).Inc()
+}
+
+// AlertHandlerFunc is the HTTP handler for `/alert`, the Alertmanager webhook. Notifications are handled by the
+// receivers of the payload right away, or held by coalesced if it is enabled, and recorded in notifications.
+func AlertHandlerFunc(h *payloadHandler, coalesced *coalescer, notifications *notificationLog) func(http.ResponseWriter, *http.Request) {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		logger := requestLogger(req.Context(), h.logger)
+		level.Debug(logger).Log("msg", "handling /alert webhook request")
+		defer func() { _ = req.Body.Close() }()
+		pendingNotifications.Inc()
+		defer pendingNotifications.Dec()
+
+		// https://godoc.org/github.com/prometheus/alertmanager/template#Data
+		data := alertmanager.Data{}
+		w := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		start := time.Now()
+		defer func() {
+			n := notificationRecord{
+				Time:        start,
+				Receiver:    data.Receiver,
+				GroupKey:    data.GroupKey,
+				GroupLabels: data.GroupLabels,
+				Status:      w.status,
+				Duration:    time.Since(start),
+			}
+			for _, m := range h.state.Mappings() {
+				if m.GroupKey != data.GroupKey {
+					continue
+				}
+				for _, rc := range h.cfg.ReceiversFor(data.Receiver) {
+					if m.Receiver == rc.Name {
+						n.IssueKeys = append(n.IssueKeys, m.IssueKey)
+					}
+				}
+			}
+			notifications.Add(n)
+		}()
+		if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+			errorHandler(w, http.StatusBadRequest, err, unknownReceiver, &data, nil, h.failures, h.deadLetters, logger)
+			return
+		}
+
+		confs := h.cfg.ReceiversFor(data.Receiver)
+		if len(confs) == 0 {
+			errorHandler(w, http.StatusNotFound, fmt.Errorf("receiver missing: %s", data.Receiver), unknownReceiver, &data, nil, h.failures, h.deadLetters, logger)
+			return
+		}
+		for _, conf := range confs {
+			level.Debug(logger).Log("msg", "  matched receiver", "receiver", conf.Name)
+		}
+
+		hash := payloadHash(&data)
+		if h.payloads.Seen(hash, time.Now()) {
+			for _, conf := range confs {
+				level.Debug(logger).Log("msg", "identical notification already processed; skipping", "receiver", conf.Name, "groupKey", data.GroupKey, "groupKeyHash", notify.GroupKeyHash(data.GroupKey))
+				deduplicatedTotal.WithLabelValues(conf.Name).Inc()
+				requestTotal.WithLabelValues(conf.Name, "200").Inc()
+			}
+			return
+		}
+
+		// All receivers are notified, even if one fails. The first failing receiver determines the response, which
+		// lists the outcome of every alert group.
+		notifyAll := func(ctx context.Context, data *alertmanager.Data) (groups []webhookGroup, failed string, retry bool, err error) {
+			for _, conf := range confs {
+				results, r, e := notifyWithFallback(ctx, h.cfg, conf, h.tmpl, h.state, h.retries, data, logger)
+				for _, res := range results {
+					groups = append(groups, webhookGroup{Receiver: conf.Name, GroupResult: res})
+				}
+				if e != nil && err == nil {
+					failed, retry, err = conf.Name, r, e
+				}
+			}
+			return groups, failed, retry, err
+		}
+
+		if coalesced.Enabled() {
+			// Answered once held durably, the notification is handled in the background once the coalescing window
+			// passed.
+			ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(req.Header))
+			merged, err := coalesced.Hold(ctx, &data)
+			if err != nil {
+				errorHandler(w, http.StatusServiceUnavailable, err, data.Receiver, &data, nil, h.failures, h.deadLetters, logger)
+				return
+			}
+			if merged {
+				level.Debug(logger).Log("msg", "notification replaced pending notification of the alert group", "receiver", data.Receiver, "groupKeyHash", notify.GroupKeyHash(data.GroupKey))
+			}
+			for _, conf := range confs {
+				if merged {
+					coalescedTotal.WithLabelValues(conf.Name).Inc()
+				}
+				requestTotal.WithLabelValues(conf.Name, strconv.Itoa(http.StatusAccepted)).Inc()
+			}
+			writeWebhookResponse(w, http.StatusAccepted, "", nil)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		groups, failed, retry, err := notifyAll(ctx, &data)
+		if err != nil {
+			var status int
+			if retry {
+				// Instruct Alertmanager to retry.
+				status = http.StatusServiceUnavailable
+			} else {
+				status = http.StatusInternalServerError
+			}
+			errorHandler(w, status, err, failed, &data, groups, h.failures, h.deadLetters, logger)
+			return
+		}
+		if !anyPaused(h.state, confs) {
+			h.payloads.Add(hash, time.Now())
+		}
+		for _, conf := range confs {
+			requestTotal.WithLabelValues(conf.Name, "200").Inc()
+		}
+		writeWebhookResponse(w, http.StatusOK, "", groups)
+	}
+}
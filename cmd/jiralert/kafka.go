@@ -0,0 +1,174 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	kafkaClientID   = "jiralert"
+	kafkaErrorDelay = 5 * time.Second
+)
+
+// kafkaConsumer reads Alertmanager webhook payloads from the partitions of a Kafka topic and handles them like the
+// /alert endpoint. It joins a consumer group, so replicas sharing the group split the partitions between them, and
+// commits the offset of every handled record, so consumption resumes after it after restarts and rebalances.
+//
+// Records are handled in order. A record whose notification failed and can be retried is retried every retryDelay,
+// holding back the later records of its partition, until it succeeds, fails permanently or its partition is
+// revoked, leaving it to the partition's new owner.
+type kafkaConsumer struct {
+	*payloadHandler
+
+	brokers    []string
+	topic      string
+	group      string
+	retryDelay time.Duration
+	client     *kgo.Client
+	// commit commits the offsets of handled records, client.CommitRecords but in tests.
+	commit func(ctx context.Context, rs ...*kgo.Record) error
+
+	mtx sync.Mutex
+	// revocations counts the revocations of each partition, to tell whether records polled before are still owned.
+	revocations map[int32]int
+}
+
+// newKafkaConsumer returns a consumer of the topic for the group, bootstrapping from the comma-separated brokers.
+// opts are the client options of the connections to the brokers, e.g. TLS and SASL.
+func newKafkaConsumer(h *payloadHandler, brokers, topic, group, initialOffset string, retryDelay time.Duration, opts ...kgo.Opt) (*kafkaConsumer, error) {
+	offset, err := parseKafkaInitialOffset(initialOffset)
+	if err != nil {
+		return nil, err
+	}
+	c := &kafkaConsumer{
+		payloadHandler: h,
+		topic:          topic,
+		group:          group,
+		retryDelay:     retryDelay,
+		revocations:    map[int32]int{},
+	}
+	for _, b := range strings.Split(brokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			c.brokers = append(c.brokers, b)
+		}
+	}
+	if len(c.brokers) == 0 {
+		return nil, errors.Errorf("invalid Kafka brokers %q", brokers)
+	}
+	if topic == "" || group == "" {
+		return nil, errors.New("the Kafka topic and consumer group must be set")
+	}
+
+	opts = append([]kgo.Opt{
+		kgo.SeedBrokers(c.brokers...),
+		kgo.ClientID(kafkaClientID),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeResetOffset(offset),
+		kgo.DisableAutoCommit(),
+		kgo.OnPartitionsRevoked(c.revoke),
+		kgo.OnPartitionsLost(c.revoke),
+	}, opts...)
+	if c.client, err = kgo.NewClient(opts...); err != nil {
+		return nil, err
+	}
+	c.commit = c.client.CommitRecords
+	return c, nil
+}
+
+// run polls and handles records forever.
+func (c *kafkaConsumer) run() {
+	logger := log.With(c.logger, "topic", c.topic, "group", c.group)
+	level.Info(logger).Log("msg", "consuming alerts from Kafka")
+	for {
+		fetches := c.client.PollFetches(context.Background())
+		errs := fetches.Errors()
+		for _, err := range errs {
+			level.Error(logger).Log("msg", "error consuming from Kafka", "partition", err.Partition, "err", err.Err)
+		}
+		c.handleFetches(context.Background(), fetches, logger)
+		if len(errs) > 0 {
+			time.Sleep(kafkaErrorDelay)
+		}
+	}
+}
+
+// handleFetches handles the polled records of each partition in order, committing the offset of each, as long as
+// the partition is not revoked.
+func (c *kafkaConsumer) handleFetches(ctx context.Context, fetches kgo.Fetches, logger log.Logger) {
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		revocations := c.revocationsOf(p.Partition)
+		logger := log.With(logger, "partition", p.Partition)
+		for _, r := range p.Records {
+			if !c.handleRecord(r, revocations, log.With(logger, "offset", r.Offset)) {
+				level.Info(logger).Log("msg", "Kafka partition revoked; leaving its records to its new owner", "offset", r.Offset)
+				return
+			}
+			if err := c.commit(ctx, r); err != nil {
+				level.Warn(logger).Log("msg", "error committing Kafka offset", "offset", r.Offset, "err", err)
+			}
+		}
+	})
+}
+
+// handleRecord handles the payload of a record, retrying as long as the notification can be retried. It returns
+// false, leaving the record unhandled, if the partition was revoked since the record was polled.
+func (c *kafkaConsumer) handleRecord(r *kgo.Record, revocations int, logger log.Logger) bool {
+	if c.revocationsOf(r.Partition) != revocations {
+		return false
+	}
+	for c.handle(r.Value, "Kafka message", logger) == payloadRetry {
+		level.Warn(logger).Log("msg", "retrying Kafka message", "delay", c.retryDelay)
+		time.Sleep(c.retryDelay)
+		if c.revocationsOf(r.Partition) != revocations {
+			return false
+		}
+	}
+	return true
+}
+
+// revoke records the revocation of partitions, when the group is rebalanced or the consumer dropped from it.
+func (c *kafkaConsumer) revoke(_ context.Context, _ *kgo.Client, revoked map[string][]int32) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, p := range revoked[c.topic] {
+		c.revocations[p]++
+	}
+}
+
+func (c *kafkaConsumer) revocationsOf(partition int32) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.revocations[partition]
+}
+
+// parseKafkaInitialOffset parses the value of -kafka.initial-offset.
+func parseKafkaInitialOffset(s string) (kgo.Offset, error) {
+	switch s {
+	case "oldest":
+		return kgo.NewOffset().AtStart(), nil
+	case "newest":
+		return kgo.NewOffset().AtEnd(), nil
+	}
+	return kgo.Offset{}, errors.Errorf("invalid initial offset %q, must be oldest or newest", s)
+}
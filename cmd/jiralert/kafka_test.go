@@ -0,0 +1,90 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// testFetches returns fetches of the topic alerts with the given offsets of each partition.
+func testFetches(offsets map[int32][]int64) kgo.Fetches {
+	// The payloads are for an unknown receiver: they are dropped, and their offsets committed.
+	payload := []byte(`{"receiver":"unknown","status":"firing","alerts":[]}`)
+	topic := kgo.FetchTopic{Topic: "alerts"}
+	for p, os := range offsets {
+		fp := kgo.FetchPartition{Partition: p}
+		for _, o := range os {
+			fp.Records = append(fp.Records, &kgo.Record{Topic: "alerts", Partition: p, Offset: o, Value: payload})
+		}
+		topic.Partitions = append(topic.Partitions, fp)
+	}
+	return kgo.Fetches{{Topics: []kgo.FetchTopic{topic}}}
+}
+
+func TestKafkaConsumerHandleFetches(t *testing.T) {
+	var commits []int64
+	c := &kafkaConsumer{
+		payloadHandler: &payloadHandler{cfg: &config.Config{}, logger: log.NewNopLogger()},
+		topic:          "alerts",
+		revocations:    map[int32]int{},
+	}
+	c.commit = func(_ context.Context, rs ...*kgo.Record) error {
+		for _, r := range rs {
+			commits = append(commits, r.Offset)
+		}
+		return nil
+	}
+
+	// Records are handled and committed in order.
+	c.handleFetches(context.Background(), testFetches(map[int32][]int64{0: {4, 5, 6}}), log.NewNopLogger())
+	require.Equal(t, []int64{4, 5, 6}, commits)
+
+	// The records of a partition revoked meanwhile are left to its new owner.
+	commits = nil
+	c.commit = func(_ context.Context, rs ...*kgo.Record) error {
+		commits = append(commits, rs[0].Offset)
+		c.revoke(context.Background(), nil, map[string][]int32{"alerts": {0}, "other": {1}})
+		return nil
+	}
+	c.handleFetches(context.Background(), testFetches(map[int32][]int64{0: {7, 8}}), log.NewNopLogger())
+	require.Equal(t, []int64{7}, commits)
+	require.Equal(t, map[int32]int{0: 1}, c.revocations)
+
+	// Records polled after the revocation are handled.
+	commits = nil
+	c.handleFetches(context.Background(), testFetches(map[int32][]int64{0: {8}}), log.NewNopLogger())
+	require.Equal(t, []int64{8}, commits)
+}
+
+func TestNewKafkaConsumer(t *testing.T) {
+	h := &payloadHandler{}
+	c, err := newKafkaConsumer(h, "kafka-1, kafka-2:9093,", "alerts", "jiralert", "oldest", time.Minute)
+	require.NoError(t, err)
+	c.client.Close()
+	require.Equal(t, []string{"kafka-1", "kafka-2:9093"}, c.brokers)
+
+	_, err = newKafkaConsumer(h, "kafka-1", "alerts", "jiralert", "latest", time.Minute)
+	require.Error(t, err)
+	_, err = newKafkaConsumer(h, " , ", "alerts", "jiralert", "newest", time.Minute)
+	require.Error(t, err)
+	_, err = newKafkaConsumer(h, "kafka-1", "alerts", "", "newest", time.Minute)
+	require.Error(t, err)
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+)
+
+// lintPayload is an Alertmanager webhook payload templates are rendered with by `jiralert lint-templates`.
+type lintPayload struct {
+	name string
+	data alertmanager.Data
+}
+
+// stringsFlag is a flag that may be given multiple times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// lintTemplates implements `jiralert lint-templates`. It compiles all templates used by all receivers and renders
+// the issues of each receiver for bundled and user-supplied sample payloads, reporting the problems found to out. It
+// returns the process exit code.
+func lintTemplates(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("lint-templates", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configFile := fs.String("config", "config/jiralert.yml", "The JIRAlert configuration file")
+	var overlays stringsFlag
+	fs.Var(&overlays, "config.overlay", "A configuration file merged into the JIRAlert configuration file. May be repeated")
+	strict := fs.Bool("strict", false, "Fail on references to missing labels, annotations and other map keys instead of rendering them empty")
+	var payloadFiles stringsFlag
+	fs.Var(&payloadFiles, "payload", "An Alertmanager webhook payload (JSON) to render the templates with, in addition to the bundled ones. May be repeated")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	nop := log.NewNopLogger()
+	cfg, _, err := config.LoadFile(*configFile, nop, overlays...)
+	if err != nil {
+		fmt.Fprintf(out, "error loading configuration %s: %s\n", *configFile, err)
+		return 1
+	}
+	tmpl, err := template.LoadTemplate(cfg.Template, nop)
+	if err == nil && *strict {
+		tmpl, err = tmpl.Strict()
+	}
+	if err != nil {
+		fmt.Fprintf(out, "error loading templates %s: %s\n", cfg.Template, err)
+		return 1
+	}
+
+	payloads := samplePayloads(time.Now())
+	for _, f := range payloadFiles {
+		p, err := loadPayload(f)
+		if err != nil {
+			fmt.Fprintf(out, "error loading payload %s: %s\n", f, err)
+			return 1
+		}
+		payloads = append(payloads, p)
+	}
+
+	problems := 0
+	for _, rc := range cfg.Receivers {
+		if err := checkReceiverTemplates(tmpl, rc); err != nil {
+			fmt.Fprintf(out, "receiver %q: %s\n", rc.Name, err)
+			problems++
+			continue
+		}
+		// Rendering must not talk to on-call providers.
+		conf := *rc
+		conf.OnCall = nil
+		r := notify.NewReceiver(nop, &conf, tmpl, nil, nil)
+		for _, p := range payloads {
+			data := p.data
+			data.Receiver = rc.Name
+			if _, err := r.Render(&data, true); err != nil {
+				fmt.Fprintf(out, "receiver %q, payload %s: %s\n", rc.Name, p.name, err)
+				problems++
+			}
+		}
+	}
+
+	if problems > 0 {
+		fmt.Fprintf(out, "%d problem(s) found\n", problems)
+		return 1
+	}
+	fmt.Fprintf(out, "templates of %d receiver(s) rendered %d payload(s) without problems\n", len(cfg.Receivers), len(payloads))
+	return 0
+}
+
+func loadPayload(path string) (lintPayload, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return lintPayload{}, err
+	}
+	p := lintPayload{name: path}
+	if err := json.Unmarshal(b, &p.data); err != nil {
+		return lintPayload{}, err
+	}
+	return p, nil
+}
+
+// samplePayloads returns the bundled payloads: a single firing alert, several alerts of a group with some already
+// resolved, and a resolved group.
+func samplePayloads(now time.Time) []lintPayload {
+	alert := func(status, instance string) alertmanager.Alert {
+		a := alertmanager.Alert{
+			Status: status,
+			Labels: alertmanager.KV{
+				"alertname": "HighErrorRate",
+				"severity":  "critical",
+				"job":       "api",
+				"instance":  instance,
+			},
+			Annotations: alertmanager.KV{
+				"summary":     "High error rate on " + instance,
+				"description": "More than 5% of the requests to " + instance + " fail.",
+			},
+			StartsAt:     now.Add(-time.Hour),
+			GeneratorURL: "http://prometheus.example.com/graph?g0.expr=job%3Aerrors%3Arate5m+%3E+0.05",
+			Fingerprint:  "3a2c5b6e7f8d9a0b",
+		}
+		if status == alertmanager.AlertResolved {
+			a.EndsAt = now
+		}
+		return a
+	}
+	group := func(status string, alerts ...alertmanager.Alert) alertmanager.Data {
+		d := alertmanager.Data{
+			Version:           "4",
+			Status:            status,
+			Alerts:            alerts,
+			GroupKey:          `{}:{alertname="HighErrorRate"}`,
+			GroupLabels:       alertmanager.KV{"alertname": "HighErrorRate"},
+			CommonLabels:      alertmanager.KV{"alertname": "HighErrorRate", "severity": "critical", "job": "api"},
+			CommonAnnotations: alertmanager.KV{},
+			ExternalURL:       "http://alertmanager.example.com",
+		}
+		if len(alerts) == 1 {
+			d.CommonLabels = alerts[0].Labels
+			d.CommonAnnotations = alerts[0].Annotations
+		}
+		return d
+	}
+	return []lintPayload{
+		{name: "<bundled: single firing alert>", data: group(alertmanager.AlertFiring, alert(alertmanager.AlertFiring, "api-1:8080"))},
+		{name: "<bundled: partially resolved group>", data: group(alertmanager.AlertFiring,
+			alert(alertmanager.AlertFiring, "api-1:8080"),
+			alert(alertmanager.AlertFiring, "api-2:8080"),
+			alert(alertmanager.AlertResolved, "api-3:8080"),
+		)},
+		{name: "<bundled: resolved group>", data: group(alertmanager.AlertResolved, alert(alertmanager.AlertResolved, "api-1:8080"))},
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeLintConfig writes a configuration with a receiver of the given summary and a template file to dir, and returns
+// the path of the configuration file.
+func writeLintConfig(t *testing.T, dir, summary string) string {
+	tmplFile := filepath.Join(dir, "jiralert.tmpl")
+	require.NoError(t, os.WriteFile(tmplFile, []byte(`{{ define "jira.summary" }}[{{ .Status | toUpper }}] {{ .GroupLabels.alertname }}{{ end }}`), 0o600))
+	configFile := filepath.Join(dir, "jiralert.yml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: secret
+  project: AB
+  issue_type: Bug
+  summary: '{{ template "jira.summary" . }}'
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: `+tmplFile+`
+receivers:
+  - name: 'jira-ab'
+  - name: 'jira-cd'
+    project: CD
+    summary: '`+summary+`'
+`), 0o600))
+	return configFile
+}
+
+func TestLintTemplates(t *testing.T) {
+	payload := filepath.Join(t.TempDir(), "payload.json")
+	require.NoError(t, os.WriteFile(payload, []byte(`{"status":"firing","groupLabels":{"alertname":"Custom"},"alerts":[{"status":"firing","labels":{"alertname":"Custom"}}]}`), 0o600))
+
+	for _, tc := range []struct {
+		name    string
+		summary string
+		args    []string
+		want    int
+		wantOut string
+	}{
+		{
+			name:    "valid",
+			summary: `{{ .CommonLabels.alertname }}`,
+			wantOut: "templates of 2 receiver(s) rendered 3 payload(s) without problems",
+		},
+		{
+			name:    "user payload",
+			summary: `{{ .CommonLabels.alertname }}`,
+			args:    []string{"-payload", payload},
+			wantOut: "templates of 2 receiver(s) rendered 4 payload(s) without problems",
+		},
+		{
+			name:    "invalid payload",
+			summary: `{{ .CommonLabels.alertname }}`,
+			args:    []string{"-payload", filepath.Join(t.TempDir(), "missing.json")},
+			want:    1,
+			wantOut: "error loading payload",
+		},
+		{
+			name:    "unknown function",
+			summary: `{{ .CommonLabels.alertname | shout }}`,
+			want:    1,
+			wantOut: `receiver "jira-cd": `,
+		},
+		{
+			name:    "bad field reference",
+			summary: `{{ .CommonLabels.alertname.Name }}`,
+			want:    1,
+			wantOut: "3 problem(s) found",
+		},
+		{
+			// Missing labels render empty unless -strict.
+			name:    "missing label",
+			summary: `{{ .CommonLabels.team }}`,
+			wantOut: "without problems",
+		},
+		{
+			name:    "missing label strict",
+			summary: `{{ .CommonLabels.team }}`,
+			args:    []string{"-strict"},
+			want:    1,
+			wantOut: `receiver "jira-cd", payload <bundled: single firing alert>: `,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			configFile := writeLintConfig(t, t.TempDir(), tc.summary)
+			var out bytes.Buffer
+			code := lintTemplates(append([]string{"-config", configFile}, tc.args...), &out)
+			require.Equal(t, tc.want, code, out.String())
+			require.Contains(t, out.String(), tc.wantOut)
+			// The templates of the valid receiver are not reported.
+			require.NotContains(t, out.String(), `"jira-ab"`)
+		})
+	}
+
+	var out bytes.Buffer
+	require.Equal(t, 1, lintTemplates([]string{"-config", filepath.Join(t.TempDir(), "missing.yml")}, &out))
+	require.Contains(t, out.String(), "error loading configuration")
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// logLevels ranks the levels of -log.level and log_level.
+var logLevels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+// levelFilter filters log lines by level like level.NewFilter. Lines of receivers with a log_level, i.e. with a
+// "receiver" key naming one, are filtered by that level instead, so a single receiver can be debugged without
+// flipping the level of the whole instance.
+type levelFilter struct {
+	next      log.Logger
+	threshold int
+
+	mtx       sync.RWMutex
+	receivers map[string]int
+}
+
+func newLevelFilter(next log.Logger, lvl string) *levelFilter {
+	threshold, ok := logLevels[lvl]
+	if !ok {
+		threshold = logLevels["info"]
+	}
+	return &levelFilter{next: next, threshold: threshold, receivers: map[string]int{}}
+}
+
+// SetReceivers applies the log_level of the configured receivers.
+func (f *levelFilter) SetReceivers(cfg *config.Config) {
+	receivers := map[string]int{}
+	for _, rc := range cfg.Receivers {
+		if lvl, ok := logLevels[rc.LogLevel]; ok {
+			receivers[rc.Name] = lvl
+		}
+	}
+	f.mtx.Lock()
+	f.receivers = receivers
+	f.mtx.Unlock()
+}
+
+// Log implements log.Logger. Lines without level are always logged.
+func (f *levelFilter) Log(keyvals ...interface{}) error {
+	lvl, receiver := -1, ""
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case level.Key():
+			if v, ok := keyvals[i+1].(level.Value); ok {
+				lvl = logLevels[v.String()]
+			}
+		case "receiver":
+			receiver, _ = keyvals[i+1].(string)
+		}
+	}
+	if lvl < 0 {
+		return f.next.Log(keyvals...)
+	}
+
+	threshold := f.threshold
+	f.mtx.RLock()
+	if l, ok := f.receivers[receiver]; ok {
+		threshold = l
+	}
+	f.mtx.RUnlock()
+	if lvl < threshold {
+		return nil
+	}
+	return f.next.Log(keyvals...)
+}
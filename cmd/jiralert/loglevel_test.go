@@ -0,0 +1,68 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelFilter(t *testing.T) {
+	var logged []string
+	levels := newLevelFilter(log.LoggerFunc(func(keyvals ...interface{}) error {
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			if keyvals[i] == "msg" {
+				logged = append(logged, keyvals[i+1].(string))
+			}
+		}
+		return nil
+	}), "info")
+	levels.SetReceivers(&config.Config{Receivers: []*config.ReceiverConfig{
+		{Name: "debugged", LogLevel: "debug"},
+		{Name: "quiet", LogLevel: "error"},
+		{Name: "default"},
+	}})
+	// Receivers log through a logger with their name, like the notification handlers.
+	logLines := func(receiver string) []string {
+		logged = nil
+		logger := log.Logger(levels)
+		if receiver != "" {
+			logger = log.With(levels, "receiver", receiver)
+		}
+		level.Debug(logger).Log("msg", "d")
+		level.Info(logger).Log("msg", "i")
+		level.Warn(logger).Log("msg", "w")
+		level.Error(logger).Log("msg", "e")
+		return logged
+	}
+
+	require.Equal(t, []string{"i", "w", "e"}, logLines(""))
+	require.Equal(t, []string{"i", "w", "e"}, logLines("default"))
+	require.Equal(t, []string{"d", "i", "w", "e"}, logLines("debugged"))
+	require.Equal(t, []string{"e"}, logLines("quiet"))
+
+	// Lines without level are always logged.
+	logged = nil
+	_ = log.With(levels, "receiver", "quiet").Log("msg", "no level")
+	require.Equal(t, []string{"no level"}, logged)
+
+	// Reapplied receivers replace the previous levels.
+	levels.SetReceivers(&config.Config{Receivers: []*config.ReceiverConfig{{Name: "debugged"}}})
+	require.Equal(t, []string{"i", "w", "e"}, logLines("debugged"))
+	require.Equal(t, []string{"i", "w", "e"}, logLines("quiet"))
+}
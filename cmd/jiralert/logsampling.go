@@ -0,0 +1,94 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// samplingLogger limits the debug lines logged per alert group, identified by the groupKeyHash of the log line,
+// to limit per window, so debug logging stays usable during alert storms. Of the further lines, every thereafter-th
+// is logged, none if thereafter is 0. Lines of other levels are always logged. Debug lines without group are limited
+// together.
+type samplingLogger struct {
+	next       log.Logger
+	limit      int
+	thereafter int
+	window     time.Duration
+
+	mtx     sync.Mutex
+	start   time.Time
+	counts  map[string]int
+	timeNow func() time.Time
+}
+
+func newSamplingLogger(next log.Logger, limit, thereafter int, window time.Duration) *samplingLogger {
+	return &samplingLogger{next: next, limit: limit, thereafter: thereafter, window: window, counts: map[string]int{}, timeNow: time.Now}
+}
+
+// Log implements log.Logger.
+func (l *samplingLogger) Log(keyvals ...interface{}) error {
+	var debug bool
+	group := ""
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case level.Key():
+			debug = keyvals[i+1] == level.DebugValue()
+		case "groupKeyHash":
+			group, _ = keyvals[i+1].(string)
+		}
+	}
+	if !debug {
+		return l.next.Log(keyvals...)
+	}
+
+	l.mtx.Lock()
+	if now := l.timeNow(); now.Sub(l.start) >= l.window {
+		l.start = now
+		l.counts = map[string]int{}
+	}
+	l.counts[group]++
+	n := l.counts[group]
+	l.mtx.Unlock()
+
+	if n <= l.limit || l.thereafter > 0 && (n-l.limit)%l.thereafter == 0 {
+		return l.next.Log(keyvals...)
+	}
+	debugLinesDroppedTotal.Inc()
+	if n == l.limit+1 {
+		// Log once per window that lines are dropped, so gaps in the debug log are not mistaken for inactivity.
+		msg := "debug log limit reached; dropping further debug lines of this alert group for the current window"
+		if l.thereafter > 0 {
+			msg = "debug log limit reached; sampling further debug lines of this alert group for the current window"
+		}
+		marker := make([]interface{}, 0, len(keyvals)+4)
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			v := keyvals[i+1]
+			if keyvals[i] == "msg" {
+				v = msg
+			}
+			marker = append(marker, keyvals[i], v)
+		}
+		marker = append(marker, "limit", l.limit)
+		if l.thereafter > 0 {
+			marker = append(marker, "sample", l.thereafter)
+		}
+		return l.next.Log(marker...)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingLogger(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		thereafter int
+		// want are the messages logged of the lines 1 to 10 of a window.
+		want []string
+	}{
+		{
+			name: "drop",
+			want: []string{"1", "2", "3", "debug log limit reached; dropping further debug lines of this alert group for the current window"},
+		},
+		{
+			name:       "sample",
+			thereafter: 3,
+			want:       []string{"1", "2", "3", "debug log limit reached; sampling further debug lines of this alert group for the current window", "6", "9"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logged []string
+			next := log.LoggerFunc(func(keyvals ...interface{}) error {
+				for i := 0; i+1 < len(keyvals); i += 2 {
+					if keyvals[i] == "msg" {
+						logged = append(logged, keyvals[i+1].(string))
+					}
+				}
+				return nil
+			})
+			now := time.Date(2022, 11, 5, 22, 0, 0, 0, time.UTC)
+			logger := newSamplingLogger(next, 3, tc.thereafter, time.Minute)
+			logger.timeNow = func() time.Time { return now }
+			logLines := func(group string) {
+				for i := 1; i <= 10; i++ {
+					level.Debug(logger).Log("msg", fmt.Sprint(i), "groupKeyHash", group)
+				}
+			}
+
+			logLines("a")
+			require.Equal(t, tc.want, logged)
+
+			// Other alert groups and levels are not limited.
+			logged = nil
+			logLines("b")
+			level.Info(logger).Log("msg", "info", "groupKeyHash", "a")
+			require.Equal(t, append(append([]string{}, tc.want...), "info"), logged)
+
+			// Within the window, the alert group stays limited.
+			logged = nil
+			now = now.Add(59 * time.Second)
+			level.Debug(logger).Log("msg", "11", "groupKeyHash", "a")
+			require.Empty(t, logged)
+
+			// The limit starts over in the next window.
+			now = now.Add(time.Second)
+			logLines("a")
+			require.Equal(t, tc.want, logged)
+		})
+	}
+}
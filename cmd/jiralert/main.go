@@ -14,41 +14,105 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/andygrunwald/go-jira"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus-community/jiralert/pkg/alertmanager"
 	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+	"github.com/prometheus-community/jiralert/pkg/github"
 	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/servicenow"
 	"github.com/prometheus-community/jiralert/pkg/template"
 
-	_ "net/http/pprof"
-
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/version"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
 )
 
 const (
 	unknownReceiver = "<unknown>"
 	logFormatLogfmt = "logfmt"
 	logFormatJSON   = "json"
+
+	// deferredInterval is how often alert groups deferred until business hours or held for min_firing_duration are
+	// checked.
+	deferredInterval = time.Minute
 )
 
 var (
-	listenAddress = flag.String("listen-address", ":9097", "The address to listen on for HTTP requests.")
-	configFile    = flag.String("config", "config/jiralert.yml", "The JIRAlert configuration file")
-	logLevel      = flag.String("log.level", "info", "Log filtering level (debug, info, warn, error)")
-	logFormat     = flag.String("log.format", logFormatLogfmt, "Log format to use ("+logFormatLogfmt+", "+logFormatJSON+")")
-	hashJiraLabel = flag.Bool("hash-jira-label", false, "if enabled: renames ALERT{...} to JIRALERT{...}; also hashes the key-value pairs inside of JIRALERT{...} in the created jira issue labels"+
+	listenAddress    = flag.String("listen-address", ":9097", "The address to listen on for HTTP requests.")
+	telemetryAddress = flag.String("web.telemetry-address", "", "If set, serve /metrics and /debug/pprof on this address instead of the listen address")
+	adminAddress     = flag.String("web.admin-address", "", "If set, serve the administrative endpoints, which change mappings, pause receivers, handle test or dead-lettered notifications or expose payloads, together with all other endpoints on this address. They are disabled otherwise")
+	configFile       = flag.String("config", "config/jiralert.yml", "The JIRAlert configuration file")
+	logLevel         = flag.String("log.level", "info", "Log filtering level (debug, info, warn, error)")
+	logFormat        = flag.String("log.format", logFormatLogfmt, "Log format to use ("+logFormatLogfmt+", "+logFormatJSON+")")
+	logDebugLimit    = flag.Int("log.debug-limit", 0, "Maximum number of debug log lines per alert group and minute, further lines are dropped (0 means unlimited)")
+	logDebugSample   = flag.Int("log.debug-sample", 0, "Log every n-th debug line of an alert group beyond -log.debug-limit (0 drops them all)")
+	hashJiraLabel    = flag.Bool("hash-jira-label", false, "if enabled: renames ALERT{...} to JIRALERT{...}; also hashes the key-value pairs inside of JIRALERT{...} in the created jira issue labels"+
 		"- this ensures that the label text does not overflow the allowed length in jira (255)")
 
+	reconcileAlertmanagerURL = flag.String("reconcile.alertmanager-url", "", "If set, periodically resolve open issues whose alerts are no longer active in this Alertmanager (receivers with auto_resolve only)")
+	reconcileInterval        = flag.Duration("reconcile.interval", 10*time.Minute, "How often to reconcile open issues against Alertmanager")
+	pullAlertmanagerURL      = flag.String("pull.alertmanager-url", "", "If set, periodically poll the active alerts of this Alertmanager and notify the receivers of them, instead of or in addition to receiving webhooks")
+	pullReceiver             = flag.String("pull.receiver", "", "The receiver notified of polled alerts, like the receiver of an Alertmanager route, together with the receivers having it as alertmanager_receiver. Required if more than one receiver is configured")
+	pullInterval             = flag.Duration("pull.interval", time.Minute, "How often to poll Alertmanager for alerts")
+	pullGroupBy              = flag.String("pull.group-by", "alertname", "Comma-separated labels to group polled alerts by, like the group_by of an Alertmanager route")
+	pullRepeatInterval       = flag.Duration("pull.repeat-interval", 4*time.Hour, "How often to notify the receivers of polled alert groups that did not change")
+	natsURL                  = flag.String("nats.url", "", "If set, also read Alertmanager webhook payloads from these comma-separated NATS servers (nats://[user:password@]host[:port], or tls:// to always use TLS)")
+	natsSubject              = flag.String("nats.subject", "jiralert.alerts", "The NATS subject to read payloads from, e.g. the deliver subject of a JetStream push consumer")
+	natsQueue                = flag.String("nats.queue", "", "If set, subscribe in this NATS queue group, so replicas share the payloads")
+	kafkaBrokers             = flag.String("kafka.brokers", "", "If set, also read Alertmanager webhook payloads from Kafka, bootstrapping from these comma-separated brokers (host[:port])")
+	kafkaTopic               = flag.String("kafka.topic", "jiralert-alerts", "The Kafka topic to read payloads from")
+	kafkaGroup               = flag.String("kafka.group", "jiralert", "The Kafka consumer group to join. Replicas sharing the group split the partitions of the topic")
+	kafkaInitialOffset       = flag.String("kafka.initial-offset", "newest", "Where to start reading partitions without committed offsets: oldest or newest")
+	kafkaRetryDelay          = flag.Duration("kafka.retry-delay", time.Minute, "How long to wait before retrying a Kafka message whose notification failed and can be retried")
+	kafkaTLS                 = flag.Bool("kafka.tls", false, "Connect to the Kafka brokers over TLS")
+	kafkaSASLUsername        = flag.String("kafka.sasl-username", "", "If set, authenticate to the Kafka brokers with SASL/PLAIN. The password is read from the KAFKA_SASL_PASSWORD environment variable")
+	sqsQueueURL              = flag.String("sqs.queue-url", "", "If set, also read Alertmanager webhook payloads, optionally wrapped in SNS notifications, from this AWS SQS queue. Credentials are taken from the default AWS credential chain")
+	sqsRegion                = flag.String("sqs.region", "", "The AWS region of the SQS queue, if it cannot be taken from the queue URL")
+	sqsRetryDelay            = flag.Duration("sqs.retry-delay", time.Minute, "How long SQS messages whose notification failed and can be retried stay invisible before they are redelivered")
+	staleIssuesInterval      = flag.Duration("stale-issues.interval", time.Hour, "How often to look for stale issues of receivers with stale_issues configured (requires -reconcile.alertmanager-url)")
+	slaWarningsInterval      = flag.Duration("sla-warnings.interval", 5*time.Minute, "How often to check the SLAs of requests of receivers with service_desk sla_warning configured (requires -reconcile.alertmanager-url)")
+	dedupWindow              = flag.Duration("dedup.window", 0, "Skip notifications identical to one successfully processed within this window (0 disables deduplication)")
+	coalesceWindow           = flag.Duration("coalesce.window", 0, "Hold notifications for this long, replaced by further deliveries for the same alert group, for a single Jira operation (0 disables coalescing). Requires -state.file")
+	stateFile                = flag.String("state.file", "", "If set, persist the state JIRAlert needs across restarts, like the alert group to issue mappings, to this file. Required by -reconcile.alertmanager-url, business_hours, min_firing_duration and pausing receivers")
+	deadLetterDir            = flag.String("dead-letter.dir", "", "If set, store permanently failed notifications in this directory for inspection and replay")
+	deadLetterMaxEntries     = flag.Int("dead-letter.max-entries", 1000, "Maximum number of dead letters to keep, dropping the oldest ones (0 means unlimited)")
+	tracingEndpoint          = flag.String("tracing.endpoint", "", "If set, export traces of notifications and API calls to this OTLP/HTTP endpoint (host:port)")
+	tracingInsecure          = flag.Bool("tracing.insecure", false, "Export traces over plain HTTP instead of HTTPS")
+	assetsCacheTTL           = flag.Duration("jira.assets-cache-ttl", time.Hour, "How long the objects found by the Assets queries of assets_fields are cached")
+	jiraRecordDir            = flag.String("jira.record-dir", "", "If set, record all Jira requests and responses, without credentials, to files in this directory")
+	jiraReplayDir            = flag.String("jira.replay-dir", "", "If set, answer all Jira requests with the responses recorded in this directory instead of sending them")
+	testMode                 = flag.Bool("test-mode", false, "Send the requests of all Jira receivers to a built-in, in-memory fake Jira instead of their api_url, e.g. for integration tests and demos")
+	jiraProbeInterval        = flag.Duration("jira-probe.interval", time.Minute, "How often to probe connectivity to each Jira instance (0 disables probing)")
+	issueInfoLimit           = flag.Int("metrics.issue-info-limit", 0, "Maximum number of alert group to issue mappings exposed by the jiralert_issue_info metric, the most recently updated first (0 disables the metric)")
+
+	// configOverlays are the files given with -config.overlay.
+	configOverlays stringsFlag
+	// pullFilters are the Alertmanager matchers given with -pull.filter.
+	pullFilters stringsFlag
+
+	// startupRequestTimeout bounds the Jira requests checking a project on startup, so an unresponsive Jira does not
+	// keep JIRAlert from starting.
+	startupRequestTimeout = 30 * time.Second
+
 	// Version is the build version, set by make to latest git tag/hash via `-ldflags "-X main.Version=$(VERSION)"`.
 	Version = "<local build>"
 )
@@ -59,9 +123,22 @@ func main() {
 		runtime.SetMutexProfileFraction(1)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "lint-templates" {
+		os.Exit(lintTemplates(os.Args[2:], os.Stdout))
+	}
+
+	flag.Var(&configOverlays, "config.overlay", "A configuration file merged into the JIRAlert configuration file, e.g. with the API URLs and projects of an environment. May be repeated, later overlays take precedence")
+	flag.Var(&pullFilters, "pull.filter", "An Alertmanager matcher, e.g. team=\"storage\", polled alerts must match. May be repeated, alerts must match all filters")
 	flag.Parse()
+	startTime := time.Now()
 
-	var logger = setupLogger(*logLevel, *logFormat)
+	// Builds via promu set the version package, builds via `-X main.Version` only the latter.
+	if version.Version == "" {
+		version.Version = Version
+	}
+	prometheus.MustRegister(version.NewCollector("jiralert"))
+
+	logger, levels := setupLogger(*logLevel, *logFormat, *logDebugLimit, *logDebugSample)
 	level.Info(logger).Log("msg", "starting JIRAlert", "version", Version)
 
 	if !*hashJiraLabel {
@@ -70,11 +147,57 @@ func main() {
 			"and try -hash-jira-label")
 	}
 
-	config, _, err := config.LoadFile(*configFile, logger)
+	if *tracingEndpoint != "" {
+		if err := setupTracing(context.Background(), *tracingEndpoint, *tracingInsecure); err != nil {
+			level.Error(logger).Log("msg", "error setting up tracing", "endpoint", *tracingEndpoint, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	config, _, err := config.LoadFile(*configFile, logger, configOverlays...)
 	if err != nil {
 		level.Error(logger).Log("msg", "error loading configuration", "path", *configFile, "err", err)
 		os.Exit(1)
 	}
+	configLoadTime := time.Now()
+	levels.SetReceivers(config)
+	assetsObjects.ttl = *assetsCacheTTL
+
+	switch {
+	case *jiraRecordDir != "" && *jiraReplayDir != "":
+		level.Error(logger).Log("msg", "-jira.record-dir and -jira.replay-dir are mutually exclusive")
+		os.Exit(1)
+	case *jiraRecordDir != "":
+		if jiraTransport, err = newRecordingTransport(http.DefaultTransport, *jiraRecordDir); err != nil {
+			level.Error(logger).Log("msg", "error setting up Jira recording", "path", *jiraRecordDir, "err", err)
+			os.Exit(1)
+		}
+		level.Warn(logger).Log("msg", "recording Jira requests and responses", "path", *jiraRecordDir)
+	case *jiraReplayDir != "":
+		if jiraTransport, err = newReplayTransport(*jiraReplayDir); err != nil {
+			level.Error(logger).Log("msg", "error loading Jira recordings", "path", *jiraReplayDir, "err", err)
+			os.Exit(1)
+		}
+		level.Warn(logger).Log("msg", "replaying recorded Jira responses instead of talking to Jira", "path", *jiraReplayDir)
+	}
+
+	var fake *fakejira.Server
+	if *testMode {
+		if fake, err = startFakeJira(config, logger); err != nil {
+			level.Error(logger).Log("msg", "error starting fake Jira", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := validateProjects(config, logger); err != nil {
+		level.Error(logger).Log("msg", "error validating projects", "err", err)
+		os.Exit(1)
+	}
+	if err := validatePriorities(config, logger); err != nil {
+		level.Error(logger).Log("msg", "error validating priorities", "err", err)
+		os.Exit(1)
+	}
+	createMappedComponents(config, logger)
 
 	tmpl, err := template.LoadTemplate(config.Template, logger)
 	if err != nil {
@@ -82,117 +205,759 @@ func main() {
 		os.Exit(1)
 	}
 
-	http.HandleFunc("/alert", func(w http.ResponseWriter, req *http.Request) {
-		level.Debug(logger).Log("msg", "handling /alert webhook request")
-		defer func() { _ = req.Body.Close() }()
+	payloads := newPayloadCache(*dedupWindow)
+	failures := newFailureNotifier(config.FailureWebhook, logger)
+	var deadLetters *deadLetterStore
+	if *deadLetterDir != "" {
+		if deadLetters, err = newDeadLetterStore(*deadLetterDir, *deadLetterMaxEntries); err != nil {
+			level.Error(logger).Log("msg", "error setting up dead letter store", "path", *deadLetterDir, "err", err)
+			os.Exit(1)
+		}
+	}
+	state := notify.NewState()
+	if *stateFile != "" {
+		if state, err = notify.LoadState(*stateFile); err != nil {
+			level.Error(logger).Log("msg", "error loading state", "path", *stateFile, "err", err)
+			os.Exit(1)
+		}
+	}
+	for _, rc := range config.Receivers {
+		if rc.BusinessHours != nil && *stateFile == "" {
+			level.Error(logger).Log("msg", "business_hours requires -state.file, to keep the deferred alert groups across restarts", "receiver", rc.Name)
+			os.Exit(1)
+		}
+		if rc.MinFiringDuration != nil && *stateFile == "" {
+			level.Error(logger).Log("msg", "min_firing_duration requires -state.file, to keep the held alert groups across restarts", "receiver", rc.Name)
+			os.Exit(1)
+		}
+	}
+	if *issueInfoLimit > 0 {
+		prometheus.MustRegister(&issueInfoCollector{state: state, limit: *issueInfoLimit})
+	}
+	notifications := newNotificationLog()
+	var reportRetriesAfter time.Duration
+	if config.FailureWebhook != nil {
+		reportRetriesAfter = time.Duration(*config.FailureWebhook.RetryingFor)
+	}
+	retries := newRetryTracker(failures, reportRetriesAfter)
+	go deferredLoop(config, tmpl, state, logger)
+	ingest := &payloadHandler{
+		cfg:         config,
+		tmpl:        tmpl,
+		state:       state,
+		retries:     retries,
+		payloads:    payloads,
+		failures:    failures,
+		deadLetters: deadLetters,
+		logger:      logger,
+	}
+	coalesced := newCoalescer(*coalesceWindow, state, ingest.notifyCoalesced, logger)
+	if coalesced.Enabled() {
+		if *stateFile == "" {
+			level.Error(logger).Log("msg", "-coalesce.window requires -state.file, to keep the held notifications across restarts")
+			os.Exit(1)
+		}
+		coalesced.Restore()
+	}
+	if *natsURL != "" {
+		if _, err := newNATSConsumer(ingest, *natsURL, *natsSubject, *natsQueue); err != nil {
+			level.Error(logger).Log("msg", "error setting up NATS consumer", "err", err)
+			os.Exit(1)
+		}
+	}
+	if *kafkaBrokers != "" {
+		var opts []kgo.Opt
+		if *kafkaTLS {
+			opts = append(opts, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+		}
+		if *kafkaSASLUsername != "" {
+			opts = append(opts, kgo.SASL(plain.Auth{User: *kafkaSASLUsername, Pass: os.Getenv("KAFKA_SASL_PASSWORD")}.AsMechanism()))
+		}
+		consumer, err := newKafkaConsumer(ingest, *kafkaBrokers, *kafkaTopic, *kafkaGroup, *kafkaInitialOffset, *kafkaRetryDelay, opts...)
+		if err != nil {
+			level.Error(logger).Log("msg", "error setting up Kafka consumer", "err", err)
+			os.Exit(1)
+		}
+		go consumer.run()
+	}
+	if *sqsQueueURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		consumer, err := newSQSConsumer(ctx, ingest, *sqsQueueURL, *sqsRegion, *sqsRetryDelay)
+		cancel()
+		if err != nil {
+			level.Error(logger).Log("msg", "error setting up SQS consumer", "err", err)
+			os.Exit(1)
+		}
+		go consumer.run()
+	}
+	telemetry := newTelemetryMux()
+	mux := newServeMux(telemetry, *telemetryAddress != "")
+	mux.Handle("/alert", withRequestLogging(http.HandlerFunc(AlertHandlerFunc(ingest, coalesced, notifications)), logger))
 
-		// https://godoc.org/github.com/prometheus/alertmanager/template#Data
-		data := alertmanager.Data{}
-		if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
-			errorHandler(w, http.StatusBadRequest, err, unknownReceiver, &data, logger)
-			return
+	if *reconcileAlertmanagerURL == "" {
+		for _, rc := range config.Receivers {
+			if rc.StaleIssues != nil {
+				level.Error(logger).Log("msg", "stale_issues requires -reconcile.alertmanager-url, to leave issues of firing alerts open", "receiver", rc.Name)
+				os.Exit(1)
+			}
+		}
+	}
+	if *reconcileAlertmanagerURL != "" {
+		if *stateFile == "" {
+			level.Error(logger).Log("msg", "-reconcile.alertmanager-url requires -state.file, to know the issues of receivers across restarts")
+			os.Exit(1)
+		}
+		amClient, err := alertmanager.NewClient(*reconcileAlertmanagerURL, nil)
+		if err != nil {
+			level.Error(logger).Log("msg", "error setting up reconciliation", "err", err)
+			os.Exit(1)
+		}
+		go reconcileLoop(amClient, config, tmpl, state, *reconcileInterval, logger)
+		go slaWarningsLoop(amClient, config, tmpl, state, *slaWarningsInterval, logger)
+		go staleIssuesLoop(amClient, config, tmpl, state, *staleIssuesInterval, logger)
+	}
+	if *pullAlertmanagerURL != "" {
+		pullClient, err := alertmanager.NewClient(*pullAlertmanagerURL, nil)
+		if err != nil {
+			level.Error(logger).Log("msg", "error setting up pull mode", "err", err)
+			os.Exit(1)
+		}
+		receiver := *pullReceiver
+		if receiver == "" && len(config.Receivers) == 1 {
+			receiver = config.Receivers[0].Name
+		}
+		if receiver == "" {
+			level.Error(logger).Log("msg", "-pull.receiver is required with more than one receiver")
+			os.Exit(1)
+		}
+		if len(config.ReceiversFor(receiver)) == 0 {
+			level.Error(logger).Log("msg", "unknown -pull.receiver", "receiver", receiver)
+			os.Exit(1)
 		}
+		go pullLoop(newPuller(pullClient, receiver, pullFilters, splitLabels(*pullGroupBy), *pullRepeatInterval), config, tmpl, state, *pullInterval, logger)
+	}
+	if *jiraProbeInterval > 0 {
+		go jiraProbeLoop(config, *jiraProbeInterval, logger)
+	}
+
+	mux.HandleFunc("/", HomeHandlerFunc())
+	mux.HandleFunc("/config", ConfigHandlerFunc(config))
+	mux.HandleFunc("/status", StatusHandlerFunc(config, notifications))
+	mux.HandleFunc("/api/v1/status", BuildStatusHandlerFunc(startTime, configLoadTime))
+	mux.HandleFunc("/api/v1/receivers", ReceiversHandlerFunc(config))
+	mux.HandleFunc("/api/v1/receivers/", ReceiversHandlerFunc(config))
+	mux.HandleFunc("/api/v1/mappings", MappingsHandlerFunc(state))
+	mux.HandleFunc("/api/v1/config-schema", ConfigSchemaHandlerFunc())
+	mux.HandleFunc("/api/v1/validate-config", ValidateConfigHandlerFunc(tmpl, logger))
+	if fake != nil {
+		mux.Handle(testModePath+"/", http.StripPrefix(testModePath, fake))
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "OK", http.StatusOK) })
+
+	if os.Getenv("PORT") != "" {
+		*listenAddress = ":" + os.Getenv("PORT")
+	}
 
-		conf := config.ReceiverByName(data.Receiver)
-		if conf == nil {
-			errorHandler(w, http.StatusNotFound, fmt.Errorf("receiver missing: %s", data.Receiver), unknownReceiver, &data, logger)
-			return
+	// The administrative endpoints act on behalf of any caller, so they are only served on their own, e.g.
+	// internal-only, address, which serves the other endpoints too.
+	if *adminAddress != "" {
+		admin := http.NewServeMux()
+		admin.Handle("/", mux)
+		admin.HandleFunc("/playground", PlaygroundHandlerFunc(config, tmpl, *hashJiraLabel))
+		admin.HandleFunc("/api/v1/dead-letters", DeadLettersHandlerFunc(deadLetters))
+		admin.HandleFunc("/api/v1/dead-letters/", DeadLettersHandlerFunc(deadLetters))
+		admin.HandleFunc("/api/v1/replay", ReplayHandlerFunc(config, tmpl, state, deadLetters, logger))
+		admin.HandleFunc("/api/v1/replay/", ReplayHandlerFunc(config, tmpl, state, deadLetters, logger))
+		admin.HandleFunc("/api/v1/test", TestHandlerFunc(config, tmpl, state, *hashJiraLabel, logger))
+		admin.HandleFunc("/api/v1/mappings/detach", MappingActionHandlerFunc(config, tmpl, state, false, logger))
+		admin.HandleFunc("/api/v1/mappings/relink", MappingActionHandlerFunc(config, tmpl, state, true, logger))
+		admin.HandleFunc("/api/v1/incident", IncidentHandlerFunc(config, state))
+		admin.HandleFunc("/api/v1/events", EventsHandlerFunc())
+		admin.HandleFunc("/api/v1/pause", PauseHandlerFunc(config, state, logger))
+		admin.HandleFunc("/api/v1/resume", ResumeHandlerFunc(config, tmpl, state, *hashJiraLabel, logger))
+		go func() {
+			level.Info(logger).Log("msg", "listening for administration", "address", *adminAddress)
+			if err := http.ListenAndServe(*adminAddress, admin); err != nil {
+				level.Error(logger).Log("msg", "failed to start administration HTTP server", "address", *adminAddress, "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	if *telemetryAddress != "" {
+		go func() {
+			level.Info(logger).Log("msg", "listening for telemetry", "address", *telemetryAddress)
+			if err := http.ListenAndServe(*telemetryAddress, telemetry); err != nil {
+				level.Error(logger).Log("msg", "failed to start telemetry HTTP server", "address", *telemetryAddress, "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Writes of the state file are deferred, write the pending ones before exiting.
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		if err := state.Flush(); err != nil {
+			level.Error(logger).Log("msg", "error persisting state", "path", *stateFile, "err", err)
+			os.Exit(1)
 		}
-		level.Debug(logger).Log("msg", "  matched receiver", "receiver", conf.Name)
+		os.Exit(0)
+	}()
 
-		// TODO: Consider reusing notifiers or just jira clients to reuse connections.
-		var client *jira.Client
-		var err error
-		if conf.User != "" && conf.Password != "" {
-			tp := jira.BasicAuthTransport{
-				Username: conf.User,
-				Password: string(conf.Password),
+	level.Info(logger).Log("msg", "listening", "address", *listenAddress)
+	err = http.ListenAndServe(*listenAddress, mux)
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to start HTTP server", "address", *listenAddress)
+		os.Exit(1)
+	}
+}
+
+// validateProjects checks that the static projects of Jira receivers exist and that issues can be created in them,
+// listing the projects the credentials can access if not. Connectivity problems, including requests taking longer than
+// startupRequestTimeout, are only logged, so JIRAlert can still start while Jira is down.
+func validateProjects(cfg *config.Config, logger log.Logger) error {
+	for _, rc := range cfg.Receivers {
+		if rc.Backend != config.BackendJira {
+			continue
+		}
+		client, err := newJiraClient(rc)
+		if err != nil {
+			return err
+		}
+		for _, project := range rc.StaticProjects() {
+			if err := validateProject(client, rc, project, logger); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateProject checks a single project of a receiver for validateProjects.
+func validateProject(client *jira.Client, rc *config.ReceiverConfig, project string, logger log.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), startupRequestTimeout)
+	defer cancel()
+
+	_, resp, err := client.Project.GetWithContext(ctx, project)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("project %q of receiver %q does not exist or is not visible to its credentials%s", project, rc.Name, accessibleProjects(ctx, client))
+		}
+		level.Warn(logger).Log("msg", "could not validate project", "project", project, "receiver", rc.Name, "err", err)
+		return nil
+	}
+	if rc.Shadow {
+		return nil
+	}
+	ok, err := canCreateIssues(ctx, client, project)
+	if err != nil {
+		level.Warn(logger).Log("msg", "could not check permission to create issues", "project", project, "receiver", rc.Name, "err", err)
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("the credentials of receiver %q cannot create issues in project %q%s", rc.Name, project, accessibleProjects(ctx, client))
+	}
+	return nil
+}
+
+// canCreateIssues reports whether the user the client authenticates as may create issues in project.
+func canCreateIssues(ctx context.Context, client *jira.Client, project string) (bool, error) {
+	req, err := client.NewRequestWithContext(ctx, "GET", "rest/api/2/mypermissions?permissions=CREATE_ISSUES&projectKey="+url.QueryEscape(project), nil)
+	if err != nil {
+		return false, err
+	}
+	var res struct {
+		Permissions map[string]struct {
+			HavePermission bool `json:"havePermission"`
+		} `json:"permissions"`
+	}
+	if _, err := client.Do(req, &res); err != nil {
+		return false, err
+	}
+	p, ok := res.Permissions["CREATE_ISSUES"]
+	if !ok {
+		return false, fmt.Errorf("permission CREATE_ISSUES missing from response")
+	}
+	return p.HavePermission, nil
+}
+
+// accessibleProjects returns the keys of the projects visible to the client, for error messages.
+func accessibleProjects(ctx context.Context, client *jira.Client) string {
+	list, _, err := client.Project.GetListWithContext(ctx)
+	if err != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(*list))
+	for _, p := range *list {
+		keys = append(keys, p.Key)
+	}
+	if len(keys) == 0 {
+		return ", no project is accessible"
+	}
+	return ", accessible projects: " + strings.Join(keys, ", ")
+}
+
+// validatePriorities checks that the static priorities of receivers, i.e. priority and the service_desk sla_warning
+// priority unless templated, are allowed by the priority scheme of their projects, as reported by the create metadata
+// of the issue type. Connectivity problems, including requests taking longer than startupRequestTimeout, and projects
+// whose create metadata has no priority field are only logged. JIRAlert has no configuration reload: changed
+// priorities take effect on restart, where they are validated again.
+func validatePriorities(cfg *config.Config, logger log.Logger) error {
+	for _, rc := range cfg.Receivers {
+		if rc.Backend != config.BackendJira {
+			continue
+		}
+		var priorities []string
+		for _, p := range []string{rc.Priority, slaWarningPriority(rc)} {
+			if p != "" && !strings.Contains(p, "{{") {
+				priorities = append(priorities, p)
+			}
+		}
+		if len(priorities) == 0 {
+			continue
+		}
+		client, err := newJiraClient(rc)
+		if err != nil {
+			return err
+		}
+		for _, project := range rc.StaticProjects() {
+			ctx, cancel := context.WithTimeout(context.Background(), startupRequestTimeout)
+			meta, _, err := client.Issue.GetCreateMetaWithOptionsWithContext(ctx, &jira.GetQueryOptions{ProjectKeys: project, Expand: "projects.issuetypes.fields"})
+			cancel()
+			if err != nil {
+				level.Warn(logger).Log("msg", "could not validate priorities", "project", project, "receiver", rc.Name, "err", err)
+				continue
 			}
-			client, err = jira.NewClient(tp.Client(), conf.APIURL)
-		} else if conf.PersonalAccessToken != "" {
-			tp := jira.PATAuthTransport{
-				Token: string(conf.PersonalAccessToken),
+			allowed := allowedPriorities(meta.GetProjectWithKey(project), rc.IssueType)
+			if len(allowed) == 0 {
+				level.Debug(logger).Log("msg", "no priorities in create metadata, not validating priorities", "project", project, "receiver", rc.Name)
+				continue
 			}
-			client, err = jira.NewClient(tp.Client(), conf.APIURL)
+			for _, p := range priorities {
+				if !containsFold(allowed, p) {
+					return fmt.Errorf("priority %q of receiver %q is not valid in project %q, must be one of: %s", p, rc.Name, project, strings.Join(allowed, ", "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func slaWarningPriority(rc *config.ReceiverConfig) string {
+	if rc.ServiceDesk == nil || rc.ServiceDesk.SLAWarning == nil {
+		return ""
+	}
+	return rc.ServiceDesk.SLAWarning.Priority
+}
+
+// allowedPriorities returns the names of the priorities allowed for issues of the given type in project, or of any
+// type if the issue type is templated.
+func allowedPriorities(project *jira.MetaProject, issueType string) []string {
+	if project == nil {
+		return nil
+	}
+	var names []string
+	for _, it := range project.IssueTypes {
+		if !strings.Contains(issueType, "{{") && !strings.EqualFold(it.Name, issueType) {
+			continue
+		}
+		field, _ := it.Fields["priority"].(map[string]interface{})
+		values, _ := field["allowedValues"].([]interface{})
+		for _, v := range values {
+			v, _ := v.(map[string]interface{})
+			if name, ok := v["name"].(string); ok && !containsFold(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// createMappedComponents creates the components of receivers with component_mapping and create_missing that do not
+// exist yet in their projects. Failures, including requests taking longer than startupRequestTimeout, are only logged,
+// issue creation reports missing components anyway.
+func createMappedComponents(cfg *config.Config, logger log.Logger) {
+	for _, rc := range cfg.Receivers {
+		if rc.ComponentMapping == nil || !rc.ComponentMapping.CreateMissing || rc.Backend != config.BackendJira || rc.Shadow {
+			continue
+		}
+		client, err := newJiraClient(rc)
+		if err != nil {
+			level.Warn(logger).Log("msg", "could not create mapped components", "receiver", rc.Name, "err", err)
+			continue
+		}
+		for _, project := range rc.StaticProjects() {
+			ctx, cancel := context.WithTimeout(context.Background(), startupRequestTimeout)
+			p, _, err := client.Project.GetWithContext(ctx, project)
+			cancel()
+			if err != nil {
+				level.Warn(logger).Log("msg", "could not list components", "project", project, "receiver", rc.Name, "err", err)
+				continue
+			}
+			existing := map[string]struct{}{}
+			for _, c := range p.Components {
+				existing[c.Name] = struct{}{}
+			}
+			for _, name := range rc.ComponentMapping.Components() {
+				if _, ok := existing[name]; ok {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), startupRequestTimeout)
+				_, _, err := client.Component.CreateWithContext(ctx, &jira.CreateComponentOptions{Name: name, Project: project})
+				cancel()
+				if err != nil {
+					level.Warn(logger).Log("msg", "could not create component", "component", name, "project", project, "receiver", rc.Name, "err", err)
+					continue
+				}
+				level.Info(logger).Log("msg", "created component", "component", name, "project", project, "receiver", rc.Name)
+			}
+		}
+	}
+}
+
+// notifyReceiver handles a notification for the given receiver, returning the outcome of each of its alert groups.
+func notifyReceiver(ctx context.Context, conf *config.ReceiverConfig, tmpl *template.Template, state *notify.State, data *alertmanager.Data, logger log.Logger) ([]notify.GroupResult, bool, error) {
+	logger = log.With(logger, "receiver", conf.Name)
+	ctx = withEventGroup(ctx, data.GroupKey)
+	events.publish(activityEvent{Type: eventReceived, Receiver: conf.Name, GroupKeyHash: notify.GroupKeyHash(data.GroupKey)})
+	results, retry, err := func() ([]notify.GroupResult, bool, error) {
+		// TODO: Consider reusing notifiers or just jira clients to reuse connections.
+		ticketer, err := newTicketer(ctx, conf, logger)
+		if err != nil {
+			return nil, false, err
 		}
+		ticketer = &eventTicketer{Ticketer: ticketer, receiver: conf.Name}
+		return notify.NewReceiver(logger, conf, tmpl, ticketer, state).NotifyGroups(ctx, data, *hashJiraLabel)
+	}()
+	e := activityEvent{Type: eventHandled, Receiver: conf.Name, GroupKeyHash: notify.GroupKeyHash(data.GroupKey)}
+	if err != nil {
+		e.Type, e.Error = eventFailed, err.Error()
+	}
+	events.publish(e)
+	return results, retry, err
+}
 
+// newTicketer returns the issue tracker API of the receiver's backend. Jira Cloud instances are detected, so users
+// are identified by account ID there.
+func newTicketer(ctx context.Context, conf *config.ReceiverConfig, logger log.Logger) (notify.Ticketer, error) {
+	ticketer, err := newBackendTicketer(ctx, conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &heartbeatTicketer{Ticketer: ticketer, receiver: conf.Name}, nil
+}
+
+// newBackendTicketer returns the issue tracker API of the receiver's backend.
+func newBackendTicketer(ctx context.Context, conf *config.ReceiverConfig, logger log.Logger) (notify.Ticketer, error) {
+	switch conf.Backend {
+	case config.BackendGitHub:
+		return github.NewClient(conf.APIURL, string(conf.PersonalAccessToken), &http.Client{Transport: newTracingTransport(http.DefaultTransport, conf.Backend, conf.Name)})
+	case config.BackendServiceNow:
+		return servicenow.NewClient(conf.APIURL, conf.User, string(conf.Password), string(conf.PersonalAccessToken), conf.ServiceNow.CloseCode, conf.ServiceNow.CloseNotes, &http.Client{Transport: newTracingTransport(http.DefaultTransport, conf.Backend, conf.Name)})
+	}
+	if testModeJira != nil {
+		return testModeJira, nil
+	}
+	client, err := newJiraClient(conf)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := jiraDeployments.Get(ctx, conf.APIURL, client)
+	if err != nil {
+		level.Warn(logger).Log("msg", "could not detect Jira deployment type, assuming Server", "err", err)
+	}
+	var ticketer notify.Ticketer = jiraIssueService{client.Issue}
+	if deployment == deploymentCloud {
+		ticketer = &cloudIssueService{jiraIssueService: jiraIssueService{client.Issue}, users: client.User}
+	}
+	ticketer = &updateOptionsIssueService{Ticketer: ticketer, client: client}
+	if len(conf.AssetsFields) > 0 || conf.ServiceDesk != nil {
+		ticketer = &serviceManagementIssueService{Ticketer: ticketer, client: client, apiURL: conf.APIURL, cloud: deployment == deploymentCloud}
+	}
+	return ticketer, nil
+}
+
+// newJiraClient returns a Jira client authenticated as configured in the receiver.
+func newJiraClient(conf *config.ReceiverConfig) (*jira.Client, error) {
+	if conf.Connect != nil {
+		tp := jira.JWTAuthTransport{
+			Secret:    []byte(conf.Connect.SharedSecret),
+			Issuer:    conf.Connect.Key,
+			Transport: newTracingTransport(jiraTransport, config.BackendJira, conf.Name),
+		}
+		return jira.NewClient(tp.Client(), conf.APIURL)
+	}
+	if (conf.User == "" || conf.Password == "") && conf.PersonalAccessToken == "" {
+		return nil, fmt.Errorf("missing authentication in receiver %q", conf.Name)
+	}
+	tp := newAuthTransport(conf, newTracingTransport(jiraTransport, config.BackendJira, conf.Name))
+	return jira.NewClient(&http.Client{Transport: tp}, conf.APIURL)
+}
+
+// reconcileLoop periodically resolves issues whose alerts disappeared from Alertmanager without JIRAlert receiving
+// the resolve notification.
+func reconcileLoop(am *alertmanager.Client, cfg *config.Config, tmpl *template.Template, state *notify.State, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		groups, err := am.Groups(ctx)
+		cancel()
 		if err != nil {
-			errorHandler(w, http.StatusInternalServerError, err, conf.Name, &data, logger)
-			return
+			level.Error(logger).Log("msg", "error fetching alert groups from Alertmanager; skipping reconciliation", "err", err)
+			continue
 		}
 
-		if retry, err := notify.NewReceiver(logger, conf, tmpl, client.Issue).Notify(&data, *hashJiraLabel); err != nil {
-			var status int
-			if retry {
-				// Instruct Alertmanager to retry.
-				status = http.StatusServiceUnavailable
-			} else {
-				status = http.StatusInternalServerError
+		for _, conf := range cfg.Receivers {
+			if conf.AutoResolve == nil || state.Paused(conf.Name) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			logger := log.With(logger, "receiver", conf.Name)
+			ticketer, err := newTicketer(ctx, conf, logger)
+			if err != nil {
+				cancel()
+				level.Error(logger).Log("msg", "error creating issue tracker client", "err", err)
+				continue
+			}
+			if err := notify.NewReceiver(logger, conf, tmpl, ticketer, state).Reconcile(ctx, groups, *hashJiraLabel); err != nil {
+				level.Error(logger).Log("msg", "error reconciling open issues", "err", err)
 			}
-			errorHandler(w, status, err, conf.Name, &data, logger)
-			return
+			cancel()
 		}
-		requestTotal.WithLabelValues(conf.Name, "200").Inc()
+	}
+}
 
-	})
+// staleIssuesLoop periodically transitions issues that were neither updated nor fired for a while. Issues whose alerts
+// are still active in Alertmanager are never considered stale.
+func staleIssuesLoop(am *alertmanager.Client, cfg *config.Config, tmpl *template.Template, state *notify.State, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	http.HandleFunc("/", HomeHandlerFunc())
-	http.HandleFunc("/config", ConfigHandlerFunc(config))
-	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "OK", http.StatusOK) })
-	http.Handle("/metrics", promhttp.Handler())
+	for ; true; <-ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		groups, err := am.Groups(ctx)
+		cancel()
+		if err != nil {
+			level.Error(logger).Log("msg", "error fetching alert groups from Alertmanager; skipping stale issue cleanup", "err", err)
+			continue
+		}
 
-	if os.Getenv("PORT") != "" {
-		*listenAddress = ":" + os.Getenv("PORT")
+		for _, conf := range cfg.Receivers {
+			if conf.StaleIssues == nil || state.Paused(conf.Name) {
+				continue
+			}
+			closeStaleIssues(conf, tmpl, state, groups, interval, log.With(logger, "receiver", conf.Name))
+		}
 	}
+}
 
-	level.Info(logger).Log("msg", "listening", "address", *listenAddress)
-	err = http.ListenAndServe(*listenAddress, nil)
+// closeStaleIssues transitions the stale issues tracked by the receiver in state, see notify.Receiver.CloseStale.
+func closeStaleIssues(conf *config.ReceiverConfig, tmpl *template.Template, state *notify.State, groups []alertmanager.Data, timeout time.Duration, logger log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ticketer, err := newTicketer(ctx, conf, logger)
 	if err != nil {
-		level.Error(logger).Log("msg", "failed to start HTTP server", "address", *listenAddress)
-		os.Exit(1)
+		level.Error(logger).Log("msg", "error creating issue tracker client", "err", err)
+		return
+	}
+	if err := notify.NewReceiver(logger, conf, tmpl, ticketer, state).CloseStale(ctx, groups, *hashJiraLabel); err != nil {
+		level.Error(logger).Log("msg", "error cleaning up stale issues", "err", err)
 	}
 }
 
-func errorHandler(w http.ResponseWriter, status int, err error, receiver string, data *alertmanager.Data, logger log.Logger) {
+// slaWarningsLoop periodically warns about SLAs of requests that are about to breach while their alerts are still
+// firing in Alertmanager.
+func slaWarningsLoop(am *alertmanager.Client, cfg *config.Config, tmpl *template.Template, state *notify.State, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		groups, err := am.Groups(ctx)
+		cancel()
+		if err != nil {
+			level.Error(logger).Log("msg", "error fetching alert groups from Alertmanager; skipping SLA warnings", "err", err)
+			continue
+		}
+
+		for _, conf := range cfg.Receivers {
+			if conf.ServiceDesk == nil || conf.ServiceDesk.SLAWarning == nil || state.Paused(conf.Name) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			logger := log.With(logger, "receiver", conf.Name)
+			ticketer, err := newTicketer(ctx, conf, logger)
+			if err != nil {
+				cancel()
+				level.Error(logger).Log("msg", "error creating issue tracker client", "err", err)
+				continue
+			}
+			if err := notify.NewReceiver(logger, conf, tmpl, ticketer, state).WarnSLABreaches(ctx, groups, *hashJiraLabel); err != nil {
+				level.Error(logger).Log("msg", "error checking SLAs", "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// deferredLoop periodically creates the issues deferred until business hours or held until their alerts fired for
+// min_firing_duration.
+func deferredLoop(cfg *config.Config, tmpl *template.Template, state *notify.State, logger log.Logger) {
+	ticker := time.NewTicker(deferredInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, conf := range cfg.Receivers {
+			if conf.BusinessHours == nil && conf.MinFiringDuration == nil && state.Queued(conf.Name) == 0 {
+				continue
+			}
+			if state.Paused(conf.Name) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), deferredInterval)
+			logger := log.With(logger, "receiver", conf.Name)
+			ticketer, err := newTicketer(ctx, conf, logger)
+			if err != nil {
+				cancel()
+				level.Error(logger).Log("msg", "error creating issue tracker client", "err", err)
+				continue
+			}
+			receiver := notify.NewReceiver(logger, conf, tmpl, ticketer, state)
+			if _, err := receiver.NotifyHeld(ctx, *hashJiraLabel); err != nil {
+				level.Error(logger).Log("msg", "error processing held alert groups", "err", err)
+			}
+			if _, err := receiver.NotifyDeferred(ctx, *hashJiraLabel); err != nil {
+				level.Error(logger).Log("msg", "error processing deferred alert groups", "err", err)
+			}
+			// Notifications left queued after a failure draining them on resume.
+			if _, _, err := receiver.NotifyQueued(ctx, *hashJiraLabel); err != nil {
+				level.Error(logger).Log("msg", "error processing queued notifications", "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// jiraProbeLoop periodically checks that the credentials of each receiver can reach Jira. Receivers sharing the same
+// API URL and credentials are probed once.
+func jiraProbeLoop(cfg *config.Config, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		type result struct {
+			success  float64
+			duration float64
+		}
+		results := map[string]result{}
+		for _, conf := range cfg.Receivers {
+			if conf.Backend != config.BackendJira {
+				continue
+			}
+			key := strings.Join([]string{conf.APIURL, conf.User, string(conf.Password), string(conf.PersonalAccessToken)}, "\x00")
+			if conf.Connect != nil {
+				key = strings.Join([]string{conf.APIURL, conf.Connect.Key, string(conf.Connect.SharedSecret)}, "\x00")
+			}
+			res, ok := results[key]
+			if !ok {
+				start := time.Now()
+				client, err := newJiraClient(conf)
+				if err == nil {
+					ctx, cancel := context.WithTimeout(context.Background(), interval)
+					_, _, err = client.User.GetSelfWithContext(ctx)
+					cancel()
+				}
+				res = result{success: 1, duration: time.Since(start).Seconds()}
+				if err != nil {
+					level.Warn(logger).Log("msg", "Jira connectivity probe failed", "receiver", conf.Name, "url", conf.APIURL, "err", err)
+					res.success = 0
+				}
+				results[key] = res
+			}
+			jiraProbeSuccess.WithLabelValues(conf.Name).Set(res.success)
+			jiraProbeDuration.WithLabelValues(conf.Name).Set(res.duration)
+		}
+	}
+}
+
+// webhookGroup is the outcome of an alert group of a webhook notification for one of its receivers.
+type webhookGroup struct {
+	Receiver string `json:"receiver"`
+	notify.GroupResult
+}
+
+// webhookResponse is the JSON body of the responses to /alert requests.
+type webhookResponse struct {
+	Error   bool
+	Status  int
+	Message string
+	// Groups are the outcomes of the alert groups of the notification, if it got that far.
+	Groups []webhookGroup `json:",omitempty"`
+}
+
+func writeWebhookResponse(w http.ResponseWriter, status int, message string, groups []webhookGroup) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
+	b, _ := json.Marshal(webhookResponse{Error: status >= http.StatusBadRequest, Status: status, Message: message, Groups: groups})
+	_, _ = w.Write(b)
+}
+
+func errorHandler(w http.ResponseWriter, status int, err error, receiver string, data *alertmanager.Data, groups []webhookGroup, failures *failureNotifier, deadLetters *deadLetterStore, logger log.Logger) {
+	writeWebhookResponse(w, status, err.Error(), groups)
+	reportFailure(status, err, receiver, data, failures, deadLetters, logger)
 
-	response := struct {
-		Error   bool
-		Status  int
-		Message string
-	}{
-		true,
-		status,
-		err.Error(),
-	}
-	// JSON response
-	bytes, _ := json.Marshal(response)
-	json := string(bytes[:])
-	fmt.Fprint(w, json)
-
-	level.Error(logger).Log("msg", "error handling request", "statusCode", status, "statusText", http.StatusText(status), "err", err, "receiver", receiver, "groupLabels", data.GroupLabels)
+	level.Error(logger).Log("msg", "error handling request", "statusCode", status, "statusText", http.StatusText(status), "err", err, "receiver", receiver, "groupKeyHash", notify.GroupKeyHash(data.GroupKey), "groupLabels", data.GroupLabels)
 	requestTotal.WithLabelValues(receiver, strconv.FormatInt(int64(status), 10)).Inc()
 }
 
-func setupLogger(lvl string, fmt string) (logger log.Logger) {
-	var filter level.Option
-	switch lvl {
-	case "error":
-		filter = level.AllowError()
-	case "warn":
-		filter = level.AllowWarn()
-	case "debug":
-		filter = level.AllowDebug()
-	default:
-		filter = level.AllowInfo()
+// reportFailure sends the failure webhook and stores a dead letter for a notification that failed permanently, i.e.
+// with any status but 503, on which Alertmanager retries. Failures that keep being retried are reported by the
+// retryTracker.
+func reportFailure(status int, err error, receiver string, data *alertmanager.Data, failures *failureNotifier, deadLetters *deadLetterStore, logger log.Logger) {
+	if status == http.StatusServiceUnavailable {
+		return
 	}
+	failures.Notify(failureEvent{
+		Receiver:    receiver,
+		GroupKey:    data.GroupKey,
+		GroupLabels: data.GroupLabels,
+		Status:      status,
+		Error:       err.Error(),
+		Timestamp:   time.Now(),
+	})
+	// Notifications for unknown receivers cannot succeed on replay.
+	if receiver != unknownReceiver {
+		if err := deadLetters.Add(deadLetter{Time: time.Now(), Receiver: receiver, Status: status, Error: err.Error(), Data: *data}); err != nil {
+			level.Error(logger).Log("msg", "error storing dead letter", "receiver", receiver, "err", err)
+		}
+	}
+}
 
+// setupLogger returns the logger of the instance and its level filter, which applies the log_level of receivers.
+func setupLogger(lvl string, fmt string, debugLimit, debugSample int) (log.Logger, *levelFilter) {
+	var logger log.Logger
 	if fmt == logFormatJSON {
 		logger = log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
 	} else {
 		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
 	}
-	logger = level.NewFilter(logger, filter)
-	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
-	return
+	if debugLimit > 0 {
+		logger = newSamplingLogger(logger, debugLimit, debugSample, time.Minute)
+	}
+	levels := newLevelFilter(logger, lvl)
+	return log.With(levels, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller), levels
 }
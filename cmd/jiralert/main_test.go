@@ -0,0 +1,195 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateProjects(t *testing.T) {
+	defer func(d time.Duration) { startupRequestTimeout = d }(startupRequestTimeout)
+	startupRequestTimeout = 100 * time.Millisecond
+
+	// HANG does not answer until the request is cancelled, MISSING does not exist.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/project/HANG":
+			<-r.Context().Done()
+		case "/rest/api/2/project":
+			_, _ = w.Write([]byte(`[{"key":"OK"}]`))
+		case "/rest/api/2/project/OK":
+			_, _ = w.Write([]byte(`{"key":"OK"}`))
+		case "/rest/api/2/mypermissions":
+			_, _ = w.Write([]byte(`{"permissions":{"CREATE_ISSUES":{"havePermission":true}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		project string
+		wantErr string
+	}{
+		{project: "OK"},
+		{project: "HANG"},
+		{project: "MISSING", wantErr: `project "MISSING" of receiver "jira" does not exist or is not visible to its credentials, accessible projects: OK`},
+	} {
+		t.Run(tc.project, func(t *testing.T) {
+			cfg := &config.Config{Receivers: []*config.ReceiverConfig{{
+				Name:     "jira",
+				Backend:  config.BackendJira,
+				APIURL:   srv.URL,
+				User:     "jiralert",
+				Password: "secret",
+				Project:  tc.project,
+			}}}
+			start := time.Now()
+			err := validateProjects(cfg, log.NewNopLogger())
+			require.Less(t, time.Since(start), 5*time.Second)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidatePriorities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/createmeta" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("projectKeys") {
+		case "AB":
+			_, _ = w.Write([]byte(`{"projects":[{"key":"AB","issuetypes":[
+				{"name":"Bug","fields":{"priority":{"allowedValues":[{"name":"High"},{"name":"Low"}]}}},
+				{"name":"Task","fields":{"priority":{"allowedValues":[{"name":"Trivial"}]}}}]}]}`))
+		case "NOPRIO":
+			_, _ = w.Write([]byte(`{"projects":[{"key":"NOPRIO","issuetypes":[{"name":"Bug","fields":{}}]}]}`))
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name       string
+		project    string
+		issueType  string
+		priority   string
+		slaWarning string
+		wantErr    string
+	}{
+		{name: "valid", project: "AB", issueType: "Bug", priority: "High"},
+		{name: "case insensitive", project: "AB", issueType: "Bug", priority: "low"},
+		{name: "unknown", project: "AB", issueType: "Bug", priority: "Urgent", wantErr: `priority "Urgent" of receiver "jira" is not valid in project "AB", must be one of: High, Low`},
+		{name: "other issue type", project: "AB", issueType: "Bug", priority: "Trivial", wantErr: `priority "Trivial" of receiver "jira" is not valid in project "AB", must be one of: High, Low`},
+		{name: "templated issue type", project: "AB", issueType: `{{ .CommonLabels.type }}`, priority: "Trivial"},
+		{name: "templated priority", project: "AB", issueType: "Bug", priority: `{{ .CommonLabels.priority }}`},
+		{name: "unknown SLA warning priority", project: "AB", issueType: "Bug", slaWarning: "Urgent", wantErr: `priority "Urgent" of receiver "jira" is not valid in project "AB", must be one of: High, Low`},
+		{name: "no priority field", project: "NOPRIO", issueType: "Bug", priority: "Urgent"},
+		{name: "create metadata unavailable", project: "BROKEN", issueType: "Bug", priority: "Urgent"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := &config.ReceiverConfig{
+				Name:      "jira",
+				Backend:   config.BackendJira,
+				APIURL:    srv.URL,
+				User:      "jiralert",
+				Password:  "secret",
+				Project:   tc.project,
+				IssueType: tc.issueType,
+				Priority:  tc.priority,
+			}
+			if tc.slaWarning != "" {
+				rc.ServiceDesk = &config.ServiceDesk{SLAWarning: &config.SLAWarning{Priority: tc.slaWarning}}
+			}
+			err := validatePriorities(&config.Config{Receivers: []*config.ReceiverConfig{rc}}, log.NewNopLogger())
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCloseStaleIssues(t *testing.T) {
+	fake := fakejira.New()
+	testModeJira = fake
+	defer func() { testModeJira = nil }()
+
+	reopen := config.Duration(0)
+	after := config.Duration(time.Millisecond)
+	conf := &config.ReceiverConfig{
+		Name:           "jira",
+		Backend:        config.BackendJira,
+		Project:        "AB",
+		IssueType:      "Bug",
+		Summary:        `{{ .GroupLabels.alertname }}`,
+		ReopenState:    "To Do",
+		ReopenDuration: &reopen,
+		StaleIssues:    &config.StaleIssues{After: &after, State: "Done"},
+	}
+	state := notify.NewState()
+	for _, alertname := range []string{"Tracked", "Firing"} {
+		_, err := notify.NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fake, state).Notify(context.Background(), &alertmanager.Data{
+			Receiver:    "jira",
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"alertname": alertname},
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		}, *hashJiraLabel)
+		require.NoError(t, err)
+	}
+	// An issue with a JIRAlert label the receiver does not track.
+	_, _, err := fake.CreateWithContext(context.Background(), &jira.Issue{Fields: &jira.IssueFields{
+		Project: jira.Project{Key: "AB"},
+		Summary: "Untracked",
+		Labels:  []string{`ALERT{alertname="Untracked"}`},
+	}})
+	require.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+
+	groups := []alertmanager.Data{{
+		Receiver:    "jira",
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"alertname": "Firing"},
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+	}}
+	closeStaleIssues(conf, template.SimpleTemplate(), state, groups, time.Minute, log.NewNopLogger())
+
+	issues, _, err := fake.SearchWithContext(context.Background(), notify.Query{Project: "AB"}, nil)
+	require.NoError(t, err)
+	statuses := map[string]string{}
+	for _, i := range issues {
+		statuses[i.Fields.Summary] = i.Fields.Status.Name
+	}
+	require.Equal(t, map[string]string{"Tracked": "Done", "Firing": "To Do", "Untracked": "To Do"}, statuses)
+}
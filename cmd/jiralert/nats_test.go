@@ -0,0 +1,143 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificate returns a self-signed certificate for 127.0.0.1 and a pool trusting it.
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestNATSConsumer(t *testing.T) {
+	cert, pool := testCertificate(t)
+	for _, tc := range []struct {
+		name   string
+		scheme string
+		info   string
+		tls    bool
+		queue  string
+	}{
+		{name: "plain", scheme: "nats", info: `{"server_id":"test","max_payload":1048576}`},
+		{name: "queue group", scheme: "nats", info: `{"server_id":"test","max_payload":1048576}`, queue: "jiralert"},
+		{name: "tls required", scheme: "nats", info: `{"tls_required":true,"max_payload":1048576}`, tls: true},
+		{name: "tls scheme", scheme: "tls", info: `{"tls_available":true,"max_payload":1048576}`, tls: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer l.Close()
+
+			// The server answers the subscription with a JetStream message for an unknown receiver, which is
+			// terminated.
+			subs := make(chan string, 1)
+			replies := make(chan string, 1)
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_, _ = conn.Write([]byte("INFO " + tc.info + "\r\n"))
+				if tc.tls {
+					sc := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+					if err := sc.Handshake(); err != nil {
+						replies <- "handshake: " + err.Error()
+						return
+					}
+					conn = sc
+				}
+				r := bufio.NewReader(conn)
+				readLine := func() (string, error) {
+					line, err := r.ReadString('\n')
+					return strings.TrimRight(line, "\r\n"), err
+				}
+				for {
+					line, err := readLine()
+					if err != nil {
+						return
+					}
+					switch {
+					case line == "PING":
+						_, _ = conn.Write([]byte("PONG\r\n"))
+					case strings.HasPrefix(line, "SUB "):
+						f := strings.Fields(line)
+						subs <- strings.Join(f, " ")
+						payload := `{"receiver":"unknown","status":"firing","alerts":[]}`
+						_, _ = conn.Write([]byte("MSG jiralert.alerts " + f[len(f)-1] + " $JS.ACK.1 " + strconv.Itoa(len(payload)) + "\r\n" + payload + "\r\n"))
+					case strings.HasPrefix(line, "PUB $JS.ACK.1 "):
+						reply, _ := readLine()
+						replies <- reply
+					}
+				}
+			}()
+
+			var opts []nats.Option
+			if tc.tls {
+				opts = append(opts, nats.Secure(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}))
+			}
+			h := &payloadHandler{cfg: &config.Config{}, logger: log.NewNopLogger()}
+			c, err := newNATSConsumer(h, tc.scheme+"://"+l.Addr().String(), "jiralert.alerts", tc.queue, opts...)
+			require.NoError(t, err)
+			defer c.conn.Close()
+
+			for _, want := range []string{strings.Join(strings.Fields("SUB jiralert.alerts "+tc.queue+" 1"), " "), "+TERM"} {
+				select {
+				case sub := <-subs:
+					require.Equal(t, want, sub)
+				case reply := <-replies:
+					require.Equal(t, want, reply)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timed out waiting for %q", want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+)
+
+// playgroundMaxBody is the maximum size of a submitted playground form.
+const playgroundMaxBody = 1 << 20
+
+// playground is the state of the `/playground` page.
+type playground struct {
+	Receivers []string
+
+	// The submitted form, or the defaults.
+	Receiver    string
+	Payload     string
+	Summary     string
+	Description string
+
+	Err     error
+	Issues  []playgroundIssue
+	Queries []string
+}
+
+// playgroundIssue is an issue rendered by the playground.
+type playgroundIssue struct {
+	Project, Summary, Description string
+	// Fields are all fields of the issue, as sent to Jira.
+	Fields string
+}
+
+// PlaygroundHandlerFunc is the HTTP handler for the `/playground` page. It renders the issues and the JQL queries of a
+// receiver for a pasted Alertmanager webhook payload, optionally with other summary and description templates.
+func PlaygroundHandlerFunc(cfg *config.Config, tmpl *template.Template, hashJiraLabel bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := &playground{}
+		for _, rc := range cfg.Receivers {
+			p.Receivers = append(p.Receivers, rc.Name)
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			sample := samplePayloads(time.Now())[0].data
+			b, _ := json.MarshalIndent(sample, "", "  ")
+			p.Payload = string(b)
+		case http.MethodPost:
+			r.Body = http.MaxBytesReader(w, r.Body, playgroundMaxBody)
+			if err := r.ParseForm(); err != nil {
+				p.Err = err
+				break
+			}
+			p.Receiver, p.Payload = r.PostForm.Get("receiver"), r.PostForm.Get("payload")
+			p.Summary, p.Description = r.PostForm.Get("summary"), r.PostForm.Get("description")
+			p.Issues, p.Queries, p.Err = renderPlayground(cfg, tmpl, p, hashJiraLabel)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("only GET and POST allowed"))
+			return
+		}
+
+		if err := playgroundTemplate.Execute(w, &tdata{
+			DocsURL:    docsURL,
+			Playground: p,
+		}); err != nil {
+			w.WriteHeader(500)
+		}
+	}
+}
+
+func renderPlayground(cfg *config.Config, tmpl *template.Template, p *playground, hashJiraLabel bool) ([]playgroundIssue, []string, error) {
+	rc := cfg.ReceiverByName(p.Receiver)
+	if rc == nil {
+		return nil, nil, fmt.Errorf("receiver missing: %s", p.Receiver)
+	}
+	var data alertmanager.Data
+	if err := json.Unmarshal([]byte(p.Payload), &data); err != nil {
+		return nil, nil, fmt.Errorf("invalid payload: %s", err)
+	}
+	data.Receiver = rc.Name
+
+	// Rendering must not talk to on-call providers.
+	conf := *rc
+	conf.OnCall = nil
+	if strings.TrimSpace(p.Summary) != "" {
+		conf.Summary = p.Summary
+	}
+	if strings.TrimSpace(p.Description) != "" {
+		conf.Description = p.Description
+	}
+	r := notify.NewReceiver(log.NewNopLogger(), &conf, tmpl, nil, nil)
+
+	rendered, err := r.Render(&data, hashJiraLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+	var issues []playgroundIssue
+	for _, issue := range rendered {
+		fields, err := json.MarshalIndent(issue.Fields, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		issues = append(issues, playgroundIssue{
+			Project:     issue.Fields.Project.Key,
+			Summary:     issue.Fields.Summary,
+			Description: issue.Fields.Description,
+			Fields:      string(fields),
+		})
+	}
+	queries, err := r.SearchQueries(&data, hashJiraLabel)
+	return issues, queries, err
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/stretchr/testify/require"
+)
+
+const playgroundPayload = `{"status":"firing","groupLabels":{"alertname":"DiskFull"},"commonLabels":{"alertname":"DiskFull","severity":"critical"},"alerts":[{"status":"firing","labels":{"alertname":"DiskFull","severity":"critical"}}]}`
+
+func testPlaygroundConfig() *config.Config {
+	reopen := config.Duration(0)
+	return &config.Config{Receivers: []*config.ReceiverConfig{
+		{
+			Name:           "jira-ab",
+			Project:        "AB",
+			IssueType:      "Bug",
+			Summary:        `{{ template "jira.summary" . }}`,
+			Description:    `Severity {{ .CommonLabels.severity }}`,
+			ReopenState:    "To Do",
+			ReopenDuration: &reopen,
+		},
+		{Name: "jira-cd", Project: "CD", IssueType: "Bug", Summary: "CD", ReopenState: "To Do", ReopenDuration: &reopen},
+	}}
+}
+
+func TestPlaygroundHandler(t *testing.T) {
+	tmpl, err := template.ParseTemplate(`{{ define "jira.summary" }}[{{ .Status | toUpper }}] {{ .GroupLabels.alertname }}{{ end }}`, log.NewNopLogger())
+	require.NoError(t, err)
+	handler := PlaygroundHandlerFunc(testPlaygroundConfig(), tmpl, false)
+
+	// The form offers the receivers and a sample payload.
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/playground", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := html.UnescapeString(w.Body.String())
+	require.Contains(t, body, "<option>jira-ab</option><option>jira-cd</option>")
+	require.Contains(t, body, `"alertname": "HighErrorRate"`)
+
+	post := func(form url.Values) string {
+		req := httptest.NewRequest(http.MethodPost, "/playground", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return html.UnescapeString(w.Body.String())
+	}
+
+	// The issue is rendered with the receiver's templates, and the submitted form kept.
+	body = post(url.Values{"receiver": {"jira-ab"}, "payload": {playgroundPayload}})
+	require.Contains(t, body, "<option selected>jira-ab</option>")
+	require.Contains(t, body, "<h2>AB: [FIRING] DiskFull</h2>")
+	require.Contains(t, body, "<pre>Severity critical</pre>")
+	require.Contains(t, body, `<pre>project="AB" and labels="ALERT{alertname=\"DiskFull\"}" order by resolutiondate desc</pre>`)
+	require.NotContains(t, body, "<h2>Error</h2>")
+
+	// Or with the submitted templates.
+	body = post(url.Values{"receiver": {"jira-ab"}, "payload": {playgroundPayload}, "summary": {"{{ .CommonLabels.severity }}: {{ .GroupLabels.alertname }}"}, "description": {"Edited"}})
+	require.Contains(t, body, "<h2>AB: critical: DiskFull</h2>")
+	require.Contains(t, body, "<pre>Edited</pre>")
+
+	for _, tc := range []struct {
+		name    string
+		form    url.Values
+		wantErr string
+	}{
+		{name: "unknown receiver", form: url.Values{"receiver": {"missing"}, "payload": {playgroundPayload}}, wantErr: "receiver missing: missing"},
+		{name: "invalid payload", form: url.Values{"receiver": {"jira-ab"}, "payload": {"{"}}, wantErr: "invalid payload: "},
+		{name: "invalid template", form: url.Values{"receiver": {"jira-ab"}, "payload": {playgroundPayload}, "summary": {"{{ .GroupLabels.alertname | shout }}"}}, wantErr: `function "shout" not defined`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := post(tc.form)
+			require.Contains(t, body, "<h2>Error</h2>")
+			require.Contains(t, body, tc.wantErr)
+			require.NotContains(t, body, "<h3>Description</h3>")
+		})
+	}
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodDelete, "/playground", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,238 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+)
+
+// pulledGroup is an alert group the puller last notified a receiver of.
+type pulledGroup struct {
+	groupLabels alertmanager.KV
+	alerts      map[string]alertmanager.Alert
+	notified    time.Time
+}
+
+// puller drives the receivers from the alerts polled from Alertmanager, for setups where Alertmanager cannot be
+// configured to send webhooks to JIRAlert. Polled alerts are grouped by the groupBy labels, like an Alertmanager route
+// would, and the receivers of the Alertmanager receiver named receiver are notified of a group when its alerts change and
+// every repeatInterval. Alerts that are no longer returned by Alertmanager are notified as resolved. Silenced and
+// inhibited alerts are neither notified nor resolved: as with webhooks, their groups are left as they were notified.
+type puller struct {
+	am             *alertmanager.Client
+	receiver       string
+	filters        []string
+	groupBy        []string
+	repeatInterval time.Duration
+
+	// groups are the groups last notified, by receiver name and group key. Groups which failed to notify are kept as
+	// they were, so the notification is retried on the next poll.
+	groups map[string]map[string]*pulledGroup
+}
+
+func newPuller(am *alertmanager.Client, receiver string, filters, groupBy []string, repeatInterval time.Duration) *puller {
+	return &puller{
+		am:             am,
+		receiver:       receiver,
+		filters:        filters,
+		groupBy:        groupBy,
+		repeatInterval: repeatInterval,
+		groups:         map[string]map[string]*pulledGroup{},
+	}
+}
+
+// pullLoop periodically polls Alertmanager and notifies the receivers of the changes.
+func pullLoop(p *puller, cfg *config.Config, tmpl *template.Template, state *notify.State, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		alerts, suppressed, err := p.am.Alerts(ctx, p.filters)
+		cancel()
+		if err != nil {
+			level.Error(logger).Log("msg", "error fetching alerts from Alertmanager; skipping poll", "err", err)
+			continue
+		}
+
+		for _, conf := range cfg.ReceiversFor(p.receiver) {
+			for _, data := range p.changes(conf.Name, alerts, suppressed, time.Now()) {
+				data := data
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				_, retry, err := notifyReceiver(ctx, conf, tmpl, state, &data, logger)
+				cancel()
+				if err != nil {
+					level.Error(logger).Log("msg", "error notifying pulled alert group", "receiver", conf.Name, "groupKey", data.GroupKey, "retry", retry, "err", err)
+					continue
+				}
+				p.notified(conf.Name, data, time.Now())
+			}
+		}
+	}
+}
+
+// changes returns the notifications due for the receiver given the currently active and suppressed alerts: groups
+// whose active alerts changed since they were last notified, or which were last notified more than repeatInterval ago.
+// Suppressed alerts are kept in the groups they were last notified with, and ignored otherwise.
+func (p *puller) changes(receiver string, alerts, suppressed alertmanager.Alerts, now time.Time) []alertmanager.Data {
+	previous := p.groups[receiver]
+	active := map[string]*pulledGroup{}
+	// Groups with active alerts. Groups with only suppressed alerts are only notified when some of their alerts resolved,
+	// not repeatedly.
+	notify := map[string]bool{}
+	add := func(a alertmanager.Alert, isActive bool) {
+		groupLabels := alertmanager.KV{}
+		for _, name := range p.groupBy {
+			if v, ok := a.Labels[name]; ok {
+				groupLabels[name] = v
+			}
+		}
+		key := pullGroupKey(receiver, groupLabels)
+		if !isActive {
+			prev, ok := previous[key]
+			if !ok {
+				return
+			}
+			if _, ok := prev.alerts[alertID(a)]; !ok {
+				return
+			}
+		}
+		g, ok := active[key]
+		if !ok {
+			g = &pulledGroup{groupLabels: groupLabels, alerts: map[string]alertmanager.Alert{}}
+			active[key] = g
+		}
+		g.alerts[alertID(a)] = a
+		notify[key] = notify[key] || isActive
+	}
+	for _, a := range alerts {
+		add(a, true)
+	}
+	for _, a := range suppressed {
+		add(a, false)
+	}
+
+	var res []alertmanager.Data
+	for key, g := range active {
+		prev, ok := previous[key]
+		if !notify[key] && sameAlerts(prev.alerts, g.alerts) {
+			continue
+		}
+		if ok && sameAlerts(prev.alerts, g.alerts) && now.Sub(prev.notified) < p.repeatInterval {
+			continue
+		}
+		var as alertmanager.Alerts
+		for _, a := range g.alerts {
+			as = append(as, a)
+		}
+		if ok {
+			as = append(as, resolvedSince(prev.alerts, g.alerts, now)...)
+		}
+		res = append(res, alertmanager.NewData(receiver, key, g.groupLabels, sortAlerts(as)))
+	}
+	for key, prev := range previous {
+		if _, ok := active[key]; ok {
+			continue
+		}
+		res = append(res, alertmanager.NewData(receiver, key, prev.groupLabels, sortAlerts(resolvedSince(prev.alerts, nil, now))))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GroupKey < res[j].GroupKey })
+	return res
+}
+
+// notified records that the receiver was successfully notified of the group.
+func (p *puller) notified(receiver string, data alertmanager.Data, now time.Time) {
+	if p.groups[receiver] == nil {
+		p.groups[receiver] = map[string]*pulledGroup{}
+	}
+	if data.Status == alertmanager.AlertResolved {
+		delete(p.groups[receiver], data.GroupKey)
+		return
+	}
+	g := &pulledGroup{groupLabels: data.GroupLabels, alerts: map[string]alertmanager.Alert{}, notified: now}
+	for _, a := range data.Alerts.Firing() {
+		g.alerts[alertID(a)] = a
+	}
+	p.groups[receiver][data.GroupKey] = g
+}
+
+// pullGroupKey returns a group key for polled alerts that is stable across polls and restarts.
+func pullGroupKey(receiver string, groupLabels alertmanager.KV) string {
+	return fmt.Sprintf("pull/%s:%v", receiver, groupLabels.SortedPairs())
+}
+
+// alertID identifies an alert across polls.
+func alertID(a alertmanager.Alert) string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	return fmt.Sprint(a.Labels.SortedPairs())
+}
+
+// sameAlerts reports whether both sets contain the same alerts.
+func sameAlerts(a, b map[string]alertmanager.Alert) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvedSince returns the alerts of prev that are not active anymore, marked resolved at now.
+func resolvedSince(prev, active map[string]alertmanager.Alert, now time.Time) alertmanager.Alerts {
+	var res alertmanager.Alerts
+	for id, a := range prev {
+		if _, ok := active[id]; ok {
+			continue
+		}
+		a.Status = alertmanager.AlertResolved
+		a.EndsAt = now
+		res = append(res, a)
+	}
+	return res
+}
+
+// sortAlerts orders alerts by their labels, so rendered templates do not change between polls.
+func sortAlerts(as alertmanager.Alerts) alertmanager.Alerts {
+	sort.Slice(as, func(i, j int) bool {
+		return fmt.Sprint(as[i].Labels.SortedPairs()) < fmt.Sprint(as[j].Labels.SortedPairs())
+	})
+	return as
+}
+
+// splitLabels parses the comma-separated label names of -pull.group-by.
+func splitLabels(s string) []string {
+	var res []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			res = append(res, name)
+		}
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullerChanges(t *testing.T) {
+	p := newPuller(nil, "jira", nil, []string{"alertname"}, time.Hour)
+	alert := func(name, instance string) alertmanager.Alert {
+		return alertmanager.Alert{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"alertname": name, "instance": instance}, Fingerprint: name + instance}
+	}
+	// poll returns the alerts of the notifications due per group, as alert name, instance and status, and records
+	// them as notified.
+	poll := func(active, suppressed alertmanager.Alerts, now time.Time) map[string][]string {
+		res := map[string][]string{}
+		for _, data := range p.changes("jira", active, suppressed, now) {
+			name := data.GroupLabels["alertname"]
+			for _, a := range data.Alerts {
+				res[name] = append(res[name], a.Labels["instance"]+":"+a.Status)
+			}
+			p.notified("jira", data, now)
+		}
+		return res
+	}
+	start := time.Now()
+
+	require.Equal(t, map[string][]string{
+		"A": {"1:firing", "2:firing"},
+		"B": {"1:firing"},
+	}, poll(alertmanager.Alerts{alert("A", "1"), alert("A", "2"), alert("B", "1")}, nil, start))
+
+	// Unchanged groups are not notified before the repeat interval.
+	require.Empty(t, poll(alertmanager.Alerts{alert("A", "1"), alert("A", "2"), alert("B", "1")}, nil, start.Add(time.Minute)))
+
+	// Suppressed alerts are neither resolved nor notified, unless they are in a group with changes.
+	require.Equal(t, map[string][]string{
+		"A": {"1:firing", "2:firing", "3:firing"},
+	}, poll(alertmanager.Alerts{alert("A", "1"), alert("A", "3")}, alertmanager.Alerts{alert("A", "2"), alert("B", "1"), alert("C", "1")}, start.Add(2*time.Minute)))
+	require.Empty(t, poll(nil, alertmanager.Alerts{alert("A", "1"), alert("A", "2"), alert("A", "3"), alert("B", "1")}, start.Add(2*time.Hour)))
+
+	// Groups of suppressed alerts are notified when some of their alerts resolve, and resolved once none is returned.
+	require.Equal(t, map[string][]string{
+		"A": {"1:firing", "2:resolved", "3:resolved"},
+		"B": {"1:resolved"},
+	}, poll(nil, alertmanager.Alerts{alert("A", "1")}, start.Add(3*time.Hour)))
+}
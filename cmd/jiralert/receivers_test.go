@@ -0,0 +1,104 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiversHandler(t *testing.T) {
+	t.Setenv("JIRALERT_TEST_PASSWORD", "hunter2")
+	t.Setenv("JIRALERT_TEST_PROJECT", "AB")
+	cfg, err := config.Parse([]byte(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: $(JIRALERT_TEST_PASSWORD)
+  issue_type: Bug
+  summary: '{{ template "jira.summary" . }}'
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+    project: $(JIRALERT_TEST_PROJECT)
+    oncall:
+      provider: pagerduty
+      token: pagerduty-token
+      schedule: PSCHED1
+`), t.TempDir(), log.NewNopLogger())
+	require.NoError(t, err)
+	handler := ReceiversHandlerFunc(cfg)
+
+	get := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	for _, path := range []string{"/api/v1/receivers", "/api/v1/receivers/jira-ab/effective"} {
+		w := get(http.MethodGet, path)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NotContains(t, w.Body.String(), "hunter2", path)
+		require.NotContains(t, w.Body.String(), "pagerduty-token", path)
+		// Unset options are omitted.
+		require.NotContains(t, w.Body.String(), "maintenance_windows", path)
+	}
+
+	// The effective configuration has the defaults applied, the environment expanded and the secrets redacted.
+	w := get(http.MethodGet, "/api/v1/receivers/jira-ab/effective")
+	var resp struct {
+		Status string
+		Data   struct {
+			Name      string
+			Project   string
+			IssueType string `json:"issue_type"`
+			Password  string
+			OnCall    struct {
+				Token    string
+				Schedule string
+			} `json:"oncall"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "success", resp.Status)
+	require.Equal(t, "jira-ab", resp.Data.Name)
+	require.Equal(t, "AB", resp.Data.Project)
+	require.Equal(t, "Bug", resp.Data.IssueType)
+	require.Equal(t, "<secret>", resp.Data.Password)
+	require.Equal(t, "<secret>", resp.Data.OnCall.Token)
+	require.Equal(t, "PSCHED1", resp.Data.OnCall.Schedule)
+
+	for _, tc := range []struct {
+		name, method, path string
+		want               int
+		wantBody           string
+	}{
+		{name: "unknown receiver", method: http.MethodGet, path: "/api/v1/receivers/jira-xy/effective", want: http.StatusNotFound, wantBody: "receiver missing: jira-xy"},
+		{name: "unknown endpoint", method: http.MethodGet, path: "/api/v1/receivers/jira-ab/other", want: http.StatusNotFound, wantBody: "unknown endpoint"},
+		{name: "POST", method: http.MethodPost, path: "/api/v1/receivers/jira-ab/effective", want: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := get(tc.method, tc.path)
+			require.Equal(t, tc.want, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), tc.wantBody)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jiraTransport sends the requests of all Jira clients. It is replaced to record or replay Jira traffic.
+var jiraTransport http.RoundTripper = http.DefaultTransport
+
+// redactedHeaders are not recorded, so recordings can be shared without leaking credentials.
+var redactedHeaders = map[string]struct{}{"Authorization": {}, "Cookie": {}, "Set-Cookie": {}}
+
+// recording is a single Jira request and its response, stored as one JSON file.
+type recording struct {
+	Time           time.Time   `json:"time"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"requestHeader,omitempty"`
+	RequestBody    string      `json:"requestBody,omitempty"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"responseHeader,omitempty"`
+	ResponseBody   string      `json:"responseBody,omitempty"`
+}
+
+// key identifies the requests a recording answers on replay: method, path and query, i.e. independent of the host
+// of the Jira instance.
+func (r *recording) key() string {
+	return r.Method + " " + r.URL
+}
+
+func requestKey(req *http.Request) string {
+	return req.Method + " " + req.URL.RequestURI()
+}
+
+func redact(h http.Header) http.Header {
+	c := http.Header{}
+	for k, v := range h {
+		if _, ok := redactedHeaders[http.CanonicalHeaderKey(k)]; !ok {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// recordingTransport records the requests it sends and their responses to files in a directory, named after their
+// sequence number, method and path.
+type recordingTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	mtx sync.Mutex
+	seq int
+}
+
+func newRecordingTransport(base http.RoundTripper, dir string) (*recordingTransport, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &recordingTransport{base: base, dir: dir}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &recording{Time: time.Now(), Method: req.Method, URL: req.URL.RequestURI(), RequestHeader: redact(req.Header)}
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		rec.RequestBody = string(b)
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	rec.Status = resp.StatusCode
+	rec.ResponseHeader = redact(resp.Header)
+	rec.ResponseBody = string(b)
+
+	// Failing to record must not fail the request.
+	_ = t.write(rec)
+	return resp, nil
+}
+
+func (t *recordingTransport) write(rec *recording) error {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	t.mtx.Lock()
+	t.seq++
+	seq := t.seq
+	t.mtx.Unlock()
+
+	path := strings.Trim(strings.ReplaceAll(strings.SplitN(rec.URL, "?", 2)[0], "/", "_"), "_")
+	return os.WriteFile(filepath.Join(t.dir, fmt.Sprintf("%06d-%s-%s.json", seq, rec.Method, path)), b, 0o640)
+}
+
+// replayTransport answers requests with recorded responses instead of sending them. Requests are matched to
+// recordings by method, path and query; recordings of the same request are replayed in the order they were
+// recorded, the last one is repeated once all were replayed.
+type replayTransport struct {
+	mtx        sync.Mutex
+	recordings map[string][]*recording
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recordings found in %s", dir)
+	}
+	sort.Strings(files)
+
+	t := &replayTransport{recordings: map[string][]*recording{}}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		rec := &recording{}
+		if err := json.Unmarshal(b, rec); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", f, err)
+		}
+		t.recordings[rec.key()] = append(t.recordings[rec.key()], rec)
+	}
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	key := requestKey(req)
+
+	t.mtx.Lock()
+	recs := t.recordings[key]
+	var rec *recording
+	if len(recs) > 0 {
+		rec = recs[0]
+		if len(recs) > 1 {
+			t.recordings[key] = recs[1:]
+		}
+	}
+	t.mtx.Unlock()
+
+	if rec == nil {
+		rec = &recording{
+			Status:         http.StatusNotImplemented,
+			ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+			ResponseBody:   fmt.Sprintf(`{"errorMessages":[%q],"errors":{}}`, "no recorded response for "+key),
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.ResponseHeader,
+		Body:          io.NopCloser(strings.NewReader(rec.ResponseBody)),
+		ContentLength: int64(len(rec.ResponseBody)),
+		Request:       req,
+	}, nil
+}
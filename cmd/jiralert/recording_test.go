@@ -0,0 +1,92 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		fmt.Fprintf(w, `{"call":%d,"body":%q}`, calls, body)
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "recordings")
+	rec, err := newRecordingTransport(http.DefaultTransport, dir)
+	require.NoError(t, err)
+	do := func(rt http.RoundTripper, method, path, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.SetBasicAuth("jiralert", "password")
+		resp, err := (&http.Client{Transport: rt}).Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	// The recording transport passes the request body on and returns the response unchanged.
+	_, body := do(rec, http.MethodPost, "/rest/api/2/issue", `{"fields":{}}`)
+	require.Equal(t, `{"call":1,"body":"{\"fields\":{}}"}`, body)
+	_, body = do(rec, http.MethodGet, "/rest/api/2/search?jql=a", "")
+	require.Equal(t, `{"call":2,"body":""}`, body)
+	_, body = do(rec, http.MethodGet, "/rest/api/2/search?jql=a", "")
+	require.Equal(t, `{"call":3,"body":""}`, body)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "000001-POST-rest_api_2_issue.json"),
+		filepath.Join(dir, "000002-GET-rest_api_2_search.json"),
+		filepath.Join(dir, "000003-GET-rest_api_2_search.json"),
+	}, files)
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		require.NoError(t, err)
+		require.NotContains(t, string(b), "Authorization")
+		require.NotContains(t, string(b), "session=secret")
+	}
+
+	// Recordings are replayed in order, the last one repeatedly, independent of the host.
+	srv.Close()
+	replay, err := newReplayTransport(dir)
+	require.NoError(t, err)
+	_, body = do(replay, http.MethodGet, "/rest/api/2/search?jql=a", "")
+	require.Equal(t, `{"call":2,"body":""}`, body)
+	_, body = do(replay, http.MethodGet, "/rest/api/2/search?jql=a", "")
+	require.Equal(t, `{"call":3,"body":""}`, body)
+	_, body = do(replay, http.MethodGet, "/rest/api/2/search?jql=a", "")
+	require.Equal(t, `{"call":3,"body":""}`, body)
+	status, _ := do(replay, http.MethodGet, "/rest/api/2/search?jql=b", "")
+	require.Equal(t, http.StatusNotImplemented, status)
+
+	_, err = newReplayTransport(t.TempDir())
+	require.Error(t, err)
+}
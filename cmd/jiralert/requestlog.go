@@ -0,0 +1,70 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// requestIDHeader carries the ID of a webhook request, both ways: an ID set by the caller or a proxy is reused,
+// otherwise one is generated. It is always returned in the response.
+const requestIDHeader = "X-Request-Id"
+
+// validRequestID restricts reused request IDs, so callers cannot inject arbitrary content into log lines.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type requestLoggerKey struct{}
+
+// withRequestLogging assigns a request ID to every request, returns it in the response header and logs the method,
+// path, status and duration of the request once it is handled. Handlers log through requestLogger, so all log
+// lines of a request carry its ID.
+func withRequestLogging(next http.Handler, logger log.Logger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		rw.Header().Set(requestIDHeader, id)
+		reqLogger := log.With(logger, "request_id", id)
+
+		w := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), requestLoggerKey{}, reqLogger)))
+		level.Info(reqLogger).Log("msg", "handled request", "method", req.Method, "path", req.URL.Path, "status", w.status, "duration", time.Since(start))
+	})
+}
+
+// requestLogger returns the logger of the request with the given context, or logger if it has none.
+func requestLogger(ctx context.Context, logger log.Logger) log.Logger {
+	if l, ok := ctx.Value(requestLoggerKey{}).(log.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	handler := withRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		level.Debug(requestLogger(r.Context(), log.NewNopLogger())).Log("msg", "handling request")
+		w.WriteHeader(http.StatusAccepted)
+	}), log.NewJSONLogger(&buf))
+
+	for _, tc := range []struct {
+		name   string
+		id     string
+		wantID string
+	}{
+		{name: "reused ID", id: "req-1", wantID: "req-1"},
+		{name: "invalid ID", id: "req 1\nlevel=error"},
+		{name: "no ID"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodPost, "/alert?token=query-secret", strings.NewReader(`{"password":"body-secret"}`))
+			req.Header.Set("Authorization", "Bearer header-secret")
+			if tc.id != "" {
+				req.Header.Set(requestIDHeader, tc.id)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(requestIDHeader)
+			if tc.wantID != "" {
+				require.Equal(t, tc.wantID, id)
+			} else {
+				require.Regexp(t, `^[0-9a-f]{16}$`, id)
+			}
+
+			// The line of the handler carries the request ID, the request is logged once handled.
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 2, buf.String())
+			require.Contains(t, lines[0], `"request_id":"`+id+`"`)
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(lines[1]), &line))
+			require.Equal(t, "handled request", line["msg"])
+			require.Equal(t, "info", line["level"])
+			require.Equal(t, id, line["request_id"])
+			require.Equal(t, http.MethodPost, line["method"])
+			require.Equal(t, "/alert", line["path"])
+			require.Equal(t, float64(http.StatusAccepted), line["status"])
+			require.NotEmpty(t, line["duration"])
+
+			// Neither the query, the headers nor the body are logged.
+			for _, secret := range []string{"query-secret", "header-secret", "body-secret", "level=error"} {
+				require.NotContains(t, buf.String(), secret)
+			}
+		})
+	}
+}
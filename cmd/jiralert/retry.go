@@ -0,0 +1,137 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// retryForgetAfter is how long alert groups that stopped failing without succeeding, e.g. because Alertmanager gave
+// up retrying, are remembered.
+const retryForgetAfter = 24 * time.Hour
+
+type retryKey struct {
+	receiver string
+	groupKey string
+}
+
+type retryEntry struct {
+	since    time.Time
+	failures int
+	// The group labels and error of the last failure, and whether the failures were reported.
+	groupLabels alertmanager.KV
+	err         string
+	reported    bool
+}
+
+// retryTracker remembers since when notifications of alert groups are failing with retryable errors, i.e. are being
+// retried by Alertmanager. It is used to expose the number of retried alert groups and to only use fallback receivers
+// once the failures persist for the configured duration.
+//
+// Retried failures are not reported when they happen, as Alertmanager's retries usually succeed. They are reported to
+// the failure webhook once an alert group has been failing for reportAfter, or when it is forgotten without having
+// succeeded, i.e. Alertmanager gave up.
+type retryTracker struct {
+	mtx     sync.Mutex
+	failing map[retryKey]*retryEntry
+
+	failures    *failureNotifier
+	reportAfter time.Duration
+}
+
+func newRetryTracker(failures *failureNotifier, reportAfter time.Duration) *retryTracker {
+	return &retryTracker{failing: map[retryKey]*retryEntry{}, failures: failures, reportAfter: reportAfter}
+}
+
+// Failed records a retryable failure of the alert group and returns how long it has been failing.
+func (t *retryTracker) Failed(receiver string, data *alertmanager.Data, err error, now time.Time) time.Duration {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for k, e := range t.failing {
+		if now.Sub(e.since) >= retryForgetAfter {
+			delete(t.failing, k)
+			t.updateGauge(k.receiver)
+			if !e.reported {
+				t.report(k, e, fmt.Sprintf("no longer retried after %d failures, last error: %s", e.failures, e.err), now)
+			}
+		}
+	}
+	k := retryKey{receiver: receiver, groupKey: data.GroupKey}
+	e, ok := t.failing[k]
+	if !ok {
+		e = &retryEntry{since: now}
+		t.failing[k] = e
+		t.updateGauge(receiver)
+	}
+	e.failures++
+	e.groupLabels, e.err = data.GroupLabels, err.Error()
+	if failingFor := now.Sub(e.since); !e.reported && failingFor >= t.reportAfter {
+		e.reported = true
+		t.report(k, e, fmt.Sprintf("still failing after %d retries for %s: %s", e.failures, failingFor.Round(time.Second), e.err), now)
+	}
+	return now.Sub(e.since)
+}
+
+// report sends the failure webhook for the retried failures of the alert group.
+func (t *retryTracker) report(k retryKey, e *retryEntry, msg string, now time.Time) {
+	t.failures.Notify(failureEvent{
+		Receiver:    k.receiver,
+		GroupKey:    k.groupKey,
+		GroupLabels: e.groupLabels,
+		Status:      http.StatusServiceUnavailable,
+		Error:       msg,
+		Timestamp:   now,
+	})
+}
+
+// Retries returns the number of retryable failures of the alert group since it last succeeded, i.e. how often the
+// current notification is being retried.
+func (t *retryTracker) Retries(receiver, groupKey string) int {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if e, ok := t.failing[retryKey{receiver: receiver, groupKey: groupKey}]; ok {
+		return e.failures
+	}
+	return 0
+}
+
+// Succeeded forgets the failures of the alert group, i.e. it is not retried anymore.
+func (t *retryTracker) Succeeded(receiver, groupKey string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	k := retryKey{receiver: receiver, groupKey: groupKey}
+	if _, ok := t.failing[k]; ok {
+		delete(t.failing, k)
+		t.updateGauge(receiver)
+	}
+}
+
+// updateGauge must be called with mtx held.
+func (t *retryTracker) updateGauge(receiver string) {
+	n := 0
+	for k := range t.failing {
+		if k.receiver == receiver {
+			n++
+		}
+	}
+	retryingGroups.WithLabelValues(receiver).Set(float64(n))
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTrackerReports(t *testing.T) {
+	events := make(chan failureEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e failureEvent
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+	}))
+	defer srv.Close()
+
+	failures := newFailureNotifier(&config.FailureWebhook{URL: config.Secret(srv.URL), Format: config.FailureWebhookGeneric}, log.NewNopLogger())
+	tracker := newRetryTracker(failures, time.Hour)
+	noEvent := func() {
+		t.Helper()
+		select {
+		case e := <-events:
+			t.Fatalf("unexpected event %+v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	nextEvent := func() failureEvent {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the failure webhook")
+		}
+		return failureEvent{}
+	}
+
+	start := time.Now()
+	a := &alertmanager.Data{GroupKey: "a", GroupLabels: alertmanager.KV{"alertname": "A"}}
+	b := &alertmanager.Data{GroupKey: "b", GroupLabels: alertmanager.KV{"alertname": "B"}}
+	jiraDown := errors.New("jira is down")
+
+	// Retries are not reported until the group has been failing for an hour, and then only once.
+	require.Equal(t, time.Duration(0), tracker.Failed("jira", a, jiraDown, start))
+	require.Equal(t, 30*time.Minute, tracker.Failed("jira", a, jiraDown, start.Add(30*time.Minute)))
+	noEvent()
+	require.Equal(t, time.Hour, tracker.Failed("jira", a, jiraDown, start.Add(time.Hour)))
+	e := nextEvent()
+	require.Equal(t, "jira", e.Receiver)
+	require.Equal(t, "a", e.GroupKey)
+	require.Equal(t, alertmanager.KV{"alertname": "A"}, e.GroupLabels)
+	require.Equal(t, http.StatusServiceUnavailable, e.Status)
+	require.Equal(t, "still failing after 3 retries for 1h0m0s: jira is down", e.Error)
+	tracker.Failed("jira", a, jiraDown, start.Add(2*time.Hour))
+	noEvent()
+
+	// A group that stops failing without succeeding is reported when it is forgotten, unless it already was.
+	tracker.Failed("jira", b, errors.New("timeout"), start.Add(2*time.Hour))
+	tracker.Failed("jira", b, jiraDown, start.Add(2*time.Hour+time.Minute))
+	tracker.Failed("other", a, jiraDown, start.Add(2*time.Hour+retryForgetAfter))
+	e = nextEvent()
+	require.Equal(t, "b", e.GroupKey)
+	require.Equal(t, "no longer retried after 2 failures, last error: jira is down", e.Error)
+	noEvent()
+
+	// Succeeded groups are not reported.
+	tracker.Failed("jira", b, jiraDown, start.Add(3*time.Hour+retryForgetAfter))
+	tracker.Succeeded("jira", "b")
+	tracker.Succeeded("other", "a")
+	tracker.Failed("jira", a, jiraDown, start.Add(4*time.Hour+2*retryForgetAfter))
+	noEvent()
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// organizationsCacheTTL is how long the organizations of a Jira Service Management instance are cached.
+const organizationsCacheTTL = 10 * time.Minute
+
+// jsmOrganizations caches the organization IDs of Jira Service Management instances, by API URL and name.
+var jsmOrganizations = &organizationCache{entries: map[string]organizationCacheEntry{}}
+
+type organizationCacheEntry struct {
+	ids     map[string]int
+	expires time.Time
+}
+
+type organizationCache struct {
+	mtx     sync.Mutex
+	entries map[string]organizationCacheEntry
+}
+
+// OrganizationIDsWithContext implements notify.ServiceDeskResolver. Numeric organizations are taken as IDs, others
+// are looked up by name.
+func (s *serviceManagementIssueService) OrganizationIDsWithContext(ctx context.Context, organizations []string) ([]int, error) {
+	var ids []int
+	for _, o := range organizations {
+		if id, err := strconv.Atoi(o); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		byName, err := s.organizations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		id, ok := byName[o]
+		if !ok {
+			return nil, fmt.Errorf("unknown organization %q", o)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// organizations returns the IDs of all organizations of the instance, by name.
+func (s *serviceManagementIssueService) organizations(ctx context.Context) (map[string]int, error) {
+	jsmOrganizations.mtx.Lock()
+	e, ok := jsmOrganizations.entries[s.apiURL]
+	jsmOrganizations.mtx.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.ids, nil
+	}
+
+	ids := map[string]int{}
+	for start := 0; ; {
+		req, err := s.client.NewRequestWithContext(ctx, "GET", fmt.Sprintf("rest/servicedeskapi/organization?start=%d&limit=50", start), nil)
+		if err != nil {
+			return nil, err
+		}
+		// Required by older Jira Service Management Server versions.
+		req.Header.Set("X-ExperimentalApi", "opt-in")
+		var page struct {
+			Values []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"values"`
+			IsLastPage bool `json:"isLastPage"`
+		}
+		if _, err := s.client.Do(req, &page); err != nil {
+			return nil, errors.Wrap(err, "list organizations")
+		}
+		for _, o := range page.Values {
+			id, err := strconv.Atoi(o.ID)
+			if err != nil {
+				return nil, errors.Wrapf(err, "organization %q", o.Name)
+			}
+			ids[o.Name] = id
+		}
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start += len(page.Values)
+	}
+
+	jsmOrganizations.mtx.Lock()
+	jsmOrganizations.entries[s.apiURL] = organizationCacheEntry{ids: ids, expires: time.Now().Add(organizationsCacheTTL)}
+	jsmOrganizations.mtx.Unlock()
+	return ids, nil
+}
+
+// ParticipantsWithContext implements notify.ServiceDeskResolver. Jira Cloud references users by account ID, which are
+// looked up like assignees, Server and Data Center by name.
+func (s *serviceManagementIssueService) ParticipantsWithContext(ctx context.Context, users []string) ([]interface{}, error) {
+	var values []interface{}
+	for _, u := range users {
+		if !s.cloud {
+			values = append(values, map[string]interface{}{"name": u})
+			continue
+		}
+		accountID, _, err := (&cloudIssueService{users: s.client.User}).accountID(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, map[string]interface{}{"accountId": accountID})
+	}
+	return values, nil
+}
+
+// SLAsWithContext implements notify.ServiceDeskResolver. SLAs without an ongoing cycle, e.g. already met, are skipped.
+func (s *serviceManagementIssueService) SLAsWithContext(ctx context.Context, issueKey string) ([]notify.SLA, error) {
+	var slas []notify.SLA
+	for start := 0; ; {
+		req, err := s.client.NewRequestWithContext(ctx, "GET", fmt.Sprintf("rest/servicedeskapi/request/%s/sla?start=%d&limit=50", issueKey, start), nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Values []struct {
+				Name         string `json:"name"`
+				OngoingCycle *struct {
+					StartTime struct {
+						EpochMillis int64 `json:"epochMillis"`
+					} `json:"startTime"`
+					RemainingTime struct {
+						Millis int64 `json:"millis"`
+					} `json:"remainingTime"`
+					Breached bool `json:"breached"`
+					Paused   bool `json:"paused"`
+				} `json:"ongoingCycle"`
+			} `json:"values"`
+			IsLastPage bool `json:"isLastPage"`
+		}
+		if _, err := s.client.Do(req, &page); err != nil {
+			return nil, errors.Wrap(err, "list SLAs")
+		}
+		for _, v := range page.Values {
+			c := v.OngoingCycle
+			if c == nil {
+				continue
+			}
+			slas = append(slas, notify.SLA{
+				Name:      v.Name,
+				StartTime: time.Unix(0, c.StartTime.EpochMillis*int64(time.Millisecond)),
+				Remaining: time.Duration(c.RemainingTime.Millis) * time.Millisecond,
+				Breached:  c.Breached,
+				Paused:    c.Paused,
+			})
+		}
+		if page.IsLastPage || len(page.Values) == 0 {
+			break
+		}
+		start += len(page.Values)
+	}
+	return slas, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	// sqsWaitTime is how long a ReceiveMessage call waits for messages (long polling, at most 20s).
+	sqsWaitTime   = 20
+	sqsMaxBatch   = 10
+	sqsErrorDelay = 5 * time.Second
+)
+
+// sqsAPI are the SQS operations used by the consumer, implemented by *sqs.Client.
+type sqsAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+// snsNotification is the envelope SNS wraps messages in when delivering them to SQS without raw message delivery.
+type snsNotification struct {
+	Type     string `json:"Type"`
+	TopicArn string `json:"TopicArn"`
+	Message  string `json:"Message"`
+}
+
+// sqsConsumer reads Alertmanager webhook payloads from an SQS queue, unwrapping SNS notifications, and handles them
+// like the /alert endpoint. Handled messages are deleted. Messages whose notification can be retried are made visible
+// again after retryDelay, so SQS redelivers them until the queue's redrive policy moves them to a dead-letter queue.
+type sqsConsumer struct {
+	*payloadHandler
+
+	queueURL   string
+	retryDelay time.Duration
+	client     sqsAPI
+}
+
+// newSQSConsumer returns a consumer of the queue with the given URL, e.g.
+// https://sqs.eu-west-1.amazonaws.com/123456789012/alerts. The region is taken from the URL unless given. Credentials
+// are taken from the default AWS credential chain, and checked to be available.
+func newSQSConsumer(ctx context.Context, h *payloadHandler, queueURL, region string, retryDelay time.Duration) (*sqsConsumer, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("invalid SQS queue URL %q", queueURL)
+	}
+	if region == "" {
+		// sqs.<region>.amazonaws.com, or the legacy <region>.queue.amazonaws.com.
+		parts := strings.Split(u.Hostname(), ".")
+		switch {
+		case len(parts) >= 4 && parts[0] == "sqs":
+			region = parts[1]
+		case len(parts) >= 4 && parts[1] == "queue":
+			region = parts[0]
+		default:
+			return nil, errors.Errorf("cannot determine the region of SQS queue URL %q, set it explicitly", queueURL)
+		}
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, errors.Wrap(err, "load AWS configuration")
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, errors.Wrap(err, "retrieve AWS credentials")
+	}
+	return &sqsConsumer{
+		payloadHandler: h,
+		queueURL:       queueURL,
+		retryDelay:     retryDelay,
+		client: sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+			// The queue is served by the host of its URL, which need not be the default endpoint of the region.
+			o.BaseEndpoint = aws.String(u.Scheme + "://" + u.Host)
+		}),
+	}, nil
+}
+
+// run receives and handles messages forever.
+func (c *sqsConsumer) run() {
+	logger := log.With(c.logger, "queue", c.queueURL)
+	level.Info(logger).Log("msg", "consuming alerts from SQS")
+	for {
+		res, err := c.client.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(c.queueURL),
+			MaxNumberOfMessages: sqsMaxBatch,
+			WaitTimeSeconds:     sqsWaitTime,
+		})
+		if err != nil {
+			level.Error(logger).Log("msg", "error receiving SQS messages", "err", err)
+			time.Sleep(sqsErrorDelay)
+			continue
+		}
+		for _, m := range res.Messages {
+			c.handleMessage(m, log.With(logger, "messageID", aws.ToString(m.MessageId)))
+		}
+	}
+}
+
+func (c *sqsConsumer) handleMessage(m types.Message, logger log.Logger) {
+	var err error
+	switch c.handle(sqsPayload(aws.ToString(m.Body)), "SQS message", logger) {
+	case payloadRetry:
+		_, err = c.client.ChangeMessageVisibility(context.Background(), &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          aws.String(c.queueURL),
+			ReceiptHandle:     m.ReceiptHandle,
+			VisibilityTimeout: int32(c.retryDelay.Seconds()),
+		})
+	default:
+		// So are the messages which cannot succeed on redelivery, whose failure was reported like that of a webhook
+		// request.
+		_, err = c.client.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(c.queueURL),
+			ReceiptHandle: m.ReceiptHandle,
+		})
+	}
+	if err != nil {
+		level.Error(logger).Log("msg", "error acknowledging SQS message", "err", err)
+	}
+}
+
+// sqsPayload returns the payload of a message body, unwrapping SNS notifications.
+func sqsPayload(body string) []byte {
+	var n snsNotification
+	if err := json.Unmarshal([]byte(body), &n); err == nil && n.Type == "Notification" && n.TopicArn != "" {
+		return []byte(n.Message)
+	}
+	return []byte(body)
+}
@@ -0,0 +1,114 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// testAWSEnv isolates the AWS credential chain from the environment, setting static credentials if accessKeyID is.
+func testAWSEnv(t *testing.T, accessKeyID string) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_ACCESS_KEY_ID", accessKeyID)
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+}
+
+func TestNewSQSConsumer(t *testing.T) {
+	testAWSEnv(t, "AKID")
+	h := &payloadHandler{}
+	for _, tc := range []struct {
+		url     string
+		region  string
+		wantErr bool
+	}{
+		{url: "https://sqs.eu-west-1.amazonaws.com/123456789012/alerts"},
+		{url: "https://eu-west-1.queue.amazonaws.com/123456789012/alerts"},
+		{url: "http://localhost:4566/000000000000/alerts", region: "us-east-1"},
+		{url: "http://localhost:4566/000000000000/alerts", wantErr: true},
+		{url: "alerts", region: "us-east-1", wantErr: true},
+	} {
+		_, err := newSQSConsumer(context.Background(), h, tc.url, tc.region, time.Minute)
+		if tc.wantErr {
+			require.Error(t, err, tc.url)
+		} else {
+			require.NoError(t, err, tc.url)
+		}
+	}
+
+	// Without credentials, setting up the consumer fails.
+	testAWSEnv(t, "")
+	_, err := newSQSConsumer(context.Background(), h, "https://sqs.eu-west-1.amazonaws.com/123456789012/alerts", "", time.Minute)
+	require.Error(t, err)
+}
+
+func TestSQSPayload(t *testing.T) {
+	payload := `{"receiver":"jira","status":"firing","alerts":[]}`
+	require.Equal(t, payload, string(sqsPayload(payload)))
+	require.Equal(t, payload, string(sqsPayload(`{"Type":"Notification","TopicArn":"arn:aws:sns:eu-west-1:123456789012:alerts","Message":"{\"receiver\":\"jira\",\"status\":\"firing\",\"alerts\":[]}"}`)))
+	// Only SNS notifications are unwrapped.
+	other := `{"Type":"SubscriptionConfirmation","TopicArn":"arn:aws:sns:eu-west-1:123456789012:alerts","Message":"confirm"}`
+	require.Equal(t, other, string(sqsPayload(other)))
+}
+
+func TestSQSConsumer(t *testing.T) {
+	testAWSEnv(t, "AKID")
+	deleted := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/sqs/aws4_request") ||
+			r.PostForm.Get("Action") != "DeleteMessage" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		deleted <- r.PostForm.Get("QueueUrl") + " " + r.PostForm.Get("ReceiptHandle")
+		fmt.Fprint(w, `<DeleteMessageResponse><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DeleteMessageResponse>`)
+	}))
+	defer srv.Close()
+
+	h := &payloadHandler{cfg: &config.Config{}, logger: log.NewNopLogger()}
+	c, err := newSQSConsumer(context.Background(), h, srv.URL+"/123456789012/alerts", "eu-west-1", time.Minute)
+	require.NoError(t, err)
+
+	// The payload, wrapped by SNS, is for an unknown receiver: it is dropped, and the message deleted.
+	body := `{"Type":"Notification","TopicArn":"arn:aws:sns:eu-west-1:123456789012:alerts","Message":"{\"receiver\":\"unknown\",\"status\":\"firing\",\"alerts\":[]}"}`
+	c.handleMessage(types.Message{MessageId: aws.String("1"), ReceiptHandle: aws.String("receipt"), Body: aws.String(body)}, log.NewNopLogger())
+	select {
+	case d := <-deleted:
+		require.Equal(t, srv.URL+"/123456789012/alerts receipt", d)
+	default:
+		t.Fatal("the message was not deleted")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// recentNotificationsSize is the number of notifications kept for the status page.
+const recentNotificationsSize = 50
+
+// notificationRecord is the outcome of a single `/alert` request.
+type notificationRecord struct {
+	Time        time.Time
+	Receiver    string
+	GroupKey    string
+	GroupLabels alertmanager.KV
+	Status      int
+	IssueKeys   []string
+	Duration    time.Duration
+}
+
+// OK reports whether the notification was handled successfully.
+func (n notificationRecord) OK() bool { return n.Status < 300 }
+
+// notificationLog keeps the most recent notifications and error counts per receiver, for the status page.
+type notificationLog struct {
+	mtx    sync.Mutex
+	recent []notificationRecord
+	errors map[string]int
+}
+
+func newNotificationLog() *notificationLog {
+	return &notificationLog{errors: map[string]int{}}
+}
+
+// Add records a notification, dropping the oldest one if the log is full.
+func (l *notificationLog) Add(n notificationRecord) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if len(l.recent) >= recentNotificationsSize {
+		l.recent = l.recent[1:]
+	}
+	l.recent = append(l.recent, n)
+	if !n.OK() {
+		l.errors[n.Receiver]++
+	}
+}
+
+// Recent returns the recorded notifications, newest first.
+func (l *notificationLog) Recent() []notificationRecord {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	res := make([]notificationRecord, 0, len(l.recent))
+	for i := len(l.recent) - 1; i >= 0; i-- {
+		res = append(res, l.recent[i])
+	}
+	return res
+}
+
+// Errors returns the number of failed notifications of the given receiver since startup.
+func (l *notificationLog) Errors(receiver string) int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.errors[receiver]
+}
+
+// statusRecorder is a http.ResponseWriter remembering the response status code.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
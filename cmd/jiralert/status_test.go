@@ -0,0 +1,88 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationLog(t *testing.T) {
+	l := newNotificationLog()
+	for i := 0; i < recentNotificationsSize+2; i++ {
+		status := http.StatusOK
+		if i%2 == 1 {
+			status = http.StatusInternalServerError
+		}
+		l.Add(notificationRecord{Receiver: "jira", GroupKey: time.Duration(i).String(), Status: status})
+	}
+
+	// The log keeps the most recent notifications, newest first, but counts all errors.
+	recent := l.Recent()
+	require.Len(t, recent, recentNotificationsSize)
+	require.Equal(t, time.Duration(recentNotificationsSize+1).String(), recent[0].GroupKey)
+	require.Equal(t, time.Duration(2).String(), recent[len(recent)-1].GroupKey)
+	require.False(t, recent[0].OK())
+	require.True(t, recent[1].OK())
+	require.Equal(t, recentNotificationsSize/2+1, l.Errors("jira"))
+	require.Equal(t, 0, l.Errors("other"))
+}
+
+func TestStatusHandler(t *testing.T) {
+	cfg := &config.Config{Receivers: []*config.ReceiverConfig{
+		{Name: "jira-ab", APIURL: "https://jira.example.com", Project: "AB"},
+		{Name: "jira-cd", APIURL: "https://jira.example.com", Project: "CD"},
+	}}
+	l := newNotificationLog()
+	handler := StatusHandlerFunc(cfg, l)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "<tr><td>jira-ab</td><td>https://jira.example.com</td><td>AB</td><td>0</td></tr>")
+	require.Contains(t, w.Body.String(), "No notifications received since startup.")
+
+	l.Add(notificationRecord{
+		Time:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Receiver:    "jira-ab",
+		GroupLabels: alertmanager.KV{"alertname": "<Down>", "cluster": "eu"},
+		Status:      http.StatusOK,
+		IssueKeys:   []string{"AB-1", "AB-2"},
+		Duration:    1500 * time.Millisecond,
+	})
+	l.Add(notificationRecord{Receiver: "jira-cd", Status: http.StatusServiceUnavailable})
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	require.Contains(t, body, "<tr><td>jira-cd</td><td>https://jira.example.com</td><td>CD</td><td>1</td></tr>")
+	require.Contains(t, body, "<td>2024-01-02 03:04:05</td>")
+	// Group labels are escaped.
+	require.Contains(t, body, `<td>alertname="&lt;Down&gt;", cluster="eu"</td>`)
+	require.Contains(t, body, "<td>AB-1, AB-2</td>")
+	require.Contains(t, body, "<td>1.5s</td>")
+	require.Contains(t, body, `<td class="error">503</td>`)
+	require.NotContains(t, body, "No notifications received since startup.")
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/status", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
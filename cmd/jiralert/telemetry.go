@@ -13,7 +13,17 @@
 
 package main
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
 
 var (
 	requestTotal = prometheus.NewCounterVec(
@@ -23,8 +33,199 @@ var (
 		},
 		[]string{"receiver", "code"},
 	)
+	deduplicatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_deduplicated_requests_total",
+			Help: "Requests skipped because an identical notification was recently processed, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	coalescedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_coalesced_requests_total",
+			Help: "Requests replacing a pending notification of the same alert group, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	lastSuccessfulNotify = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jiralert_last_successful_notify_timestamp_seconds",
+			Help: "Unix timestamp of the last successful write to the issue tracker, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	jiraProbeSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jiralert_jira_probe_success",
+			Help: "Whether the last synthetic Jira connectivity probe succeeded (1) or not (0), by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	jiraProbeDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jiralert_jira_probe_duration_seconds",
+			Help: "Duration of the last synthetic Jira connectivity probe, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	pendingNotifications = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "jiralert_pending_notifications",
+			Help: "Notifications currently being handled.",
+		},
+	)
+	retryingGroups = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jiralert_retrying_alert_groups",
+			Help: "Alert groups whose last notification failed with a retryable error, i.e. is being retried by Alertmanager, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	fallbackTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_fallback_notifications_total",
+			Help: "Notifications handled by the fallback receiver after the receiver kept failing, by receiver and fallback.",
+		},
+		[]string{"receiver", "fallback"},
+	)
+	deadLettersGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "jiralert_dead_letters",
+			Help: "Number of permanently failed notifications in the dead letter store.",
+		},
+	)
+	deadLettersDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "jiralert_dead_letters_dropped_total",
+			Help: "Dead letters dropped because the dead letter store was full.",
+		},
+	)
+	credentialRefreshesTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "jiralert_credential_refreshes_total",
+			Help: "Jira requests retried with credentials re-read from their file after Jira rejected the previous ones.",
+		},
+	)
+	debugLinesDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "jiralert_debug_log_lines_dropped_total",
+			Help: "Debug log lines dropped because an alert group exceeded -log.debug-limit.",
+		},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(requestTotal)
+	prometheus.MustRegister(deduplicatedTotal)
+	prometheus.MustRegister(coalescedTotal)
+	prometheus.MustRegister(lastSuccessfulNotify)
+	prometheus.MustRegister(jiraProbeSuccess)
+	prometheus.MustRegister(jiraProbeDuration)
+	prometheus.MustRegister(pendingNotifications)
+	prometheus.MustRegister(retryingGroups)
+	prometheus.MustRegister(fallbackTotal)
+	prometheus.MustRegister(deadLettersGauge)
+	prometheus.MustRegister(deadLettersDroppedTotal)
+	prometheus.MustRegister(debugLinesDroppedTotal)
+	prometheus.MustRegister(credentialRefreshesTotal)
+}
+
+// newTelemetryMux returns the mux serving telemetry: /metrics and the profiling endpoints under /debug/pprof.
+func newTelemetryMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// newServeMux returns the mux of the listen address. Unless telemetry is served on its own address, it serves the
+// telemetry endpoints too.
+func newServeMux(telemetry *http.ServeMux, separateTelemetry bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	if !separateTelemetry {
+		mux.Handle("/metrics", telemetry)
+		mux.Handle("/debug/pprof/", telemetry)
+	}
+	return mux
+}
+
+var issueInfoDesc = prometheus.NewDesc(
+	"jiralert_issue_info",
+	"Issues tracking alert groups, by receiver, hash of the group key, issue key and status. Always 1.",
+	[]string{"receiver", "groupkey_hash", "issue_key", "status"}, nil,
+)
+
+// issueInfoCollector exposes the most recently updated alert group to issue mappings of the state as info metric.
+type issueInfoCollector struct {
+	state *notify.State
+	limit int
+}
+
+// Describe implements prometheus.Collector.
+func (c *issueInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- issueInfoDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *issueInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	mappings := c.state.Mappings()
+	sort.SliceStable(mappings, func(i, j int) bool { return mappings[i].LastUpdate.After(mappings[j].LastUpdate) })
+	if len(mappings) > c.limit {
+		mappings = mappings[:c.limit]
+	}
+	for _, m := range mappings {
+		ch <- prometheus.MustNewConstMetric(issueInfoDesc, prometheus.GaugeValue, 1, m.Receiver, notify.GroupKeyHash(m.GroupKey), m.IssueKey, m.Status)
+	}
+}
+
+// heartbeatTicketer sets lastSuccessfulNotify whenever a receiver's issues were written successfully, so it only
+// advances while JIRAlert actually reaches the issue tracker, not while notifications are queued or skipped.
+type heartbeatTicketer struct {
+	notify.Ticketer
+	receiver string
+}
+
+// Unwrap returns the wrapped Ticketer, so optional interfaces it implements can still be found.
+func (t *heartbeatTicketer) Unwrap() notify.Ticketer {
+	return t.Ticketer
+}
+
+func (t *heartbeatTicketer) beat(err error) {
+	if err == nil {
+		lastSuccessfulNotify.WithLabelValues(t.receiver).SetToCurrentTime()
+	}
+}
+
+func (t *heartbeatTicketer) CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	created, resp, err := t.Ticketer.CreateWithContext(ctx, issue)
+	t.beat(err)
+	return created, resp, err
+}
+
+func (t *heartbeatTicketer) UpdateWithOptionsWithContext(ctx context.Context, issue *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	updated, resp, err := t.Ticketer.UpdateWithOptionsWithContext(ctx, issue, opts)
+	t.beat(err)
+	return updated, resp, err
+}
+
+func (t *heartbeatTicketer) UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	resp, err := t.Ticketer.UpdateIssueWithContext(ctx, jiraID, data)
+	t.beat(err)
+	return resp, err
+}
+
+func (t *heartbeatTicketer) DoTransitionWithContext(ctx context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	resp, err := t.Ticketer.DoTransitionWithContext(ctx, ticketID, transitionID)
+	t.beat(err)
+	return resp, err
+}
+
+func (t *heartbeatTicketer) AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	added, resp, err := t.Ticketer.AddCommentWithContext(ctx, issueID, comment)
+	t.beat(err)
+	return added, resp, err
 }
@@ -0,0 +1,250 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// metricValue returns the value of the counter or gauge, or the sample count of the histogram, with the given name
+// and labels in the default registry, 0 if there is none.
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range f.GetMetric() {
+			if len(m.GetLabel()) != len(labels) {
+				continue
+			}
+			for _, l := range m.GetLabel() {
+				if v, ok := labels[l.GetName()]; !ok || v != l.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case m.Counter != nil:
+				return m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				return m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				return float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+// newGitHubReceiver returns a GitHub receiver whose API is served by handler.
+func newGitHubReceiver(t *testing.T, name string, handler http.HandlerFunc) *config.ReceiverConfig {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	reopen := config.Duration(0)
+	return &config.ReceiverConfig{
+		Name:           name,
+		Backend:        config.BackendGitHub,
+		APIURL:         srv.URL,
+		Project:        "o/r",
+		Summary:        `{{ .GroupLabels.alertname }}`,
+		ReopenState:    "open",
+		ReopenDuration: &reopen,
+	}
+}
+
+// writeFailingTicketer is a Ticketer whose searches succeed and whose writes fail while fail is set.
+type writeFailingTicketer struct {
+	notify.Ticketer
+	fail bool
+}
+
+func (t *writeFailingTicketer) SearchWithContext(context.Context, notify.Query, *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	return nil, nil, nil
+}
+
+func (t *writeFailingTicketer) CreateWithContext(_ context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	if t.fail {
+		return nil, nil, errors.New("jira is down")
+	}
+	return issue, nil, nil
+}
+
+func TestHeartbeatTicketer(t *testing.T) {
+	f := &writeFailingTicketer{fail: true}
+	ticketer := &heartbeatTicketer{Ticketer: f, receiver: "heartbeat"}
+	gauge := lastSuccessfulNotify.WithLabelValues("heartbeat")
+
+	// Neither reads nor failed writes count.
+	_, _, err := ticketer.SearchWithContext(context.Background(), notify.Query{}, nil)
+	require.NoError(t, err)
+	_, _, err = ticketer.CreateWithContext(context.Background(), &jira.Issue{})
+	require.Error(t, err)
+	require.Zero(t, testutil.ToFloat64(gauge))
+
+	f.fail = false
+	_, _, err = ticketer.CreateWithContext(context.Background(), &jira.Issue{})
+	require.NoError(t, err)
+	require.NotZero(t, testutil.ToFloat64(gauge))
+	require.Equal(t, f, ticketer.Unwrap())
+}
+
+func TestRequestMetrics(t *testing.T) {
+	// Searches succeed, issues cannot be created.
+	conf := newGitHubReceiver(t, "request-metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			http.Error(w, `{"message":"failed"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	})
+	_, _, err := notifyReceiver(context.Background(), conf, template.SimpleTemplate(), notify.NewState(), &alertmanager.Data{
+		Receiver:    "request-metrics",
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"alertname": "Disk"},
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+	}, log.NewNopLogger())
+	require.Error(t, err)
+
+	for _, op := range []string{"search", "create"} {
+		labels := map[string]string{"receiver": "request-metrics", "operation": op}
+		require.Equal(t, 1.0, metricValue(t, "jiralert_jira_request_duration_seconds", labels), op)
+	}
+	require.Equal(t, 1.0, metricValue(t, "jiralert_jira_request_errors_total", map[string]string{"receiver": "request-metrics", "operation": "create", "code": "422"}))
+	require.Zero(t, metricValue(t, "jiralert_jira_request_errors_total", map[string]string{"receiver": "request-metrics", "operation": "search", "code": ""}))
+}
+
+func TestWorkGauges(t *testing.T) {
+	// Issue creation blocks until released, then fails with the given status.
+	creating, release := make(chan struct{}), make(chan int)
+	conf := newGitHubReceiver(t, "work-gauges", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			creating <- struct{}{}
+			if status := <-release; status != http.StatusCreated {
+				http.Error(w, `{"message":"failed"}`, status)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	})
+	state := notify.NewState()
+	h := &payloadHandler{
+		cfg:      &config.Config{Receivers: []*config.ReceiverConfig{conf}},
+		tmpl:     template.SimpleTemplate(),
+		state:    state,
+		retries:  newRetryTracker(nil, time.Hour),
+		payloads: newPayloadCache(0),
+		logger:   log.NewNopLogger(),
+	}
+	handler := AlertHandlerFunc(h, newCoalescer(0, state, nil, log.NewNopLogger()), newNotificationLog())
+	receiver := map[string]string{"receiver": "work-gauges"}
+	alert := func(status int) int {
+		done := make(chan int)
+		go func() {
+			w := httptest.NewRecorder()
+			handler(w, httptest.NewRequest(http.MethodPost, "/alert", strings.NewReader(`{
+				"receiver": "work-gauges",
+				"status": "firing",
+				"groupKey": "{}:{alertname=\"Disk\"}",
+				"groupLabels": {"alertname": "Disk"},
+				"alerts": [{"status": "firing"}]
+			}`)))
+			done <- w.Code
+		}()
+		<-creating
+		require.Equal(t, 1.0, metricValue(t, "jiralert_pending_notifications", nil))
+		require.Equal(t, 1.0, metricValue(t, "jiralert_jira_requests_in_flight", receiver))
+		release <- status
+		code := <-done
+		require.Zero(t, metricValue(t, "jiralert_pending_notifications", nil))
+		require.Zero(t, metricValue(t, "jiralert_jira_requests_in_flight", receiver))
+		return code
+	}
+
+	// Alertmanager retries the alert group until it succeeds.
+	require.Equal(t, http.StatusServiceUnavailable, alert(http.StatusServiceUnavailable))
+	require.Equal(t, 1.0, metricValue(t, "jiralert_retrying_alert_groups", receiver))
+	require.Equal(t, http.StatusOK, alert(http.StatusCreated))
+	require.Zero(t, metricValue(t, "jiralert_retrying_alert_groups", receiver))
+}
+
+func TestIssueInfoCollector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"mappings": [
+		{"receiver": "jira-ab", "project": "AB", "groupKey": "old", "issueLabel": "ALERT{a=old}", "issueKey": "AB-1", "status": "resolved", "lastUpdate": "2022-11-05T20:00:00Z"},
+		{"receiver": "jira-ab", "project": "AB", "groupKey": "new", "issueLabel": "ALERT{a=new}", "issueKey": "AB-3", "status": "open", "lastUpdate": "2022-11-05T22:00:00Z"},
+		{"receiver": "jira-cd", "project": "CD", "groupKey": "mid", "issueLabel": "ALERT{a=mid}", "issueKey": "CD-2", "status": "open", "lastUpdate": "2022-11-05T21:00:00Z"}
+	]}`), 0o600))
+	state, err := notify.LoadState(path)
+	require.NoError(t, err)
+
+	const header = `
+# HELP jiralert_issue_info Issues tracking alert groups, by receiver, hash of the group key, issue key and status. Always 1.
+# TYPE jiralert_issue_info gauge
+`
+	// Only the most recently updated mappings are exposed.
+	require.NoError(t, testutil.CollectAndCompare(&issueInfoCollector{state: state, limit: 2}, strings.NewReader(header+fmt.Sprintf(`
+jiralert_issue_info{groupkey_hash=%q,issue_key="AB-3",receiver="jira-ab",status="open"} 1
+jiralert_issue_info{groupkey_hash=%q,issue_key="CD-2",receiver="jira-cd",status="open"} 1
+`, notify.GroupKeyHash("new"), notify.GroupKeyHash("mid")))))
+	require.Equal(t, 3, testutil.CollectAndCount(&issueInfoCollector{state: state, limit: 10}))
+}
+
+func TestServeMux(t *testing.T) {
+	get := func(h http.Handler, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	telemetry := newTelemetryMux()
+
+	// By default, the listen address serves telemetry too.
+	mux := newServeMux(telemetry, false)
+	require.Equal(t, http.StatusOK, get(mux, "/metrics"))
+	require.Equal(t, http.StatusOK, get(mux, "/debug/pprof/"))
+
+	// With -web.telemetry-address, only the telemetry address does.
+	mux = newServeMux(telemetry, true)
+	mux.HandleFunc("/alert", func(http.ResponseWriter, *http.Request) {})
+	require.Equal(t, http.StatusNotFound, get(mux, "/metrics"))
+	require.Equal(t, http.StatusNotFound, get(mux, "/debug/pprof/"))
+	require.Equal(t, http.StatusOK, get(mux, "/alert"))
+	require.Equal(t, http.StatusOK, get(telemetry, "/metrics"))
+	require.Equal(t, http.StatusOK, get(telemetry, "/debug/pprof/"))
+	require.Equal(t, http.StatusNotFound, get(telemetry, "/alert"))
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/fakejira"
+)
+
+// testModePath is where the fake Jira of -test-mode is mounted on the web server, for inspecting the issues.
+const testModePath = "/test-mode/jira"
+
+// testModeJira is the Ticketer of all Jira receivers in -test-mode, nil otherwise.
+var testModeJira *fakejira.Server
+
+// startFakeJira serves an in-memory fake Jira on a local port and points all Jira receivers at it: their issues are
+// handled by the fake directly, its API only serves the lookups made at startup. The statuses receivers transition
+// issues to are added to the fake's workflow.
+func startFakeJira(cfg *config.Config, logger log.Logger) (*fakejira.Server, error) {
+	fake := fakejira.New()
+	for _, rc := range cfg.Receivers {
+		if rc.ReopenState != "" {
+			fake.AddStatus(rc.ReopenState, fakejira.CategoryNew)
+		}
+		if rc.AutoResolve != nil {
+			fake.AddStatus(rc.AutoResolve.State, fakejira.CategoryDone)
+		}
+		if rc.StaleIssues != nil {
+			fake.AddStatus(rc.StaleIssues.State, fakejira.CategoryDone)
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := http.Serve(l, fake); err != nil {
+			level.Error(logger).Log("msg", "fake Jira stopped", "err", err)
+		}
+	}()
+
+	url := "http://" + l.Addr().String()
+	for _, rc := range cfg.Receivers {
+		if rc.Backend != config.BackendJira {
+			level.Warn(logger).Log("msg", "test mode only fakes Jira, receiver still talks to its backend", "receiver", rc.Name, "backend", rc.Backend)
+			continue
+		}
+		rc.APIURL = url
+	}
+	testModeJira = fake
+	level.Warn(logger).Log("msg", "test mode: sending all Jira requests to an in-memory fake Jira", "url", url, "inspect", testModePath+"/rest/api/2/search")
+	return fake, nil
+}
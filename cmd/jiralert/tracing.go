@@ -0,0 +1,123 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/common/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of notifications and API calls. It is a no-op unless tracing is set up.
+var tracer = otel.Tracer("github.com/prometheus-community/jiralert")
+
+// rateLimitHeaders are the response headers recorded as span attributes, as sent by Jira Cloud, GitHub and others.
+var rateLimitHeaders = []string{
+	"Retry-After",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"X-RateLimit-NearLimit",
+	"X-RateLimit-Used",
+}
+
+// setupTracing installs a tracer provider exporting spans to the OTLP/HTTP endpoint (host:port) and propagating
+// W3C trace context, so spans join the traces of Alertmanager and Jira.
+func setupTracing(ctx context.Context, endpoint string, insecure bool) error {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "jiralert"),
+			attribute.String("service.version", version.Version),
+		)),
+	))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return nil
+}
+
+type retryCountKey struct{}
+
+// withRetryCount returns a context carrying how often the current notification is being retried.
+func withRetryCount(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, retryCountKey{}, n)
+}
+
+func retryCount(ctx context.Context) int {
+	n, _ := ctx.Value(retryCountKey{}).(int)
+	return n
+}
+
+// tracingTransport creates a span per API call of a receiver, with the status code, the retry count of the
+// notification and the rate limit headers of the response as attributes.
+type tracingTransport struct {
+	base     http.RoundTripper
+	backend  string
+	receiver string
+}
+
+func newTracingTransport(base http.RoundTripper, backend, receiver string) *tracingTransport {
+	return &tracingTransport{base: base, backend: backend, receiver: receiver}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(req.Context(), t.backend+" "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.Redacted()),
+			attribute.String("jiralert.receiver", t.receiver),
+			attribute.Int("jiralert.retry_count", retryCount(req.Context())),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	for _, h := range rateLimitHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			span.SetAttributes(attribute.String("http.response.header."+strings.ToLower(h), v))
+		}
+	}
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
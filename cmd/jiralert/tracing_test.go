@@ -0,0 +1,106 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAlertTracing(t *testing.T) {
+	// The package tracer delegates to the first tracer provider set, so this is the only test setting one.
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer gh.Close()
+
+	reopen := config.Duration(0)
+	cfg := &config.Config{Receivers: []*config.ReceiverConfig{{
+		Name:           "github-ab",
+		Backend:        config.BackendGitHub,
+		APIURL:         gh.URL,
+		Project:        "o/r",
+		IssueType:      "Bug",
+		Summary:        `{{ .GroupLabels.alertname }}`,
+		ReopenState:    "open",
+		ReopenDuration: &reopen,
+	}}}
+	state := notify.NewState()
+	h := &payloadHandler{
+		cfg:      cfg,
+		tmpl:     template.SimpleTemplate(),
+		state:    state,
+		retries:  newRetryTracker(nil, time.Hour),
+		payloads: newPayloadCache(0),
+		logger:   log.NewNopLogger(),
+	}
+	handler := AlertHandlerFunc(h, newCoalescer(0, state, nil, log.NewNopLogger()), newNotificationLog())
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/alert", strings.NewReader(`{
+		"receiver": "github-ab",
+		"status": "firing",
+		"groupKey": "{}:{alertname=\"Disk\"}",
+		"groupLabels": {"alertname": "Disk"},
+		"alerts": [{"status": "firing"}]
+	}`))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var notifySpan, createSpan sdktrace.ReadOnlySpan
+	for _, s := range spans.Ended() {
+		switch s.Name() {
+		case "notify":
+			notifySpan = s
+		case "github POST":
+			createSpan = s
+		}
+	}
+	require.NotNil(t, notifySpan)
+	require.Contains(t, notifySpan.Attributes(), attribute.String("jiralert.receiver", "github-ab"))
+	// The span joins the trace of the webhook request.
+	require.Equal(t, traceID, notifySpan.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", notifySpan.Parent().SpanID().String())
+
+	require.NotNil(t, createSpan)
+	require.Equal(t, notifySpan.SpanContext().SpanID(), createSpan.Parent().SpanID())
+	require.Contains(t, createSpan.Attributes(), attribute.String("jiralert.receiver", "github-ab"))
+	require.Contains(t, createSpan.Attributes(), attribute.Int("http.status_code", http.StatusCreated))
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// updateOptionsIssueService sends the UpdateQueryOptions of issue updates as they are. go-jira omits false options
+// from the query string, so it cannot send notifyUsers=false.
+type updateOptionsIssueService struct {
+	notify.Ticketer
+	client *jira.Client
+}
+
+// Unwrap returns the wrapped Ticketer, so optional interfaces it implements can still be found.
+func (s *updateOptionsIssueService) Unwrap() notify.Ticketer {
+	return s.Ticketer
+}
+
+// UpdateWithOptionsWithContext updates the issue, sending notifyUsers=false if opts disable it.
+func (s *updateOptionsIssueService) UpdateWithOptionsWithContext(ctx context.Context, issue *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	if opts == nil || opts.NotifyUsers {
+		return s.Ticketer.UpdateWithOptionsWithContext(ctx, issue, opts)
+	}
+	params := url.Values{"notifyUsers": []string{"false"}}
+	if opts.OverrideScreenSecurity {
+		params.Set("overrideScreenSecurity", "true")
+	}
+	if opts.OverrideEditableFlag {
+		params.Set("overrideEditableFlag", "true")
+	}
+	req, err := s.client.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("rest/api/2/issue/%s?%s", issue.Key, params.Encode()), issue)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.client.Do(req, nil)
+	if err != nil {
+		return nil, resp, jira.NewJiraError(resp, err)
+	}
+	// Like go-jira, return a copy of the issue.
+	updated := *issue
+	return &updated, resp, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateOptionsIssueService(t *testing.T) {
+	var query, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.Equal(t, "/rest/api/2/issue/ABC-1", r.URL.Path)
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		query, body = r.URL.RawQuery, string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	client, err := jira.NewClient(srv.Client(), srv.URL)
+	require.NoError(t, err)
+	s := &updateOptionsIssueService{Ticketer: jiraIssueService{client.Issue}, client: client}
+	issue := &jira.Issue{Key: "ABC-1", Fields: &jira.IssueFields{Summary: "updated"}}
+
+	for _, tc := range []struct {
+		opts     *jira.UpdateQueryOptions
+		expected string
+	}{
+		{opts: nil, expected: ""},
+		{opts: &jira.UpdateQueryOptions{NotifyUsers: true, OverrideEditableFlag: true}, expected: "notifyUsers=true&overrideEditableFlag=true"},
+		// go-jira would omit notifyUsers=false.
+		{opts: &jira.UpdateQueryOptions{}, expected: "notifyUsers=false"},
+		{opts: &jira.UpdateQueryOptions{OverrideScreenSecurity: true}, expected: "notifyUsers=false&overrideScreenSecurity=true"},
+	} {
+		updated, _, err := s.UpdateWithOptionsWithContext(context.Background(), issue, tc.opts)
+		require.NoError(t, err)
+		require.Equal(t, "ABC-1", updated.Key)
+		require.Equal(t, tc.expected, query)
+		require.JSONEq(t, `{"key": "ABC-1", "fields": {"summary": "updated"}}`, body)
+	}
+}
@@ -154,3 +154,14 @@ func (as Alerts) Firing() []Alert {
 	}
 	return res
 }
+
+// Resolved returns the subset of alerts that are resolved.
+func (as Alerts) Resolved() []Alert {
+	var res []Alert
+	for _, a := range as {
+		if a.Status == AlertResolved {
+			res = append(res, a)
+		}
+	}
+	return res
+}
@@ -0,0 +1,196 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// apiAlert is a single alert of the Alertmanager `/api/v2/alerts` and `/api/v2/alerts/groups` responses.
+type apiAlert struct {
+	Labels       KV        `json:"labels"`
+	Annotations  KV        `json:"annotations"`
+	StartsAt     time.Time `json:"startsAt"`
+	EndsAt       time.Time `json:"endsAt"`
+	GeneratorURL string    `json:"generatorURL"`
+	Fingerprint  string    `json:"fingerprint"`
+	Status       struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// apiAlertGroup is a single entry of the Alertmanager `/api/v2/alerts/groups` response.
+type apiAlertGroup struct {
+	Labels   KV `json:"labels"`
+	Receiver struct {
+		Name string `json:"name"`
+	} `json:"receiver"`
+	Alerts []apiAlert `json:"alerts"`
+}
+
+// Client queries the Alertmanager v2 API.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// NewClient returns a Client for the Alertmanager reachable at the given base URL.
+func NewClient(baseURL string, client *http.Client) (*Client, error) {
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid Alertmanager URL %q", baseURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{url: strings.TrimSuffix(baseURL, "/"), client: client}, nil
+}
+
+// Alerts returns the currently firing alerts matching all of the given filters, Alertmanager matchers such as
+// `team="storage"`: the active ones and the suppressed (silenced or inhibited) ones.
+func (c *Client) Alerts(ctx context.Context, filters []string) (active, suppressed Alerts, err error) {
+	q := url.Values{"active": {"true"}, "silenced": {"true"}, "inhibited": {"true"}}
+	for _, f := range filters {
+		q.Add("filter", f)
+	}
+	var alerts []apiAlert
+	if err := c.get(ctx, "/api/v2/alerts?"+q.Encode(), &alerts); err != nil {
+		return nil, nil, errors.Wrap(err, "query Alertmanager alerts")
+	}
+	active, suppressed = Alerts{}, Alerts{}
+	for _, a := range alerts {
+		switch a.Status.State {
+		case "active":
+			active = append(active, a.alert())
+		case "suppressed":
+			suppressed = append(suppressed, a.alert())
+		}
+	}
+	return active, suppressed, nil
+}
+
+// Groups returns the alert groups of the currently firing alerts, converted to the same Data structure Alertmanager
+// pushes through the webhook. Silenced and inhibited alerts are included: they are not notified, but still firing.
+func (c *Client) Groups(ctx context.Context) ([]Data, error) {
+	var groups []apiAlertGroup
+	if err := c.get(ctx, "/api/v2/alerts/groups?active=true&silenced=true&inhibited=true", &groups); err != nil {
+		return nil, errors.Wrap(err, "query Alertmanager alert groups")
+	}
+
+	res := make([]Data, 0, len(groups))
+	for _, g := range groups {
+		// The API does not expose the route's group key, so synthesize a stable one.
+		d := Data{
+			Version:     "4",
+			GroupKey:    fmt.Sprintf("%s:%v", g.Receiver.Name, g.Labels.SortedPairs()),
+			Receiver:    g.Receiver.Name,
+			Status:      AlertFiring,
+			GroupLabels: g.Labels,
+		}
+		for _, a := range g.Alerts {
+			// Suppressed alerts are silenced or inhibited, unprocessed ones were just received.
+			d.Alerts = append(d.Alerts, a.alert())
+		}
+		if len(d.Alerts) == 0 {
+			continue
+		}
+		d.CommonLabels, d.CommonAnnotations = d.Alerts.common()
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// get decodes the JSON response to a GET request of the given API path into v.
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("Alertmanager request %s returned status %s, body %q", req.URL, resp.Status, string(body))
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(v), "decode response")
+}
+
+// alert converts an active alert of the API into a firing Alert.
+func (a apiAlert) alert() Alert {
+	return Alert{
+		Status:       AlertFiring,
+		Labels:       a.Labels,
+		Annotations:  a.Annotations,
+		StartsAt:     a.StartsAt,
+		EndsAt:       a.EndsAt,
+		GeneratorURL: a.GeneratorURL,
+		Fingerprint:  a.Fingerprint,
+	}
+}
+
+// NewData returns the Data of a notification of the given alerts, as Alertmanager would send it through the webhook.
+func NewData(receiver, groupKey string, groupLabels KV, alerts Alerts) Data {
+	d := Data{
+		Version:     "4",
+		GroupKey:    groupKey,
+		Receiver:    receiver,
+		Status:      AlertResolved,
+		Alerts:      alerts,
+		GroupLabels: groupLabels,
+	}
+	if len(alerts.Firing()) > 0 {
+		d.Status = AlertFiring
+	}
+	d.CommonLabels, d.CommonAnnotations = alerts.common()
+	return d
+}
+
+// common returns the labels and annotations shared by all alerts.
+func (as Alerts) common() (KV, KV) {
+	labels, annotations := KV{}, KV{}
+	if len(as) == 0 {
+		return labels, annotations
+	}
+	for k, v := range as[0].Labels {
+		labels[k] = v
+	}
+	for k, v := range as[0].Annotations {
+		annotations[k] = v
+	}
+	for _, a := range as[1:] {
+		for k, v := range labels {
+			if a.Labels[k] != v {
+				delete(labels, k)
+			}
+		}
+		for k, v := range annotations {
+			if a.Annotations[k] != v {
+				delete(annotations, k)
+			}
+		}
+	}
+	return labels, annotations
+}
@@ -14,11 +14,14 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -41,42 +44,189 @@ func (s Secret) MarshalYAML() (interface{}, error) {
 	return nil, nil
 }
 
+// MarshalJSON implements the json.Marshaler interface.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if s != "" {
+		return json.Marshal("<secret>")
+	}
+	return json.Marshal("")
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface for Secrets.
 func (s *Secret) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type plain Secret
 	return unmarshal((*plain)(s))
 }
 
-// Load parses the YAML input into a Config.
+// ReadSecretFile returns the content of a credential file, without surrounding whitespace such as a trailing newline.
+func ReadSecretFile(path string) (Secret, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return Secret(strings.TrimSpace(string(b))), nil
+}
+
+// Connect is the struct used for authenticating to Jira Cloud as an installed Atlassian Connect app, with JWTs
+// signed by the shared secret of the installation.
+type Connect struct {
+	// Key is the key of the app, as in its descriptor.
+	Key string `yaml:"key" json:"key"`
+	// SharedSecret is the secret Jira sent to the app when it was installed.
+	SharedSecret Secret `yaml:"shared_secret" json:"shared_secret"`
+}
+
+func (c *Connect) validate() error {
+	if c.Key == "" {
+		return fmt.Errorf("'key' must be set")
+	}
+	if c.SharedSecret == "" {
+		return fmt.Errorf("'shared_secret' must be set")
+	}
+	return nil
+}
+
+// Regexp is a regular expression anchored at both ends, like the matchers of Alertmanager routes.
+type Regexp struct {
+	*regexp.Regexp
+	original string
+}
+
+// NewRegexp returns the anchored regular expression of s.
+func NewRegexp(s string) (Regexp, error) {
+	re, err := regexp.Compile("^(?:" + s + ")$")
+	return Regexp{Regexp: re, original: s}, err
+}
+
+// String returns the regular expression as configured, i.e. without anchors.
+func (re Regexp) String() string {
+	return re.original
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (re Regexp) MarshalYAML() (interface{}, error) {
+	return re.original, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (re Regexp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(re.original)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for Regexps.
+func (re *Regexp) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	r, err := NewRegexp(s)
+	if err != nil {
+		return err
+	}
+	*re = r
+	return nil
+}
+
+// Load parses the YAML input into a Config, migrating configurations of older versions.
 func Load(s string) (*Config, error) {
-	cfg := &Config{}
-	err := yaml.Unmarshal([]byte(s), cfg)
+	cfg, _, err := load(s)
+	return cfg, err
+}
+
+// load is Load, also returning the warnings of the migrations applied. The rewrites are applied to the migrated
+// document before it is decoded.
+func load(s string, rewrites ...func(*yaml.Node) error) (*Config, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(s), &doc); err != nil {
+		return nil, nil, err
+	}
+	warnings, err := migrate(&doc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return cfg, nil
+	for _, rewrite := range rewrites {
+		if err := rewrite(&doc); err != nil {
+			return nil, nil, err
+		}
+	}
+	cfg := &Config{}
+	if doc.Kind != 0 {
+		if err := doc.Decode(cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	cfg.Version = CurrentVersion
+	return cfg, warnings, nil
 }
 
-// LoadFile parses the given YAML file into a Config.
-func LoadFile(filename string, logger log.Logger) (*Config, []byte, error) {
+// LoadFile parses the given YAML file into a Config, after merging the given overlay files into it in order (see
+// MergeOverlay). Relative paths in overlays are resolved against the directory of the given file.
+func LoadFile(filename string, logger log.Logger, overlays ...string) (*Config, []byte, error) {
 	level.Info(logger).Log("msg", "loading configuration", "path", filename)
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, nil, err
 	}
+	for _, overlay := range overlays {
+		level.Info(logger).Log("msg", "loading configuration overlay", "path", overlay)
+		b, err := os.ReadFile(overlay)
+		if err != nil {
+			return nil, nil, err
+		}
+		if content, err = MergeOverlay(content, b); err != nil {
+			return nil, nil, fmt.Errorf("failed to merge overlay %s: %w", overlay, err)
+		}
+	}
 
-	content, err = substituteEnvVars(content, logger)
+	cfg, err := Parse(content, filepath.Dir(filename), logger)
 	if err != nil {
 		return nil, nil, err
 	}
+	return cfg, content, nil
+}
 
-	cfg, err := Load(string(content))
+// Parse parses the content of a configuration file like LoadFile, i.e. substituting environment variables and
+// resolving relative paths against baseDir.
+func Parse(content []byte, baseDir string, logger log.Logger) (*Config, error) {
+	content, err := substituteEnvVars(content, logger)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	resolveFilepaths(filepath.Dir(filename), cfg, logger)
-	return cfg, content, nil
+	cfg, warnings, err := load(string(content))
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		level.Warn(logger).Log("msg", "deprecated configuration, please update it", "warning", w)
+	}
+
+	resolveFilepaths(baseDir, cfg, logger)
+	return cfg, nil
+}
+
+// ParseUntrusted parses the content of a configuration file from an untrusted source, for validation only. Unlike
+// Parse, it neither substitutes environment variables nor reads credential files: `$(VAR)` references are kept as is,
+// and the paths of password_file and personal_access_token_file are taken for the credentials they would be read into.
+// Paths are not resolved, as nothing is read from them.
+func ParseUntrusted(content []byte) (*Config, error) {
+	cfg, _, err := load(string(content), func(doc *yaml.Node) error {
+		root := mappingNode(doc)
+		if root == nil {
+			return nil
+		}
+		for _, n := range receiverNodes(root) {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key := strings.TrimSuffix(n.Content[i].Value, "_file")
+				// With both set, decoding fails before the file is read.
+				if (key == "password" || key == "personal_access_token") && key != n.Content[i].Value && mappingValue(n, key) == nil {
+					n.Content[i].Value = key
+				}
+			}
+		}
+		return nil
+	})
+	return cfg, err
 }
 
 // expand env variables $(var) from the config file
@@ -118,8 +268,332 @@ func resolveFilepaths(baseDir string, cfg *Config, logger log.Logger) {
 // AutoResolve is the struct used for defining jira resolution state when alert is resolved.
 type AutoResolve struct {
 	State string `yaml:"state" json:"state"`
+	// Comment is a template for the comment added to issues when they are resolved. Optional.
+	Comment string `yaml:"comment,omitempty" json:"comment,omitempty"`
+	// Jira (custom) fields set to the end time (date-time) and firing duration (number of seconds) of the alert group
+	// when the issue is resolved. Optional.
+	EndedAtField  string `yaml:"ended_at_field,omitempty" json:"ended_at_field,omitempty"`
+	DurationField string `yaml:"duration_field,omitempty" json:"duration_field,omitempty"`
+}
+
+// StaleIssues is the struct used for defining the cleanup of open issues that have not been updated in a while.
+type StaleIssues struct {
+	After   *Duration `yaml:"after" json:"after"`
+	State   string    `yaml:"state" json:"state"`
+	Comment string    `yaml:"comment" json:"comment"`
+}
+
+// AssetsField is the struct used for setting a Jira Assets (formerly Insight) object custom field to the objects found
+// by an AQL query.
+type AssetsField struct {
+	Field string `yaml:"field" json:"field"`
+	// Query is a template for the AQL query, e.g. using alert labels.
+	Query string `yaml:"query" json:"query"`
+	// WorkspaceID is the Assets workspace of Jira Cloud instances, looked up if empty.
+	WorkspaceID string `yaml:"workspace_id,omitempty" json:"workspace_id,omitempty"`
+	// MaxObjects is the maximum number of objects set (default: 1).
+	MaxObjects int `yaml:"max_objects,omitempty" json:"max_objects,omitempty"`
+}
+
+func (f *AssetsField) validate() error {
+	if f.Field == "" {
+		return fmt.Errorf("'field' must be set")
+	}
+	if f.Query == "" {
+		return fmt.Errorf("'query' must be set")
+	}
+	if f.MaxObjects < 0 {
+		return fmt.Errorf("'max_objects' must not be negative")
+	}
+	if f.MaxObjects == 0 {
+		f.MaxObjects = 1
+	}
+	return nil
+}
+
+// CascadingField is the struct used for setting a Jira cascading select custom field to a parent and child option.
+type CascadingField struct {
+	Field string `yaml:"field" json:"field"`
+	// Parent is a template for the parent option, e.g. using alert labels.
+	Parent string `yaml:"parent" json:"parent"`
+	// Child is a template for the child option. Optional, only the parent is set if it renders empty.
+	Child string `yaml:"child,omitempty" json:"child,omitempty"`
+}
+
+func (f *CascadingField) validate() error {
+	if f.Field == "" {
+		return fmt.Errorf("'field' must be set")
+	}
+	if f.Parent == "" {
+		return fmt.Errorf("'parent' must be set")
+	}
+	return nil
+}
+
+// FieldUpdate is the struct used for setting a field of issues reused for an alert group, which are otherwise only set
+// when creating issues.
+type FieldUpdate struct {
+	// Update sets the field to its value in fields again. Implied by OnUpdate.
+	Update *bool `yaml:"update,omitempty" json:"update,omitempty"`
+	// OnUpdate is the value, e.g. a template, the field is set to instead of its value in fields.
+	OnUpdate interface{} `yaml:"on_update,omitempty" json:"on_update,omitempty"`
+}
+
+// Enabled reports whether the field is set on reused issues.
+func (u *FieldUpdate) Enabled() bool {
+	if u.Update != nil {
+		return *u.Update
+	}
+	return u.OnUpdate != nil
 }
 
+const (
+	// FillDefault fills required fields with their default value.
+	FillDefault = "default"
+	// FillFirstAllowed fills required fields with their default value or, without one, their first allowed value.
+	FillFirstAllowed = "first_allowed"
+)
+
+// RequiredFields is the struct used for filling the fields Jira requires when creating issues, as reported by the
+// create metadata of the project and issue type, but the receiver does not set.
+type RequiredFields struct {
+	// Fill is how missing required fields are filled, see the Fill constants (default: FillDefault).
+	Fill string `yaml:"fill,omitempty" json:"fill,omitempty"`
+}
+
+func (f *RequiredFields) validate() error {
+	switch f.Fill {
+	case "":
+		f.Fill = FillDefault
+	case FillDefault, FillFirstAllowed:
+	default:
+		return fmt.Errorf("'fill' must be %q or %q", FillDefault, FillFirstAllowed)
+	}
+	return nil
+}
+
+// FieldProfile is a named set of issue fields, labels and components receivers refer to by name, so receivers that
+// differ in little else than their project share them.
+type FieldProfile struct {
+	Name                  string                 `yaml:"name" json:"name"`
+	Fields                map[string]interface{} `yaml:"fields,omitempty" json:"fields,omitempty"`
+	FieldTypes            map[string]string      `yaml:"field_types,omitempty" json:"field_types,omitempty"`
+	AdditionalIssueLabels map[string]string      `yaml:"additional_labels,omitempty" json:"additional_labels,omitempty"`
+	Components            []string               `yaml:"components,omitempty" json:"components,omitempty"`
+}
+
+// applyFieldProfiles layers the given profiles, later ones overriding earlier ones, beneath the receiver's own fields,
+// field types and labels. Components of all profiles are added to the receiver's.
+func (rc *ReceiverConfig) applyFieldProfiles(profiles []*FieldProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+	fields := map[string]interface{}{}
+	fieldTypes := map[string]string{}
+	labels := map[string]string{}
+	var components []string
+	for _, p := range profiles {
+		for key, value := range p.Fields {
+			fields[key] = value
+		}
+		for key, t := range p.FieldTypes {
+			fieldTypes[key] = t
+		}
+		for key, value := range p.AdditionalIssueLabels {
+			labels[key] = value
+		}
+		components = append(components, p.Components...)
+	}
+	for key, value := range rc.Fields {
+		fields[key] = value
+	}
+	for key, t := range rc.FieldTypes {
+		fieldTypes[key] = t
+	}
+	for key, value := range rc.AdditionalIssueLabels {
+		labels[key] = value
+	}
+	rc.Fields, rc.FieldTypes, rc.AdditionalIssueLabels = fields, fieldTypes, labels
+	for _, c := range components {
+		duplicate := false
+		for _, existing := range rc.Components {
+			duplicate = duplicate || existing == c
+		}
+		if !duplicate {
+			rc.Components = append(rc.Components, c)
+		}
+	}
+}
+
+// Fallback is the struct used for handling notifications with another receiver once the receiver keeps failing.
+type Fallback struct {
+	Receiver string    `yaml:"receiver" json:"receiver"`
+	After    *Duration `yaml:"after" json:"after"`
+}
+
+// CreationLimit is the struct used for limiting the number of issues created in a project per time window.
+type CreationLimit struct {
+	MaxIssues    int       `yaml:"max_issues" json:"max_issues"`
+	Window       *Duration `yaml:"window" json:"window"`
+	StormSummary string    `yaml:"storm_summary" json:"storm_summary"`
+}
+
+// DiffIgnore are the labels and annotations whose values do not matter when deciding whether to update the summary
+// or description of an existing issue, e.g. a timestamp annotation changing on every evaluation.
+type DiffIgnore struct {
+	Labels      []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations []string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+// UpdateOptions are the query options of the issue updates made by JIRAlert, e.g. for fields missing from the edit
+// screen of Jira Data Center projects. Both require JIRAlert's user to be a Jira administrator.
+type UpdateOptions struct {
+	// Update fields hidden from the edit screen.
+	OverrideScreenSecurity bool `yaml:"override_screen_security,omitempty" json:"override_screen_security,omitempty"`
+	// Update issues whose workflow status makes them read-only.
+	OverrideEditableFlag bool `yaml:"override_editable_flag,omitempty" json:"override_editable_flag,omitempty"`
+}
+
+// templatedSettings are the settings template_defaults may be given for, besides fields.<field>.
+var templatedSettings = []string{"summary", "description", "description_header", "description_footer", "priority", "assignee", "reporter", "components"}
+
+// requiredSettings are the templated settings that never fall back, as issues would end up in the wrong place or could
+// not be found again.
+var requiredSettings = []string{"project", "issue_type", "issue_identifier_label"}
+
+func validateTemplateDefaults(defaults map[string]interface{}) error {
+	for key, v := range defaults {
+		if strings.HasPrefix(key, "fields.") && key != "fields." {
+			continue
+		}
+		for _, s := range requiredSettings {
+			if key == s {
+				return fmt.Errorf("'template_defaults' cannot be given for %q, %s never fall back", key, strings.Join(requiredSettings, ", "))
+			}
+		}
+		known := false
+		for _, s := range templatedSettings {
+			known = known || key == s
+		}
+		if !known {
+			return fmt.Errorf("'template_defaults' %q is unknown, must be one of %s or fields.<field>", key, strings.Join(templatedSettings, ", "))
+		}
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("'template_defaults' %q must be a string", key)
+		}
+	}
+	return nil
+}
+
+// DefaultSeverityOrder are the severities of min_severity, from lowest to highest, unless configured.
+var DefaultSeverityOrder = []string{"info", "warning", "critical"}
+
+// MinSeverity is the struct used for ignoring alert groups whose highest severity is below a threshold.
+type MinSeverity struct {
+	Severity string `yaml:"severity" json:"severity"`
+	// Label is the alert label holding the severity (default: severity).
+	Label string `yaml:"label,omitempty" json:"label,omitempty"`
+	// Order are the known severities, from lowest to highest (default: info, warning, critical).
+	Order []string `yaml:"order,omitempty" json:"order,omitempty"`
+}
+
+func (m *MinSeverity) validate() error {
+	if m.Label == "" {
+		m.Label = "severity"
+	}
+	if len(m.Order) == 0 {
+		m.Order = DefaultSeverityOrder
+	}
+	if m.Severity == "" {
+		return fmt.Errorf("'severity' cannot be empty")
+	}
+	if m.Rank(m.Severity) < 0 {
+		return fmt.Errorf("'severity' %q must be one of 'order' %q", m.Severity, m.Order)
+	}
+	return nil
+}
+
+// Rank returns the position of severity in the order, or -1 if it is unknown.
+func (m *MinSeverity) Rank(severity string) int {
+	for i, s := range m.Order {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// IncidentMode is the struct used for aggregating correlated alert groups into a single umbrella issue per outage.
+type IncidentMode struct {
+	// Window is how long after the last aggregated alert group new ones still join the umbrella issue.
+	Window *Duration `yaml:"window" json:"window"`
+	// Matchers are the labels of the aggregated alert groups (default: all).
+	Matchers map[string]string `yaml:"matchers,omitempty" json:"matchers,omitempty"`
+	Summary  string            `yaml:"summary,omitempty" json:"summary,omitempty"`
+}
+
+// Matches reports whether alert groups with the given labels are aggregated.
+func (m *IncidentMode) Matches(labels map[string]string) bool {
+	for k, v := range m.Matchers {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// BusinessHours is the struct used for deferring the creation of issues for non-critical alerts to business hours.
+type BusinessHours struct {
+	Intervals        TimeIntervals `yaml:"intervals" json:"intervals"`
+	SeverityLabel    string        `yaml:"severity_label" json:"severity_label"`
+	BypassSeverities []string      `yaml:"bypass_severities" json:"bypass_severities"`
+}
+
+const (
+	// AssigneePoolRoundRobin assigns the pool's users in turn.
+	AssigneePoolRoundRobin = "round_robin"
+	// AssigneePoolLeastRecentlyAssigned assigns the pool's user whose last assignment is the oldest.
+	AssigneePoolLeastRecentlyAssigned = "least_recently_assigned"
+)
+
+// AssigneePool is the struct used for spreading new issues across a team of Jira users.
+type AssigneePool struct {
+	Users    []string `yaml:"users" json:"users"`
+	Strategy string   `yaml:"strategy" json:"strategy"`
+}
+
+const (
+	// OnCallPagerDuty looks up the on-call user in a PagerDuty schedule.
+	OnCallPagerDuty = "pagerduty"
+	// OnCallOpsgenie looks up the on-call user in an Opsgenie schedule.
+	OnCallOpsgenie = "opsgenie"
+)
+
+// OnCall is the struct used for assigning new issues to whoever is on call according to PagerDuty or Opsgenie.
+type OnCall struct {
+	Provider string `yaml:"provider" json:"provider"`
+	APIURL   string `yaml:"api_url" json:"api_url"`
+	Token    Secret `yaml:"token" json:"token"`
+	Schedule string `yaml:"schedule" json:"schedule"`
+	// Users maps the email addresses of on-call users to Jira users. Unmapped addresses are used as they are.
+	Users   map[string]string `yaml:"users,omitempty" json:"users,omitempty"`
+	Timeout *Duration         `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// DefaultIncidentSummary is the summary of the umbrella issues created by incident_mode.
+const DefaultIncidentSummary = "Incident: correlated alert groups, see comments"
+
+// DefaultStormSummary is the summary of the umbrella issue created once a creation limit is exceeded.
+const DefaultStormSummary = "Alert storm: too many alerts, see comments"
+
+const (
+	// TemplateErrorsFail fails notifications whose templates fail to render.
+	TemplateErrorsFail = "fail"
+	// TemplateErrorsFallback renders the summary and description of notifications whose templates fail to render as
+	// a dump of the alert labels and leaves other optional settings unset, so issues are still created.
+	TemplateErrorsFallback = "fallback"
+)
+
 const (
 	// AlertGroup groups issues in jira by alertmanager group.
 	AlertGroup string = "AlertGroup"
@@ -129,32 +603,306 @@ const (
 	Alert string = "Alert"
 )
 
+const (
+	// BackendJira tracks alerts in Jira issues.
+	BackendJira = "jira"
+	// BackendGitHub tracks alerts in GitHub issues. The project is the repository, e.g. "owner/name".
+	BackendGitHub = "github"
+	// BackendServiceNow tracks alerts in ServiceNow incidents. The project is the assignment group.
+	BackendServiceNow = "servicenow"
+
+	// DefaultGitHubAPIURL is the API URL of GitHub receivers without api_url.
+	DefaultGitHubAPIURL = "https://api.github.com"
+
+	// DefaultServiceNowCloseCode is the resolution code of incidents resolved by JIRAlert.
+	DefaultServiceNowCloseCode = "Solved (Permanently)"
+	// DefaultServiceNowCloseNotes are the resolution notes of incidents resolved by JIRAlert.
+	DefaultServiceNowCloseNotes = "Resolved by JIRAlert."
+)
+
+// ServiceNow is the struct used for ServiceNow specific settings of receivers with the servicenow backend.
+type ServiceNow struct {
+	CloseCode  string `yaml:"close_code" json:"close_code"`
+	CloseNotes string `yaml:"close_notes" json:"close_notes"`
+}
+
+// Epics is the struct used for creating issues as children of an epic per value of a label set.
+type Epics struct {
+	// Label whose value selects the epic (default: alertname). Shorthand for a single label in labels.
+	Label string `yaml:"label,omitempty" json:"label,omitempty"`
+	// Labels whose values select the epic.
+	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	// IssueType of the epics (default: Epic).
+	IssueType string `yaml:"issue_type,omitempty" json:"issue_type,omitempty"`
+	// Summary is a template for the summary of new epics.
+	Summary string `yaml:"summary,omitempty" json:"summary,omitempty"`
+	// LinkField is the Epic Link custom field of Jira Server and Data Center. If empty, issues are linked to their
+	// epic by parent, as on Jira Cloud.
+	LinkField string `yaml:"link_field,omitempty" json:"link_field,omitempty"`
+	// NameField is the Epic Name custom field of Jira Server and Data Center, set to the summary of new epics.
+	NameField string `yaml:"name_field,omitempty" json:"name_field,omitempty"`
+}
+
+func (e *Epics) validate() error {
+	if e.Label != "" {
+		if len(e.Labels) > 0 && (len(e.Labels) != 1 || e.Labels[0] != e.Label) {
+			return fmt.Errorf("'label' and 'labels' are mutually exclusive")
+		}
+		e.Labels = []string{e.Label}
+	}
+	if len(e.Labels) == 0 {
+		e.Labels = []string{"alertname"}
+	}
+	if e.IssueType == "" {
+		e.IssueType = "Epic"
+	}
+	if e.Summary == "" {
+		e.Summary = labelValuesTemplate(".CommonLabels", e.Labels)
+	}
+	return nil
+}
+
+// Subtasks is the struct used for creating a sub-task of the issue per value of a label set among the alerts of the
+// group.
+type Subtasks struct {
+	// Labels whose values select the sub-task of an alert.
+	Labels []string `yaml:"labels" json:"labels"`
+	// IssueType of the sub-tasks (default: Sub-task).
+	IssueType string `yaml:"issue_type,omitempty" json:"issue_type,omitempty"`
+	// Summary is a template for the summary of new sub-tasks, executed with the alert group narrowed down to the
+	// sub-task's alerts.
+	Summary string `yaml:"summary,omitempty" json:"summary,omitempty"`
+}
+
+func (st *Subtasks) validate() error {
+	if len(st.Labels) == 0 {
+		return fmt.Errorf("'labels' cannot be empty")
+	}
+	if st.IssueType == "" {
+		st.IssueType = "Sub-task"
+	}
+	if st.Summary == "" {
+		st.Summary = labelValuesTemplate(".CommonLabels", st.Labels)
+	}
+	return nil
+}
+
+// labelValuesTemplate returns a template joining the values of the given labels of a label set with spaces.
+func labelValuesTemplate(set string, labels []string) string {
+	values := make([]string, 0, len(labels))
+	for _, l := range labels {
+		values = append(values, fmt.Sprintf("{{ index %s %q }}", set, l))
+	}
+	return strings.Join(values, " ")
+}
+
+// CurrentIncident is the struct used for linking new issues to the issue of a declared major incident.
+type CurrentIncident struct {
+	// IssueKey is the incident issue, until another one is set through the API. Optional.
+	IssueKey string `yaml:"issue_key,omitempty" json:"issue_key,omitempty"`
+	// LinkType of the links to the incident issue (default: Relates).
+	LinkType string `yaml:"link_type,omitempty" json:"link_type,omitempty"`
+}
+
+func (ci *CurrentIncident) validate() error {
+	if ci.LinkType == "" {
+		ci.LinkType = "Relates"
+	}
+	return nil
+}
+
+// InhibitRule is the struct used for suppressing issues of alert groups while the issue of an umbrella alert group is
+// open, similar to Alertmanager inhibition rules.
+type InhibitRule struct {
+	// SourceMatchers are the labels of the umbrella alert group.
+	SourceMatchers map[string]string `yaml:"source_matchers" json:"source_matchers"`
+	// TargetMatchers are the labels of the suppressed alert groups (default: all).
+	TargetMatchers map[string]string `yaml:"target_matchers,omitempty" json:"target_matchers,omitempty"`
+	// Equal are the labels that must have the same value in both alert groups.
+	Equal []string `yaml:"equal,omitempty" json:"equal,omitempty"`
+}
+
+func (ir *InhibitRule) validate() error {
+	if len(ir.SourceMatchers) == 0 {
+		return fmt.Errorf("'source_matchers' cannot be empty")
+	}
+	return nil
+}
+
+// Inhibits reports whether an alert group with the source labels inhibits one with the target labels.
+func (ir *InhibitRule) Inhibits(source, target map[string]string) bool {
+	for k, v := range ir.SourceMatchers {
+		if source[k] != v {
+			return false
+		}
+	}
+	for k, v := range ir.TargetMatchers {
+		if target[k] != v {
+			return false
+		}
+	}
+	for _, l := range ir.Equal {
+		if source[l] != target[l] {
+			return false
+		}
+	}
+	return true
+}
+
+// ServiceDesk is the struct used for setting the Jira Service Management fields of requests created by receivers.
+type ServiceDesk struct {
+	// Organizations are templates for the names or IDs of the organizations of the request, set in the
+	// organizations field.
+	OrganizationsField string   `yaml:"organizations_field,omitempty" json:"organizations_field,omitempty"`
+	Organizations      []string `yaml:"organizations,omitempty" json:"organizations,omitempty"`
+	// Participants are templates for the users added as participants of the request, set in the participants field.
+	ParticipantsField string   `yaml:"participants_field,omitempty" json:"participants_field,omitempty"`
+	Participants      []string `yaml:"participants,omitempty" json:"participants,omitempty"`
+	// Warn about SLAs of requests that are about to breach while their alerts are still firing.
+	SLAWarning *SLAWarning `yaml:"sla_warning,omitempty" json:"sla_warning,omitempty"`
+}
+
+// DefaultSLAWarningComment is the comment added to requests whose SLA is about to breach.
+const DefaultSLAWarningComment = `SLA "{{ .SLA }}" breaches in {{ .Remaining }} while the alerts of this request are still firing.`
+
+// SLAWarning is the struct used for warning about Jira Service Management SLAs about to breach.
+type SLAWarning struct {
+	// Before is how long before the breach the warning is added.
+	Before *Duration `yaml:"before" json:"before"`
+	// SLAs are the names of the SLAs to watch (default: all).
+	SLAs []string `yaml:"slas,omitempty" json:"slas,omitempty"`
+	// Comment is a template for the warning, executed with the request key (.Key), the SLA name (.SLA) and the time
+	// remaining until the breach (.Remaining).
+	Comment string `yaml:"comment,omitempty" json:"comment,omitempty"`
+	// Priority is set on the request along with the warning, if not empty.
+	Priority string `yaml:"priority,omitempty" json:"priority,omitempty"`
+}
+
+func (w *SLAWarning) validate() error {
+	if w.Before == nil || *w.Before <= 0 {
+		return fmt.Errorf("'before' must be a positive duration")
+	}
+	if w.Comment == "" {
+		w.Comment = DefaultSLAWarningComment
+	}
+	return nil
+}
+
+func (sd *ServiceDesk) validate() error {
+	if len(sd.Organizations) > 0 && sd.OrganizationsField == "" {
+		return fmt.Errorf("'organizations' require 'organizations_field'")
+	}
+	if len(sd.Participants) > 0 && sd.ParticipantsField == "" {
+		return fmt.Errorf("'participants' require 'participants_field'")
+	}
+	if sd.SLAWarning != nil {
+		if err := sd.SLAWarning.validate(); err != nil {
+			return fmt.Errorf("'sla_warning' %s", err)
+		}
+	}
+	return nil
+}
+
 // ReceiverConfig is the configuration for one receiver. It has a unique name and includes API access fields (url and
 // auth) and issue fields (required -- e.g. project, issue type -- and optional -- e.g. priority).
 type ReceiverConfig struct {
 	Name string `yaml:"name" json:"name"`
 
 	// API access fields
+	Backend             string `yaml:"backend" json:"backend"`
 	APIURL              string `yaml:"api_url" json:"api_url"`
 	User                string `yaml:"user" json:"user"`
 	Password            Secret `yaml:"password" json:"password"`
 	PersonalAccessToken Secret `yaml:"personal_access_token" json:"personal_access_token"`
+	// Files holding the password or personal access token instead, re-read when Jira rejects them, e.g. after
+	// rotating them.
+	PasswordFile            string `yaml:"password_file,omitempty" json:"password_file,omitempty"`
+	PersonalAccessTokenFile string `yaml:"personal_access_token_file,omitempty" json:"personal_access_token_file,omitempty"`
+	// Authenticate as an Atlassian Connect app instead of a user. Jira Cloud only.
+	Connect *Connect `yaml:"connect,omitempty" json:"connect,omitempty"`
 
-	// Required issue fields
+	// Required issue fields. The project may be a comma-separated list (or a YAML list) of projects, each getting a
+	// linked issue.
 	Project        string    `yaml:"project" json:"project"`
 	IssueType      string    `yaml:"issue_type" json:"issue_type"`
 	Summary        string    `yaml:"summary" json:"summary"`
 	ReopenState    string    `yaml:"reopen_state" json:"reopen_state"`
 	ReopenDuration *Duration `yaml:"reopen_duration" json:"reopen_duration"`
+	// Only reopen issues resolved at least this long ago, so a single flapping evaluation does not reopen them.
+	ReopenDelay *Duration `yaml:"reopen_delay,omitempty" json:"reopen_delay,omitempty"`
+	// Only create issues for alert groups firing at least this long, so blips resolving within minutes are filtered.
+	MinFiringDuration *Duration `yaml:"min_firing_duration,omitempty" json:"min_firing_duration,omitempty"`
+	// Do not create issues for alerts that started longer ago than this, e.g. replayed after an Alertmanager restart.
+	MaxAlertAge *Duration `yaml:"max_alert_age,omitempty" json:"max_alert_age,omitempty"`
+	// Skip notifications of firing alert groups whose issue was updated less than this long ago, so repeated
+	// notifications do not search and update the issue each time.
+	MinUpdateInterval *Duration `yaml:"min_update_interval,omitempty" json:"min_update_interval,omitempty"`
+
+	// What to do when templates fail to render, see the TemplateErrors constants (default: TemplateErrorsFail).
+	TemplateErrors string `yaml:"template_errors,omitempty" json:"template_errors,omitempty"`
 
 	// Optional issue fields
 	GroupIssueBy         string                 `yaml:"group_issue_by" json:"group_issue_by"`
 	IssueIdentifierLabel string                 `yaml:"issue_identifier_label" json:"issue_identifier_label"`
 	Priority             string                 `yaml:"priority" json:"priority"`
 	Description          string                 `yaml:"description" json:"description"`
+	Assignee             string                 `yaml:"assignee" json:"assignee"`
 	WontFixResolution    string                 `yaml:"wont_fix_resolution" json:"wont_fix_resolution"`
+	WontFixDuration      *Duration              `yaml:"wont_fix_duration,omitempty" json:"wont_fix_duration,omitempty"`
 	Fields               map[string]interface{} `yaml:"fields" json:"fields"`
 	Components           []string               `yaml:"components" json:"components"`
+	// Templates rendered before and after the description and separated from it by a blank line, e.g. escalation
+	// instructions or dashboard links shared by all receivers through the defaults.
+	DescriptionHeader string `yaml:"description_header,omitempty" json:"description_header,omitempty"`
+	DescriptionFooter string `yaml:"description_footer,omitempty" json:"description_footer,omitempty"`
+	// Reporter is a template for the reporter of created issues, e.g. a service account per team. Defaults to the user
+	// JIRAlert authenticates as.
+	Reporter string `yaml:"reporter,omitempty" json:"reporter,omitempty"`
+	// Types of fields, marshaled into the structure Jira expects for them, see the FieldType constants.
+	FieldTypes map[string]string `yaml:"field_types,omitempty" json:"field_types,omitempty"`
+	// Fields set on reused issues as well, by field.
+	FieldUpdates map[string]*FieldUpdate `yaml:"field_updates,omitempty" json:"field_updates,omitempty"`
+	// Values used when the template of a setting renders empty or fails, by setting, e.g. priority or
+	// fields.customfield_10001. Defaults are not templates themselves.
+	TemplateDefaults map[string]interface{} `yaml:"template_defaults,omitempty" json:"template_defaults,omitempty"`
+
+	// Update the summary of existing issues to the rendered summary (default: true).
+	UpdateSummary *bool `yaml:"update_summary,omitempty" json:"update_summary,omitempty"`
+	// Update the description of existing issues to the rendered description (default: true).
+	UpdateDescription *bool `yaml:"update_description,omitempty" json:"update_description,omitempty"`
+	// Notify the watchers of issues by email of the updates JIRAlert makes to them (default: true). Disabling this
+	// requires the Jira user to be an administrator of the project. Jira only.
+	NotifyUsers *bool `yaml:"notify_users,omitempty" json:"notify_users,omitempty"`
+	// Query options of the issue updates. Jira only.
+	UpdateOptions *UpdateOptions `yaml:"update_options,omitempty" json:"update_options,omitempty"`
+	// Do not update the summary or description if they differ only in the values of these labels and annotations.
+	DiffIgnore *DiffIgnore `yaml:"diff_ignore,omitempty" json:"diff_ignore,omitempty"`
+	// Jira (custom) field set to the number of firing alerts of the group on every notification.
+	AlertCountField string `yaml:"alert_count_field,omitempty" json:"alert_count_field,omitempty"`
+	// Jira date-time (custom) fields set to the earliest start of the group's alerts and the last notification.
+	FiringSinceField string `yaml:"firing_since_field,omitempty" json:"firing_since_field,omitempty"`
+	LastSeenField    string `yaml:"last_seen_field,omitempty" json:"last_seen_field,omitempty"`
+	// Set Jira Assets object custom fields to the objects found by AQL queries.
+	AssetsFields []*AssetsField `yaml:"assets_fields,omitempty" json:"assets_fields,omitempty"`
+	// Fill fields Jira requires but the receiver does not set.
+	RequiredFields *RequiredFields `yaml:"required_fields,omitempty" json:"required_fields,omitempty"`
+	// Cascading select custom fields, set to templated parent and child options.
+	CascadingFields []*CascadingField `yaml:"cascading_fields,omitempty" json:"cascading_fields,omitempty"`
+
+	// Create new issues under an epic per value of a label, e.g. per alertname.
+	Epics *Epics `yaml:"epics,omitempty" json:"epics,omitempty"`
+
+	// Link new issues to the issue of the current major incident.
+	CurrentIncident *CurrentIncident `yaml:"current_incident,omitempty" json:"current_incident,omitempty"`
+
+	// Create a sub-task of the issue per value of a label set among the alerts.
+	Subtasks *Subtasks `yaml:"subtasks,omitempty" json:"subtasks,omitempty"`
+
+	// Set the organizations and participants of Jira Service Management requests.
+	ServiceDesk *ServiceDesk `yaml:"service_desk,omitempty" json:"service_desk,omitempty"`
+
+	// Only manage a marker-delimited section of the description, keeping the rest for humans (default: false).
+	ManagedDescription *bool `yaml:"managed_description,omitempty" json:"managed_description,omitempty"`
 
 	// Label copy settings
 	AddGroupLabels  bool `yaml:"add_group_labels" json:"add_group_labels"`
@@ -164,15 +912,307 @@ type ReceiverConfig struct {
 
 	// Flag to auto-resolve opened issue when the alert is resolved.
 	AutoResolve *AutoResolve `yaml:"auto_resolve" json:"auto_resolve"`
+	// Template for a comment added to open issues when some alerts of the group resolve while others keep firing,
+	// executed with the alerts resolved since the last comment as .Alerts.Resolved. Optional.
+	PartialResolutionComment string `yaml:"partial_resolution_comment,omitempty" json:"partial_resolution_comment,omitempty"`
+
+	// Transition open issues that were neither updated nor fired for a while.
+	StaleIssues *StaleIssues `yaml:"stale_issues,omitempty" json:"stale_issues,omitempty"`
+
+	// Aggregate alert groups into a single issue once too many issues were created in a project.
+	CreationLimit *CreationLimit `yaml:"creation_limit,omitempty" json:"creation_limit,omitempty"`
+
+	// Ignore alert groups whose highest severity is below a threshold.
+	MinSeverity *MinSeverity `yaml:"min_severity,omitempty" json:"min_severity,omitempty"`
+
+	// Aggregate matching alert groups arriving within a rolling window into a single issue.
+	IncidentMode *IncidentMode `yaml:"incident_mode,omitempty" json:"incident_mode,omitempty"`
+
+	// Only create issues for non-critical alerts during business hours.
+	BusinessHours *BusinessHours `yaml:"business_hours,omitempty" json:"business_hours,omitempty"`
+
+	// Do not create or reopen issues for matching alert groups during these windows.
+	MaintenanceWindows []*MaintenanceWindow `yaml:"maintenance_windows,omitempty" json:"maintenance_windows,omitempty"`
+
+	// Do not create or reopen issues during these named time intervals.
+	MuteTimeIntervals []string `yaml:"mute_time_intervals,omitempty" json:"mute_time_intervals,omitempty"`
+	muteTimeIntervals []*NamedTimeInterval
+	// Names of the field profiles whose fields, labels and components the receiver uses, later ones overriding earlier
+	// ones.
+	FieldProfiles []string `yaml:"field_profiles,omitempty" json:"field_profiles,omitempty"`
+
+	// Comment on the open issue of a matching umbrella alert group instead of creating issues for other groups.
+	InhibitRules []*InhibitRule `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
+
+	// Choose the issue type by label value, falling back to issue_type.
+	IssueTypeMapping *LabelMapping `yaml:"issue_type_mapping,omitempty" json:"issue_type_mapping,omitempty"`
+
+	// Choose the project by label value, falling back to project.
+	ProjectMapping *LabelMapping `yaml:"project_mapping,omitempty" json:"project_mapping,omitempty"`
+
+	// Add components by label value, in addition to components.
+	ComponentMapping *ComponentMapping `yaml:"component_mapping,omitempty" json:"component_mapping,omitempty"`
+
+	// Choose the assignee by label value, falling back to oncall, assignee_pool and assignee.
+	AssigneeMapping *LabelMapping `yaml:"assignee_mapping,omitempty" json:"assignee_mapping,omitempty"`
+
+	// Spread new issues across a pool of users, falling back to assignee.
+	AssigneePool *AssigneePool `yaml:"assignee_pool,omitempty" json:"assignee_pool,omitempty"`
+
+	// Assign new issues to the current on-call user, falling back to assignee_pool and assignee.
+	OnCall *OnCall `yaml:"oncall,omitempty" json:"oncall,omitempty"`
+
+	// ServiceNow specific settings.
+	ServiceNow *ServiceNow `yaml:"servicenow,omitempty" json:"servicenow,omitempty"`
+
+	// Handle notifications with another receiver once this one keeps failing.
+	Fallback *Fallback `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+
+	// Only handle the alerts with these label values, or label values matching these regular expressions.
+	Match   map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+
+	// Also handle the notifications of this Alertmanager receiver, e.g. to feed several selective receivers from a
+	// single route. Not inherited from defaults.
+	AlertmanagerReceiver string `yaml:"alertmanager_receiver,omitempty" json:"alertmanager_receiver,omitempty"`
+
+	// Inherit the settings of this receiver, see extend. Not inherited from defaults.
+	Extends string `yaml:"extends,omitempty" json:"extends,omitempty"`
+
+	// Only create one issue per alert group across all receivers of the same dedupe group.
+	DedupeGroup string `yaml:"dedupe_group,omitempty" json:"dedupe_group,omitempty"`
+	// Jira users added as watchers of the issue of another receiver of the dedupe group, instead of this receiver
+	// creating its own. Requires dedupe_group.
+	DedupeWatchers []string `yaml:"dedupe_watchers,omitempty" json:"dedupe_watchers,omitempty"`
+
+	// Log the mutations the receiver would perform instead of sending them. Not inherited from defaults.
+	Shadow bool `yaml:"shadow,omitempty" json:"shadow,omitempty"`
+
+	// Log the receiver's lines at this level instead of the instance's -log.level.
+	LogLevel string `yaml:"log_level,omitempty" json:"log_level,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-// UnmarshalYAML implements the yaml.Unmarshaler interface.
-func (rc *ReceiverConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+// StaticProjects returns the keys of the projects the receiver creates issues in that are not templated, i.e.
+// project and the values of project_mapping, without duplicates.
+func (rc *ReceiverConfig) StaticProjects() []string {
+	var projects []string
+	seen := map[string]struct{}{}
+	add := func(p string) {
+		if _, ok := seen[p]; ok || p == "" || strings.Contains(p, "{{") {
+			return
+		}
+		seen[p] = struct{}{}
+		projects = append(projects, p)
+	}
+	for _, p := range SplitProjects(rc.Project) {
+		add(p)
+	}
+	if rc.ProjectMapping != nil {
+		mapped := make([]string, 0, len(rc.ProjectMapping.Values))
+		for _, v := range rc.ProjectMapping.Values {
+			mapped = append(mapped, v)
+		}
+		sort.Strings(mapped)
+		for _, v := range mapped {
+			for _, p := range SplitProjects(v) {
+				add(p)
+			}
+		}
+	}
+	return projects
+}
+
+// SplitProjects returns the projects of a comma-separated list, without blanks and duplicates.
+func SplitProjects(s string) []string {
+	var projects []string
+	seen := map[string]struct{}{}
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if _, ok := seen[p]; ok || p == "" {
+			continue
+		}
+		seen[p] = struct{}{}
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+// readCredentialFiles sets the password and personal access token to the content of their files, if configured.
+func (rc *ReceiverConfig) readCredentialFiles(section string) error {
+	for _, f := range []struct {
+		name, fileName string
+		file           string
+		secret         *Secret
+	}{
+		{name: "password", fileName: "password_file", file: rc.PasswordFile, secret: &rc.Password},
+		{name: "personal_access_token", fileName: "personal_access_token_file", file: rc.PersonalAccessTokenFile, secret: &rc.PersonalAccessToken},
+	} {
+		if f.file == "" {
+			continue
+		}
+		if *f.secret != "" {
+			return fmt.Errorf("bad auth config in %s: %s and %s are mutually exclusive", section, f.name, f.fileName)
+		}
+		secret, err := ReadSecretFile(f.file)
+		if err != nil {
+			return fmt.Errorf("bad auth config in %s: read %s: %s", section, f.fileName, err)
+		}
+		*f.secret = secret
+	}
+	return nil
+}
+
+// notExtended are the settings receivers do not inherit from the receiver they extend, as they identify the receiver
+// or only make sense per receiver.
+var notExtended = map[string]bool{"Name": true, "Extends": true, "AlertmanagerReceiver": true, "Shadow": true, "XXX": true}
+
+// extend fills the settings rc does not set with those of parent. Maps are merged, rc's entries overriding parent's.
+// Other settings, including lists, are taken from parent unless rc sets them, so boolean settings can only be
+// overridden if they are optional, i.e. pointers.
+func (rc *ReceiverConfig) extend(parent *ReceiverConfig) {
+	v, pv := reflect.ValueOf(rc).Elem(), reflect.ValueOf(parent).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" || notExtended[f.Name] {
+			continue
+		}
+		field, pfield := v.Field(i), pv.Field(i)
+		if field.Kind() == reflect.Map && !pfield.IsNil() {
+			// Always copied, as receivers add to their maps when inheriting from the defaults.
+			merged := reflect.MakeMapWithSize(field.Type(), pfield.Len()+field.Len())
+			for _, m := range []reflect.Value{pfield, field} {
+				iter := m.MapRange()
+				for iter.Next() {
+					merged.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+			field.Set(merged)
+			continue
+		}
+		if field.IsZero() {
+			field.Set(pfield)
+		}
+	}
+}
+
+// resolveExtends makes receivers inherit the settings of the receivers they extend, which may extend other receivers
+// in turn.
+func resolveExtends(receivers []*ReceiverConfig) error {
+	byName := make(map[string]*ReceiverConfig, len(receivers))
+	for _, rc := range receivers {
+		byName[rc.Name] = rc
+	}
+	resolved := map[string]bool{}
+	var resolve func(rc *ReceiverConfig, path []string) error
+	resolve = func(rc *ReceiverConfig, path []string) error {
+		if rc.Extends == "" || resolved[rc.Name] {
+			return nil
+		}
+		for i, name := range path {
+			if name == rc.Name {
+				return fmt.Errorf("bad config in receiver %q, 'extends' forms a cycle: %s", rc.Name, strings.Join(append(path[i:], rc.Name), " -> "))
+			}
+		}
+		parent, ok := byName[rc.Extends]
+		if !ok {
+			return fmt.Errorf("bad config in receiver %q, 'extends' %q does not exist", rc.Name, rc.Extends)
+		}
+		if err := resolve(parent, append(path, rc.Name)); err != nil {
+			return err
+		}
+		rc.extend(parent)
+		resolved[rc.Name] = true
+		return nil
+	}
+	for _, rc := range receivers {
+		if err := resolve(rc, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGitHub fills in the API access and required issue fields that differ from Jira for GitHub receivers, so they
+// aren't inherited from Jira defaults. GitHub only supports token authentication and has fixed open/closed states.
+func (rc *ReceiverConfig) checkGitHub(defaults *ReceiverConfig) error {
+	if rc.User != "" || rc.Password != "" {
+		return fmt.Errorf("bad auth config in receiver %q: GitHub only supports personal_access_token authentication", rc.Name)
+	}
+	if rc.APIURL == "" {
+		rc.APIURL = DefaultGitHubAPIURL
+		if defaults.Backend == BackendGitHub && defaults.APIURL != "" {
+			rc.APIURL = defaults.APIURL
+		}
+	}
+	if rc.PersonalAccessToken == "" {
+		if defaults.PersonalAccessToken == "" {
+			return fmt.Errorf("missing personal_access_token in receiver %q", rc.Name)
+		}
+		rc.PersonalAccessToken = defaults.PersonalAccessToken
+		rc.PersonalAccessTokenFile = defaults.PersonalAccessTokenFile
+	}
+	if rc.IssueType == "" && (defaults.Backend != BackendGitHub || defaults.IssueType == "") {
+		rc.IssueType = "Issue"
+	}
+	if rc.ReopenState == "" && (defaults.Backend != BackendGitHub || defaults.ReopenState == "") {
+		rc.ReopenState = "open"
+	}
+	return nil
+}
+
+// checkServiceNow makes sure ServiceNow receivers do not inherit the API access fields of Jira defaults and fills in
+// the required issue fields that differ from Jira.
+func (rc *ReceiverConfig) checkServiceNow(defaults *ReceiverConfig) error {
+	if defaults.Backend != BackendServiceNow {
+		if rc.APIURL == "" {
+			return fmt.Errorf("missing api_url in receiver %q", rc.Name)
+		}
+		if (rc.User == "" || rc.Password == "") && rc.PersonalAccessToken == "" {
+			return fmt.Errorf("missing authentication in receiver %q", rc.Name)
+		}
+	}
+	if rc.IssueType == "" && (defaults.Backend != BackendServiceNow || defaults.IssueType == "") {
+		rc.IssueType = "Incident"
+	}
+	if rc.ReopenState == "" && (defaults.Backend != BackendServiceNow || defaults.ReopenState == "") {
+		rc.ReopenState = "In Progress"
+	}
+	if rc.ServiceNow == nil {
+		rc.ServiceNow = defaults.ServiceNow
+	}
+	if rc.ServiceNow == nil {
+		rc.ServiceNow = &ServiceNow{}
+	}
+	if rc.ServiceNow.CloseCode == "" {
+		rc.ServiceNow.CloseCode = DefaultServiceNowCloseCode
+	}
+	if rc.ServiceNow.CloseNotes == "" {
+		rc.ServiceNow.CloseNotes = DefaultServiceNowCloseNotes
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface. A list of projects is joined into a comma-separated
+// project.
+func (rc *ReceiverConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			k, v := value.Content[i], value.Content[i+1]
+			if k.Value != "project" || v.Kind != yaml.SequenceNode {
+				continue
+			}
+			var projects []string
+			if err := v.Decode(&projects); err != nil {
+				return err
+			}
+			value.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.Join(projects, ","), Line: v.Line, Column: v.Column}
+		}
+	}
 	type plain ReceiverConfig
-	if err := unmarshal((*plain)(rc)); err != nil {
+	if err := value.Decode((*plain)(rc)); err != nil {
 		return err
 	}
 	// Recursively convert any maps to map[string]interface{}, filtering out all non-string keys, so the json encoder
@@ -185,12 +1225,72 @@ func (rc *ReceiverConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return checkOverflow(rc.XXX, "receiver")
 }
 
+const (
+	// FieldTypeOption is a select list field, {"value": ...}. Lists are multi-select values.
+	FieldTypeOption = "option"
+	// FieldTypeArray is a list of strings, e.g. a labels field. Strings are split at commas.
+	FieldTypeArray = "array"
+	// FieldTypeNumber is a number field. Strings are parsed as numbers.
+	FieldTypeNumber = "number"
+	// FieldTypeUser is a user picker field, {"name": ...} ({"accountId": ...} on Jira Cloud). Lists are multi-user
+	// values.
+	FieldTypeUser = "user"
+	// FieldTypeDateTime is a date-time field. Strings are parsed as RFC 3339 or Go's default time format.
+	FieldTypeDateTime = "datetime"
+	// FieldTypeCascading is a cascading select field, given as a list of the parent and child option or a string of
+	// both separated by a slash.
+	FieldTypeCascading = "cascading"
+)
+
+// validFieldType reports whether t is one of the FieldType constants.
+func validFieldType(t string) bool {
+	switch t {
+	case FieldTypeOption, FieldTypeArray, FieldTypeNumber, FieldTypeUser, FieldTypeDateTime, FieldTypeCascading:
+		return true
+	}
+	return false
+}
+
+const (
+	// FailureWebhookGeneric posts the failure event as JSON.
+	FailureWebhookGeneric = "generic"
+	// FailureWebhookSlack posts the failure event as a Slack incoming webhook message.
+	FailureWebhookSlack = "slack"
+	// FailureWebhookAlertmanager posts the failure event as an alert to the Alertmanager v2 API.
+	FailureWebhookAlertmanager = "alertmanager"
+)
+
+// DefaultFailureWebhookRetryingFor is how long an alert group's notifications fail with retryable errors before the
+// failure webhook is notified, unless configured.
+const DefaultFailureWebhookRetryingFor = Duration(time.Hour)
+
+// FailureWebhook is the configuration of the webhook notified when a notification fails permanently.
+type FailureWebhook struct {
+	// URL is secret, as webhook URLs such as Slack's carry their credentials.
+	URL    Secret `yaml:"url" json:"url"`
+	Format string `yaml:"format" json:"format"`
+	// RetryingFor is how long an alert group's notifications fail with retryable errors, i.e. are retried by
+	// Alertmanager, before the failure is reported too.
+	RetryingFor *Duration `yaml:"retrying_for,omitempty" json:"retrying_for,omitempty"`
+}
+
 // Config is the top-level configuration for JIRAlert's config file.
 type Config struct {
+	// Version of the configuration format, see CurrentVersion.
+	Version int `yaml:"version,omitempty" json:"version,omitempty"`
+
 	Defaults  *ReceiverConfig   `yaml:"defaults,omitempty" json:"defaults,omitempty"`
 	Receivers []*ReceiverConfig `yaml:"receivers,omitempty" json:"receivers,omitempty"`
 	Template  string            `yaml:"template" json:"template"`
 
+	FailureWebhook *FailureWebhook `yaml:"failure_webhook,omitempty" json:"failure_webhook,omitempty"`
+
+	// Named time intervals, referenced by the mute_time_intervals of receivers.
+	TimeIntervals []*NamedTimeInterval `yaml:"time_intervals,omitempty" json:"time_intervals,omitempty"`
+
+	// Named sets of issue fields, labels and components, referenced by the field_profiles of receivers.
+	FieldProfiles []*FieldProfile `yaml:"field_profiles,omitempty" json:"field_profiles,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -200,40 +1300,254 @@ func (c Config) String() string {
 	if err != nil {
 		return fmt.Sprintf("<error creating config string: %s>", err)
 	}
-	return string(b)
-}
+	return string(b)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// We want to set c to the defaults and then overwrite it with the input.
+	// To make unmarshal fill the plain data struct rather than calling UnmarshalYAML
+	// again, we have to hide it using a type indirection.
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Defaults == nil {
+		c.Defaults = &ReceiverConfig{}
+	}
+
+	switch c.Defaults.Backend {
+	case "", BackendJira, BackendGitHub, BackendServiceNow:
+	default:
+		return fmt.Errorf("bad config in defaults section, unknown 'backend' %q, must be one of %q, %q, %q", c.Defaults.Backend, BackendJira, BackendGitHub, BackendServiceNow)
+	}
+
+	if err := c.Defaults.readCredentialFiles("defaults section"); err != nil {
+		return err
+	}
+	if (c.Defaults.User != "" || c.Defaults.Password != "") && c.Defaults.PersonalAccessToken != "" {
+		return fmt.Errorf("bad auth config in defaults section: user/password and PAT authentication are mutually exclusive")
+	}
+	if c.Defaults.Connect != nil {
+		if c.Defaults.User != "" || c.Defaults.Password != "" || c.Defaults.PersonalAccessToken != "" {
+			return fmt.Errorf("bad auth config in defaults section: user/password, PAT and connect authentication are mutually exclusive")
+		}
+		if err := c.Defaults.Connect.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'connect' %s", err)
+		}
+	}
+
+	if c.Defaults.AutoResolve != nil {
+		if c.Defaults.AutoResolve.State == "" {
+			return fmt.Errorf("bad config in defaults section: state cannot be empty")
+		}
+	}
+
+	if c.Defaults.StaleIssues != nil {
+		if err := c.Defaults.StaleIssues.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section: %s", err)
+		}
+	}
+
+	if c.Defaults.MinSeverity != nil {
+		if err := c.Defaults.MinSeverity.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'min_severity' %s", err)
+		}
+	}
+
+	if c.Defaults.IncidentMode != nil {
+		if err := c.Defaults.IncidentMode.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'incident_mode' %s", err)
+		}
+	}
+
+	if c.Defaults.CreationLimit != nil {
+		if err := c.Defaults.CreationLimit.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section: %s", err)
+		}
+	}
+
+	if c.Defaults.BusinessHours != nil {
+		if err := c.Defaults.BusinessHours.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section: %s", err)
+		}
+	}
+
+	if c.Defaults.ServiceDesk != nil {
+		if err := c.Defaults.ServiceDesk.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'service_desk' %s", err)
+		}
+	}
+
+	for _, f := range c.Defaults.AssetsFields {
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'assets_fields' %s", err)
+		}
+	}
+
+	if c.Defaults.RequiredFields != nil {
+		if err := c.Defaults.RequiredFields.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'required_fields' %s", err)
+		}
+	}
+
+	for _, f := range c.Defaults.CascadingFields {
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'cascading_fields' %s", err)
+		}
+	}
+
+	if c.Defaults.Epics != nil {
+		if err := c.Defaults.Epics.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'epics' %s", err)
+		}
+	}
+
+	if c.Defaults.CurrentIncident != nil {
+		if err := c.Defaults.CurrentIncident.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'current_incident' %s", err)
+		}
+	}
+
+	if c.Defaults.Subtasks != nil {
+		if err := c.Defaults.Subtasks.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'subtasks' %s", err)
+		}
+	}
+
+	for _, w := range c.Defaults.MaintenanceWindows {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section: %s", err)
+		}
+	}
+
+	for _, ir := range c.Defaults.InhibitRules {
+		if err := ir.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'inhibit_rules' %s", err)
+		}
+	}
+
+	if c.Defaults.IssueTypeMapping != nil {
+		if err := c.Defaults.IssueTypeMapping.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'issue_type_mapping' %s", err)
+		}
+	}
+
+	if c.Defaults.ProjectMapping != nil {
+		if err := c.Defaults.ProjectMapping.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'project_mapping' %s", err)
+		}
+	}
+
+	if c.Defaults.ComponentMapping != nil {
+		if err := c.Defaults.ComponentMapping.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'component_mapping' %s", err)
+		}
+	}
+
+	if c.Defaults.AssigneeMapping != nil {
+		if err := c.Defaults.AssigneeMapping.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'assignee_mapping' %s", err)
+		}
+	}
+
+	if c.Defaults.AssigneePool != nil {
+		if err := c.Defaults.AssigneePool.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'assignee_pool' %s", err)
+		}
+	}
+
+	if c.Defaults.OnCall != nil {
+		if err := c.Defaults.OnCall.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'oncall' %s", err)
+		}
+	}
+
+	if c.Defaults.Fallback != nil {
+		if err := c.Defaults.Fallback.validate(); err != nil {
+			return fmt.Errorf("bad config in defaults section, 'fallback' %s", err)
+		}
+	}
+
+	if c.Defaults.Shadow {
+		return fmt.Errorf("bad config in defaults section, 'shadow' can only be set per receiver")
+	}
 
-// UnmarshalYAML implements the yaml.Unmarshaler interface.
-func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	// We want to set c to the defaults and then overwrite it with the input.
-	// To make unmarshal fill the plain data struct rather than calling UnmarshalYAML
-	// again, we have to hide it using a type indirection.
+	if c.Defaults.AlertmanagerReceiver != "" {
+		return fmt.Errorf("bad config in defaults section, 'alertmanager_receiver' can only be set per receiver")
+	}
 
-	// TODO: This function panics when there are no defaults. This needs to be fixed.
+	if c.Defaults.Extends != "" {
+		return fmt.Errorf("bad config in defaults section, 'extends' can only be set per receiver")
+	}
 
-	type plain Config
-	if err := unmarshal((*plain)(c)); err != nil {
-		return err
+	if c.Defaults.LogLevel != "" && !validLogLevel(c.Defaults.LogLevel) {
+		return fmt.Errorf("bad config in defaults section, 'log_level' must be one of debug, info, warn or error")
 	}
 
-	if (c.Defaults.User != "" || c.Defaults.Password != "") && c.Defaults.PersonalAccessToken != "" {
-		return fmt.Errorf("bad auth config in defaults section: user/password and PAT authentication are mutually exclusive")
+	if c.Defaults.GroupIssueBy == "" {
+		c.Defaults.GroupIssueBy = AlertGroup
+	}
+	switch c.Defaults.TemplateErrors {
+	case "":
+		c.Defaults.TemplateErrors = TemplateErrorsFail
+	case TemplateErrorsFail, TemplateErrorsFallback:
+	default:
+		return fmt.Errorf("bad config in defaults section, 'template_errors' must be one of %q or %q", TemplateErrorsFail, TemplateErrorsFallback)
 	}
 
-	if c.Defaults.AutoResolve != nil {
-		if c.Defaults.AutoResolve.State == "" {
-			return fmt.Errorf("bad config in defaults section: state cannot be empty")
+	timeIntervals := map[string]*NamedTimeInterval{}
+	for _, ti := range c.TimeIntervals {
+		if ti.Name == "" {
+			return fmt.Errorf("bad config in 'time_intervals', missing name")
+		}
+		if _, ok := timeIntervals[ti.Name]; ok {
+			return fmt.Errorf("bad config in 'time_intervals', %q is defined more than once", ti.Name)
 		}
+		timeIntervals[ti.Name] = ti
 	}
 
-	if c.Defaults.GroupIssueBy == "" {
-		c.Defaults.GroupIssueBy = AlertGroup
+	fieldProfiles := map[string]*FieldProfile{}
+	for _, p := range c.FieldProfiles {
+		if p.Name == "" {
+			return fmt.Errorf("bad config in 'field_profiles', missing name")
+		}
+		if _, ok := fieldProfiles[p.Name]; ok {
+			return fmt.Errorf("bad config in 'field_profiles', %q is defined more than once", p.Name)
+		}
+		fieldProfiles[p.Name] = p
+	}
+
+	if err := resolveExtends(c.Receivers); err != nil {
+		return err
 	}
 
 	for _, rc := range c.Receivers {
 		if rc.Name == "" {
 			return fmt.Errorf("missing name for receiver %+v", rc)
 		}
+		if err := rc.readCredentialFiles(fmt.Sprintf("receiver %q", rc.Name)); err != nil {
+			return err
+		}
+
+		if rc.Backend == "" {
+			rc.Backend = c.Defaults.Backend
+		}
+		switch rc.Backend {
+		case "", BackendJira:
+			rc.Backend = BackendJira
+		case BackendGitHub:
+			if err := rc.checkGitHub(c.Defaults); err != nil {
+				return err
+			}
+		case BackendServiceNow:
+			if err := rc.checkServiceNow(c.Defaults); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("bad config in receiver %q, unknown 'backend' %q, must be one of %q, %q, %q", rc.Name, rc.Backend, BackendJira, BackendGitHub, BackendServiceNow)
+		}
 
 		// Check API access fields.
 		if rc.APIURL == "" {
@@ -250,19 +1564,35 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 			return fmt.Errorf("bad auth config in receiver %q: user/password and PAT authentication are mutually exclusive", rc.Name)
 		}
 
-		if (rc.User == "" || rc.Password == "") && rc.PersonalAccessToken == "" {
+		if rc.Connect != nil {
+			if rc.User != "" || rc.Password != "" || rc.PersonalAccessToken != "" {
+				return fmt.Errorf("bad auth config in receiver %q: user/password, PAT and connect authentication are mutually exclusive", rc.Name)
+			}
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'connect' is only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if err := rc.Connect.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'connect' %s", rc.Name, err)
+			}
+		}
+
+		if (rc.User == "" || rc.Password == "") && rc.PersonalAccessToken == "" && rc.Connect == nil {
 			if rc.User == "" && c.Defaults.User != "" {
 				rc.User = c.Defaults.User
 			}
 
 			if rc.Password == "" && c.Defaults.Password != "" {
 				rc.Password = c.Defaults.Password
+				rc.PasswordFile = c.Defaults.PasswordFile
 			}
 
 			if rc.User != "" && rc.Password != "" {
 				// Nothing to do, we're ready to go with basic auth.
 			} else if c.Defaults.PersonalAccessToken != "" {
 				rc.PersonalAccessToken = c.Defaults.PersonalAccessToken
+				rc.PersonalAccessTokenFile = c.Defaults.PersonalAccessTokenFile
+			} else if c.Defaults.Connect != nil && rc.User == "" && rc.Backend == BackendJira {
+				rc.Connect = c.Defaults.Connect
 			} else {
 				return fmt.Errorf("missing authentication in receiver %q", rc.Name)
 			}
@@ -299,12 +1629,47 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 			}
 			rc.ReopenDuration = c.Defaults.ReopenDuration
 		}
+		if rc.ReopenDelay == nil {
+			rc.ReopenDelay = c.Defaults.ReopenDelay
+		}
+		if rc.ReopenDelay != nil && *rc.ReopenDuration != 0 && *rc.ReopenDelay >= *rc.ReopenDuration {
+			return fmt.Errorf("bad config in receiver %q, 'reopen_delay' must be shorter than 'reopen_duration'", rc.Name)
+		}
+		if rc.MinFiringDuration == nil {
+			rc.MinFiringDuration = c.Defaults.MinFiringDuration
+		}
+		if rc.MaxAlertAge == nil {
+			rc.MaxAlertAge = c.Defaults.MaxAlertAge
+		}
+		if rc.MaxAlertAge != nil && *rc.MaxAlertAge == 0 {
+			return fmt.Errorf("bad config in receiver %q, 'max_alert_age' must be a positive duration", rc.Name)
+		}
+		if rc.MaxAlertAge != nil && rc.MinFiringDuration != nil && *rc.MinFiringDuration >= *rc.MaxAlertAge {
+			return fmt.Errorf("bad config in receiver %q, 'min_firing_duration' must be shorter than 'max_alert_age'", rc.Name)
+		}
+		if rc.MinUpdateInterval == nil {
+			rc.MinUpdateInterval = c.Defaults.MinUpdateInterval
+		}
 
 		// Populate optional issue fields, where necessary.
 		if rc.GroupIssueBy == "" && c.Defaults.GroupIssueBy != "" {
 			rc.GroupIssueBy = c.Defaults.GroupIssueBy
 		}
 
+		if rc.LogLevel == "" {
+			rc.LogLevel = c.Defaults.LogLevel
+		}
+		if rc.LogLevel != "" && !validLogLevel(rc.LogLevel) {
+			return fmt.Errorf("bad config in receiver %q, 'log_level' must be one of debug, info, warn or error", rc.Name)
+		}
+
+		if rc.TemplateErrors == "" {
+			rc.TemplateErrors = c.Defaults.TemplateErrors
+		}
+		if rc.TemplateErrors != TemplateErrorsFail && rc.TemplateErrors != TemplateErrorsFallback {
+			return fmt.Errorf("bad config in receiver %q, 'template_errors' must be one of %q or %q", rc.Name, TemplateErrorsFail, TemplateErrorsFallback)
+		}
+
 		// validate that GroupIssueBy is either Alert/AlertRule/AlertGroup
 		if rc.GroupIssueBy != Alert && rc.GroupIssueBy != AlertRule && rc.GroupIssueBy != AlertGroup {
 			return fmt.Errorf("bad config in receiver %q, 'group_issue_by' must be either Alert/AlertRule/AlertGroup", rc.Name)
@@ -319,9 +1684,136 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		if rc.Description == "" && c.Defaults.Description != "" {
 			rc.Description = c.Defaults.Description
 		}
+		if rc.DescriptionHeader == "" && c.Defaults.DescriptionHeader != "" {
+			rc.DescriptionHeader = c.Defaults.DescriptionHeader
+		}
+		if rc.DescriptionFooter == "" && c.Defaults.DescriptionFooter != "" {
+			rc.DescriptionFooter = c.Defaults.DescriptionFooter
+		}
+		if rc.Assignee == "" && c.Defaults.Assignee != "" {
+			rc.Assignee = c.Defaults.Assignee
+		}
+		if rc.Reporter == "" && c.Defaults.Reporter != "" {
+			rc.Reporter = c.Defaults.Reporter
+		}
 		if rc.WontFixResolution == "" && c.Defaults.WontFixResolution != "" {
 			rc.WontFixResolution = c.Defaults.WontFixResolution
 		}
+		if rc.UpdateSummary == nil {
+			rc.UpdateSummary = c.Defaults.UpdateSummary
+		}
+		if rc.UpdateDescription == nil {
+			rc.UpdateDescription = c.Defaults.UpdateDescription
+		}
+		if rc.ManagedDescription == nil {
+			rc.ManagedDescription = c.Defaults.ManagedDescription
+		}
+		if rc.NotifyUsers == nil {
+			rc.NotifyUsers = c.Defaults.NotifyUsers
+		}
+		if rc.UpdateOptions == nil {
+			rc.UpdateOptions = c.Defaults.UpdateOptions
+		}
+		if rc.DiffIgnore == nil {
+			rc.DiffIgnore = c.Defaults.DiffIgnore
+		}
+		if rc.Backend == BackendJira {
+			if rc.AlertCountField == "" {
+				rc.AlertCountField = c.Defaults.AlertCountField
+			}
+			if rc.FiringSinceField == "" {
+				rc.FiringSinceField = c.Defaults.FiringSinceField
+			}
+			if rc.LastSeenField == "" {
+				rc.LastSeenField = c.Defaults.LastSeenField
+			}
+		}
+		if rc.ServiceDesk == nil && rc.Backend == BackendJira {
+			rc.ServiceDesk = c.Defaults.ServiceDesk
+		}
+		if rc.ServiceDesk != nil {
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'service_desk' is only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if err := rc.ServiceDesk.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'service_desk' %s", rc.Name, err)
+			}
+		}
+		if rc.AssetsFields == nil && rc.Backend == BackendJira {
+			rc.AssetsFields = c.Defaults.AssetsFields
+		}
+		if len(rc.AssetsFields) > 0 && rc.Backend != BackendJira {
+			return fmt.Errorf("bad config in receiver %q, 'assets_fields' are only supported by the %q backend", rc.Name, BackendJira)
+		}
+		for _, f := range rc.AssetsFields {
+			if err := f.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'assets_fields' %s", rc.Name, err)
+			}
+		}
+		if rc.RequiredFields == nil && rc.Backend == BackendJira {
+			rc.RequiredFields = c.Defaults.RequiredFields
+		}
+		if rc.RequiredFields != nil {
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'required_fields' is only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if err := rc.RequiredFields.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'required_fields' %s", rc.Name, err)
+			}
+		}
+		if rc.CascadingFields == nil && rc.Backend == BackendJira {
+			rc.CascadingFields = c.Defaults.CascadingFields
+		}
+		if len(rc.CascadingFields) > 0 && rc.Backend != BackendJira {
+			return fmt.Errorf("bad config in receiver %q, 'cascading_fields' are only supported by the %q backend", rc.Name, BackendJira)
+		}
+		for _, f := range rc.CascadingFields {
+			if err := f.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'cascading_fields' %s", rc.Name, err)
+			}
+		}
+		if rc.Epics == nil && rc.Backend == BackendJira {
+			rc.Epics = c.Defaults.Epics
+		}
+		if rc.Epics != nil {
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'epics' are only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if err := rc.Epics.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'epics' %s", rc.Name, err)
+			}
+		}
+		if rc.CurrentIncident == nil && rc.Backend == BackendJira {
+			rc.CurrentIncident = c.Defaults.CurrentIncident
+		}
+		if rc.CurrentIncident != nil {
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'current_incident' is only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if err := rc.CurrentIncident.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'current_incident' %s", rc.Name, err)
+			}
+		}
+		if rc.Subtasks == nil && rc.Backend == BackendJira {
+			rc.Subtasks = c.Defaults.Subtasks
+		}
+		if rc.Subtasks != nil {
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'subtasks' are only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if err := rc.Subtasks.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'subtasks' %s", rc.Name, err)
+			}
+		}
+		if rc.Backend != BackendJira && (rc.AlertCountField != "" || rc.FiringSinceField != "" || rc.LastSeenField != "") {
+			return fmt.Errorf("bad config in receiver %q, 'alert_count_field', 'firing_since_field' and 'last_seen_field' are only supported by the %q backend", rc.Name, BackendJira)
+		}
+		if rc.WontFixDuration == nil {
+			rc.WontFixDuration = c.Defaults.WontFixDuration
+		}
+		if rc.WontFixDuration != nil && rc.WontFixResolution == "" {
+			return fmt.Errorf("bad config in receiver %q, 'wont_fix_duration' requires 'wont_fix_resolution'", rc.Name)
+		}
 		if rc.AutoResolve != nil {
 			if rc.AutoResolve.State == "" {
 				return fmt.Errorf("bad config in receiver %q, 'auto_resolve' was defined with empty 'state' field", rc.Name)
@@ -330,6 +1822,168 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		if rc.AutoResolve == nil && c.Defaults.AutoResolve != nil {
 			rc.AutoResolve = c.Defaults.AutoResolve
 		}
+		if rc.PartialResolutionComment == "" && c.Defaults.PartialResolutionComment != "" {
+			rc.PartialResolutionComment = c.Defaults.PartialResolutionComment
+		}
+		if rc.AutoResolve != nil && rc.Backend != BackendJira && (rc.AutoResolve.EndedAtField != "" || rc.AutoResolve.DurationField != "") {
+			return fmt.Errorf("bad config in receiver %q, 'auto_resolve' 'ended_at_field' and 'duration_field' are only supported by the %q backend", rc.Name, BackendJira)
+		}
+		if rc.StaleIssues != nil {
+			if err := rc.StaleIssues.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'stale_issues' %s", rc.Name, err)
+			}
+		}
+		if rc.StaleIssues == nil && c.Defaults.StaleIssues != nil {
+			rc.StaleIssues = c.Defaults.StaleIssues
+		}
+		if rc.CreationLimit != nil {
+			if err := rc.CreationLimit.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'creation_limit' %s", rc.Name, err)
+			}
+		}
+		if rc.CreationLimit == nil && c.Defaults.CreationLimit != nil {
+			rc.CreationLimit = c.Defaults.CreationLimit
+		}
+		if rc.Match == nil {
+			rc.Match = c.Defaults.Match
+		}
+		if rc.MatchRE == nil {
+			rc.MatchRE = c.Defaults.MatchRE
+		}
+		if rc.MinSeverity != nil {
+			if err := rc.MinSeverity.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'min_severity' %s", rc.Name, err)
+			}
+		}
+		if rc.MinSeverity == nil && c.Defaults.MinSeverity != nil {
+			rc.MinSeverity = c.Defaults.MinSeverity
+		}
+		if rc.IncidentMode != nil {
+			if err := rc.IncidentMode.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'incident_mode' %s", rc.Name, err)
+			}
+		}
+		if rc.IncidentMode == nil && c.Defaults.IncidentMode != nil {
+			rc.IncidentMode = c.Defaults.IncidentMode
+		}
+		if rc.BusinessHours != nil {
+			if err := rc.BusinessHours.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'business_hours' %s", rc.Name, err)
+			}
+		}
+		if rc.BusinessHours == nil && c.Defaults.BusinessHours != nil {
+			rc.BusinessHours = c.Defaults.BusinessHours
+		}
+		for _, w := range rc.MaintenanceWindows {
+			if err := w.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q: %s", rc.Name, err)
+			}
+		}
+		if rc.MaintenanceWindows == nil && c.Defaults.MaintenanceWindows != nil {
+			rc.MaintenanceWindows = c.Defaults.MaintenanceWindows
+		}
+		if rc.MuteTimeIntervals == nil {
+			rc.MuteTimeIntervals = c.Defaults.MuteTimeIntervals
+		}
+		rc.muteTimeIntervals = nil
+		for _, name := range rc.MuteTimeIntervals {
+			ti, ok := timeIntervals[name]
+			if !ok {
+				return fmt.Errorf("bad config in receiver %q, 'mute_time_intervals' %q does not exist", rc.Name, name)
+			}
+			rc.muteTimeIntervals = append(rc.muteTimeIntervals, ti)
+		}
+		for _, ir := range rc.InhibitRules {
+			if err := ir.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'inhibit_rules' %s", rc.Name, err)
+			}
+		}
+		if rc.InhibitRules == nil {
+			rc.InhibitRules = c.Defaults.InhibitRules
+		}
+		if rc.IssueTypeMapping != nil {
+			if err := rc.IssueTypeMapping.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'issue_type_mapping' %s", rc.Name, err)
+			}
+		}
+		if rc.IssueTypeMapping == nil && c.Defaults.IssueTypeMapping != nil {
+			rc.IssueTypeMapping = c.Defaults.IssueTypeMapping
+		}
+		if rc.ProjectMapping != nil {
+			if err := rc.ProjectMapping.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'project_mapping' %s", rc.Name, err)
+			}
+		}
+		if rc.ProjectMapping == nil && c.Defaults.ProjectMapping != nil {
+			rc.ProjectMapping = c.Defaults.ProjectMapping
+		}
+		if rc.ComponentMapping != nil {
+			if err := rc.ComponentMapping.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'component_mapping' %s", rc.Name, err)
+			}
+		}
+		if rc.ComponentMapping == nil && c.Defaults.ComponentMapping != nil {
+			rc.ComponentMapping = c.Defaults.ComponentMapping
+		}
+		if rc.AssigneeMapping != nil {
+			if err := rc.AssigneeMapping.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'assignee_mapping' %s", rc.Name, err)
+			}
+		}
+		if rc.AssigneeMapping == nil && c.Defaults.AssigneeMapping != nil {
+			rc.AssigneeMapping = c.Defaults.AssigneeMapping
+		}
+		if rc.AssigneePool != nil {
+			if err := rc.AssigneePool.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'assignee_pool' %s", rc.Name, err)
+			}
+		}
+		if rc.AssigneePool == nil && c.Defaults.AssigneePool != nil {
+			rc.AssigneePool = c.Defaults.AssigneePool
+		}
+		if rc.OnCall != nil {
+			if err := rc.OnCall.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'oncall' %s", rc.Name, err)
+			}
+		}
+		if rc.OnCall == nil && c.Defaults.OnCall != nil {
+			rc.OnCall = c.Defaults.OnCall
+		}
+		if rc.Fallback != nil {
+			if err := rc.Fallback.validate(); err != nil {
+				return fmt.Errorf("bad config in receiver %q, 'fallback' %s", rc.Name, err)
+			}
+		}
+		// The fallback receiver itself does not inherit the default fallback.
+		if rc.Fallback == nil && c.Defaults.Fallback != nil && c.Defaults.Fallback.Receiver != rc.Name {
+			rc.Fallback = c.Defaults.Fallback
+		}
+		if rc.DedupeGroup == "" {
+			rc.DedupeGroup = c.Defaults.DedupeGroup
+		}
+		if rc.DedupeWatchers == nil && rc.Backend == BackendJira {
+			rc.DedupeWatchers = c.Defaults.DedupeWatchers
+		}
+		if len(rc.DedupeWatchers) > 0 {
+			if rc.Backend != BackendJira {
+				return fmt.Errorf("bad config in receiver %q, 'dedupe_watchers' is only supported by the %q backend", rc.Name, BackendJira)
+			}
+			if rc.DedupeGroup == "" {
+				return fmt.Errorf("bad config in receiver %q, 'dedupe_watchers' requires 'dedupe_group'", rc.Name)
+			}
+		}
+		if rc.FieldProfiles == nil {
+			rc.FieldProfiles = c.Defaults.FieldProfiles
+		}
+		var profiles []*FieldProfile
+		for _, name := range rc.FieldProfiles {
+			p, ok := fieldProfiles[name]
+			if !ok {
+				return fmt.Errorf("bad config in receiver %q, 'field_profiles' %q does not exist", rc.Name, name)
+			}
+			profiles = append(profiles, p)
+		}
+		rc.applyFieldProfiles(profiles)
 		if len(c.Defaults.Fields) > 0 {
 			for key, value := range c.Defaults.Fields {
 				if _, ok := rc.Fields[key]; !ok {
@@ -337,19 +1991,249 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				}
 			}
 		}
+		if len(c.Defaults.FieldTypes) > 0 && rc.FieldTypes == nil {
+			rc.FieldTypes = map[string]string{}
+		}
+		for key, t := range c.Defaults.FieldTypes {
+			if _, ok := rc.FieldTypes[key]; !ok {
+				rc.FieldTypes[key] = t
+			}
+		}
+		if len(c.Defaults.FieldUpdates) > 0 && rc.FieldUpdates == nil {
+			rc.FieldUpdates = map[string]*FieldUpdate{}
+		}
+		for key, u := range c.Defaults.FieldUpdates {
+			if _, ok := rc.FieldUpdates[key]; !ok {
+				rc.FieldUpdates[key] = u
+			}
+		}
+		if len(c.Defaults.TemplateDefaults) > 0 && rc.TemplateDefaults == nil {
+			rc.TemplateDefaults = map[string]interface{}{}
+		}
+		for key, v := range c.Defaults.TemplateDefaults {
+			if _, ok := rc.TemplateDefaults[key]; !ok {
+				rc.TemplateDefaults[key] = v
+			}
+		}
+		for key, u := range rc.FieldUpdates {
+			if u == nil {
+				return fmt.Errorf("bad config in receiver %q, 'field_updates' %q must set 'update' or 'on_update'", rc.Name, key)
+			}
+			if u.Update != nil && !*u.Update && u.OnUpdate != nil {
+				return fmt.Errorf("bad config in receiver %q, 'field_updates' %q cannot set 'on_update' with 'update' false", rc.Name, key)
+			}
+			if _, ok := rc.Fields[key]; !ok && u.Enabled() && u.OnUpdate == nil {
+				return fmt.Errorf("bad config in receiver %q, 'field_updates' %q is not in 'fields', 'on_update' must be set", rc.Name, key)
+			}
+		}
+		for key, t := range rc.FieldTypes {
+			if !validFieldType(t) {
+				return fmt.Errorf("bad config in receiver %q, 'field_types' %q has unknown type %q, must be one of %q, %q, %q, %q, %q, %q", rc.Name, key, t,
+					FieldTypeOption, FieldTypeArray, FieldTypeNumber, FieldTypeUser, FieldTypeDateTime, FieldTypeCascading)
+			}
+		}
+		if err := validateTemplateDefaults(rc.TemplateDefaults); err != nil {
+			return fmt.Errorf("bad config in receiver %q, %s", rc.Name, err)
+		}
 	}
 
 	if len(c.Receivers) == 0 {
 		return fmt.Errorf("no receivers defined")
 	}
 
+	for _, rc := range c.Receivers {
+		if rc.Fallback == nil {
+			continue
+		}
+		if rc.Fallback.Receiver == rc.Name {
+			return fmt.Errorf("bad config in receiver %q, 'fallback' cannot be the receiver itself", rc.Name)
+		}
+		if c.ReceiverByName(rc.Fallback.Receiver) == nil {
+			return fmt.Errorf("bad config in receiver %q, 'fallback' receiver %q does not exist", rc.Name, rc.Fallback.Receiver)
+		}
+	}
+
+	// Receivers of a dedupe group comment on each other's issues, so they must use the same issue tracker.
+	dedupeGroups := map[string]*ReceiverConfig{}
+	for _, rc := range c.Receivers {
+		if rc.DedupeGroup == "" {
+			continue
+		}
+		first, ok := dedupeGroups[rc.DedupeGroup]
+		if !ok {
+			dedupeGroups[rc.DedupeGroup] = rc
+			continue
+		}
+		if rc.Backend != first.Backend || rc.APIURL != first.APIURL {
+			return fmt.Errorf("bad config in receiver %q, 'dedupe_group' %q must only contain receivers of the same issue tracker as %q", rc.Name, rc.DedupeGroup, first.Name)
+		}
+	}
+
 	if c.Template == "" {
 		return fmt.Errorf("missing template file")
 	}
 
+	if fw := c.FailureWebhook; fw != nil {
+		if u, err := url.Parse(string(fw.URL)); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("bad config in failure_webhook: invalid url")
+		}
+		if fw.RetryingFor == nil {
+			d := DefaultFailureWebhookRetryingFor
+			fw.RetryingFor = &d
+		}
+		switch fw.Format {
+		case "":
+			fw.Format = FailureWebhookGeneric
+		case FailureWebhookGeneric, FailureWebhookSlack, FailureWebhookAlertmanager:
+		default:
+			return fmt.Errorf("bad config in failure_webhook: 'format' must be either %s/%s/%s", FailureWebhookGeneric, FailureWebhookSlack, FailureWebhookAlertmanager)
+		}
+	}
+
 	return checkOverflow(c.XXX, "config")
 }
 
+func (s *StaleIssues) validate() error {
+	if s.After == nil || *s.After == 0 {
+		return fmt.Errorf("'after' must be a positive duration")
+	}
+	if s.State == "" {
+		return fmt.Errorf("'state' cannot be empty")
+	}
+	return nil
+}
+
+func (f *Fallback) validate() error {
+	if f.Receiver == "" {
+		return fmt.Errorf("'receiver' cannot be empty")
+	}
+	if f.After == nil {
+		f.After = new(Duration)
+	}
+	return nil
+}
+
+func (l *CreationLimit) validate() error {
+	if l.MaxIssues <= 0 {
+		return fmt.Errorf("'max_issues' must be positive")
+	}
+	if l.Window == nil || *l.Window == 0 {
+		return fmt.Errorf("'window' must be a positive duration")
+	}
+	if l.StormSummary == "" {
+		l.StormSummary = DefaultStormSummary
+	}
+	return nil
+}
+
+func (m *IncidentMode) validate() error {
+	if m.Window == nil || *m.Window == 0 {
+		return fmt.Errorf("'window' must be a positive duration")
+	}
+	if m.Summary == "" {
+		m.Summary = DefaultIncidentSummary
+	}
+	return nil
+}
+
+func (b *BusinessHours) validate() error {
+	if len(b.Intervals) == 0 {
+		return fmt.Errorf("'intervals' cannot be empty")
+	}
+	if b.SeverityLabel == "" {
+		b.SeverityLabel = "severity"
+	}
+	if b.BypassSeverities == nil {
+		b.BypassSeverities = []string{"critical"}
+	}
+	return nil
+}
+
+func (p *AssigneePool) validate() error {
+	if len(p.Users) == 0 {
+		return fmt.Errorf("'users' cannot be empty")
+	}
+	switch p.Strategy {
+	case "":
+		p.Strategy = AssigneePoolRoundRobin
+	case AssigneePoolRoundRobin, AssigneePoolLeastRecentlyAssigned:
+	default:
+		return fmt.Errorf("'strategy' must be either %s/%s", AssigneePoolRoundRobin, AssigneePoolLeastRecentlyAssigned)
+	}
+	return nil
+}
+
+func (o *OnCall) validate() error {
+	switch o.Provider {
+	case OnCallPagerDuty:
+		if o.APIURL == "" {
+			o.APIURL = "https://api.pagerduty.com"
+		}
+	case OnCallOpsgenie:
+		if o.APIURL == "" {
+			o.APIURL = "https://api.opsgenie.com"
+		}
+	default:
+		return fmt.Errorf("'provider' must be either %s/%s", OnCallPagerDuty, OnCallOpsgenie)
+	}
+	if _, err := url.Parse(o.APIURL); err != nil {
+		return fmt.Errorf("invalid 'api_url' %q: %s", o.APIURL, err)
+	}
+	if o.Token == "" {
+		return fmt.Errorf("'token' cannot be empty")
+	}
+	if o.Schedule == "" {
+		return fmt.Errorf("'schedule' cannot be empty")
+	}
+	if o.Timeout == nil {
+		d := Duration(5 * time.Second)
+		o.Timeout = &d
+	}
+	return nil
+}
+
+// ReceiversFor returns the receivers handling the notifications of the Alertmanager receiver with the given name, i.e.
+// the receiver with that name and the ones with it as alertmanager_receiver.
+func (c *Config) ReceiversFor(name string) []*ReceiverConfig {
+	var res []*ReceiverConfig
+	for _, rc := range c.Receivers {
+		if rc.Receives(name) {
+			res = append(res, rc)
+		}
+	}
+	return res
+}
+
+// Receives reports whether the receiver handles the notifications of the Alertmanager receiver with the given name.
+func (rc *ReceiverConfig) Receives(name string) bool {
+	return rc.Name == name || rc.AlertmanagerReceiver == name
+}
+
+// MutingTimeInterval returns the name of the first of the receiver's mute_time_intervals containing t, if any.
+func (rc *ReceiverConfig) MutingTimeInterval(t time.Time) string {
+	for _, ti := range rc.muteTimeIntervals {
+		if ti.TimeIntervals.Contains(t) {
+			return ti.Name
+		}
+	}
+	return ""
+}
+
+// Matches reports whether the receiver handles alerts with the given labels.
+func (rc *ReceiverConfig) Matches(labels map[string]string) bool {
+	for k, v := range rc.Match {
+		if labels[k] != v {
+			return false
+		}
+	}
+	for k, re := range rc.MatchRE {
+		if !re.MatchString(labels[k]) {
+			return false
+		}
+	}
+	return true
+}
+
 // ReceiverByName loops the receiver list and returns the first instance with that name
 func (c *Config) ReceiverByName(name string) *ReceiverConfig {
 	for _, rc := range c.Receivers {
@@ -360,6 +2244,15 @@ func (c *Config) ReceiverByName(name string) *ReceiverConfig {
 	return nil
 }
 
+// validLogLevel reports whether lvl is one of the levels accepted by -log.level.
+func validLogLevel(lvl string) bool {
+	switch lvl {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
 func checkOverflow(m map[string]interface{}, ctx string) error {
 	if len(m) > 0 {
 		var keys []string
@@ -446,6 +2339,11 @@ func (d Duration) MarshalYAML() (interface{}, error) {
 	return d.String(), nil
 }
 
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
 func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var s string
 	if err := unmarshal(&s); err != nil {
@@ -13,10 +13,14 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/stretchr/testify/require"
@@ -92,6 +96,45 @@ func TestLoadFile(t *testing.T) {
 
 }
 
+func TestLoadFileOverlays(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "config.yaml"), []byte(testConf), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "staging.yaml"), []byte(`
+defaults:
+  api_url: https://jiralert-staging.atlassian.net
+receivers:
+  - name: 'jira-xy'
+    project: XYSTAGE
+    components: [ 'Staging' ]
+  - name: 'jira-new'
+    project: NEW
+`), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "priority.yaml"), []byte(`
+defaults:
+  priority: Minor
+`), 0o600))
+
+	cfg, _, err := LoadFile(path.Join(dir, "config.yaml"), log.NewNopLogger(), path.Join(dir, "staging.yaml"), path.Join(dir, "priority.yaml"))
+	require.NoError(t, err)
+	require.Len(t, cfg.Receivers, 3)
+	for _, rc := range cfg.Receivers {
+		require.Equal(t, "https://jiralert-staging.atlassian.net", rc.APIURL)
+		require.Equal(t, "Minor", rc.Priority)
+	}
+	require.Equal(t, "AB", cfg.Receivers[0].Project)
+	require.Equal(t, "XYSTAGE", cfg.Receivers[1].Project)
+	require.Equal(t, "Task", cfg.Receivers[1].IssueType)
+	require.Equal(t, []string{"Staging"}, cfg.Receivers[1].Components)
+	require.Equal(t, "Random text", cfg.Receivers[1].Fields["customfield_10001"])
+	require.Equal(t, "NEW", cfg.Receivers[2].Project)
+	require.Equal(t, path.Join(dir, "jiralert.tmpl"), cfg.Template)
+
+	require.NoError(t, os.WriteFile(path.Join(dir, "broken.yaml"), []byte("receivers: {"), 0o600))
+	_, _, err = LoadFile(path.Join(dir, "config.yaml"), log.NewNopLogger(), path.Join(dir, "broken.yaml"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to merge overlay "+path.Join(dir, "broken.yaml"))
+}
+
 // Checks if the env var substitution is happening correctly in the loaded file
 func TestEnvSubstitution(t *testing.T) {
 
@@ -128,6 +171,7 @@ type receiverTestConfig struct {
 	AddGroupLabels    bool   `yaml:"add_group_labels,omitempty"`
 
 	AutoResolve *AutoResolve `yaml:"auto_resolve" json:"auto_resolve"`
+	StaleIssues *StaleIssues `yaml:"stale_issues,omitempty"`
 
 	// TODO(rporres): Add support for these.
 	// Fields            map[string]interface{} `yaml:"fields,omitempty"`
@@ -459,3 +503,1502 @@ func TestAutoResolveConfigDefault(t *testing.T) {
 	configErrorTestRunner(t, config, "bad config in defaults section: state cannot be empty")
 
 }
+
+func TestStaleIssuesConfig(t *testing.T) {
+	mandatory := mandatoryReceiverFields()
+	defaultsConfig := newReceiverTestConfig(mandatory, []string{})
+
+	for _, test := range []struct {
+		staleIssues  *StaleIssues
+		errorMessage string
+	}{
+		{&StaleIssues{State: "Done"}, `bad config in receiver "test", 'stale_issues' 'after' must be a positive duration`},
+		{&StaleIssues{After: durationPtr(30 * 24 * time.Hour)}, `bad config in receiver "test", 'stale_issues' 'state' cannot be empty`},
+	} {
+		receiverConfig := &receiverTestConfig{Name: "test", StaleIssues: test.staleIssues}
+		config := testConfig{
+			Defaults:  defaultsConfig,
+			Receivers: []*receiverTestConfig{receiverConfig},
+			Template:  "jiralert.tmpl",
+		}
+		configErrorTestRunner(t, config, test.errorMessage)
+	}
+}
+
+func durationPtr(d time.Duration) *Duration {
+	res := Duration(d)
+	return &res
+}
+
+func TestTimeInterval(t *testing.T) {
+	var ti TimeInterval
+	require.NoError(t, yaml.Unmarshal([]byte(`
+weekdays: ['monday:wednesday', 'friday']
+times: [{start_time: '09:00', end_time: '17:30'}]
+location: 'Europe/Berlin'
+`), &ti))
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	for _, test := range []struct {
+		t        time.Time
+		expected bool
+	}{
+		{time.Date(2022, 10, 17, 9, 0, 0, 0, berlin), true},    // Monday.
+		{time.Date(2022, 10, 17, 17, 30, 0, 0, berlin), false}, // End is exclusive.
+		{time.Date(2022, 10, 17, 8, 0, 0, 0, time.UTC), true},  // 10:00 in Berlin.
+		{time.Date(2022, 10, 20, 12, 0, 0, 0, berlin), false},  // Thursday.
+		{time.Date(2022, 10, 21, 12, 0, 0, 0, berlin), true},   // Friday.
+		{time.Date(2022, 10, 22, 12, 0, 0, 0, berlin), false},  // Saturday.
+	} {
+		require.Equal(t, test.expected, ti.Contains(test.t), test.t.String())
+	}
+
+	for _, bad := range []string{
+		`weekdays: ['someday']`,
+		`weekdays: ['friday:monday']`,
+		`times: [{start_time: '17:00', end_time: '09:00'}]`,
+		`times: [{start_time: '9', end_time: '10:00'}]`,
+		`location: 'Nowhere/Special'`,
+	} {
+		require.Error(t, yaml.Unmarshal([]byte(bad), &TimeInterval{}), bad)
+	}
+}
+
+func TestFailureWebhookConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+receivers:
+  - name: 'jira-ab'
+template: jiralert.tmpl
+`
+	cfg, err := Load(base + `
+failure_webhook:
+  url: http://localhost:8080/hook
+`)
+	require.NoError(t, err)
+	require.Equal(t, FailureWebhookGeneric, cfg.FailureWebhook.Format)
+	require.Equal(t, DefaultFailureWebhookRetryingFor, *cfg.FailureWebhook.RetryingFor)
+	// The URL is redacted, as it may carry credentials.
+	require.NotContains(t, cfg.String(), "localhost:8080")
+
+	cfg, err = Load(base + `
+failure_webhook:
+  url: http://localhost:8080/hook
+  retrying_for: 15m
+`)
+	require.NoError(t, err)
+	require.Equal(t, Duration(15*time.Minute), *cfg.FailureWebhook.RetryingFor)
+
+	_, err = Load(base + `
+failure_webhook:
+  url: http://localhost:8080/hook
+  format: pigeon
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad config in failure_webhook: 'format' must be either generic/slack/alertmanager")
+
+	_, err = Load(base + `
+failure_webhook:
+  url: hooks.slack.com/services/T0/B0/secret
+`)
+	require.Error(t, err)
+	require.Equal(t, "bad config in failure_webhook: invalid url", err.Error())
+}
+
+func TestReceiverConfigJSON(t *testing.T) {
+	d := Duration(90 * time.Minute)
+	rc := ReceiverConfig{
+		Name:                "jira-ab",
+		Password:            "hunter2",
+		PersonalAccessToken: "",
+		ReopenDuration:      &d,
+	}
+	b, err := json.Marshal(rc)
+	require.NoError(t, err)
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &out))
+	require.Equal(t, "<secret>", out["password"])
+	require.Equal(t, "", out["personal_access_token"])
+	require.Equal(t, "90m", out["reopen_duration"])
+}
+
+func TestLabelMappingConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+`
+	cfg, err := Load(base + `
+    issue_type_mapping:
+      label: severity
+      values:
+        critical: Incident
+`)
+	require.NoError(t, err)
+	m := cfg.Receivers[0].IssueTypeMapping
+	v, ok := m.Lookup(map[string]string{"severity": "critical"})
+	require.True(t, ok)
+	require.Equal(t, "Incident", v)
+	_, ok = m.Lookup(map[string]string{"severity": "warning"})
+	require.False(t, ok)
+
+	_, err = Load(base + `
+    issue_type_mapping:
+      label: severity
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'issue_type_mapping' 'values' must not be empty`)
+
+	cfg, err = Load(base + `
+    assignee_pool:
+      users: ['jdoe']
+`)
+	require.NoError(t, err)
+	require.Equal(t, AssigneePoolRoundRobin, cfg.Receivers[0].AssigneePool.Strategy)
+
+	_, err = Load(base + `
+    assignee_pool:
+      users: ['jdoe']
+      strategy: random
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `'assignee_pool' 'strategy' must be either round_robin/least_recently_assigned`)
+}
+
+func TestBackendConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+  - name: 'github-ab'
+    project: example/alerts
+`
+	cfg, err := Load(base + `
+    backend: github
+    personal_access_token: token
+`)
+	require.NoError(t, err)
+	require.Equal(t, BackendJira, cfg.Receivers[0].Backend)
+	gh := cfg.Receivers[1]
+	require.Equal(t, BackendGitHub, gh.Backend)
+	require.Equal(t, DefaultGitHubAPIURL, gh.APIURL)
+	require.Equal(t, "open", gh.ReopenState)
+	require.Equal(t, "Issue", gh.IssueType)
+	require.Equal(t, "", gh.User)
+
+	_, err = Load(base + `
+    backend: github
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `missing personal_access_token in receiver "github-ab"`)
+
+	_, err = Load(base + `
+    backend: servicenow
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `missing api_url in receiver "github-ab"`)
+
+	cfg, err = Load(base + `
+    backend: servicenow
+    api_url: https://example.service-now.com
+    user: jiralert
+    password: secret
+`)
+	require.NoError(t, err)
+	sn := cfg.Receivers[1]
+	require.Equal(t, "In Progress", sn.ReopenState)
+	require.Equal(t, DefaultServiceNowCloseCode, sn.ServiceNow.CloseCode)
+	require.Equal(t, DefaultServiceNowCloseNotes, sn.ServiceNow.CloseNotes)
+
+	_, err = Load(base + `
+    backend: gitlab
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "github-ab", unknown 'backend' "gitlab"`)
+}
+
+func TestFallbackConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  fallback:
+    receiver: jira-catch-all
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-catch-all'
+    project: ALL
+  - name: 'jira-ab'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Nil(t, cfg.Receivers[0].Fallback)
+	require.Equal(t, "jira-catch-all", cfg.Receivers[1].Fallback.Receiver)
+	require.Equal(t, Duration(0), *cfg.Receivers[1].Fallback.After)
+
+	_, err = Load(base + `
+    fallback:
+      receiver: jira-xy
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'fallback' receiver "jira-xy" does not exist`)
+
+	_, err = Load(base + `
+    fallback:
+      receiver: jira-ab
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'fallback' cannot be the receiver itself`)
+}
+
+func TestLogLevelConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+`
+	cfg, err := Load(base + `
+    log_level: debug
+`)
+	require.NoError(t, err)
+	require.Equal(t, "", cfg.Receivers[0].LogLevel)
+	require.Equal(t, "debug", cfg.Receivers[1].LogLevel)
+
+	_, err = Load(base + `
+    log_level: verbose
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'log_level' must be one of debug, info, warn or error`)
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema()
+	_, err := json.Marshal(schema)
+	require.NoError(t, err)
+	require.Equal(t, false, schema["additionalProperties"])
+
+	properties := schema["properties"].(map[string]interface{})
+	require.Contains(t, properties, "template")
+	receivers := properties["receivers"].(map[string]interface{})
+	receiver := receivers["items"].(map[string]interface{})
+	require.Equal(t, false, receiver["additionalProperties"])
+
+	fields := receiver["properties"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "string", "pattern": durationRE.String()}, fields["reopen_duration"])
+	require.Equal(t, map[string]interface{}{"type": "string"}, fields["password"])
+	require.NotContains(t, fields, "XXX")
+	stale := fields["stale_issues"].(map[string]interface{})
+	require.Equal(t, true, stale["additionalProperties"])
+}
+
+func TestParseWithoutDefaults(t *testing.T) {
+	_, err := Parse([]byte("receivers: [{name: jira-ab}]\ntemplate: jiralert.tmpl\n"), ".", log.NewNopLogger())
+	require.EqualError(t, err, `missing api_url in receiver "jira-ab"`)
+}
+
+func TestConfigVersion(t *testing.T) {
+	const conf = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  old_priority: Critical
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+  - name: 'jira-xy'
+    old_priority: Major
+`
+	_, err := Load("version: 1\n" + conf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown fields in receiver: old_priority")
+
+	_, err = Load("version: 2\n" + conf)
+	require.EqualError(t, err, "config version 2 is newer than the supported version 1, upgrade JIRAlert")
+
+	defer func(m []migration) { migrations = m }(migrations)
+	migrations = append(migrations, migration{from: 1, apply: func(doc *yaml.Node) ([]string, error) {
+		return renameReceiverKey(doc, "old_priority", "priority")
+	}})
+
+	cfg, warnings, err := load(conf)
+	require.NoError(t, err)
+	require.Equal(t, 1, cfg.Version)
+	require.Equal(t, "Critical", cfg.Receivers[0].Priority)
+	require.Equal(t, "Major", cfg.Receivers[1].Priority)
+	require.Equal(t, []string{
+		`config version 1: "old_priority" was renamed to "priority" (line 11)`,
+		`config version 1: "old_priority" was renamed to "priority" (line 16)`,
+	}, warnings)
+
+	_, err = Load(conf + "    priority: Minor\n")
+	require.EqualError(t, err, `migrate config from version 1: both "old_priority" and its replacement "priority" are set`)
+}
+
+func TestShadowConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    shadow: true
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.False(t, cfg.Receivers[0].Shadow)
+	require.True(t, cfg.Receivers[1].Shadow)
+
+	_, err = Load(strings.Replace(base, "defaults:\n", "defaults:\n  shadow: true\n", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad config in defaults section, 'shadow' can only be set per receiver")
+}
+
+func TestReopenDelayConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 1h
+  reopen_delay: 10m
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, Duration(10*time.Minute), *cfg.Receivers[0].ReopenDelay)
+
+	_, err = Load(base + `
+    reopen_delay: 2h
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'reopen_delay' must be shorter than 'reopen_duration'`)
+}
+
+func TestWontFixDurationConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  wont_fix_duration: 90d
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    wont_fix_resolution: "Won't Fix"
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, Duration(90*24*time.Hour), *cfg.Receivers[0].WontFixDuration)
+
+	_, err = Load(base + `
+  - name: 'jira-ab'
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'wont_fix_duration' requires 'wont_fix_resolution'`)
+}
+
+func TestAlertCountFieldConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  alert_count_field: customfield_10050
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'github-ab'
+    backend: github
+    personal_access_token: token
+    project: example/alerts
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, "customfield_10050", cfg.Receivers[0].AlertCountField)
+	require.Equal(t, "", cfg.Receivers[1].AlertCountField)
+
+	_, err = Load(base + `
+    alert_count_field: customfield_10050
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "github-ab", 'alert_count_field', 'firing_since_field' and 'last_seen_field' are only supported by the "jira" backend`)
+}
+
+func TestFieldTypesConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  field_types:
+    customfield_10001: option
+    customfield_10002: number
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    field_types:
+      customfield_10002: user
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"customfield_10001": FieldTypeOption, "customfield_10002": FieldTypeUser}, cfg.Receivers[0].FieldTypes)
+
+	_, err = Load(base + `      customfield_10003: list
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'field_types' "customfield_10003" has unknown type "list"`)
+}
+
+func TestCascadingFieldsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  cascading_fields:
+  - field: customfield_10001
+    parent: '{{ .CommonLabels.region }}'
+    child: '{{ .CommonLabels.zone }}'
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'github-ab'
+    backend: github
+    personal_access_token: token
+    project: example/alerts
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, []*CascadingField{{Field: "customfield_10001", Parent: "{{ .CommonLabels.region }}", Child: "{{ .CommonLabels.zone }}"}}, cfg.Receivers[0].CascadingFields)
+	require.Nil(t, cfg.Receivers[1].CascadingFields)
+
+	_, err = Load(base + `
+    cascading_fields:
+    - field: customfield_10001
+      parent: eu-west
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "github-ab", 'cascading_fields' are only supported by the "jira" backend`)
+
+	_, err = Load(strings.Replace(base, "    parent: '{{ .CommonLabels.region }}'\n", "", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in defaults section, 'cascading_fields' 'parent' must be set`)
+}
+
+func TestFieldUpdatesConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  fields:
+    customfield_10001: '{{ .CommonLabels.team }}'
+  field_updates:
+    customfield_10001:
+      update: true
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    field_updates:
+      customfield_10002:
+        on_update: '{{ len .Alerts.Firing }}'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Len(t, cfg.Receivers[0].FieldUpdates, 2)
+	require.True(t, cfg.Receivers[0].FieldUpdates["customfield_10001"].Enabled())
+	require.True(t, cfg.Receivers[0].FieldUpdates["customfield_10002"].Enabled())
+
+	_, err = Load(base + `      customfield_10003:
+        update: true
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'field_updates' "customfield_10003" is not in 'fields', 'on_update' must be set`)
+
+	_, err = Load(base + `      customfield_10001:
+        update: false
+        on_update: x
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'field_updates' "customfield_10001" cannot set 'on_update' with 'update' false`)
+}
+
+func TestRequiredFieldsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  required_fields: {}
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    required_fields:
+      fill: first_allowed
+  - name: 'github-ab'
+    backend: github
+    personal_access_token: token
+    project: example/alerts
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, &RequiredFields{Fill: FillDefault}, cfg.Receivers[0].RequiredFields)
+	require.Equal(t, &RequiredFields{Fill: FillFirstAllowed}, cfg.Receivers[1].RequiredFields)
+	require.Nil(t, cfg.Receivers[2].RequiredFields)
+
+	_, err = Load(strings.Replace(base, "fill: first_allowed", "fill: any", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'required_fields' 'fill' must be "default" or "first_allowed"`)
+
+	_, err = Load(base + `
+    required_fields: {}
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "github-ab", 'required_fields' is only supported by the "jira" backend`)
+}
+
+func TestTemplateErrorsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    template_errors: fallback
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, TemplateErrorsFail, cfg.Receivers[0].TemplateErrors)
+	require.Equal(t, TemplateErrorsFallback, cfg.Receivers[1].TemplateErrors)
+
+	_, err = Load(strings.Replace(base, "template_errors: fallback", "template_errors: ignore", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'template_errors' must be one of "fail" or "fallback"`)
+}
+
+func TestTemplateDefaultsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  template_defaults:
+    priority: Medium
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    template_defaults:
+      priority: Low
+      fields.customfield_10001: { "value": "red" }
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"priority": "Medium"}, cfg.Receivers[0].TemplateDefaults)
+	require.Equal(t, map[string]interface{}{"priority": "Low", "fields.customfield_10001": map[string]interface{}{"value": "red"}}, cfg.Receivers[1].TemplateDefaults)
+
+	_, err = Load(strings.Replace(base, "priority: Low", "severity: Low", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'template_defaults' "severity" is unknown`)
+
+	_, err = Load(strings.Replace(base, "priority: Low", "priority: [Low]", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'template_defaults' "priority" must be a string`)
+
+	// Issues would end up in the wrong place or could not be found again.
+	for _, setting := range []string{"project", "issue_type", "issue_identifier_label"} {
+		_, err = Load(strings.Replace(base, "priority: Low", setting+": Low", 1))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), fmt.Sprintf(`bad config in receiver "jira-ab", 'template_defaults' cannot be given for %q`, setting))
+	}
+}
+
+func TestCredentialFilesConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "password"), []byte("secret\n"), 0o600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "token"), []byte("token"), 0o600))
+	base := `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password_file: ` + path.Join(dir, "password") + `
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    user: ""
+    personal_access_token_file: ` + path.Join(dir, "token") + `
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, Secret("secret"), cfg.Receivers[0].Password)
+	require.Equal(t, path.Join(dir, "password"), cfg.Receivers[0].PasswordFile)
+	require.Equal(t, Secret("token"), cfg.Receivers[1].PersonalAccessToken)
+
+	_, err = Load(base + `    personal_access_token: token
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad auth config in receiver "jira-ab": personal_access_token and personal_access_token_file are mutually exclusive`)
+
+	_, err = Load(strings.Replace(base, path.Join(dir, "token"), path.Join(dir, "missing"), 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad auth config in receiver "jira-ab": read personal_access_token_file:`)
+}
+
+func TestParseUntrusted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "password"), []byte("secret\n"), 0o600))
+	t.Setenv("JIRALERT_TEST_PROJECT", "AB")
+	base := `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password_file: ` + path.Join(dir, "password") + `
+  project: $(JIRALERT_TEST_PROJECT)
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: /etc/jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    user: ""
+    personal_access_token_file: /nonexistent/token
+`
+	// Neither files nor environment variables are read.
+	cfg, err := ParseUntrusted([]byte(base))
+	require.NoError(t, err)
+	require.Equal(t, Secret(path.Join(dir, "password")), cfg.Receivers[0].Password)
+	require.Equal(t, Secret("/nonexistent/token"), cfg.Receivers[1].PersonalAccessToken)
+	require.Equal(t, "$(JIRALERT_TEST_PROJECT)", cfg.Receivers[0].Project)
+	require.Equal(t, "/etc/jiralert.tmpl", cfg.Template)
+
+	_, err = ParseUntrusted([]byte(base + `    personal_access_token: token
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad auth config in receiver "jira-ab": personal_access_token and personal_access_token_file are mutually exclusive`)
+}
+
+func TestConnectConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  connect:
+    key: com.example.jiralert
+    shared_secret: secret
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    user: jiralert
+    password: JIRAlert
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, &Connect{Key: "com.example.jiralert", SharedSecret: "secret"}, cfg.Receivers[0].Connect)
+	require.Nil(t, cfg.Receivers[1].Connect)
+
+	_, err = Load(base + `    connect:
+      key: com.example.jiralert
+      shared_secret: secret
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad auth config in receiver "jira-ab": user/password, PAT and connect authentication are mutually exclusive`)
+
+	_, err = Load(strings.Replace(base, "    shared_secret: secret\n", "", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in defaults section, 'connect' 'shared_secret' must be set`)
+}
+
+func TestFieldProfilesConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  field_profiles: [ops]
+  fields:
+    customfield_10004: default
+template: jiralert.tmpl
+field_profiles:
+  - name: ops
+    fields:
+      customfield_10001: ops
+      customfield_10002: ops
+    additional_labels:
+      team: ops
+    components: [Operations]
+  - name: storage
+    fields:
+      customfield_10002: storage
+    field_types:
+      customfield_10002: option
+    components: [Storage, Operations]
+receivers:
+  - name: 'jira-xy'
+  - name: 'jira-ab'
+    field_profiles: [ops, storage]
+    fields:
+      customfield_10001: jira-ab
+    components: [Backend]
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"customfield_10001": "ops", "customfield_10002": "ops", "customfield_10004": "default"}, cfg.Receivers[0].Fields)
+	require.Equal(t, map[string]string{"team": "ops"}, cfg.Receivers[0].AdditionalIssueLabels)
+	require.Equal(t, []string{"Operations"}, cfg.Receivers[0].Components)
+
+	require.Equal(t, map[string]interface{}{"customfield_10001": "jira-ab", "customfield_10002": "storage", "customfield_10004": "default"}, cfg.Receivers[1].Fields)
+	require.Equal(t, map[string]string{"customfield_10002": FieldTypeOption}, cfg.Receivers[1].FieldTypes)
+	require.Equal(t, []string{"Backend", "Operations", "Storage"}, cfg.Receivers[1].Components)
+
+	_, err = Load(strings.Replace(base, "field_profiles: [ops, storage]", "field_profiles: [ops, network]", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'field_profiles' "network" does not exist`)
+
+	_, err = Load(strings.Replace(base, "name: storage", "name: ops", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in 'field_profiles', "ops" is defined more than once`)
+}
+
+func TestExtendsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-base'
+    project: BASE
+    priority: High
+    alertmanager_receiver: 'team'
+    components: [Operations]
+    fields:
+      customfield_10001: base
+      customfield_10002: base
+  - name: 'jira-ab'
+    extends: 'jira-team'
+    project: AB
+    fields:
+      customfield_10002: ab
+  - name: 'jira-team'
+    extends: 'jira-base'
+    assignee: lead
+    components: [Storage]
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	ab := cfg.ReceiverByName("jira-ab")
+	require.Equal(t, "AB", ab.Project)
+	require.Equal(t, "High", ab.Priority)
+	require.Equal(t, "lead", ab.Assignee)
+	require.Equal(t, "", ab.AlertmanagerReceiver)
+	require.Equal(t, []string{"Storage"}, ab.Components)
+	require.Equal(t, map[string]interface{}{"customfield_10001": "base", "customfield_10002": "ab"}, ab.Fields)
+	// Receivers they extend are unchanged.
+	require.Equal(t, map[string]interface{}{"customfield_10001": "base", "customfield_10002": "base"}, cfg.ReceiverByName("jira-base").Fields)
+	require.Equal(t, "BASE", cfg.ReceiverByName("jira-team").Project)
+
+	_, err = Load(strings.Replace(base, "extends: 'jira-base'", "extends: 'jira-xy'", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-team", 'extends' "jira-xy" does not exist`)
+
+	_, err = Load(strings.Replace(base, "extends: 'jira-base'", "extends: 'jira-ab'", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'extends' forms a cycle: jira-ab -> jira-team -> jira-ab`)
+}
+
+func TestAssetsFieldsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  assets_fields:
+    - field: customfield_10100
+      query: 'objectType = "Server" AND Name = "{{ .CommonLabels.instance }}"'
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Len(t, cfg.Receivers[0].AssetsFields, 1)
+	require.Equal(t, 1, cfg.Receivers[0].AssetsFields[0].MaxObjects)
+
+	_, err = Load(base + `
+    assets_fields:
+      - field: customfield_10100
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'assets_fields' 'query' must be set`)
+}
+
+func TestServiceDeskConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  service_desk:
+    organizations_field: customfield_10002
+    organizations: ['{{ .CommonLabels.customer }}']
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, "customfield_10002", cfg.Receivers[0].ServiceDesk.OrganizationsField)
+
+	_, err = Load(base + `
+    service_desk:
+      participants: ['{{ .CommonLabels.owner }}']
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'service_desk' 'participants' require 'participants_field'`)
+}
+
+func TestSLAWarningConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    service_desk:
+      sla_warning:
+`
+	cfg, err := Load(base + `
+        before: 30m
+        priority: Highest
+`)
+	require.NoError(t, err)
+	require.Equal(t, Duration(30*time.Minute), *cfg.Receivers[0].ServiceDesk.SLAWarning.Before)
+	require.Equal(t, DefaultSLAWarningComment, cfg.Receivers[0].ServiceDesk.SLAWarning.Comment)
+
+	_, err = Load(base + `
+        priority: Highest
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'service_desk' 'sla_warning' 'before' must be a positive duration`)
+}
+
+func TestProjectListConfig(t *testing.T) {
+	cfg, err := Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    project: [XY, INC, '{{ .CommonLabels.team }}']
+`)
+	require.NoError(t, err)
+	require.Equal(t, "XY,INC,{{ .CommonLabels.team }}", cfg.Receivers[0].Project)
+	require.Equal(t, []string{"XY", "INC"}, cfg.Receivers[0].StaticProjects())
+}
+
+func TestDedupeGroupConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  dedupe_group: incidents
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+    project: AB
+  - name: 'jira-xy'
+    project: XY
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, "incidents", cfg.Receivers[1].DedupeGroup)
+
+	_, err = Load(base + `    api_url: https://other.atlassian.net
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'dedupe_group' "incidents" must only contain receivers of the same issue tracker as "jira-ab"`)
+
+	cfg, err = Load(base + `    dedupe_watchers: [jane]
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"jane"}, cfg.Receivers[1].DedupeWatchers)
+
+	_, err = Load(strings.Replace(base, "  dedupe_group: incidents\n", "  dedupe_watchers: [jane]\n", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'dedupe_watchers' requires 'dedupe_group'`)
+}
+
+func TestInhibitRulesConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  inhibit_rules:
+    - source_matchers: {alertname: ClusterDown}
+      equal: [cluster]
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Len(t, cfg.Receivers[0].InhibitRules, 1)
+	require.True(t, cfg.Receivers[0].InhibitRules[0].Inhibits(map[string]string{"alertname": "ClusterDown", "cluster": "a"}, map[string]string{"cluster": "a"}))
+	require.False(t, cfg.Receivers[0].InhibitRules[0].Inhibits(map[string]string{"alertname": "ClusterDown", "cluster": "a"}, map[string]string{"cluster": "b"}))
+
+	_, err = Load(base + `
+    inhibit_rules:
+      - target_matchers: {severity: warning}
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'inhibit_rules' 'source_matchers' cannot be empty`)
+}
+
+func TestEpicsConfig(t *testing.T) {
+	cfg, err := Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  epics:
+    label: service
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+  - name: 'github-xy'
+    backend: github
+    personal_access_token: token
+    project: example/alerts
+`)
+	require.NoError(t, err)
+	require.Equal(t, &Epics{Label: "service", Labels: []string{"service"}, IssueType: "Epic", Summary: `{{ index .CommonLabels "service" }}`}, cfg.Receivers[0].Epics)
+	require.Nil(t, cfg.Receivers[1].Epics)
+}
+
+func TestSubtasksConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    subtasks:
+`
+	cfg, err := Load(base + `
+      labels: [cluster, instance]
+`)
+	require.NoError(t, err)
+	require.Equal(t, &Subtasks{Labels: []string{"cluster", "instance"}, IssueType: "Sub-task", Summary: `{{ index .CommonLabels "cluster" }} {{ index .CommonLabels "instance" }}`}, cfg.Receivers[0].Subtasks)
+
+	_, err = Load(base + `
+      issue_type: Sub-task
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'subtasks' 'labels' cannot be empty`)
+}
+
+func TestCurrentIncidentConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  current_incident:
+    issue_key: OPS-1
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+`
+	cfg, err := Load(base + `
+  - name: 'github-xy'
+    backend: github
+    personal_access_token: token
+    project: example/alerts
+`)
+	require.NoError(t, err)
+	require.Equal(t, &CurrentIncident{IssueKey: "OPS-1", LinkType: "Relates"}, cfg.Receivers[0].CurrentIncident)
+	require.Nil(t, cfg.Receivers[1].CurrentIncident)
+
+	_, err = Load(base + `
+  - name: 'github-xy'
+    backend: github
+    personal_access_token: token
+    project: example/alerts
+    current_incident:
+      link_type: Blocks
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "github-xy", 'current_incident' is only supported by the "jira" backend`)
+}
+
+func TestIncidentModeConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    incident_mode:
+`
+	cfg, err := Load(base + `
+      window: 15m
+      matchers: {cluster: eu}
+`)
+	require.NoError(t, err)
+	window := Duration(15 * time.Minute)
+	require.Equal(t, &IncidentMode{Window: &window, Matchers: map[string]string{"cluster": "eu"}, Summary: DefaultIncidentSummary}, cfg.Receivers[0].IncidentMode)
+
+	_, err = Load(base + `
+      matchers: {cluster: eu}
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'incident_mode' 'window' must be a positive duration`)
+}
+
+func TestMinFiringDurationConfig(t *testing.T) {
+	cfg, err := Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  min_firing_duration: 5m
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+  - name: 'jira-xy'
+    min_firing_duration: 0s
+`)
+	require.NoError(t, err)
+	require.Equal(t, Duration(5*time.Minute), *cfg.Receivers[0].MinFiringDuration)
+	require.Equal(t, Duration(0), *cfg.Receivers[1].MinFiringDuration)
+}
+
+func TestDescriptionHeaderFooterConfig(t *testing.T) {
+	cfg, err := Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  description_header: 'Escalate to {{ .CommonLabels.team }} if unresolved.'
+  description_footer: 'See the runbook.'
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+  - name: 'jira-xy'
+    description_footer: 'See the XY dashboards.'
+`)
+	require.NoError(t, err)
+	require.Equal(t, "Escalate to {{ .CommonLabels.team }} if unresolved.", cfg.Receivers[0].DescriptionHeader)
+	require.Equal(t, "See the runbook.", cfg.Receivers[0].DescriptionFooter)
+	require.Equal(t, "Escalate to {{ .CommonLabels.team }} if unresolved.", cfg.Receivers[1].DescriptionHeader)
+	require.Equal(t, "See the XY dashboards.", cfg.Receivers[1].DescriptionFooter)
+}
+
+func TestNotifyUsersConfig(t *testing.T) {
+	cfg, err := Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  notify_users: false
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+  - name: 'jira-xy'
+    notify_users: true
+`)
+	require.NoError(t, err)
+	require.False(t, *cfg.Receivers[0].NotifyUsers)
+	require.True(t, *cfg.Receivers[1].NotifyUsers)
+}
+
+func TestUpdateOptionsConfig(t *testing.T) {
+	cfg, err := Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  update_options:
+    override_screen_security: true
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+  - name: 'jira-xy'
+    update_options:
+      override_editable_flag: true
+`)
+	require.NoError(t, err)
+	require.Equal(t, &UpdateOptions{OverrideScreenSecurity: true}, cfg.Receivers[0].UpdateOptions)
+	require.Equal(t, &UpdateOptions{OverrideEditableFlag: true}, cfg.Receivers[1].UpdateOptions)
+}
+
+func TestMaxAlertAgeConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  max_alert_age: 1d
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-ab'
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, Duration(24*time.Hour), *cfg.Receivers[0].MaxAlertAge)
+
+	_, err = Load(base + `
+    min_firing_duration: 2d
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'min_firing_duration' must be shorter than 'max_alert_age'`)
+
+	_, err = Load(base + `
+    max_alert_age: 0s
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-ab", 'max_alert_age' must be a positive duration`)
+}
+
+func TestMinSeverityConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+template: jiralert.tmpl
+receivers:
+  - name: 'jira-xy'
+    min_severity:
+`
+	cfg, err := Load(base + `
+      severity: warning
+`)
+	require.NoError(t, err)
+	require.Equal(t, &MinSeverity{Severity: "warning", Label: "severity", Order: DefaultSeverityOrder}, cfg.Receivers[0].MinSeverity)
+
+	cfg, err = Load(base + `
+      severity: P2
+      label: priority
+      order: [P4, P3, P2, P1]
+`)
+	require.NoError(t, err)
+	require.Equal(t, 2, cfg.Receivers[0].MinSeverity.Rank("P2"))
+
+	_, err = Load(base + `
+      severity: major
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'min_severity' 'severity' "major" must be one of 'order' ["info" "warning" "critical"]`)
+}
+
+func TestMatchConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  match: {env: prod}
+template: jiralert.tmpl
+receivers:
+  - name: 'jira'
+  - name: 'jira-db'
+    alertmanager_receiver: 'jira'
+    match_re: {team: 'db|storage'}
+`
+	cfg, err := Load(base)
+	require.NoError(t, err)
+	require.Equal(t, []*ReceiverConfig{cfg.Receivers[0], cfg.Receivers[1]}, cfg.ReceiversFor("jira"))
+	require.Equal(t, []*ReceiverConfig{cfg.Receivers[1]}, cfg.ReceiversFor("jira-db"))
+	require.True(t, cfg.Receivers[1].Matches(map[string]string{"env": "prod", "team": "storage"}))
+	require.False(t, cfg.Receivers[1].Matches(map[string]string{"env": "prod", "team": "db2"}))
+	require.False(t, cfg.Receivers[1].Matches(map[string]string{"env": "dev", "team": "db"}))
+	require.Equal(t, "db|storage", cfg.Receivers[1].MatchRE["team"].String())
+
+	_, err = Load(base + `
+  - name: 'jira-broken'
+    match_re: {team: '('}
+`)
+	require.Error(t, err)
+
+	_, err = Load(strings.Replace(base, "  match: {env: prod}", "  alertmanager_receiver: jira", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad config in defaults section, 'alertmanager_receiver' can only be set per receiver")
+}
+
+func TestMuteTimeIntervalsConfig(t *testing.T) {
+	const base = `
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: AB
+  issue_type: Bug
+  summary: summary
+  reopen_state: "To Do"
+  reopen_duration: 0h
+  mute_time_intervals: [weekends]
+template: jiralert.tmpl
+time_intervals:
+  - name: weekends
+    time_intervals:
+      - weekdays: [saturday, sunday]
+  - name: holidays
+    time_intervals:
+      - months: ['december']
+        days_of_month: ['-7:-1']
+      - years: ['2023']
+        months: ['1']
+        days_of_month: ['1']
+receivers:
+  - name: 'jira-ab'
+`
+	cfg, err := Load(base + `
+  - name: 'jira-xy'
+    mute_time_intervals: [holidays]
+`)
+	require.NoError(t, err)
+	saturday := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, "weekends", cfg.Receivers[0].MutingTimeInterval(saturday))
+	require.Equal(t, "", cfg.Receivers[1].MutingTimeInterval(saturday))
+	require.Equal(t, "holidays", cfg.Receivers[1].MutingTimeInterval(time.Date(2022, 12, 25, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "", cfg.Receivers[1].MutingTimeInterval(time.Date(2022, 12, 24, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "holidays", cfg.Receivers[1].MutingTimeInterval(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, "", cfg.Receivers[1].MutingTimeInterval(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	_, err = Load(base + `
+  - name: 'jira-xy'
+    mute_time_intervals: [easter]
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `bad config in receiver "jira-xy", 'mute_time_intervals' "easter" does not exist`)
+
+	_, err = Load(strings.Replace(base, "months: ['december']", "months: ['march:january']", 1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid month range "march:january": end before start`)
+}
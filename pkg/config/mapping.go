@@ -0,0 +1,94 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LabelMapping maps the values of an alert label to Jira values, e.g. severities to issue types.
+type LabelMapping struct {
+	Label  string            `yaml:"label" json:"label"`
+	Values map[string]string `yaml:"values" json:"values"`
+}
+
+func (m *LabelMapping) validate() error {
+	if m.Label == "" {
+		return fmt.Errorf("'label' must be set")
+	}
+	if len(m.Values) == 0 {
+		return fmt.Errorf("'values' must not be empty")
+	}
+	return nil
+}
+
+// Lookup returns the value mapped to the value of the mapping's label in labels, if any.
+func (m *LabelMapping) Lookup(labels map[string]string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	v, ok := labels[m.Label]
+	if !ok {
+		return "", false
+	}
+	mapped, ok := m.Values[v]
+	return mapped, ok
+}
+
+// ComponentMapping maps the values of an alert label to one or more Jira components.
+type ComponentMapping struct {
+	Label  string              `yaml:"label" json:"label"`
+	Values map[string][]string `yaml:"values" json:"values"`
+	// CreateMissing creates mapped components missing in the receiver's projects at startup.
+	CreateMissing bool `yaml:"create_missing" json:"create_missing"`
+}
+
+func (m *ComponentMapping) validate() error {
+	if m.Label == "" {
+		return fmt.Errorf("'label' must be set")
+	}
+	if len(m.Values) == 0 {
+		return fmt.Errorf("'values' must not be empty")
+	}
+	return nil
+}
+
+// Lookup returns the components mapped to the value of the mapping's label in labels, if any.
+func (m *ComponentMapping) Lookup(labels map[string]string) []string {
+	if m == nil {
+		return nil
+	}
+	v, ok := labels[m.Label]
+	if !ok {
+		return nil
+	}
+	return m.Values[v]
+}
+
+// Components returns all mapped components, sorted and without duplicates.
+func (m *ComponentMapping) Components() []string {
+	seen := map[string]struct{}{}
+	var res []string
+	for _, cs := range m.Values {
+		for _, c := range cs {
+			if _, ok := seen[c]; !ok {
+				seen[c] = struct{}{}
+				res = append(res, c)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the version of the configuration format, set as `version` in configuration files. Files
+// without version are of version 1, the format before versioning.
+const CurrentVersion = 1
+
+// migration upgrades a configuration document of version from to version from+1, e.g. renaming keys or explicitly
+// setting defaults that changed. It returns a warning per change made, telling users how to update their file.
+type migration struct {
+	from  int
+	apply func(doc *yaml.Node) ([]string, error)
+}
+
+// migrations upgrade configurations of older versions to CurrentVersion, in order. Add one whenever the format
+// changes incompatibly and bump CurrentVersion.
+var migrations = []migration{}
+
+// migrate upgrades the YAML document of a configuration to CurrentVersion and returns the warnings of the
+// migrations applied.
+func migrate(doc *yaml.Node) ([]string, error) {
+	root := mappingNode(doc)
+	if root == nil {
+		return nil, nil
+	}
+
+	version := 1
+	if v := mappingValue(root, "version"); v != nil {
+		var err error
+		if version, err = strconv.Atoi(v.Value); err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid config version %q", v.Value)
+		}
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("config version %d is newer than the supported version %d, upgrade JIRAlert", version, CurrentVersion)
+	}
+
+	var warnings []string
+	for _, m := range migrations {
+		if m.from < version {
+			continue
+		}
+		w, err := m.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("migrate config from version %d: %w", m.from, err)
+		}
+		for _, s := range w {
+			warnings = append(warnings, fmt.Sprintf("config version %d: %s", m.from, s))
+		}
+		version = m.from + 1
+	}
+	return warnings, nil
+}
+
+// mappingNode returns the mapping of a YAML document, or nil if it is none.
+func mappingNode(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// mappingValue returns the value of key in a YAML mapping, if any.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// receiverNodes returns the mappings of the defaults and of all receivers of a configuration document, which
+// migrations of receiver settings apply to.
+func receiverNodes(root *yaml.Node) []*yaml.Node {
+	var nodes []*yaml.Node
+	if d := mappingValue(root, "defaults"); d != nil && d.Kind == yaml.MappingNode {
+		nodes = append(nodes, d)
+	}
+	if rs := mappingValue(root, "receivers"); rs != nil && rs.Kind == yaml.SequenceNode {
+		for _, r := range rs.Content {
+			if r.Kind == yaml.MappingNode {
+				nodes = append(nodes, r)
+			}
+		}
+	}
+	return nodes
+}
+
+// renameReceiverKey renames the receiver setting from to to in the defaults and all receivers. It fails if both
+// are set.
+func renameReceiverKey(root *yaml.Node, from, to string) ([]string, error) {
+	var warnings []string
+	for _, n := range receiverNodes(root) {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value != from {
+				continue
+			}
+			if mappingValue(n, to) != nil {
+				return nil, fmt.Errorf("both %q and its replacement %q are set", from, to)
+			}
+			n.Content[i].Value = to
+			warnings = append(warnings, fmt.Sprintf("%q was renamed to %q (line %d)", from, to, n.Content[i].Line))
+		}
+	}
+	return warnings, nil
+}
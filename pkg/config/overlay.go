@@ -0,0 +1,96 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// MergeOverlay merges the YAML document overlay into base, e.g. an environment's API URLs and projects into a
+// configuration shared by all environments. Mappings are merged key by key. Lists whose items all have a name, such
+// as receivers, are merged item by item, matching items by name and appending new ones. Other values, including other
+// lists, are replaced.
+func MergeOverlay(base, overlay []byte) ([]byte, error) {
+	var baseDoc, overlayDoc yaml.Node
+	if err := yaml.Unmarshal(base, &baseDoc); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(overlay, &overlayDoc); err != nil {
+		return nil, err
+	}
+	if overlayDoc.Kind == 0 {
+		return base, nil
+	}
+	if baseDoc.Kind == 0 {
+		return overlay, nil
+	}
+	if err := mergeNode(baseDoc.Content[0], overlayDoc.Content[0], ""); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(&baseDoc)
+}
+
+func mergeNode(base, overlay *yaml.Node, path string) error {
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			if existing := mappingValue(base, key.Value); existing != nil {
+				if err := mergeNode(existing, value, path+"."+key.Value); err != nil {
+					return err
+				}
+				continue
+			}
+			base.Content = append(base.Content, key, value)
+		}
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && namedItems(base) && namedItems(overlay):
+		for _, item := range overlay.Content {
+			name := mappingValue(item, "name").Value
+			var existing *yaml.Node
+			for _, b := range base.Content {
+				if mappingValue(b, "name").Value == name {
+					existing = b
+					break
+				}
+			}
+			if existing == nil {
+				base.Content = append(base.Content, item)
+				continue
+			}
+			if err := mergeNode(existing, item, fmt.Sprintf("%s[%s]", path, name)); err != nil {
+				return err
+			}
+		}
+	case base.Kind == yaml.AliasNode && (overlay.Kind == yaml.MappingNode || overlay.Kind == yaml.SequenceNode):
+		return fmt.Errorf("cannot merge into the alias at %s", path)
+	default:
+		*base = *overlay
+	}
+	return nil
+}
+
+// namedItems reports whether all items of a sequence node are mappings with a scalar name.
+func namedItems(n *yaml.Node) bool {
+	for _, item := range n.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+		if name := mappingValue(item, "name"); name == nil || name.Kind != yaml.ScalarNode {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaID identifies the JSON Schema of the configuration format.
+const schemaID = "https://github.com/prometheus-community/jiralert/config.schema.json"
+
+var (
+	durationType = reflect.TypeOf(Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	regexpType   = reflect.TypeOf(Regexp{})
+)
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the configuration format, e.g. for validating
+// configuration files in editors and CI pipelines. It covers the structure and types of the configuration only, the
+// remaining checks are done when loading it.
+func JSONSchema() map[string]interface{} {
+	s := typeSchema(reflect.TypeOf(Config{}))
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = schemaID
+	s["title"] = "JIRAlert configuration"
+	return s
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case durationType:
+		return map[string]interface{}{"type": "string", "pattern": durationRE.String()}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case regexpType:
+		return map[string]interface{}{"type": "string", "format": "regex"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	// Free-form values, e.g. the values of fields.
+	return map[string]interface{}{}
+}
+
+// structSchema returns the schema of a struct from its YAML field names. Structs catching undefined fields in XXX
+// reject them when loaded, so the schema does too.
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	additional := true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			if f.Name == "XXX" {
+				additional = false
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		properties[name] = typeSchema(f.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": additional}
+}
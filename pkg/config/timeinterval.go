@@ -0,0 +1,330 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+var months = map[string]int{
+	"january":   1,
+	"february":  2,
+	"march":     3,
+	"april":     4,
+	"may":       5,
+	"june":      6,
+	"july":      7,
+	"august":    8,
+	"september": 9,
+	"october":   10,
+	"november":  11,
+	"december":  12,
+}
+
+// intRange is an inclusive range of days of the month, months or years.
+type intRange struct {
+	start, end int
+}
+
+// TimeRange is a range of the day, in the format used by Alertmanager's time_intervals, e.g. 09:00 to 17:00. The end
+// time is exclusive.
+type TimeRange struct {
+	StartTime string `yaml:"start_time" json:"start_time"`
+	EndTime   string `yaml:"end_time" json:"end_time"`
+
+	start, end int
+}
+
+// TimeInterval is a recurring period of time, modeled after Alertmanager's time_intervals. A time is contained in the
+// interval if it matches all of the defined fields; empty fields match any time.
+type TimeInterval struct {
+	Weekdays []string    `yaml:"weekdays,omitempty" json:"weekdays,omitempty"`
+	Times    []TimeRange `yaml:"times,omitempty" json:"times,omitempty"`
+	// DaysOfMonth are days or ranges of days, negative ones counting from the end of the month (-1 is the last day).
+	DaysOfMonth []string `yaml:"days_of_month,omitempty" json:"days_of_month,omitempty"`
+	// Months are months, by name or number, or ranges of them.
+	Months   []string `yaml:"months,omitempty" json:"months,omitempty"`
+	Years    []string `yaml:"years,omitempty" json:"years,omitempty"`
+	Location string   `yaml:"location,omitempty" json:"location,omitempty"`
+
+	weekdays    map[time.Weekday]struct{}
+	daysOfMonth []intRange
+	months      []intRange
+	years       []intRange
+	location    *time.Location
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (ti *TimeInterval) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TimeInterval
+	if err := unmarshal((*plain)(ti)); err != nil {
+		return err
+	}
+
+	ti.location = time.UTC
+	if ti.Location != "" {
+		loc, err := time.LoadLocation(ti.Location)
+		if err != nil {
+			return fmt.Errorf("invalid location %q: %s", ti.Location, err)
+		}
+		ti.location = loc
+	}
+
+	if len(ti.Weekdays) > 0 {
+		ti.weekdays = map[time.Weekday]struct{}{}
+	}
+	for _, wd := range ti.Weekdays {
+		from, to, isRange := strings.Cut(strings.ToLower(wd), ":")
+		if !isRange {
+			to = from
+		}
+		start, ok := weekdays[from]
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", from)
+		}
+		end, ok := weekdays[to]
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", to)
+		}
+		if end < start {
+			return fmt.Errorf("invalid weekday range %q: end before start", wd)
+		}
+		for d := start; d <= end; d++ {
+			ti.weekdays[d] = struct{}{}
+		}
+	}
+
+	for _, d := range ti.DaysOfMonth {
+		r, err := parseRange(d, func(s string) (int, error) {
+			day, err := strconv.Atoi(s)
+			if err != nil || day == 0 || day < -31 || day > 31 {
+				return 0, fmt.Errorf("invalid day of month %q", s)
+			}
+			return day, nil
+		})
+		if err != nil {
+			return err
+		}
+		// Ranges mixing positive and negative days depend on the length of the month.
+		if (r.start > 0) == (r.end > 0) && r.end < r.start {
+			return fmt.Errorf("invalid day of month range %q: end before start", d)
+		}
+		ti.daysOfMonth = append(ti.daysOfMonth, r)
+	}
+	for _, m := range ti.Months {
+		r, err := parseRange(strings.ToLower(m), func(s string) (int, error) {
+			if month, ok := months[s]; ok {
+				return month, nil
+			}
+			month, err := strconv.Atoi(s)
+			if err != nil || month < 1 || month > 12 {
+				return 0, fmt.Errorf("invalid month %q", s)
+			}
+			return month, nil
+		})
+		if err != nil {
+			return err
+		}
+		if r.end < r.start {
+			return fmt.Errorf("invalid month range %q: end before start", m)
+		}
+		ti.months = append(ti.months, r)
+	}
+	for _, y := range ti.Years {
+		r, err := parseRange(y, func(s string) (int, error) {
+			year, err := strconv.Atoi(s)
+			if err != nil || year < 0 {
+				return 0, fmt.Errorf("invalid year %q", s)
+			}
+			return year, nil
+		})
+		if err != nil {
+			return err
+		}
+		if r.end < r.start {
+			return fmt.Errorf("invalid year range %q: end before start", y)
+		}
+		ti.years = append(ti.years, r)
+	}
+
+	for i := range ti.Times {
+		tr := &ti.Times[i]
+		var err error
+		if tr.start, err = parseTimeOfDay(tr.StartTime); err != nil {
+			return err
+		}
+		if tr.end, err = parseTimeOfDay(tr.EndTime); err != nil {
+			return err
+		}
+		if tr.end <= tr.start {
+			return fmt.Errorf("invalid time range %s-%s: end must be after start", tr.StartTime, tr.EndTime)
+		}
+	}
+	return nil
+}
+
+// parseRange parses a single value or an inclusive range of values separated by a colon.
+func parseRange(s string, parse func(string) (int, error)) (intRange, error) {
+	from, to, isRange := strings.Cut(s, ":")
+	if !isRange {
+		to = from
+	}
+	start, err := parse(from)
+	if err != nil {
+		return intRange{}, err
+	}
+	end, err := parse(to)
+	if err != nil {
+		return intRange{}, err
+	}
+	return intRange{start: start, end: end}, nil
+}
+
+// inRanges reports whether v is in any of the ranges. No ranges match any value.
+func inRanges(v int, ranges []intRange) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if v >= r.start && v <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimeOfDay parses HH:MM into minutes since midnight. 24:00 is allowed as the end of the day.
+func parseTimeOfDay(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// Contains reports whether t falls into the interval.
+func (ti *TimeInterval) Contains(t time.Time) bool {
+	if ti.location != nil {
+		t = t.In(ti.location)
+	}
+	if ti.weekdays != nil {
+		if _, ok := ti.weekdays[t.Weekday()]; !ok {
+			return false
+		}
+	}
+	if !inRanges(int(t.Month()), ti.months) || !inRanges(t.Year(), ti.years) {
+		return false
+	}
+	if len(ti.daysOfMonth) > 0 {
+		// Resolve negative days against the length of t's month.
+		daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		var days []intRange
+		for _, r := range ti.daysOfMonth {
+			if r.start < 0 {
+				r.start += daysInMonth + 1
+			}
+			if r.end < 0 {
+				r.end += daysInMonth + 1
+			}
+			days = append(days, r)
+		}
+		if !inRanges(t.Day(), days) {
+			return false
+		}
+	}
+	if len(ti.Times) == 0 {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	for _, tr := range ti.Times {
+		if minute >= tr.start && minute < tr.end {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeIntervals is a set of time intervals.
+type TimeIntervals []TimeInterval
+
+// Contains reports whether t falls into any of the intervals.
+func (tis TimeIntervals) Contains(t time.Time) bool {
+	for i := range tis {
+		if tis[i].Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NamedTimeInterval is a set of time intervals receivers refer to by name, like Alertmanager's time_intervals.
+type NamedTimeInterval struct {
+	Name          string        `yaml:"name" json:"name"`
+	TimeIntervals TimeIntervals `yaml:"time_intervals" json:"time_intervals"`
+}
+
+// MaintenanceWindow is a period during which matching alert groups neither create nor reopen issues. It is active
+// either during recurring intervals or between starts_at and ends_at.
+type MaintenanceWindow struct {
+	Name      string            `yaml:"name" json:"name"`
+	Intervals TimeIntervals     `yaml:"intervals,omitempty" json:"intervals,omitempty"`
+	StartsAt  *time.Time        `yaml:"starts_at,omitempty" json:"starts_at,omitempty"`
+	EndsAt    *time.Time        `yaml:"ends_at,omitempty" json:"ends_at,omitempty"`
+	Matchers  map[string]string `yaml:"matchers,omitempty" json:"matchers,omitempty"`
+}
+
+func (w *MaintenanceWindow) validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("maintenance window is missing a name")
+	}
+	if (w.StartsAt == nil) != (w.EndsAt == nil) {
+		return fmt.Errorf("maintenance window %q must define both starts_at and ends_at", w.Name)
+	}
+	if w.StartsAt != nil && !w.EndsAt.After(*w.StartsAt) {
+		return fmt.Errorf("maintenance window %q ends before it starts", w.Name)
+	}
+	if w.StartsAt == nil && len(w.Intervals) == 0 {
+		return fmt.Errorf("maintenance window %q must define either intervals or starts_at and ends_at", w.Name)
+	}
+	return nil
+}
+
+// Active reports whether the window is active at t for alerts with the given labels.
+func (w *MaintenanceWindow) Active(t time.Time, labels map[string]string) bool {
+	for k, v := range w.Matchers {
+		if labels[k] != v {
+			return false
+		}
+	}
+	if w.StartsAt != nil && (t.Before(*w.StartsAt) || !t.Before(*w.EndsAt)) {
+		return false
+	}
+	if len(w.Intervals) > 0 && !w.Intervals.Contains(t) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,414 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakejira implements an in-memory fake Jira, so the webhook to issue pipeline can be exercised without a Jira
+// instance. Server is the notify.Ticketer JIRAlert searches, creates, updates, comments on and transitions issues
+// with, and serves the Jira REST API endpoints used at startup (project, component, server info and user) plus
+// read-only issue endpoints for inspecting it.
+package fakejira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// Status categories, as exposed by Jira in the statusCategory field of statuses.
+const (
+	CategoryNew        = "new"
+	CategoryInProgress = "indeterminate"
+	CategoryDone       = "done"
+)
+
+// timeFormat is the format of timestamps in the Jira API.
+const timeFormat = "2006-01-02T15:04:05.000-0700"
+
+// apiPrefix is the path prefix of the Jira REST API v2.
+const apiPrefix = "/rest/api/2/"
+
+type status struct {
+	name     string
+	category string
+}
+
+type issue struct {
+	id       string
+	key      string
+	project  string
+	fields   map[string]interface{}
+	status   status
+	created  time.Time
+	updated  time.Time
+	resolved time.Time
+	comments []map[string]interface{}
+}
+
+// Server is a fake Jira instance. All projects exist and all users are allowed to do everything; new issues start in
+// the "To Do" status and may be transitioned to any known status.
+type Server struct {
+	mtx        sync.Mutex
+	statuses   []status
+	issues     []*issue
+	keys       map[string]int
+	components map[string][]string
+	nextID     int
+
+	timeNow func() time.Time
+}
+
+// New returns a fake Jira with the statuses of a default workflow: "To Do", "Open", "Reopened" and "Backlog" (new),
+// "In Progress" (in progress) and "Done", "Resolved" and "Closed" (done).
+func New() *Server {
+	s := &Server{keys: map[string]int{}, components: map[string][]string{}, nextID: 10000, timeNow: time.Now}
+	for _, n := range []string{"To Do", "Open", "Reopened", "Backlog"} {
+		s.AddStatus(n, CategoryNew)
+	}
+	s.AddStatus("In Progress", CategoryInProgress)
+	for _, n := range []string{"Done", "Resolved", "Closed"} {
+		s.AddStatus(n, CategoryDone)
+	}
+	return s
+}
+
+// AddStatus adds a status of the given category issues may be transitioned to, unless it exists already.
+func (s *Server) AddStatus(name, category string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.status(name); ok {
+		return
+	}
+	s.statuses = append(s.statuses, status{name: name, category: category})
+}
+
+func (s *Server) status(name string) (status, bool) {
+	for _, st := range s.statuses {
+		if st.name == name {
+			return st, true
+		}
+	}
+	return status{}, false
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		apiError(w, http.StatusNotFound, "not found: %s", r.URL.Path)
+		return
+	}
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/"), "/")
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "serverInfo":
+		respond(w, http.StatusOK, map[string]interface{}{"deploymentType": "Server", "version": "9.0.0", "serverTitle": "JIRAlert fake Jira"})
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "myself":
+		respond(w, http.StatusOK, map[string]interface{}{"name": "jiralert", "displayName": "JIRAlert", "active": true})
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "search":
+		s.search(w, r)
+	case r.Method == http.MethodGet && len(path) == 2 && path[0] == "issue":
+		s.withIssue(w, path[1], func(i *issue) { respond(w, http.StatusOK, s.render(i)) })
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "project":
+		s.projects(w)
+	case r.Method == http.MethodGet && len(path) == 2 && path[0] == "project":
+		s.project(w, path[1])
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "mypermissions":
+		s.permissions(w, r)
+	case r.Method == http.MethodPost && len(path) == 1 && path[0] == "component":
+		s.createComponent(w, r)
+	default:
+		apiError(w, http.StatusNotFound, "unsupported endpoint: %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func (s *Server) withIssue(w http.ResponseWriter, key string, f func(*issue)) {
+	n, ok := s.keys[key]
+	if !ok {
+		apiError(w, http.StatusNotFound, "Issue Does Not Exist")
+		return
+	}
+	f(s.issues[n])
+}
+
+// issue returns the issue with the given key or ID.
+func (s *Server) issue(key string) (*issue, error) {
+	n, ok := s.keys[key]
+	if !ok {
+		return nil, fmt.Errorf("issue %s does not exist", key)
+	}
+	return s.issues[n], nil
+}
+
+func (s *Server) create(fields map[string]interface{}) (*issue, error) {
+	project := stringField(fields, "project", "key")
+	if project == "" {
+		return nil, fmt.Errorf("project is required")
+	}
+	if stringField(fields, "summary") == "" {
+		return nil, fmt.Errorf("summary is required")
+	}
+
+	s.nextID++
+	now := s.timeNow()
+	i := &issue{
+		id:      strconv.Itoa(s.nextID),
+		key:     fmt.Sprintf("%s-%d", project, s.projectIssues(project)+1),
+		project: project,
+		fields:  map[string]interface{}{},
+		status:  s.statuses[0],
+		created: now,
+		updated: now,
+	}
+	for k, v := range fields {
+		i.fields[k] = v
+	}
+	s.keys[i.key] = len(s.issues)
+	s.keys[i.id] = len(s.issues)
+	s.issues = append(s.issues, i)
+	return i, nil
+}
+
+func (s *Server) projectIssues(project string) int {
+	n := 0
+	for _, i := range s.issues {
+		if i.project == project {
+			n++
+		}
+	}
+	return n
+}
+
+// update sets the given fields of the issue and applies the operations of the update section of an issue edit.
+func (s *Server) update(i *issue, fields map[string]interface{}, update map[string][]map[string]interface{}) error {
+	for k, v := range fields {
+		i.fields[k] = v
+	}
+	for field, ops := range update {
+		for _, op := range ops {
+			for verb, v := range op {
+				if err := applyUpdate(i.fields, field, verb, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	i.updated = s.timeNow()
+	return nil
+}
+
+// applyUpdate applies a single operation of the update section of an issue edit to the given fields.
+func applyUpdate(fields map[string]interface{}, field, verb string, v interface{}) error {
+	values, _ := fields[field].([]interface{})
+	switch verb {
+	case "set":
+		fields[field] = v
+	case "add":
+		fields[field] = append(values, v)
+	case "remove":
+		var kept []interface{}
+		for _, e := range values {
+			if e != v {
+				kept = append(kept, e)
+			}
+		}
+		fields[field] = kept
+	default:
+		return fmt.Errorf("unsupported update operation %q of field %q", verb, field)
+	}
+	return nil
+}
+
+// transitions returns the transitions of the issue, one to each status but its current one.
+func (s *Server) transitions(i *issue) []map[string]interface{} {
+	transitions := []map[string]interface{}{}
+	for n, st := range s.statuses {
+		if st.name == i.status.name {
+			continue
+		}
+		transitions = append(transitions, map[string]interface{}{
+			"id":   strconv.Itoa(n + 1),
+			"name": st.name,
+			"to":   renderStatus(st),
+		})
+	}
+	return transitions
+}
+
+func (s *Server) transition(i *issue, id string) error {
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 1 || n > len(s.statuses) {
+		return fmt.Errorf("transition %q is not valid for this issue", id)
+	}
+
+	now := s.timeNow()
+	i.status = s.statuses[n-1]
+	i.updated = now
+	i.resolved = time.Time{}
+	if i.status.category == CategoryDone {
+		i.resolved = now
+	}
+	return nil
+}
+
+func (s *Server) comment(i *issue, c map[string]interface{}) map[string]interface{} {
+	now := s.timeNow()
+	c["id"] = strconv.Itoa(len(i.comments) + 1)
+	c["created"] = now.Format(timeFormat)
+	i.comments = append(i.comments, c)
+	i.updated = now
+	return c
+}
+
+func (s *Server) project(w http.ResponseWriter, key string) {
+	components := []map[string]interface{}{}
+	for n, c := range s.components[key] {
+		components = append(components, map[string]interface{}{"id": strconv.Itoa(n + 1), "name": c})
+	}
+	respond(w, http.StatusOK, map[string]interface{}{"id": key, "key": key, "name": key, "components": components})
+}
+
+// projects lists the projects issues or components were created in, as all projects exist in the fake.
+func (s *Server) projects(w http.ResponseWriter) {
+	seen := map[string]bool{}
+	for _, i := range s.issues {
+		seen[i.project] = true
+	}
+	for key := range s.components {
+		seen[key] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	projects := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		projects = append(projects, map[string]interface{}{"id": key, "key": key, "name": key})
+	}
+	respond(w, http.StatusOK, projects)
+}
+
+// permissions grants all requested permissions.
+func (s *Server) permissions(w http.ResponseWriter, r *http.Request) {
+	permissions := map[string]interface{}{}
+	for _, p := range strings.Split(r.URL.Query().Get("permissions"), ",") {
+		if p != "" {
+			permissions[p] = map[string]interface{}{"key": p, "havePermission": true}
+		}
+	}
+	respond(w, http.StatusOK, map[string]interface{}{"permissions": permissions})
+}
+
+func (s *Server) createComponent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string `json:"name"`
+		Project string `json:"project"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || req.Project == "" {
+		apiError(w, http.StatusBadRequest, "invalid component")
+		return
+	}
+	s.components[req.Project] = append(s.components[req.Project], req.Name)
+	respond(w, http.StatusCreated, map[string]interface{}{"id": strconv.Itoa(len(s.components[req.Project])), "name": req.Name, "project": req.Project})
+}
+
+// search lists the issues, optionally only those of the project and with the label given as URL parameters, most
+// recently created first.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := notify.Query{Project: r.URL.Query().Get("project"), Label: r.URL.Query().Get("label")}
+	matches := s.find(q)
+	sortIssues(matches, func(i *issue) time.Time { return i.created }, true)
+
+	startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+	maxResults, err := strconv.Atoi(r.URL.Query().Get("maxResults"))
+	if err != nil || maxResults <= 0 {
+		maxResults = 50
+	}
+	issues := []interface{}{}
+	for n := startAt; n < len(matches) && n < startAt+maxResults; n++ {
+		issues = append(issues, s.render(matches[n]))
+	}
+	respond(w, http.StatusOK, map[string]interface{}{"startAt": startAt, "maxResults": maxResults, "total": len(matches), "issues": issues})
+}
+
+// render returns the API representation of an issue.
+func (s *Server) render(i *issue) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for k, v := range i.fields {
+		fields[k] = v
+	}
+	fields["status"] = renderStatus(i.status)
+	fields["created"] = i.created.Format(timeFormat)
+	fields["updated"] = i.updated.Format(timeFormat)
+	comments := append([]map[string]interface{}{}, i.comments...)
+	fields["comment"] = map[string]interface{}{"comments": comments, "total": len(comments)}
+	if !i.resolved.IsZero() {
+		fields["resolution"] = map[string]interface{}{"name": "Done"}
+		fields["resolutiondate"] = i.resolved.Format(timeFormat)
+	} else {
+		delete(fields, "resolution")
+		delete(fields, "resolutiondate")
+	}
+	return map[string]interface{}{"id": i.id, "key": i.key, "self": apiPrefix + "issue/" + i.id, "fields": fields}
+}
+
+func renderStatus(st status) map[string]interface{} {
+	return map[string]interface{}{
+		"name":           st.name,
+		"statusCategory": map[string]interface{}{"key": st.category, "name": st.category},
+	}
+}
+
+// stringField returns the string at the given path of nested objects in fields, if any.
+func stringField(fields map[string]interface{}, path ...string) string {
+	var v interface{} = fields
+	for _, p := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[p]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// apiError responds with an error in the format of the Jira API.
+func apiError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	respond(w, status, map[string]interface{}{"errorMessages": []string{fmt.Sprintf(format, args...)}, "errors": map[string]string{}})
+}
+
+// sortIssues sorts issues by the given time, most recent first if desc is set.
+func sortIssues(issues []*issue, by func(*issue) time.Time, desc bool) {
+	sort.SliceStable(issues, func(a, b int) bool {
+		if desc {
+			return by(issues[a]).After(by(issues[b]))
+		}
+		return by(issues[a]).Before(by(issues[b]))
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakejira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/stretchr/testify/require"
+)
+
+func keys(issues []jira.Issue) []string {
+	var res []string
+	for _, i := range issues {
+		res = append(res, i.Key)
+	}
+	return res
+}
+
+func TestReceiver(t *testing.T) {
+	fake := New()
+	reopen := config.Duration(time.Hour)
+	conf := &config.ReceiverConfig{
+		Name:           "test",
+		Project:        "AB",
+		IssueType:      "Bug",
+		Summary:        `{{ .GroupLabels.alertname }}`,
+		ReopenState:    "To Do",
+		ReopenDuration: &reopen,
+		AutoResolve:    &config.AutoResolve{State: "Done"},
+	}
+	notifyGroup := func(alertname, status string) {
+		_, err := notify.NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fake, nil).Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: status}},
+			Status:      status,
+			GroupLabels: alertmanager.KV{"alertname": alertname},
+		}, true)
+		require.NoError(t, err)
+	}
+	search := func() []jira.Issue {
+		issues, _, err := fake.SearchWithContext(context.Background(), notify.Query{Project: "AB"}, nil)
+		require.NoError(t, err)
+		return issues
+	}
+
+	notifyGroup("Down", alertmanager.AlertFiring)
+	notifyGroup("Down", alertmanager.AlertFiring)
+	notifyGroup("Slow", alertmanager.AlertFiring)
+	issues := search()
+	require.Equal(t, []string{"AB-1", "AB-2"}, keys(issues))
+	require.Equal(t, "Down", issues[0].Fields.Summary)
+	require.Equal(t, "Bug", issues[0].Fields.Type.Name)
+
+	notifyGroup("Down", alertmanager.AlertResolved)
+	issues = search()
+	require.Equal(t, "Done", issues[0].Fields.Status.Name)
+	require.NotNil(t, issues[0].Fields.Resolution)
+
+	notifyGroup("Down", alertmanager.AlertFiring)
+	issues = search()
+	require.Len(t, issues, 2)
+	require.Equal(t, "To Do", issues[0].Fields.Status.Name)
+	require.Nil(t, issues[0].Fields.Resolution)
+}
+
+func TestSearch(t *testing.T) {
+	fake := New()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake.timeNow = func() time.Time { return now }
+	ctx := context.Background()
+	create := func(fields *jira.IssueFields) string {
+		fields.Project = jira.Project{Key: "AB"}
+		fields.Summary = "summary"
+		created, _, err := fake.CreateWithContext(ctx, &jira.Issue{Fields: fields})
+		require.NoError(t, err)
+		now = now.Add(time.Hour)
+		return created.Key
+	}
+	search := func(q notify.Query) []string {
+		q.Project = "AB"
+		issues, _, err := fake.SearchWithContext(ctx, q, nil)
+		require.NoError(t, err)
+		return keys(issues)
+	}
+	transition := func(key, status string) {
+		transitions, _, err := fake.GetTransitionsWithContext(ctx, key)
+		require.NoError(t, err)
+		for _, tr := range transitions {
+			if tr.Name == status {
+				_, err := fake.DoTransitionWithContext(ctx, key, tr.ID)
+				require.NoError(t, err)
+				now = now.Add(time.Hour)
+				return
+			}
+		}
+		t.Fatalf("no transition to %s", status)
+	}
+
+	epic := create(&jira.IssueFields{Labels: []string{"epic"}})
+	child := create(&jira.IssueFields{Labels: []string{"a"}, Parent: &jira.Parent{Key: epic}})
+	linked := create(&jira.IssueFields{Labels: []string{"a"}, Unknowns: map[string]interface{}{"customfield_10001": epic}})
+	transition(child, "Done")
+	transition(epic, "Done")
+
+	require.Equal(t, []string{epic, child, linked}, search(notify.Query{}))
+	require.Equal(t, []string{child, linked}, search(notify.Query{Label: "a"}))
+	require.Equal(t, []string{linked}, search(notify.Query{Unresolved: true}))
+	require.Equal(t, []string{child}, search(notify.Query{Parent: epic}))
+	require.Equal(t, []string{linked}, search(notify.Query{Parent: epic, ParentField: "customfield_10001"}))
+	require.Equal(t, []string{linked, epic, child}, search(notify.Query{OrderByResolutionDate: true}))
+	require.Equal(t, []string{child, linked}, search(notify.Query{NotUpdatedFor: 2 * time.Hour}))
+	require.Equal(t, []string{linked}, search(notify.Query{NotUpdatedFor: 3 * time.Hour}))
+
+	issues, _, err := fake.SearchWithContext(ctx, notify.Query{Project: "AB"}, &jira.SearchOptions{StartAt: 1, MaxResults: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{child}, keys(issues))
+
+	_, err = fake.UpdateIssueWithContext(ctx, linked, map[string]interface{}{"update": map[string]interface{}{"labels": []map[string]string{{"remove": "a"}, {"add": "b"}}}})
+	require.NoError(t, err)
+	require.Equal(t, []string{linked}, search(notify.Query{Label: "b"}))
+
+	_, err = fake.DoTransitionWithContext(ctx, "AB-42", "1")
+	require.EqualError(t, err, "issue AB-42 does not exist")
+}
+
+func TestServeSearch(t *testing.T) {
+	fake := New()
+	for _, project := range []string{"AB", "XY", "AB"} {
+		_, _, err := fake.CreateWithContext(context.Background(), &jira.Issue{Fields: &jira.IssueFields{Project: jira.Project{Key: project}, Summary: "summary"}})
+		require.NoError(t, err)
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/rest/api/2/search?project=AB")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var res struct {
+		Total  int          `json:"total"`
+		Issues []jira.Issue `json:"issues"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.Equal(t, 2, res.Total)
+	require.Equal(t, []string{"AB-2", "AB-1"}, keys(res.Issues))
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakejira
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// find returns the issues matching q, in creation order. An empty project matches the issues of all projects.
+func (s *Server) find(q notify.Query) []*issue {
+	now := s.timeNow()
+	var matches []*issue
+	for _, i := range s.issues {
+		if matchesQuery(q, i, now) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func matchesQuery(q notify.Query, i *issue, now time.Time) bool {
+	if q.Project != "" && i.project != q.Project {
+		return false
+	}
+	if q.Label != "" && !hasLabel(i, q.Label) {
+		return false
+	}
+	if q.Unresolved && i.status.category == CategoryDone {
+		return false
+	}
+	if q.NotUpdatedFor > 0 && i.updated.After(now.Add(-q.NotUpdatedFor)) {
+		return false
+	}
+	if q.Parent != "" {
+		parent := stringField(i.fields, "parent", "key")
+		if q.ParentField != "" {
+			parent = stringField(i.fields, q.ParentField)
+		}
+		if parent != q.Parent {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLabel(i *issue, label string) bool {
+	labels, _ := i.fields["labels"].([]interface{})
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// sortByResolution sorts the unresolved issues first, then the resolved ones, most recently resolved first, like
+// Jira does when ordering by resolution date descending.
+func sortByResolution(issues []*issue) {
+	sort.SliceStable(issues, func(a, b int) bool {
+		ra, rb := issues[a].resolved, issues[b].resolved
+		if ra.IsZero() || rb.IsZero() {
+			return ra.IsZero() && !rb.IsZero()
+		}
+		return ra.After(rb)
+	})
+}
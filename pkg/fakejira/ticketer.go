@@ -0,0 +1,184 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakejira
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+var _ notify.Ticketer = (*Server)(nil)
+
+// convert round-trips in through JSON into out, so the issues JIRAlert sends and gets back look like the ones of the
+// Jira API.
+func convert(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// toJira returns the issue as returned by the Jira API.
+func (s *Server) toJira(i *issue) (*jira.Issue, error) {
+	var res jira.Issue
+	if err := convert(s.render(i), &res); err != nil {
+		return nil, errors.Wrapf(err, "convert issue %s", i.key)
+	}
+	return &res, nil
+}
+
+// SearchWithContext implements notify.Ticketer.
+func (s *Server) SearchWithContext(_ context.Context, query notify.Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	matches := s.find(query)
+	if query.OrderByResolutionDate {
+		sortByResolution(matches)
+	}
+	if options != nil {
+		if options.StartAt >= len(matches) {
+			return nil, nil, nil
+		}
+		matches = matches[options.StartAt:]
+		if options.MaxResults > 0 && len(matches) > options.MaxResults {
+			matches = matches[:options.MaxResults]
+		}
+	}
+	res := make([]jira.Issue, 0, len(matches))
+	for _, i := range matches {
+		ji, err := s.toJira(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		res = append(res, *ji)
+	}
+	return res, nil, nil
+}
+
+// GetTransitionsWithContext implements notify.Ticketer.
+func (s *Server) GetTransitionsWithContext(_ context.Context, id string) ([]jira.Transition, *jira.Response, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, err := s.issue(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var res []jira.Transition
+	if err := convert(s.transitions(i), &res); err != nil {
+		return nil, nil, err
+	}
+	return res, nil, nil
+}
+
+// CreateWithContext implements notify.Ticketer.
+func (s *Server) CreateWithContext(_ context.Context, ji *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	var req struct {
+		Fields map[string]interface{} `json:"fields"`
+	}
+	if err := convert(ji, &req); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid issue")
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, err := s.create(req.Fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &jira.Issue{ID: i.id, Key: i.key, Self: apiPrefix + "issue/" + i.id}, nil, nil
+}
+
+// UpdateWithOptionsWithContext implements notify.Ticketer. The options are ignored.
+func (s *Server) UpdateWithOptionsWithContext(_ context.Context, ji *jira.Issue, _ *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	var req struct {
+		Fields map[string]interface{} `json:"fields"`
+	}
+	if err := convert(ji, &req); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid update")
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, err := s.issue(ji.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.update(i, req.Fields, nil); err != nil {
+		return nil, nil, err
+	}
+	updated, err := s.toJira(i)
+	return updated, nil, err
+}
+
+// DoTransitionWithContext implements notify.Ticketer.
+func (s *Server) DoTransitionWithContext(_ context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, err := s.issue(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.transition(i, transitionID)
+}
+
+// AddCommentWithContext implements notify.Ticketer.
+func (s *Server) AddCommentWithContext(_ context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	var c map[string]interface{}
+	if err := convert(comment, &c); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid comment")
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, err := s.issue(issueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var res jira.Comment
+	if err := convert(s.comment(i, c), &res); err != nil {
+		return nil, nil, err
+	}
+	return &res, nil, nil
+}
+
+// UpdateIssueWithContext implements notify.Ticketer.
+func (s *Server) UpdateIssueWithContext(_ context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	var req struct {
+		Fields map[string]interface{}              `json:"fields"`
+		Update map[string][]map[string]interface{} `json:"update"`
+	}
+	if err := convert(data, &req); err != nil {
+		return nil, errors.Wrap(err, "invalid update")
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i, err := s.issue(jiraID)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.update(i, req.Fields, req.Update)
+}
@@ -0,0 +1,445 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package github implements notify.Ticketer on top of GitHub Issues. Projects are repositories ("owner/name"), issue
+// keys are "owner/name#number" and the open and closed states are exposed as transitions of the same name.
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+const (
+	// DefaultAPIURL is the URL of the public GitHub API.
+	DefaultAPIURL = "https://api.github.com"
+
+	// StateOpen is the state, and transition, of open issues.
+	StateOpen = "open"
+	// StateClosed is the state, and transition, of closed issues.
+	StateClosed = "closed"
+
+	// maxLabelLength is the maximum length of GitHub label names. Longer labels, e.g. issue identifier labels, are
+	// replaced by a hash and restored from a marker in the issue body.
+	maxLabelLength = 50
+	pageSize       = 100
+)
+
+var markerRe = regexp.MustCompile(`\n*<!-- jiralert-labels: (\{.*\}) -->\s*$`)
+
+// Client talks to the GitHub REST API.
+type Client struct {
+	url    string
+	token  string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewClient returns a Client for the GitHub API at baseURL, authenticated with the given token.
+func NewClient(baseURL, token string, client *http.Client) (*Client, error) {
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid GitHub API URL %q", baseURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{url: strings.TrimSuffix(baseURL, "/"), token: token, client: client, now: time.Now}, nil
+}
+
+type issue struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	State       string `json:"state"`
+	StateReason string `json:"state_reason"`
+	Labels      []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	ClosedAt    *time.Time      `json:"closed_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+// do sends a request and decodes the response into out, if not nil. Error responses are returned as *jira.Response
+// with a readable body, so callers can handle them like Jira errors.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) (*jira.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	jresp := &jira.Response{Response: resp}
+	if err != nil {
+		return jresp, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return jresp, errors.Errorf("GitHub request %s %s returned status %s", method, path, resp.Status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(b, out); err != nil {
+			return jresp, errors.Wrap(err, "decode GitHub response")
+		}
+	}
+	return jresp, nil
+}
+
+// parseKey splits an issue key into repository and issue number.
+func parseKey(key string) (string, int, error) {
+	i := strings.LastIndex(key, "#")
+	if i < 0 {
+		return "", 0, errors.Errorf("invalid GitHub issue key %q, expected owner/name#number", key)
+	}
+	n, err := strconv.Atoi(key[i+1:])
+	if err != nil {
+		return "", 0, errors.Errorf("invalid GitHub issue key %q, expected owner/name#number", key)
+	}
+	return key[:i], n, nil
+}
+
+func key(repo string, number int) string {
+	return fmt.Sprintf("%s#%d", repo, number)
+}
+
+// shortLabel returns the GitHub label used for the given label.
+func shortLabel(l string) string {
+	if len(l) <= maxLabelLength && !strings.Contains(l, ",") {
+		return l
+	}
+	return fmt.Sprintf("jiralert-%x", sha256.Sum256([]byte(l)))[:maxLabelLength/2]
+}
+
+// splitBody returns the description and the labels that were shortened, by short label, out of an issue body.
+func splitBody(body string) (string, map[string]string) {
+	long := map[string]string{}
+	m := markerRe.FindStringSubmatchIndex(body)
+	if m == nil {
+		return body, long
+	}
+	_ = json.Unmarshal([]byte(body[m[2]:m[3]]), &long)
+	return body[:m[0]], long
+}
+
+// joinBody returns the issue body for the given description and labels.
+func joinBody(desc string, labels []string) (string, []string) {
+	long := map[string]string{}
+	short := make([]string, 0, len(labels))
+	for _, l := range labels {
+		s := shortLabel(l)
+		if s != l {
+			long[s] = l
+		}
+		short = append(short, s)
+	}
+	if len(long) == 0 {
+		return desc, short
+	}
+	// json.Marshal escapes < and >, so labels cannot end the comment.
+	b, _ := json.Marshal(long)
+	return fmt.Sprintf("%s\n\n<!-- jiralert-labels: %s -->", desc, b), short
+}
+
+func (c *Client) toJira(repo string, gi *issue) jira.Issue {
+	desc, long := splitBody(gi.Body)
+	labels := make([]string, 0, len(gi.Labels))
+	for _, l := range gi.Labels {
+		if full, ok := long[l.Name]; ok {
+			labels = append(labels, full)
+			continue
+		}
+		labels = append(labels, l.Name)
+	}
+
+	category := "new"
+	if gi.State == StateClosed {
+		category = "done"
+	}
+	fields := &jira.IssueFields{
+		Project:     jira.Project{Key: repo},
+		Summary:     gi.Title,
+		Description: desc,
+		Labels:      labels,
+		Status:      &jira.Status{Name: gi.State, StatusCategory: jira.StatusCategory{Key: category}},
+	}
+	if gi.ClosedAt != nil && gi.State == StateClosed {
+		fields.Resolutiondate = jira.Time(*gi.ClosedAt)
+		fields.Resolution = &jira.Resolution{Name: gi.StateReason}
+	}
+	return jira.Issue{Key: key(repo, gi.Number), ID: key(repo, gi.Number), Fields: fields}
+}
+
+func (c *Client) get(ctx context.Context, repo string, number int) (*issue, *jira.Response, error) {
+	var gi issue
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d", repo, number), nil, &gi)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &gi, resp, nil
+}
+
+// SearchWithContext returns the issues of the repository given as project. Children of a parent issue are not
+// supported.
+//
+// StartAt and MaxResults select a single page of GitHub's listing, so StartAt must be a multiple of MaxResults (at
+// most 100). Pull requests, and with NotUpdatedFor recently updated issues, are dropped from the page, so it may hold
+// fewer issues than requested even if more follow. GitHub cannot order by resolution date, so with
+// OrderByResolutionDate all issues are listed and sorted before the page is selected.
+func (c *Client) SearchWithContext(ctx context.Context, query notify.Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	if query.Parent != "" {
+		return nil, nil, errors.Errorf("unsupported query %q: GitHub issues have no parent", query.JQL())
+	}
+	repo := query.Project
+
+	q := url.Values{}
+	q.Set("state", "all")
+	if query.Unresolved {
+		q.Set("state", StateOpen)
+	}
+	if query.Label != "" {
+		q.Set("labels", shortLabel(query.Label))
+	}
+	var updatedBefore time.Time
+	if query.NotUpdatedFor > 0 {
+		updatedBefore = c.now().Add(-query.NotUpdatedFor)
+	}
+
+	page, perPage, all := 1, pageSize, true
+	if options != nil && options.MaxResults > 0 && !query.OrderByResolutionDate {
+		perPage = options.MaxResults
+		if perPage > pageSize {
+			perPage = pageSize
+		}
+		if options.StartAt%perPage != 0 {
+			return nil, nil, errors.Errorf("unsupported search options: start at %d is not a multiple of %d results", options.StartAt, perPage)
+		}
+		page, all = options.StartAt/perPage+1, false
+	}
+	q.Set("per_page", strconv.Itoa(perPage))
+
+	var (
+		res  []jira.Issue
+		resp *jira.Response
+	)
+	for ; ; page++ {
+		q.Set("page", strconv.Itoa(page))
+		var (
+			gis []issue
+			err error
+		)
+		resp, err = c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues?%s", repo, q.Encode()), nil, &gis)
+		if err != nil {
+			return nil, resp, err
+		}
+		for i := range gis {
+			if len(gis[i].PullRequest) > 0 {
+				continue
+			}
+			if !updatedBefore.IsZero() && gis[i].UpdatedAt.After(updatedBefore) {
+				continue
+			}
+			res = append(res, c.toJira(repo, &gis[i]))
+		}
+		if !all || len(gis) < perPage {
+			break
+		}
+	}
+	if !all {
+		return res, resp, nil
+	}
+
+	if query.OrderByResolutionDate {
+		// Unresolved issues first, like Jira.
+		sort.SliceStable(res, func(i, j int) bool {
+			ti, tj := time.Time(res[i].Fields.Resolutiondate), time.Time(res[j].Fields.Resolutiondate)
+			if ti.IsZero() || tj.IsZero() {
+				return ti.IsZero() && !tj.IsZero()
+			}
+			return ti.After(tj)
+		})
+	}
+
+	if options != nil {
+		if options.StartAt >= len(res) {
+			return nil, resp, nil
+		}
+		res = res[options.StartAt:]
+		if options.MaxResults > 0 && len(res) > options.MaxResults {
+			res = res[:options.MaxResults]
+		}
+	}
+	return res, resp, nil
+}
+
+// GetTransitionsWithContext returns the open and closed transitions.
+func (c *Client) GetTransitionsWithContext(_ context.Context, _ string) ([]jira.Transition, *jira.Response, error) {
+	return []jira.Transition{{ID: StateOpen, Name: StateOpen}, {ID: StateClosed, Name: StateClosed}}, nil, nil
+}
+
+// DoTransitionWithContext opens or closes the issue.
+func (c *Client) DoTransitionWithContext(ctx context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	repo, number, err := parseKey(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repo, number), map[string]string{"state": transitionID}, nil)
+}
+
+// CreateWithContext creates the issue in the repository given as project. Only summary, description, labels and assignee are
+// used.
+func (c *Client) CreateWithContext(ctx context.Context, ji *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	repo := ji.Fields.Project.Key
+	body, labels := joinBody(ji.Fields.Description, ji.Fields.Labels)
+	req := map[string]interface{}{
+		"title":  ji.Fields.Summary,
+		"body":   body,
+		"labels": labels,
+	}
+	if ji.Fields.Assignee != nil && ji.Fields.Assignee.Name != "" {
+		req["assignees"] = []string{ji.Fields.Assignee.Name}
+	}
+
+	var gi issue
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues", repo), req, &gi)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &jira.Issue{Key: key(repo, gi.Number), ID: key(repo, gi.Number)}, resp, nil
+}
+
+// UpdateWithOptionsWithContext updates the summary and description of the issue, if set. Other fields, e.g. the
+// custom fields in Unknowns, have no GitHub equivalent and are ignored; if none of the set fields maps, no request
+// is made and the issue is returned unchanged.
+func (c *Client) UpdateWithOptionsWithContext(ctx context.Context, ji *jira.Issue, _ *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	repo, number, err := parseKey(ji.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := map[string]interface{}{}
+	if ji.Fields.Summary != "" {
+		req["title"] = ji.Fields.Summary
+	}
+	if ji.Fields.Description != "" {
+		current, resp, err := c.get(ctx, repo, number)
+		if err != nil {
+			return nil, resp, err
+		}
+		req["body"], _ = joinBody(ji.Fields.Description, c.toJira(repo, current).Fields.Labels)
+	}
+	if len(req) == 0 {
+		return ji, nil, nil
+	}
+
+	var gi issue
+	resp, err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repo, number), req, &gi)
+	if err != nil {
+		return nil, resp, err
+	}
+	updated := c.toJira(repo, &gi)
+	return &updated, resp, nil
+}
+
+// UpdateIssueWithContext supports adding and removing labels, i.e. `{"update": {"labels": [{"add": "..."}, {"remove": "..."}]}}`.
+func (c *Client) UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	repo, number, err := parseKey(jiraID)
+	if err != nil {
+		return nil, err
+	}
+	current, resp, err := c.get(ctx, repo, number)
+	if err != nil {
+		return resp, err
+	}
+	ji := c.toJira(repo, current)
+
+	// Round-trip through JSON, so the operations can be given as any slice of maps.
+	var update struct {
+		Update struct {
+			Labels []map[string]string `json:"labels"`
+		} `json:"update"`
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &update); err != nil {
+		return nil, errors.Wrap(err, "unsupported issue update")
+	}
+
+	labels := ji.Fields.Labels
+	for _, op := range update.Update.Labels {
+		if l, ok := op["add"]; ok {
+			labels = append(labels, l)
+		}
+		if l, ok := op["remove"]; ok {
+			kept := labels[:0]
+			for _, existing := range labels {
+				if existing != l {
+					kept = append(kept, existing)
+				}
+			}
+			labels = kept
+		}
+	}
+	body, short := joinBody(ji.Fields.Description, labels)
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repo, number), map[string]interface{}{"body": body, "labels": short}, nil)
+}
+
+// AddCommentWithContext adds a comment to the issue.
+func (c *Client) AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	repo, number, err := parseKey(issueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var res struct {
+		ID int64 `json:"id"`
+	}
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number), map[string]string{"body": comment.Body}, &res)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &jira.Comment{ID: strconv.FormatInt(res.ID, 10), Body: comment.Body}, resp, nil
+}
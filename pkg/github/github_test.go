@@ -0,0 +1,311 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub serves the issues endpoints of a single repository "o/r".
+type fakeGitHub struct {
+	mtx    sync.Mutex
+	issues []*issue
+	// Queries of the issue listings.
+	listings []string
+	// Number of issue updates.
+	patches int
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/repos/o/r/issues")
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		f.listings = append(f.listings, r.URL.RawQuery)
+		f.list(w, r)
+	case r.Method == http.MethodPost && path == "":
+		var req struct {
+			Title  string   `json:"title"`
+			Body   string   `json:"body"`
+			Labels []string `json:"labels"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gi := &issue{Number: len(f.issues) + 1, Title: req.Title, Body: req.Body, State: StateOpen, UpdatedAt: time.Now()}
+		setLabels(gi, req.Labels)
+		f.issues = append(f.issues, gi)
+		respond(w, gi)
+	case strings.HasPrefix(path, "/"):
+		n, err := strconv.Atoi(strings.TrimPrefix(path, "/"))
+		if err != nil || n < 1 || n > len(f.issues) {
+			http.NotFound(w, r)
+			return
+		}
+		gi := f.issues[n-1]
+		if r.Method == http.MethodPatch {
+			f.patches++
+			var req map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if v, ok := req["body"].(string); ok {
+				gi.Body = v
+			}
+			if v, ok := req["labels"].([]interface{}); ok {
+				var labels []string
+				for _, l := range v {
+					labels = append(labels, l.(string))
+				}
+				setLabels(gi, labels)
+			}
+			if v, ok := req["state"].(string); ok {
+				gi.State = v
+			}
+		}
+		respond(w, gi)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitHub) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	var matches []*issue
+	for _, gi := range f.issues {
+		if q.Get("state") != "all" && gi.State != q.Get("state") {
+			continue
+		}
+		if l := q.Get("labels"); l != "" && !hasLabel(gi, l) {
+			continue
+		}
+		matches = append(matches, gi)
+	}
+	start := (page - 1) * perPage
+	if start > len(matches) {
+		start = len(matches)
+	}
+	end := start + perPage
+	if end > len(matches) {
+		end = len(matches)
+	}
+	res := []json.RawMessage{}
+	for _, gi := range matches[start:end] {
+		res = append(res, encode(gi))
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// encode returns the issue as returned by GitHub, which only sets pull_request for pull requests.
+func encode(gi *issue) json.RawMessage {
+	var m map[string]interface{}
+	b, _ := json.Marshal(gi)
+	_ = json.Unmarshal(b, &m)
+	if len(gi.PullRequest) == 0 {
+		delete(m, "pull_request")
+	}
+	b, _ = json.Marshal(m)
+	return b
+}
+
+func respond(w http.ResponseWriter, gi *issue) {
+	_, _ = w.Write(encode(gi))
+}
+
+func setLabels(gi *issue, labels []string) {
+	gi.Labels = nil
+	for _, l := range labels {
+		gi.Labels = append(gi.Labels, struct {
+			Name string `json:"name"`
+		}{Name: l})
+	}
+}
+
+func hasLabel(gi *issue, label string) bool {
+	for _, l := range gi.Labels {
+		if l.Name == label {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestClient(t *testing.T) (*Client, *fakeGitHub) {
+	fake := &fakeGitHub{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, "token", srv.Client())
+	require.NoError(t, err)
+	return c, fake
+}
+
+func keys(issues []jira.Issue) []string {
+	var res []string
+	for _, i := range issues {
+		res = append(res, i.Key)
+	}
+	return res
+}
+
+func TestSearchOrderByResolutionDate(t *testing.T) {
+	c, fake := newTestClient(t)
+	closed := func(ago time.Duration) *time.Time {
+		t := time.Now().Add(-ago)
+		return &t
+	}
+	fake.issues = []*issue{
+		{Number: 1, State: StateClosed, ClosedAt: closed(2 * time.Hour)},
+		{Number: 2, State: StateClosed, ClosedAt: closed(time.Hour)},
+		{Number: 3, State: StateOpen},
+		{Number: 4, State: StateClosed, ClosedAt: closed(3 * time.Hour)},
+	}
+	for _, gi := range fake.issues {
+		setLabels(gi, []string{"ALERT{a=\"b\"}"})
+	}
+
+	issues, _, err := c.SearchWithContext(context.Background(), notify.Query{Project: "o/r", Label: "ALERT{a=\"b\"}", OrderByResolutionDate: true}, &jira.SearchOptions{MaxResults: 3})
+	require.NoError(t, err)
+	require.Equal(t, []string{"o/r#3", "o/r#2", "o/r#1"}, keys(issues))
+	require.Equal(t, "labels=ALERT%7Ba%3D%22b%22%7D&page=1&per_page=100&state=all", fake.listings[0])
+
+	// Without ordering, GitHub's order is kept.
+	issues, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"o/r#1", "o/r#2", "o/r#3", "o/r#4"}, keys(issues))
+}
+
+func TestSearchUnresolved(t *testing.T) {
+	c, fake := newTestClient(t)
+	for n := 1; n <= pageSize+1; n++ {
+		fake.issues = append(fake.issues, &issue{Number: n, State: StateOpen, UpdatedAt: time.Now()})
+	}
+	fake.issues[0].UpdatedAt = time.Now().Add(-2 * time.Hour)
+	fake.issues[1].State = StateClosed
+	fake.issues[2].PullRequest = json.RawMessage(`{}`)
+
+	issues, _, err := c.SearchWithContext(context.Background(), notify.Query{Project: "o/r", Unresolved: true}, nil)
+	require.NoError(t, err)
+	require.Len(t, issues, pageSize-1)
+	require.Equal(t, "new", issues[0].Fields.Status.StatusCategory.Key)
+	require.Equal(t, []string{"page=1&per_page=100&state=open", "page=2&per_page=100&state=open"}, fake.listings)
+
+	issues, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r", Unresolved: true, NotUpdatedFor: time.Hour}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"o/r#1"}, keys(issues))
+
+	_, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r", Parent: "o/r#1"}, nil)
+	require.EqualError(t, err, `unsupported query "project=\"o/r\" and parent = \"o/r#1\"": GitHub issues have no parent`)
+}
+
+func TestSearchPage(t *testing.T) {
+	c, fake := newTestClient(t)
+	for n := 1; n <= 5; n++ {
+		fake.issues = append(fake.issues, &issue{Number: n, State: StateOpen, UpdatedAt: time.Now()})
+	}
+	fake.issues[3].PullRequest = json.RawMessage(`{}`)
+
+	// Only the requested page is listed, without the pull request.
+	issues, _, err := c.SearchWithContext(context.Background(), notify.Query{Project: "o/r"}, &jira.SearchOptions{StartAt: 2, MaxResults: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"o/r#3"}, keys(issues))
+	require.Equal(t, []string{"page=2&per_page=2&state=all"}, fake.listings)
+
+	issues, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r"}, &jira.SearchOptions{StartAt: 4, MaxResults: 2})
+	require.NoError(t, err)
+	require.Equal(t, []string{"o/r#5"}, keys(issues))
+
+	// GitHub pages hold at most 100 issues.
+	_, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r"}, &jira.SearchOptions{StartAt: 100, MaxResults: 500})
+	require.NoError(t, err)
+	require.Equal(t, "page=2&per_page=100&state=all", fake.listings[2])
+
+	_, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r"}, &jira.SearchOptions{StartAt: 1, MaxResults: 2})
+	require.EqualError(t, err, "unsupported search options: start at 1 is not a multiple of 2 results")
+}
+
+func TestUpdate(t *testing.T) {
+	c, fake := newTestClient(t)
+	fake.issues = []*issue{{Number: 1, Title: "summary", Body: "description", State: StateOpen}}
+
+	updated, _, err := c.UpdateWithOptionsWithContext(context.Background(), &jira.Issue{Key: "o/r#1", Fields: &jira.IssueFields{
+		Description: "new description",
+	}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "new description", updated.Fields.Description)
+	require.Equal(t, 1, fake.patches)
+
+	// Custom fields have no GitHub equivalent, nothing is sent.
+	_, _, err = c.UpdateWithOptionsWithContext(context.Background(), &jira.Issue{Key: "o/r#1", Fields: &jira.IssueFields{
+		Unknowns: map[string]interface{}{"customfield_10053": "2022-11-05T22:00:00.000+0000"},
+	}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, fake.patches)
+	require.Equal(t, "new description", fake.issues[0].Body)
+}
+
+func TestLongLabels(t *testing.T) {
+	c, fake := newTestClient(t)
+	long := fmt.Sprintf("JIRALERT{%s}", strings.Repeat("a", 128))
+
+	created, _, err := c.CreateWithContext(context.Background(), &jira.Issue{Fields: &jira.IssueFields{
+		Project:     jira.Project{Key: "o/r"},
+		Summary:     "summary",
+		Description: "description",
+		Labels:      []string{"alert", long},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, "o/r#1", created.Key)
+	require.Equal(t, []string{"alert", shortLabel(long)}, []string{fake.issues[0].Labels[0].Name, fake.issues[0].Labels[1].Name})
+
+	// The full label is found and restored from the body.
+	issues, _, err := c.SearchWithContext(context.Background(), notify.Query{Project: "o/r", Label: long}, nil)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	require.Equal(t, "description", issues[0].Fields.Description)
+	require.Equal(t, []string{"alert", long}, issues[0].Fields.Labels)
+
+	_, err = c.UpdateIssueWithContext(context.Background(), "o/r#1", map[string]interface{}{
+		"update": map[string]interface{}{"labels": []map[string]string{{"remove": long}, {"add": "other"}}},
+	})
+	require.NoError(t, err)
+	issues, _, err = c.SearchWithContext(context.Background(), notify.Query{Project: "o/r"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"alert", "other"}, issues[0].Fields.Labels)
+	require.Equal(t, "description", fake.issues[0].Body)
+}
+
+func TestTransitions(t *testing.T) {
+	c, fake := newTestClient(t)
+	fake.issues = []*issue{{Number: 1, State: StateOpen}}
+
+	_, err := c.DoTransitionWithContext(context.Background(), "o/r#1", StateClosed)
+	require.NoError(t, err)
+	require.Equal(t, StateClosed, fake.issues[0].State)
+
+	_, err = c.DoTransitionWithContext(context.Background(), "o/r", StateClosed)
+	require.EqualError(t, err, `invalid GitHub issue key "o/r", expected owner/name#number`)
+}
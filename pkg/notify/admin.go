@@ -0,0 +1,75 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// detachPageSize is the maximum number of issues carrying the same identifier label that are detached at once.
+const detachPageSize = 50
+
+// Detach removes the identifier label from all issues of project carrying it, so the next notification for the
+// alert group creates a fresh issue.
+func (r *Receiver) Detach(ctx context.Context, project, idLabel string) error {
+	query := searchQuery(project, idLabel)
+	options := &jira.SearchOptions{Fields: []string{"labels"}, MaxResults: detachPageSize}
+	issues, resp, err := r.client.SearchWithContext(ctx, query, options)
+	if err != nil {
+		_, err := handleJiraErrResponse("Issue.Search", resp, err, r.logger)
+		return err
+	}
+
+	for _, issue := range issues {
+		if err := r.updateLabel(ctx, issue.Key, "remove", idLabel); err != nil {
+			return err
+		}
+		level.Info(r.logger).Log("msg", "detached issue from alert group", "key", issue.Key, "label", idLabel)
+	}
+	r.forgetMapping(project, idLabel)
+	return nil
+}
+
+// Relink makes issueKey the issue tracking the alert group with the given identifier label, detaching any other.
+func (r *Receiver) Relink(ctx context.Context, project, idLabel, issueKey string) error {
+	m, _ := r.state.Mapping(r.conf.Name, project, idLabel)
+	if err := r.Detach(ctx, project, idLabel); err != nil {
+		return err
+	}
+	if err := r.updateLabel(ctx, issueKey, "add", idLabel); err != nil {
+		return err
+	}
+	level.Info(r.logger).Log("msg", "linked issue to alert group", "key", issueKey, "label", idLabel)
+	r.recordMapping(&alertmanager.Data{GroupKey: m.GroupKey}, project, idLabel, issueKey, MappingOpen)
+	return nil
+}
+
+// updateLabel adds or removes (depending on op) a single label of an issue.
+func (r *Receiver) updateLabel(ctx context.Context, issueKey, op, label string) error {
+	update := map[string]interface{}{
+		"update": map[string]interface{}{
+			"labels": []map[string]string{{op: label}},
+		},
+	}
+	resp, err := r.client.UpdateIssueWithContext(ctx, issueKey, update)
+	if err != nil {
+		_, err := handleJiraErrResponse("Issue.UpdateIssue", resp, err, r.logger)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// AssetsSearcher is implemented by Ticketers that can look up Jira Assets (formerly Insight) objects.
+type AssetsSearcher interface {
+	// SearchAssetsWithContext returns the values referencing the objects found by the AQL query in an Assets object
+	// field, at most limit.
+	SearchAssetsWithContext(ctx context.Context, workspaceID, aql string, limit int) ([]interface{}, error)
+}
+
+// setAssetsFields sets the Assets object fields of an issue to the objects found by their queries. Failing lookups
+// are only logged, as the issue is more important than its objects.
+func (r *Receiver) setAssetsFields(ctx context.Context, issue *jira.Issue, data *alertmanager.Data) error {
+	for _, f := range r.conf.AssetsFields {
+		aql, err := r.tmpl.Execute(f.Query, data)
+		if err != nil {
+			return errors.Wrapf(err, "render assets query for field %s", f.Field)
+		}
+		if aql == "" {
+			continue
+		}
+		if r.assets == nil {
+			level.Debug(r.logger).Log("msg", "no Assets support, not looking up objects", "field", f.Field, "query", aql)
+			continue
+		}
+		values, err := r.assets.SearchAssetsWithContext(ctx, f.WorkspaceID, aql, f.MaxObjects)
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "failed to look up Assets objects", "field", f.Field, "query", aql, "err", err)
+			continue
+		}
+		if len(values) == 0 {
+			level.Warn(r.logger).Log("msg", "no Assets objects found", "field", f.Field, "query", aql)
+			continue
+		}
+		issue.Fields.Unknowns[f.Field] = values
+	}
+	return nil
+}
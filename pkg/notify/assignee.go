@@ -0,0 +1,122 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/prometheus-community/jiralert/pkg/oncall"
+)
+
+// assignee returns the user a new issue for the given alert group is assigned to unless someone is on call, see
+// onCallAssignee, if any: the assignee_mapping match, the next user of the assignee_pool or assignee, in this order.
+func (r *Receiver) assignee(data *alertmanager.Data) (string, error) {
+	assigneeTmpl := r.conf.Assignee
+	if mapped, ok := r.conf.AssigneeMapping.Lookup(data.CommonLabels); ok {
+		assigneeTmpl = mapped
+	} else if r.conf.AssigneePool != nil {
+		return r.state.nextPoolAssignee(r.conf.Name, r.conf.AssigneePool), nil
+	}
+	if assigneeTmpl == "" {
+		return "", nil
+	}
+	assignee, err := r.execute("assignee", assigneeTmpl, data)
+	if err != nil {
+		return "", errors.Wrap(err, "render issue assignee")
+	}
+	return assignee, nil
+}
+
+// onCallAssignee returns the Jira user currently on call, if configured and the alert group is not mapped to an
+// assignee by assignee_mapping. Lookup failures are logged, so issues are still created with the fallback assignee
+// returned by assignee.
+func (r *Receiver) onCallAssignee(ctx context.Context, data *alertmanager.Data) (string, bool) {
+	if r.conf.OnCall == nil {
+		return "", false
+	}
+	if _, ok := r.conf.AssigneeMapping.Lookup(data.CommonLabels); ok {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(*r.conf.OnCall.Timeout))
+	defer cancel()
+
+	email, err := oncall.Lookup(ctx, r.conf.OnCall, nil)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "on-call lookup failed, using fallback assignee", "provider", r.conf.OnCall.Provider, "schedule", r.conf.OnCall.Schedule, "err", err)
+		return "", false
+	}
+	if user, ok := r.conf.OnCall.Users[email]; ok {
+		return user, true
+	}
+	return email, true
+}
+
+// recordPoolAssignment advances the assignee pool rotation if a new issue was assigned to one of its users.
+func (r *Receiver) recordPoolAssignment(assignee *jira.User) {
+	if r.conf.AssigneePool == nil || assignee == nil {
+		return
+	}
+	for _, u := range r.conf.AssigneePool.Users {
+		if u == assignee.Name {
+			if err := r.state.poolAssigned(r.conf.Name, u, r.timeNow()); err != nil {
+				level.Warn(r.logger).Log("msg", "failed to persist state", "err", err)
+			}
+			return
+		}
+	}
+}
+
+// nextPoolAssignee returns the user of the receiver's assignee pool the next issue is assigned to. It does not
+// advance the rotation, see poolAssigned.
+func (s *State) nextPoolAssignee(receiver string, pool *config.AssigneePool) string {
+	if s == nil {
+		return pool.Users[0]
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if pool.Strategy == config.AssigneePoolLeastRecentlyAssigned {
+		next := pool.Users[0]
+		for _, u := range pool.Users[1:] {
+			if s.poolLastAssigned[receiver][u].Before(s.poolLastAssigned[receiver][next]) {
+				next = u
+			}
+		}
+		return next
+	}
+	return pool.Users[s.poolAssignments[receiver]%len(pool.Users)]
+}
+
+// poolAssigned advances the rotation of the receiver's assignee pool after an issue was assigned to user. The error
+// tells the rotation could not be persisted, it is advanced anyway.
+func (s *State) poolAssigned(receiver, user string, now time.Time) error {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.poolAssignments[receiver]++
+	if _, ok := s.poolLastAssigned[receiver]; !ok {
+		s.poolLastAssigned[receiver] = map[string]time.Time{}
+	}
+	s.poolLastAssigned[receiver][user] = now
+	return s.persistLocked()
+}
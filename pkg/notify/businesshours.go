@@ -0,0 +1,103 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// deferCreation reports whether the creation of an issue for data must wait for business hours, queuing data if so.
+func (r *Receiver) deferCreation(idLabel string, data *alertmanager.Data) bool {
+	bh := r.conf.BusinessHours
+	if r.state == nil || bh == nil || bh.Intervals.Contains(r.timeNow()) {
+		return false
+	}
+	for _, a := range data.Alerts.Firing() {
+		for _, s := range bh.BypassSeverities {
+			if a.Labels[bh.SeverityLabel] == s {
+				return false
+			}
+		}
+	}
+
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	if _, ok := r.state.deferred[r.conf.Name]; !ok {
+		r.state.deferred[r.conf.Name] = map[string]alertmanager.Data{}
+	}
+	r.state.deferred[r.conf.Name][idLabel] = *data
+	r.state.persistLockedOrWarn(r.logger)
+	return true
+}
+
+// cancelDeferred drops any queued or held creation for the given identifier label, e.g. because its alerts resolved.
+func (r *Receiver) cancelDeferred(idLabel string) {
+	if r.state == nil {
+		return
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	_, deferred := r.state.deferred[r.conf.Name][idLabel]
+	_, held := r.state.held[r.conf.Name][idLabel]
+	if deferred || held {
+		delete(r.state.deferred[r.conf.Name], idLabel)
+		delete(r.state.held[r.conf.Name], idLabel)
+		r.state.persistLockedOrWarn(r.logger)
+	}
+}
+
+// NotifyDeferred processes the groups queued outside of business hours, if business hours started.
+func (r *Receiver) NotifyDeferred(ctx context.Context, hashJiraLabel bool) (bool, error) {
+	bh := r.conf.BusinessHours
+	if r.state == nil || bh == nil || !bh.Intervals.Contains(r.timeNow()) {
+		return false, nil
+	}
+
+	r.state.mtx.Lock()
+	deferred := r.state.deferred[r.conf.Name]
+	delete(r.state.deferred, r.conf.Name)
+	r.state.mtx.Unlock()
+	if len(deferred) == 0 {
+		return false, nil
+	}
+	// The groups stay in the state file until they were processed, so a restart meanwhile processes them again.
+	defer func() {
+		r.state.mtx.Lock()
+		defer r.state.mtx.Unlock()
+		r.state.persistLockedOrWarn(r.logger)
+	}()
+
+	for idLabel, d := range deferred {
+		level.Info(r.logger).Log("msg", "business hours started, processing deferred alert group", "label", idLabel)
+		d := d
+		if retry, err := r.Notify(ctx, &d, hashJiraLabel); err != nil {
+			// Re-queue what is left, so it is retried on the next run.
+			r.state.mtx.Lock()
+			if _, ok := r.state.deferred[r.conf.Name]; !ok {
+				r.state.deferred[r.conf.Name] = map[string]alertmanager.Data{}
+			}
+			for l, d := range deferred {
+				if _, ok := r.state.deferred[r.conf.Name][l]; !ok {
+					r.state.deferred[r.conf.Name][l] = d
+				}
+			}
+			r.state.mtx.Unlock()
+			return retry, err
+		}
+		delete(deferred, idLabel)
+	}
+	return false, nil
+}
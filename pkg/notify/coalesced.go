@@ -0,0 +1,52 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// Coalesce persists the notification held under key while further deliveries of its alert group are coalesced into it,
+// replacing the one held before, so it survives restarts. The error tells it could not be persisted, so the delivery
+// must be retried.
+func (s *State) Coalesce(key string, data alertmanager.Data) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.coalesced[key] = data
+	return errors.Wrap(s.persistLocked(), "hold notification")
+}
+
+// ReleaseCoalesced drops the notification held under key once it was handled.
+func (s *State) ReleaseCoalesced(key string, logger log.Logger) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.coalesced[key]; !ok {
+		return
+	}
+	delete(s.coalesced, key)
+	s.persistLaterLocked(logger)
+}
+
+// Coalesced returns the held notifications, by key.
+func (s *State) Coalesced() map[string]alertmanager.Data {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	res := make(map[string]alertmanager.Data, len(s.coalesced))
+	for k, data := range s.coalesced {
+		res[k] = data
+	}
+	return res
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+)
+
+// WatcherAdder adds watchers to issues.
+type WatcherAdder interface {
+	AddWatcherWithContext(ctx context.Context, issueID string, userName string) (*jira.Response, error)
+}
+
+// dedupeOwner is the receiver whose issue tracks an alert group for all receivers of its dedupe group.
+type dedupeOwner struct {
+	Receiver string `json:"receiver"`
+	Project  string `json:"project"`
+	// Receivers that commented on the owner's issue instead of creating their own.
+	Notified map[string]struct{} `json:"notified,omitempty"`
+}
+
+// dedupedIssue returns the key of the open issue another receiver of the dedupe group created for the alert group with
+// the given identifier label, and whether this receiver did not comment on it yet. Otherwise, this receiver becomes
+// the owner of the alert group, as it is about to create an issue in project.
+func (r *Receiver) dedupedIssue(project, idLabel string) (string, bool, bool) {
+	if r.state == nil || r.conf.DedupeGroup == "" {
+		return "", false, false
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+
+	owners, ok := r.state.dedupeOwners[r.conf.DedupeGroup]
+	if !ok {
+		owners = map[string]*dedupeOwner{}
+		r.state.dedupeOwners[r.conf.DedupeGroup] = owners
+	}
+	if o, ok := owners[idLabel]; ok && o.Receiver != r.conf.Name {
+		if m, ok := r.state.mappings[mappingKey{receiver: o.Receiver, project: o.Project, idLabel: idLabel}]; ok && m.Status == MappingOpen {
+			_, notified := o.Notified[r.conf.Name]
+			return m.IssueKey, !notified, true
+		}
+	}
+	owners[idLabel] = &dedupeOwner{Receiver: r.conf.Name, Project: project, Notified: map[string]struct{}{}}
+	r.state.persistLaterLocked(r.logger)
+	return "", false, false
+}
+
+// dedupeNotified records that this receiver commented on the issue of the alert group's owner.
+func (r *Receiver) dedupeNotified(idLabel string) {
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	if o, ok := r.state.dedupeOwners[r.conf.DedupeGroup][idLabel]; ok {
+		if o.Notified == nil {
+			o.Notified = map[string]struct{}{}
+		}
+		o.Notified[r.conf.Name] = struct{}{}
+		r.state.persistLaterLocked(r.logger)
+	}
+}
+
+// addDedupeWatchers adds the receiver's dedupe_watchers as watchers of the issue of the alert group's owner, so they
+// follow the issue this receiver did not create.
+func (r *Receiver) addDedupeWatchers(ctx context.Context, issueKey string) (bool, error) {
+	if len(r.conf.DedupeWatchers) == 0 {
+		return false, nil
+	}
+	if r.watchers == nil {
+		level.Warn(r.logger).Log("msg", "issue tracker does not support watchers, not adding dedupe_watchers", "key", issueKey)
+		return false, nil
+	}
+	for _, user := range r.conf.DedupeWatchers {
+		level.Debug(r.logger).Log("msg", "adding watcher", "key", issueKey, "user", user)
+		if resp, err := r.watchers.AddWatcherWithContext(ctx, issueKey, user); err != nil {
+			return handleJiraErrResponse("Issue.AddWatcher", resp, err, r.logger)
+		}
+	}
+	return false, nil
+}
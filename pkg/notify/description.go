@@ -0,0 +1,58 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"strings"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// descriptionSeparator separates the description from its header and footer.
+const descriptionSeparator = "\n\n"
+
+// description renders the description of an issue, preceded by description_header and followed by
+// description_footer. Header and footer are left out if unset or rendered empty.
+func (r *Receiver) description(data *alertmanager.Data) (string, error) {
+	var parts []string
+	for _, s := range []struct{ setting, text string }{
+		{"description_header", r.conf.DescriptionHeader},
+		{"description", r.conf.Description},
+		{"description_footer", r.conf.DescriptionFooter},
+	} {
+		if s.text == "" && s.setting != "description" {
+			continue
+		}
+		v, err := r.execute(s.setting, s.text, data)
+		if err != nil {
+			return "", err
+		}
+		if v != "" || s.setting == "description" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, descriptionSeparator), nil
+}
+
+// descriptionTemplate returns the template the description is rendered from, header and footer included, for
+// diff_ignore.
+func (r *Receiver) descriptionTemplate() string {
+	var parts []string
+	for _, text := range []string{r.conf.DescriptionHeader, r.conf.Description, r.conf.DescriptionFooter} {
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, descriptionSeparator)
+}
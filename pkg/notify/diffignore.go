@@ -0,0 +1,80 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// diffIgnorePlaceholder replaces the values of the labels and annotations of diff_ignore when rendering templates to
+// find out whether the issue differs in other places.
+const diffIgnorePlaceholder = "JIRALERT_DIFF_IGNORED"
+
+// onlyIgnoredChanges reports whether current, the summary or description of an existing issue, differs from the one
+// rendered from tmpl only in the values of the labels and annotations of diff_ignore. wrap is applied to the rendered
+// text like to the one the issue is updated to, e.g. for managed descriptions. This only works for values rendered
+// as-is, changes to transformed values still update the issue.
+func (r *Receiver) onlyIgnoredChanges(tmpl string, data *alertmanager.Data, current string, wrap func(string) string) bool {
+	if r.conf.DiffIgnore == nil {
+		return false
+	}
+	rendered, err := r.tmpl.Execute(tmpl, r.diffIgnoreData(data))
+	if err != nil || !strings.Contains(rendered, diffIgnorePlaceholder) {
+		return false
+	}
+	if wrap != nil {
+		rendered = wrap(rendered)
+	}
+	parts := strings.Split(rendered, diffIgnorePlaceholder)
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	re, err := regexp.Compile(`^` + strings.Join(parts, `(?s:.*?)`) + `$`)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(current)
+}
+
+// diffIgnoreData returns a copy of data whose labels and annotations of diff_ignore are set to diffIgnorePlaceholder.
+func (r *Receiver) diffIgnoreData(data *alertmanager.Data) *alertmanager.Data {
+	mask := func(kv alertmanager.KV, keys []string) alertmanager.KV {
+		res := make(alertmanager.KV, len(kv))
+		for k, v := range kv {
+			res[k] = v
+		}
+		for _, k := range keys {
+			if _, ok := res[k]; ok {
+				res[k] = diffIgnorePlaceholder
+			}
+		}
+		return res
+	}
+	labels, annotations := r.conf.DiffIgnore.Labels, r.conf.DiffIgnore.Annotations
+
+	d := *data
+	d.GroupLabels = mask(data.GroupLabels, labels)
+	d.CommonLabels = mask(data.CommonLabels, labels)
+	d.CommonAnnotations = mask(data.CommonAnnotations, annotations)
+	d.Alerts = make(alertmanager.Alerts, len(data.Alerts))
+	for i, a := range data.Alerts {
+		a.Labels = mask(a.Labels, labels)
+		a.Annotations = mask(a.Annotations, annotations)
+		d.Alerts[i] = a
+	}
+	return &d
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// hierarchyLabel returns the label identifying an epic or sub-task by the given label values, e.g.
+// JIRALERT_EPIC{alertname="HighLatency"}. It differs from issue identifier labels, so reconciliation leaves them alone.
+func hierarchyLabel(prefix string, names []string, values alertmanager.KV) string {
+	pairs := make([]string, 0, len(names))
+	for _, n := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", n, values[n]))
+	}
+	return strings.Replace(fmt.Sprintf("%s{%s}", prefix, strings.Join(pairs, ",")), " ", "", -1)
+}
+
+// epicLabel returns the label of the epic of the given alert group, or false if the group has none of the epic labels.
+func (r *Receiver) epicLabel(data *alertmanager.Data) (string, bool) {
+	for _, n := range r.conf.Epics.Labels {
+		if data.CommonLabels[n] != "" {
+			return hierarchyLabel("JIRALERT_EPIC", r.conf.Epics.Labels, data.CommonLabels), true
+		}
+	}
+	return "", false
+}
+
+// setEpic makes the new issue a child of the open epic of its alert group's label values, creating the epic if needed.
+// Failures are only logged, as the issue is more important than the rollup.
+func (r *Receiver) setEpic(ctx context.Context, project string, issue *jira.Issue, data *alertmanager.Data) error {
+	e := r.conf.Epics
+	if e == nil {
+		return nil
+	}
+	label, ok := r.epicLabel(data)
+	if !ok {
+		level.Debug(r.logger).Log("msg", "alert group has none of the epic labels, not setting epic", "labels", strings.Join(e.Labels, ","))
+		return nil
+	}
+
+	epic, _, err := r.search(ctx, project, label)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to search epic", "label", label, "err", err)
+		return nil
+	}
+	if epic == nil || epic.Fields.Status == nil || epic.Fields.Status.StatusCategory.Key == "done" {
+		summary, err := r.tmpl.Execute(e.Summary, data)
+		if err != nil {
+			return errors.Wrap(err, "render epic summary")
+		}
+		epic = &jira.Issue{
+			Fields: &jira.IssueFields{
+				Project:  jira.Project{Key: project},
+				Type:     jira.IssueType{Name: e.IssueType},
+				Summary:  summary,
+				Labels:   []string{label},
+				Unknowns: tcontainer.NewMarshalMap(),
+			},
+		}
+		if e.NameField != "" {
+			epic.Fields.Unknowns[e.NameField] = summary
+		}
+		level.Info(r.logger).Log("msg", "creating epic", "project", project, "label", label)
+		if _, err := r.create(ctx, epic); err != nil {
+			level.Warn(r.logger).Log("msg", "failed to create epic", "label", label, "err", err)
+			return nil
+		}
+	}
+
+	if e.LinkField != "" {
+		issue.Fields.Unknowns[e.LinkField] = epic.Key
+	} else {
+		issue.Fields.Parent = &jira.Parent{Key: epic.Key}
+	}
+	return nil
+}
+
+// resolveEpic resolves the open epic of the given alert group once all of its children are resolved. Failures are only
+// logged, like for setEpic.
+func (r *Receiver) resolveEpic(ctx context.Context, project string, data *alertmanager.Data) {
+	e := r.conf.Epics
+	if e == nil || r.conf.AutoResolve == nil {
+		return
+	}
+	label, ok := r.epicLabel(data)
+	if !ok {
+		return
+	}
+	epic, _, err := r.search(ctx, project, label)
+	if err != nil || epic == nil || epic.Fields.Status == nil || epic.Fields.Status.StatusCategory.Key == "done" {
+		return
+	}
+
+	children, err := r.searchOpen(ctx, Query{Project: project, Parent: epic.Key, ParentField: e.LinkField})
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to search open children of epic", "key", epic.Key, "err", err)
+		return
+	}
+	if len(children) > 0 {
+		level.Debug(r.logger).Log("msg", "epic has open children, not resolving", "key", epic.Key, "children", len(children))
+		return
+	}
+	level.Info(r.logger).Log("msg", "all children of epic are resolved, resolving epic", "key", epic.Key)
+	if _, err := r.resolveIssue(ctx, epic.Key); err != nil {
+		level.Warn(r.logger).Log("msg", "failed to resolve epic", "key", epic.Key, "err", err)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// jiraDateTimeFormat is the format of Jira date-time field values.
+const jiraDateTimeFormat = "2006-01-02T15:04:05.000-0700"
+
+// FieldUser is the value of user fields declared in field_types. Ticketers of Jira Cloud replace the name by the
+// account ID.
+type FieldUser struct {
+	Name      string `json:"name,omitempty"`
+	AccountID string `json:"accountId,omitempty"`
+}
+
+// typedFieldValue converts the rendered value of a field into the structure Jira expects for the given field type.
+func typedFieldValue(fieldType string, value interface{}) (interface{}, error) {
+	switch fieldType {
+	case config.FieldTypeOption, config.FieldTypeUser:
+		if list, ok := value.([]interface{}); ok {
+			values := make([]interface{}, 0, len(list))
+			for _, v := range list {
+				tv, err := typedFieldValue(fieldType, v)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, tv)
+			}
+			return values, nil
+		}
+		if fieldType == config.FieldTypeUser {
+			return &FieldUser{Name: fmt.Sprint(value)}, nil
+		}
+		return map[string]interface{}{"value": fmt.Sprint(value)}, nil
+	case config.FieldTypeArray:
+		if list, ok := value.([]interface{}); ok {
+			return list, nil
+		}
+		values := []interface{}{}
+		for _, v := range strings.Split(fmt.Sprint(value), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+	case config.FieldTypeNumber:
+		switch n := value.(type) {
+		case float64, int:
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(value)), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return f, nil
+	case config.FieldTypeDateTime:
+		s := strings.TrimSpace(fmt.Sprint(value))
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format(jiraDateTimeFormat), nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a date-time", value)
+	case config.FieldTypeCascading:
+		var options []string
+		if list, ok := value.([]interface{}); ok {
+			for _, v := range list {
+				options = append(options, fmt.Sprint(v))
+			}
+		} else {
+			options = strings.SplitN(fmt.Sprint(value), "/", 2)
+		}
+		if len(options) == 0 || len(options) > 2 {
+			return nil, fmt.Errorf("cascading value %q must have a parent and at most one child option", value)
+		}
+		child := ""
+		if len(options) == 2 {
+			child = options[1]
+		}
+		return cascadingValue(options[0], child), nil
+	}
+	return value, nil
+}
+
+// cascadingValue returns the value of a cascading select field, without a child option if child is empty.
+func cascadingValue(parent, child string) map[string]interface{} {
+	value := map[string]interface{}{"value": strings.TrimSpace(parent)}
+	if child = strings.TrimSpace(child); child != "" {
+		value["child"] = map[string]interface{}{"value": child}
+	}
+	return value
+}
+
+// setCascadingFields sets the cascading select fields of an issue to their rendered options. Fields whose parent
+// renders empty are not set.
+func (r *Receiver) setCascadingFields(issue *jira.Issue, data *alertmanager.Data) error {
+	for _, f := range r.conf.CascadingFields {
+		parent, err := r.tmpl.Execute(f.Parent, data)
+		if err != nil {
+			return errors.Wrapf(err, "render parent option of %s", f.Field)
+		}
+		if strings.TrimSpace(parent) == "" {
+			continue
+		}
+		child, err := r.tmpl.Execute(f.Child, data)
+		if err != nil {
+			return errors.Wrapf(err, "render child option of %s", f.Field)
+		}
+		issue.Fields.Unknowns[f.Field] = cascadingValue(parent, child)
+	}
+	return nil
+}
+
+// fieldValue renders the value of a field in fields and converts it to its type in field_types, if any. Its value in
+// template_defaults is used if it renders empty or fails. It returns nil if the field is not to be set because its
+// template failed to render, see template_errors.
+func (r *Receiver) fieldValue(key string, value interface{}, data *alertmanager.Data) (interface{}, error) {
+	value, err := deepCopyWithTemplate(value, r.tmpl, data)
+	if err != nil && !r.templateFailed("fields."+key, err) {
+		return nil, err
+	}
+	if s, ok := value.(string); err != nil || value == nil || ok && strings.TrimSpace(s) == "" {
+		if d, ok := r.conf.TemplateDefaults["fields."+key]; ok {
+			value = d
+		} else if err != nil {
+			return nil, nil
+		}
+	}
+	if t, ok := r.conf.FieldTypes[key]; ok {
+		if value, err = typedFieldValue(t, value); err != nil {
+			return nil, errors.Wrapf(err, "field %s", key)
+		}
+	}
+	return value, nil
+}
+
+// updatedFieldNames returns the names of the fields configured to be set on reused issues.
+func (r *Receiver) updatedFieldNames() []string {
+	var names []string
+	for key, u := range r.conf.FieldUpdates {
+		if u.Enabled() {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// updateFields sets the fields configured in field_updates of an existing issue, unless they have the values already.
+func (r *Receiver) updateFields(ctx context.Context, issue *jira.Issue, data *alertmanager.Data) (bool, error) {
+	update := map[string]interface{}{}
+	for _, key := range r.updatedFieldNames() {
+		value := r.conf.FieldUpdates[key].OnUpdate
+		if value == nil {
+			value = r.conf.Fields[key]
+		}
+		value, err := r.fieldValue(key, value, data)
+		if err != nil {
+			return false, err
+		}
+		if value == nil {
+			continue
+		}
+		if current, ok := issue.Fields.Unknowns.Value(key); !ok || !fieldValueEqual(current, value) {
+			update[key] = value
+		}
+	}
+	if len(update) == 0 {
+		return false, nil
+	}
+	level.Debug(r.logger).Log("msg", "updating fields", "key", issue.Key, "fields", len(update))
+
+	return r.editFields(ctx, issue.Key, update)
+}
+
+// trackedFieldNames returns the names of the fields configured to track the state of the alert group.
+func (r *Receiver) trackedFieldNames() []string {
+	var names []string
+	for _, f := range []string{r.conf.AlertCountField, r.conf.FiringSinceField, r.conf.LastSeenField} {
+		if f != "" {
+			names = append(names, f)
+		}
+	}
+	return names
+}
+
+// trackedFields returns the values of the fields configured to track the state of the alert group, by field.
+func (r *Receiver) trackedFields(data *alertmanager.Data) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if r.conf.AlertCountField != "" {
+		fields[r.conf.AlertCountField] = len(data.Alerts.Firing())
+	}
+	if r.conf.FiringSinceField != "" {
+		var since time.Time
+		for _, a := range data.Alerts {
+			if !a.StartsAt.IsZero() && (since.IsZero() || a.StartsAt.Before(since)) {
+				since = a.StartsAt
+			}
+		}
+		if !since.IsZero() {
+			fields[r.conf.FiringSinceField] = since.Format(jiraDateTimeFormat)
+		}
+	}
+	if r.conf.LastSeenField != "" {
+		fields[r.conf.LastSeenField] = r.timeNow().Format(jiraDateTimeFormat)
+	}
+	return fields
+}
+
+// updateTrackedFields sets the tracked fields of an existing issue, unless they have the values already.
+func (r *Receiver) updateTrackedFields(ctx context.Context, issue *jira.Issue, data *alertmanager.Data) (bool, error) {
+	update := map[string]interface{}{}
+	for field, value := range r.trackedFields(data) {
+		if current, ok := issue.Fields.Unknowns.Value(field); !ok || !fieldValueEqual(current, value) {
+			update[field] = value
+		}
+	}
+	if len(update) == 0 {
+		return false, nil
+	}
+	level.Debug(r.logger).Log("msg", "updating tracked fields", "key", issue.Key, "fields", len(update))
+
+	return r.editFields(ctx, issue.Key, update)
+}
+
+// updateResolutionFields sets the fields configured to record when the alert group of an issue ended and how long it
+// was firing. The group ended with its last resolved alert, or now if Alertmanager did not tell.
+func (r *Receiver) updateResolutionFields(ctx context.Context, issueKey string, data *alertmanager.Data) (bool, error) {
+	if r.conf.AutoResolve.EndedAtField == "" && r.conf.AutoResolve.DurationField == "" {
+		return false, nil
+	}
+	var startsAt, endsAt time.Time
+	for _, a := range data.Alerts {
+		if !a.StartsAt.IsZero() && (startsAt.IsZero() || a.StartsAt.Before(startsAt)) {
+			startsAt = a.StartsAt
+		}
+		if a.EndsAt.After(endsAt) {
+			endsAt = a.EndsAt
+		}
+	}
+	if endsAt.IsZero() {
+		endsAt = r.timeNow()
+	}
+
+	update := map[string]interface{}{}
+	if r.conf.AutoResolve.EndedAtField != "" {
+		update[r.conf.AutoResolve.EndedAtField] = endsAt.Format(jiraDateTimeFormat)
+	}
+	if r.conf.AutoResolve.DurationField != "" && !startsAt.IsZero() && endsAt.After(startsAt) {
+		update[r.conf.AutoResolve.DurationField] = int(endsAt.Sub(startsAt).Seconds())
+	}
+	if len(update) == 0 {
+		return false, nil
+	}
+	level.Debug(r.logger).Log("msg", "recording resolution", "key", issueKey, "ended_at", endsAt.Format(time.RFC3339), "started_at", startsAt.Format(time.RFC3339))
+
+	return r.editFields(ctx, issueKey, update)
+}
+
+// fieldValueEqual compares a field value returned by Jira, decoded from JSON, to the value JIRAlert sets.
+func fieldValueEqual(current, value interface{}) bool {
+	switch v := value.(type) {
+	case int:
+		f, ok := current.(float64)
+		return ok && f == float64(v)
+	case string:
+		s, ok := current.(string)
+		if !ok {
+			return false
+		}
+		// Jira may return date-times in another time zone.
+		ct, cerr := time.Parse(jiraDateTimeFormat, s)
+		vt, verr := time.Parse(jiraDateTimeFormat, v)
+		if cerr == nil && verr == nil {
+			return ct.Equal(vt)
+		}
+		return s == v
+	case float64:
+		f, ok := current.(float64)
+		return ok && f == v
+	case bool:
+		b, ok := current.(bool)
+		return ok && b == v
+	case nil:
+		return current == nil
+	}
+	// Structured values, e.g. options, compare like decoded from JSON. Jira returns options and users with more
+	// properties than set, e.g. their IDs, which are ignored.
+	b, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return false
+	}
+	return jsonSubset(current, decoded)
+}
+
+// jsonSubset reports whether the decoded JSON value current has all properties of value.
+func jsonSubset(current, value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, e := range v {
+			if !jsonSubset(c[key], e) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok || len(c) != len(v) {
+			return false
+		}
+		for i := range v {
+			if !jsonSubset(c[i], v[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return fieldValueEqual(current, value)
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// SetIncident sets the current incident issue of the receiver, overriding its configured current_incident issue.
+// An empty key clears it.
+func (s *State) SetIncident(receiver, issueKey string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.incidents[receiver] = issueKey
+}
+
+// Incident returns the current incident issue of the receiver with the given configuration, if any.
+func (s *State) Incident(conf *config.ReceiverConfig) string {
+	if conf.CurrentIncident == nil {
+		return ""
+	}
+	if s != nil {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		if key, ok := s.incidents[conf.Name]; ok {
+			return key
+		}
+	}
+	return conf.CurrentIncident.IssueKey
+}
+
+// linkToIncident links the new issue to the current incident issue, if any.
+func (r *Receiver) linkToIncident(ctx context.Context, issueKey string) {
+	incident := r.state.Incident(r.conf)
+	if incident == "" {
+		return
+	}
+	r.addLinks(ctx, issueKey, r.conf.CurrentIncident.LinkType, []string{incident})
+}
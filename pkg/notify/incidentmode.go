@@ -0,0 +1,103 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// IncidentLabel is the label of the umbrella issues created by incident_mode.
+const IncidentLabel = "JIRALERT_INCIDENT"
+
+// outage is the umbrella issue of an outage and when the last alert group joined it.
+type outage struct {
+	key  string
+	last time.Time
+}
+
+// outageIssue returns the umbrella issue of the receiver's outage in project, unless no alert group joined it within
+// window.
+func (s *State) outageIssue(receiver, project string, window time.Duration, now time.Time) (string, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	o, ok := s.outages[receiver+"/"+project]
+	if !ok || now.Sub(o.last) >= window {
+		return "", false
+	}
+	return o.key, true
+}
+
+// joinOutage records that an alert group joined the umbrella issue of the receiver's outage in project at now.
+func (s *State) joinOutage(receiver, project, key string, now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.outages[receiver+"/"+project] = &outage{key: key, last: now}
+}
+
+// aggregatesIncidents reports whether the alert group joins the umbrella issue of an outage instead of creating issue.
+func (r *Receiver) aggregatesIncidents(data *alertmanager.Data) bool {
+	return r.state != nil && r.conf.IncidentMode != nil && r.conf.IncidentMode.Matches(data.CommonLabels)
+}
+
+// addToOutage comments on the umbrella issue of the current outage in project instead of creating issue, creating the
+// umbrella issue if no alert group joined it within the window.
+func (r *Receiver) addToOutage(ctx context.Context, project, idLabel string, issue *jira.Issue, data *alertmanager.Data) (bool, error) {
+	window := time.Duration(*r.conf.IncidentMode.Window)
+	now := r.timeNow()
+	umbrellaKey, ok := r.state.outageIssue(r.conf.Name, project, window, now)
+	if !ok {
+		summary, err := r.tmpl.Execute(r.conf.IncidentMode.Summary, data)
+		if err != nil {
+			return false, errors.Wrap(err, "render incident summary")
+		}
+		umbrella := &jira.Issue{
+			Fields: &jira.IssueFields{
+				Project:     jira.Project{Key: project},
+				Type:        issue.Fields.Type,
+				Summary:     summary,
+				Description: fmt.Sprintf("Alert groups arriving within %s of each other are listed in the comments.", *r.conf.IncidentMode.Window),
+				Labels:      []string{IncidentLabel},
+				Unknowns:    tcontainer.NewMarshalMap(),
+			},
+		}
+		level.Info(r.logger).Log("msg", "creating incident issue", "project", project)
+		if retry, err := r.create(ctx, umbrella); err != nil {
+			return retry, err
+		}
+		umbrellaKey = umbrella.Key
+		r.state.joinOutage(r.conf.Name, project, umbrellaKey, now)
+		r.linkToIncident(ctx, umbrellaKey)
+	}
+
+	if r.state.listed(umbrellaKey, idLabel) {
+		level.Debug(r.logger).Log("msg", "alert group already listed in incident issue", "key", umbrellaKey, "label", idLabel)
+		return false, nil
+	}
+	level.Info(r.logger).Log("msg", "adding alert group to incident issue", "key", umbrellaKey, "label", idLabel)
+	if retry, err := r.addComment(ctx, umbrellaKey, fmt.Sprintf("%s\n\n%s", issue.Fields.Summary, issue.Fields.Description)); err != nil {
+		return retry, err
+	}
+	r.state.recordListed(umbrellaKey, idLabel)
+	r.state.joinOutage(r.conf.Name, project, umbrellaKey, now)
+	return false, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"sort"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// inhibitingIssue returns the key of an open issue of this receiver whose alert group inhibits the given one
+// according to the receiver's inhibit rules, if any. Issues are only known through the State, i.e. when JIRAlert
+// created or updated them since it started.
+func (r *Receiver) inhibitingIssue(idLabel string, data *alertmanager.Data) string {
+	if r.state == nil || len(r.conf.InhibitRules) == 0 {
+		return ""
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+
+	// Check the open issues in a stable order, so inhibited groups end up on the same one.
+	var sources []*Mapping
+	for k, m := range r.state.mappings {
+		if k.receiver == r.conf.Name && k.idLabel != idLabel && m.Status == MappingOpen {
+			sources = append(sources, m)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].IssueKey < sources[j].IssueKey })
+	for _, m := range sources {
+		for _, ir := range r.conf.InhibitRules {
+			if ir.Inhibits(m.Labels, data.CommonLabels) {
+				return m.IssueKey
+			}
+		}
+	}
+	return ""
+}
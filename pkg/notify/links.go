@@ -0,0 +1,67 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// projectLinkType is the type of the links between the issues of an alert group in several projects.
+const projectLinkType = "Relates"
+
+// projectIssue is the issue of an alert group in one of its projects.
+type projectIssue struct {
+	key     string
+	created bool
+}
+
+// linkIssues links the issues of an alert group in several projects to each other, if at least one of them was just
+// created, so the links are only added once.
+func (r *Receiver) linkIssues(ctx context.Context, issues []projectIssue) {
+	if len(issues) < 2 || r.conf.Backend != config.BackendJira {
+		return
+	}
+	for i, issue := range issues {
+		var keys []string
+		for _, other := range issues[i+1:] {
+			if issue.created || other.created {
+				keys = append(keys, other.key)
+			}
+		}
+		if len(keys) > 0 {
+			r.addLinks(ctx, issue.key, projectLinkType, keys)
+		}
+	}
+}
+
+// addLinks links the issue to the given outward issues. Failures are only logged, as the issues themselves are more
+// important.
+func (r *Receiver) addLinks(ctx context.Context, issueKey, linkType string, keys []string) {
+	ops := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		ops = append(ops, map[string]interface{}{"add": map[string]interface{}{
+			"type":         map[string]interface{}{"name": linkType},
+			"outwardIssue": map[string]interface{}{"key": key},
+		}})
+	}
+	level.Debug(r.logger).Log("msg", "linking issues", "key", issueKey, "links", len(ops), "type", linkType)
+	resp, err := r.client.UpdateIssueWithContext(ctx, issueKey, map[string]interface{}{"update": map[string]interface{}{"issuelinks": ops}})
+	if err != nil {
+		_, err := handleJiraErrResponse("Issue.UpdateIssue", resp, err, r.logger)
+		level.Warn(r.logger).Log("msg", "failed to link issues", "key", issueKey, "err", err)
+	}
+}
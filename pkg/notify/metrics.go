@@ -0,0 +1,179 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Ticketer operations, as used in the operation label of the request metrics.
+const (
+	opSearch     = "search"
+	opCreate     = "create"
+	opUpdate     = "update"
+	opTransition = "transition"
+	opComment    = "comment"
+	opWatch      = "watch"
+)
+
+var (
+	suppressedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_suppressed_notifications_total",
+			Help: "Alert groups for which no issue was created or reopened, by receiver and reason.",
+		},
+		[]string{"receiver", "reason"},
+	)
+	oldAlertsSkippedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_old_alerts_skipped_total",
+			Help: "Firing alerts that did not create an issue because they started longer than max_alert_age ago, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	shadowMutationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_shadow_mutations_total",
+			Help: "Issue tracker mutations logged instead of sent by shadow receivers, by receiver and operation.",
+		},
+		[]string{"receiver", "operation"},
+	)
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "jiralert_jira_request_duration_seconds",
+			Help:    "Duration of issue tracker API requests, by receiver and operation.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"receiver", "operation"},
+	)
+	requestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jiralert_jira_requests_in_flight",
+			Help: "Issue tracker API requests currently in flight, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+	requestErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_jira_request_errors_total",
+			Help: "Failed issue tracker API requests, by receiver, operation and status code (empty if no response was received).",
+		},
+		[]string{"receiver", "operation", "code"},
+	)
+	templateErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jiralert_template_errors_total",
+			Help: "Templates that failed to render, by receiver and setting (e.g. summary or fields.customfield_10001).",
+		},
+		[]string{"receiver", "setting"},
+	)
+	queuedNotifications = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jiralert_queued_notifications",
+			Help: "Alert groups with a notification queued while the receiver is paused, by receiver.",
+		},
+		[]string{"receiver"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(suppressedTotal)
+	prometheus.MustRegister(oldAlertsSkippedTotal)
+	prometheus.MustRegister(shadowMutationsTotal)
+	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(requestsInFlight)
+	prometheus.MustRegister(requestErrorsTotal)
+	prometheus.MustRegister(templateErrorsTotal)
+	prometheus.MustRegister(queuedNotifications)
+}
+
+// instrumentedTicketer records the duration and errors of the requests of a receiver's Ticketer.
+type instrumentedTicketer struct {
+	Ticketer
+	receiver string
+}
+
+// begin marks a request as in flight and returns its start time.
+func (t *instrumentedTicketer) begin() time.Time {
+	requestsInFlight.WithLabelValues(t.receiver).Inc()
+	return time.Now()
+}
+
+// observe records the outcome of a request started with begin.
+func (t *instrumentedTicketer) observe(op string, start time.Time, resp *jira.Response, err error) {
+	requestsInFlight.WithLabelValues(t.receiver).Dec()
+	requestDuration.WithLabelValues(t.receiver, op).Observe(time.Since(start).Seconds())
+	if err == nil {
+		return
+	}
+	code := ""
+	if resp != nil && resp.Response != nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	requestErrorsTotal.WithLabelValues(t.receiver, op, code).Inc()
+}
+
+func (t *instrumentedTicketer) SearchWithContext(ctx context.Context, query Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	start := t.begin()
+	issues, resp, err := t.Ticketer.SearchWithContext(ctx, query, options)
+	t.observe(opSearch, start, resp, err)
+	return issues, resp, err
+}
+
+func (t *instrumentedTicketer) GetTransitionsWithContext(ctx context.Context, id string) ([]jira.Transition, *jira.Response, error) {
+	start := t.begin()
+	transitions, resp, err := t.Ticketer.GetTransitionsWithContext(ctx, id)
+	t.observe(opTransition, start, resp, err)
+	return transitions, resp, err
+}
+
+func (t *instrumentedTicketer) CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	start := t.begin()
+	created, resp, err := t.Ticketer.CreateWithContext(ctx, issue)
+	t.observe(opCreate, start, resp, err)
+	return created, resp, err
+}
+
+func (t *instrumentedTicketer) UpdateWithOptionsWithContext(ctx context.Context, issue *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	start := t.begin()
+	updated, resp, err := t.Ticketer.UpdateWithOptionsWithContext(ctx, issue, opts)
+	t.observe(opUpdate, start, resp, err)
+	return updated, resp, err
+}
+
+func (t *instrumentedTicketer) DoTransitionWithContext(ctx context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	start := t.begin()
+	resp, err := t.Ticketer.DoTransitionWithContext(ctx, ticketID, transitionID)
+	t.observe(opTransition, start, resp, err)
+	return resp, err
+}
+
+func (t *instrumentedTicketer) AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	start := t.begin()
+	added, resp, err := t.Ticketer.AddCommentWithContext(ctx, issueID, comment)
+	t.observe(opComment, start, resp, err)
+	return added, resp, err
+}
+
+func (t *instrumentedTicketer) UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	start := t.begin()
+	resp, err := t.Ticketer.UpdateIssueWithContext(ctx, jiraID, data)
+	t.observe(opUpdate, start, resp, err)
+	return resp, err
+}
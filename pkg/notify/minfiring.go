@@ -0,0 +1,115 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// firingLongEnough reports whether the firing alerts of data started at least min_firing_duration ago. Alerts without
+// a start time are considered firing long enough.
+func (r *Receiver) firingLongEnough(data *alertmanager.Data) bool {
+	if r.conf.MinFiringDuration == nil {
+		return true
+	}
+	var since time.Time
+	for _, a := range data.Alerts.Firing() {
+		if !a.StartsAt.IsZero() && (since.IsZero() || a.StartsAt.Before(since)) {
+			since = a.StartsAt
+		}
+	}
+	return since.IsZero() || r.timeNow().Sub(since) >= time.Duration(*r.conf.MinFiringDuration)
+}
+
+// tooOld reports whether all firing alerts of data started longer than max_alert_age ago, counting them as skipped if
+// so. Alerts without a start time are never too old.
+func (r *Receiver) tooOld(data *alertmanager.Data) bool {
+	if r.conf.MaxAlertAge == nil {
+		return false
+	}
+	firing := data.Alerts.Firing()
+	for _, a := range firing {
+		if a.StartsAt.IsZero() || r.timeNow().Sub(a.StartsAt) <= time.Duration(*r.conf.MaxAlertAge) {
+			return false
+		}
+	}
+	oldAlertsSkippedTotal.WithLabelValues(r.conf.Name).Add(float64(len(firing)))
+	return true
+}
+
+// holdCreation reports whether the creation of an issue for data must wait until its alerts fired for
+// min_firing_duration, holding data if so.
+func (r *Receiver) holdCreation(idLabel string, data *alertmanager.Data) bool {
+	if r.state == nil || r.firingLongEnough(data) {
+		return false
+	}
+
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	if _, ok := r.state.held[r.conf.Name]; !ok {
+		r.state.held[r.conf.Name] = map[string]alertmanager.Data{}
+	}
+	r.state.held[r.conf.Name][idLabel] = *data
+	r.state.persistLockedOrWarn(r.logger)
+	return true
+}
+
+// NotifyHeld processes the held groups whose alerts have fired for min_firing_duration by now.
+func (r *Receiver) NotifyHeld(ctx context.Context, hashJiraLabel bool) (bool, error) {
+	if r.state == nil || r.conf.MinFiringDuration == nil {
+		return false, nil
+	}
+
+	r.state.mtx.Lock()
+	due := map[string]alertmanager.Data{}
+	for idLabel, d := range r.state.held[r.conf.Name] {
+		d := d
+		if r.firingLongEnough(&d) {
+			due[idLabel] = d
+			delete(r.state.held[r.conf.Name], idLabel)
+		}
+	}
+	r.state.mtx.Unlock()
+	if len(due) == 0 {
+		return false, nil
+	}
+	// The groups stay in the state file until they were processed, so a restart meanwhile processes them again.
+	defer func() {
+		r.state.mtx.Lock()
+		defer r.state.mtx.Unlock()
+		r.state.persistLockedOrWarn(r.logger)
+	}()
+
+	for idLabel, d := range due {
+		level.Info(r.logger).Log("msg", "alert group fired for min_firing_duration, processing held alert group", "label", idLabel)
+		d := d
+		if retry, err := r.Notify(ctx, &d, hashJiraLabel); err != nil {
+			// Hold what is left again, so it is retried on the next run.
+			r.state.mtx.Lock()
+			for l, d := range due {
+				if _, ok := r.state.held[r.conf.Name][l]; !ok {
+					r.state.held[r.conf.Name][l] = d
+				}
+			}
+			r.state.mtx.Unlock()
+			return retry, err
+		}
+		delete(due, idLabel)
+	}
+	return false, nil
+}
@@ -15,10 +15,15 @@ package notify
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -35,29 +40,87 @@ import (
 
 // TODO(bwplotka): Consider renaming this package to ticketer.
 
-type jiraIssueService interface {
-	Search(jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error)
-	GetTransitions(id string) ([]jira.Transition, *jira.Response, error)
-
-	Create(issue *jira.Issue) (*jira.Issue, *jira.Response, error)
-	UpdateWithOptions(issue *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error)
-	DoTransition(ticketID, transitionID string) (*jira.Response, error)
+// Ticketer is the issue tracker API used by Receiver. It follows the context-aware methods of go-jira's IssueService,
+// except for searches, which are given as a Query: Jira runs its JQL, other backends (e.g. pkg/github) translate it.
+type Ticketer interface {
+	SearchWithContext(ctx context.Context, query Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error)
+	GetTransitionsWithContext(ctx context.Context, id string) ([]jira.Transition, *jira.Response, error)
+
+	CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error)
+	UpdateWithOptionsWithContext(ctx context.Context, issue *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error)
+	DoTransitionWithContext(ctx context.Context, ticketID, transitionID string) (*jira.Response, error)
+	AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error)
+	UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error)
 }
 
 // Receiver wraps a specific Alertmanager receiver with its configuration and templates, creating/updating/reopening Jira issues based on Alertmanager notifications.
 type Receiver struct {
 	logger log.Logger
-	client jiraIssueService
+	client Ticketer
+	// assets looks up Jira Assets objects, if the Ticketer supports it.
+	assets AssetsSearcher
+	// serviceDesk resolves Jira Service Management organizations and participants, if the Ticketer supports it.
+	serviceDesk ServiceDeskResolver
+	// createMeta looks up the fields Jira requires when creating issues, if the Ticketer supports it.
+	createMeta CreateMetaGetter
+	// watchers adds watchers to issues, if the Ticketer supports it.
+	watchers WatcherAdder
 	// TODO(bwplotka): Consider splitting receiver config with ticket service details.
-	conf *config.ReceiverConfig
-	tmpl *template.Template
+	conf  *config.ReceiverConfig
+	tmpl  *template.Template
+	state *State
 
 	timeNow func() time.Time
 }
 
-// NewReceiver creates a Receiver using the provided configuration, template and jiraIssueService.
-func NewReceiver(logger log.Logger, c *config.ReceiverConfig, t *template.Template, client jiraIssueService) *Receiver {
-	return &Receiver{logger: logger, conf: c, tmpl: t, client: client, timeNow: time.Now}
+// NewReceiver creates a Receiver using the provided configuration, template and Ticketer, whose requests are
+// instrumented. The mutations of shadow receivers are logged instead of sent. The state is shared across receivers
+// and may be nil, in which case features relying on it are disabled.
+func NewReceiver(logger log.Logger, c *config.ReceiverConfig, t *template.Template, client Ticketer, state *State) *Receiver {
+	// Ticketers wrapping another one expose it with Unwrap, so its optional interfaces are still found.
+	base := client
+	for {
+		w, ok := base.(interface{ Unwrap() Ticketer })
+		if !ok {
+			break
+		}
+		base = w.Unwrap()
+	}
+	assets, _ := base.(AssetsSearcher)
+	serviceDesk, _ := base.(ServiceDeskResolver)
+	createMeta, _ := base.(CreateMetaGetter)
+	watchers, _ := base.(WatcherAdder)
+	if client != nil {
+		client = &instrumentedTicketer{Ticketer: client, receiver: c.Name}
+		if c.Shadow {
+			shadow := &shadowTicketer{Ticketer: client, receiver: c.Name, logger: logger}
+			client = shadow
+			if watchers != nil {
+				watchers = shadow
+			}
+		}
+	}
+	return &Receiver{logger: logger, conf: c, tmpl: t, client: client, assets: assets, serviceDesk: serviceDesk, createMeta: createMeta, watchers: watchers, state: state, timeNow: time.Now}
+}
+
+// withGroupKey returns a copy of the receiver whose log lines carry the hash of the given group key.
+func (r *Receiver) withGroupKey(groupKey string) *Receiver {
+	c := *r
+	c.logger = log.With(r.logger, "groupKeyHash", GroupKeyHash(groupKey))
+	if s, ok := c.client.(*shadowTicketer); ok {
+		shadow := &shadowTicketer{Ticketer: s.Ticketer, receiver: s.receiver, logger: c.logger}
+		c.client = shadow
+		if c.watchers != nil {
+			c.watchers = shadow
+		}
+	}
+	return &c
+}
+
+// GroupKeyHash returns a short, stable hash of an alert group key, which can be long and contain arbitrary
+// characters. It identifies the alert group in log lines and metrics.
+func GroupKeyHash(groupKey string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(groupKey)))[:16]
 }
 
 // transforms alertmanager.Data to alertmanager.Data slice grouped by Alert
@@ -157,97 +220,229 @@ func (r *Receiver) toAlertRule(d *alertmanager.Data) []alertmanager.Data {
 	return slice
 }
 
-func (r *Receiver) Notify(data *alertmanager.Data, hashJiraLabel bool) (bool, error) {
+// matchingAlerts returns data with only the alerts matching the receiver's match and match_re, and whether there are
+// any.
+func (r *Receiver) matchingAlerts(data *alertmanager.Data) (*alertmanager.Data, bool) {
+	if len(r.conf.Match) == 0 && len(r.conf.MatchRE) == 0 {
+		return data, true
+	}
+	matching := *data
+	matching.Alerts = nil
+	for _, a := range data.Alerts {
+		if r.conf.Matches(a.Labels) {
+			matching.Alerts = append(matching.Alerts, a)
+		}
+	}
+	if len(matching.Alerts) == 0 {
+		return data, false
+	}
+	if len(matching.Alerts) < len(data.Alerts) {
+		matching.Status = alertmanager.AlertResolved
+		if len(matching.Alerts.Firing()) > 0 {
+			matching.Status = alertmanager.AlertFiring
+		}
+	}
+	return &matching, true
+}
 
-	var slice []alertmanager.Data
+// group splits alertmanager.Data according to the receiver's group_issue_by setting.
+func (r *Receiver) group(data *alertmanager.Data) []alertmanager.Data {
 	switch r.conf.GroupIssueBy {
-	// by default alerts are already grouped by AlertGroup, so no transformation is needed here
-	case config.AlertGroup, "":
-		slice = []alertmanager.Data{*data}
 	case config.AlertRule:
-		slice = r.toAlertRule(data)
+		return r.toAlertRule(data)
 	case config.Alert:
-		slice = r.toAlert(data)
+		return r.toAlert(data)
+	}
+	// by default alerts are already grouped by AlertGroup, so no transformation is needed here
+	return []alertmanager.Data{*data}
+}
+
+// GroupResult is the outcome of an alert group of a notification, as split by group_issue_by.
+type GroupResult struct {
+	// Labels are the labels common to the alerts of the group, which tell it apart from the other groups.
+	Labels alertmanager.KV `json:"labels"`
+	// IssueKeys are the issues of the group, one per project. They are set for failed groups too, up to the project
+	// that failed.
+	IssueKeys []string `json:"issueKeys,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	// Retryable reports whether the group failed with an error that may go away on retry.
+	Retryable bool `json:"retryable,omitempty"`
+}
+
+// Notify manages the issues of the alert group of an Alertmanager webhook notification. All log lines emitted
+// while handling it carry the hash of its group key, see GroupKeyHash.
+func (r *Receiver) Notify(ctx context.Context, data *alertmanager.Data, hashJiraLabel bool) (bool, error) {
+	_, retry, err := r.NotifyGroups(ctx, data, hashJiraLabel)
+	return retry, err
+}
+
+// NotifyGroups is like Notify, also returning the outcome of each alert group the notification was split into. A
+// failing group does not keep the others from being processed: the error of the first failing group is returned,
+// and the notification can be retried if any failing group can.
+func (r *Receiver) NotifyGroups(ctx context.Context, data *alertmanager.Data, hashJiraLabel bool) ([]GroupResult, bool, error) {
+	r = r.withGroupKey(data.GroupKey)
+	if queued, err := r.queueIfPaused(data); err != nil {
+		return nil, true, err
+	} else if queued {
+		level.Debug(r.logger).Log("msg", "receiver paused, queuing notification", "groupLabels", data.GroupLabels)
+		return nil, false, nil
+	}
+	data, ok := r.matchingAlerts(data)
+	if !ok {
+		level.Debug(r.logger).Log("msg", "no alert matches the receiver's match and match_re, ignoring", "groupLabels", data.GroupLabels)
+		return nil, false, nil
 	}
 
-	for _, d := range slice {
-		retry, err := r.notify(&d, hashJiraLabel)
+	groups := r.group(data)
+	var (
+		results  []GroupResult
+		retry    bool
+		firstErr error
+		failed   int
+	)
+	for _, d := range groups {
+		keys, groupRetry, err := r.notify(ctx, &d, hashJiraLabel)
+		res := GroupResult{Labels: d.CommonLabels, IssueKeys: keys}
 		if err != nil {
-			return retry, err
+			if len(groups) > 1 {
+				level.Error(r.logger).Log("msg", "error notifying alert group, continuing with the others", "labels", d.CommonLabels, "retry", groupRetry, "err", err)
+			}
+			res.Error, res.Retryable = err.Error(), groupRetry
+			retry = retry || groupRetry
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
 		}
+		results = append(results, res)
 	}
-
-	return false, nil
+	if failed > 0 && len(groups) > 1 {
+		firstErr = errors.Wrapf(firstErr, "%d of %d alert groups failed, first error", failed, len(groups))
+	}
+	return results, retry, firstErr
 }
 
-// Notify manages JIRA issues based on alertmanager webhook notify message.
-func (r *Receiver) notify(data *alertmanager.Data, hashJiraLabel bool) (bool, error) {
-	project, err := r.tmpl.Execute(r.conf.Project, data)
-	if err != nil {
-		return false, errors.Wrap(err, "generate project from template")
+// notify manages JIRA issues based on alertmanager webhook notify message, one per project of the alert group. It
+// returns the keys of the group's issues.
+func (r *Receiver) notify(ctx context.Context, data *alertmanager.Data, hashJiraLabel bool) ([]string, bool, error) {
+	if !r.severeEnough(data) {
+		level.Debug(r.logger).Log("msg", "alert group below min_severity, ignoring", "groupLabels", data.GroupLabels)
+		suppressedTotal.WithLabelValues(r.conf.Name, "severity").Inc()
+		return nil, false, nil
 	}
 
-	labels := make([]string, 0)
+	projects, err := r.projects(data)
+	if err != nil {
+		return nil, false, err
+	}
 
-	if r.conf.AddCommonLabels {
-		for _, pair := range data.CommonLabels.SortedPairs() {
-			labels = append(labels, fmt.Sprintf("%s=%q", pair.Name, pair.Value))
+	var issues []projectIssue
+	for _, project := range projects {
+		if retry, err := r.notifyProject(ctx, data, project, hashJiraLabel, &issues); err != nil {
+			return issueKeys(issues), retry, err
 		}
 	}
+	r.linkIssues(ctx, issues)
+	return issueKeys(issues), false, nil
+}
 
-	idLabel, err := r.toIssueIdentifierLabel(data, hashJiraLabel)
-	if err != nil {
-		return false, errors.Wrap(err, "build IssueIdentifierLabel")
+func issueKeys(issues []projectIssue) []string {
+	var keys []string
+	for _, i := range issues {
+		keys = append(keys, i.key)
 	}
+	return keys
+}
 
-	labels = append(labels, idLabel)
-	issue, retry, err := r.findIssueToReuse(project, idLabel)
+// notifyProject manages the JIRA issue of the alert group in the given project. The issue is appended to issues,
+// unless there is none or the group was aggregated into an alert storm or incident issue.
+func (r *Receiver) notifyProject(ctx context.Context, data *alertmanager.Data, project string, hashJiraLabel bool, issues *[]projectIssue) (bool, error) {
+	labels, idLabel, err := r.issueLabels(data, hashJiraLabel)
+	if err != nil {
+		return false, err
+	}
+	if key, ok := r.recentlyUpdated(project, idLabel, data); ok {
+		level.Debug(r.logger).Log("msg", "issue was updated less than min_update_interval ago, skipping notification", "key", key, "label", labels)
+		*issues = append(*issues, projectIssue{key: key})
+		return false, nil
+	}
+	issue, retry, err := r.findIssueToReuse(ctx, project, idLabel)
 	if err != nil {
 		return retry, err
 	}
 
 	// We want up to date title no matter what.
 	// This allows reflecting current group state if desired by user e.g {{ len $.Alerts.Firing() }}
-	issueSummary, err := r.tmpl.Execute(r.conf.Summary, data)
+	issueSummary, err := r.execute("summary", r.conf.Summary, data)
 	if err != nil {
 		return false, errors.Wrap(err, "generate summary from template")
 	}
 
-	issueDesc, err := r.tmpl.Execute(r.conf.Description, data)
+	issueDesc, err := r.description(data)
 	if err != nil {
 		return false, errors.Wrap(err, "render issue description")
 	}
 
 	if issue != nil {
-		// Update summary if needed.
-		if issue.Fields.Summary != issueSummary {
-			retry, err := r.updateSummary(issue.Key, issueSummary)
+		*issues = append(*issues, projectIssue{key: issue.Key})
+
+		// Update summary if needed, unless disabled to keep edits made by hand.
+		if issue.Fields.Summary != issueSummary && (r.conf.UpdateSummary == nil || *r.conf.UpdateSummary) &&
+			!r.onlyIgnoredChanges(r.conf.Summary, data, issue.Fields.Summary, nil) {
+			retry, err := r.updateSummary(ctx, issue.Key, issueSummary)
 			if err != nil {
 				return retry, err
 			}
 		}
 
-		if issue.Fields.Description != issueDesc {
-			retry, err := r.updateDescription(issue.Key, issueDesc)
+		var wrapDesc func(string) string
+		if r.conf.ManagedDescription != nil && *r.conf.ManagedDescription {
+			wrapDesc = func(rendered string) string { return managedDescription(issue.Fields.Description, rendered) }
+		}
+		desc := issueDesc
+		if wrapDesc != nil {
+			desc = wrapDesc(issueDesc)
+		}
+		if issue.Fields.Description != desc && (r.conf.UpdateDescription == nil || *r.conf.UpdateDescription) &&
+			!r.onlyIgnoredChanges(r.descriptionTemplate(), data, issue.Fields.Description, wrapDesc) {
+			retry, err := r.updateDescription(ctx, issue.Key, desc)
 			if err != nil {
 				return retry, err
 			}
 		}
 
+		if retry, err := r.updateTrackedFields(ctx, issue, data); err != nil {
+			return retry, err
+		}
+		if retry, err := r.updateFields(ctx, issue, data); err != nil {
+			return retry, err
+		}
+
+		status := MappingOpen
+		if issue.Fields.Status != nil && issue.Fields.Status.StatusCategory.Key == "done" {
+			status = MappingResolved
+		}
+		r.recordMapping(data, project, idLabel, issue.Key, status)
+		if status == MappingOpen {
+			if retry, err := r.syncSubtasks(ctx, project, issue.Key, data); err != nil {
+				return retry, err
+			}
+		}
+
 		if len(data.Alerts.Firing()) == 0 {
 			if r.conf.AutoResolve != nil {
 				level.Debug(r.logger).Log("msg", "no firing alert; resolving issue", "key", issue.Key, "label", labels)
-				retry, err := r.resolveIssue(issue.Key)
-				if err != nil {
-					return retry, err
-				}
-				return false, nil
+				return r.autoResolve(ctx, data, project, idLabel, issue.Key, status == MappingOpen)
 			}
 
 			level.Debug(r.logger).Log("msg", "no firing alert; summary checked, nothing else to do.", "key", issue.Key, "label", labels)
 			return false, nil
 		}
 
+		if status == MappingOpen {
+			r.addPartialResolutionComment(ctx, issue.Key, data)
+		}
+
 		// The set of JIRA status categories is fixed, this is a safe check to make.
 		if issue.Fields.Status.StatusCategory.Key != "done" {
 			level.Debug(r.logger).Log("msg", "issue is unresolved, all is done", "key", issue.Key, "label", labels)
@@ -260,23 +455,162 @@ func (r *Receiver) notify(data *alertmanager.Data, hashJiraLabel bool) (bool, er
 			return false, nil
 		}
 
+		if w := r.maintenanceWindow(data); w != "" {
+			level.Info(r.logger).Log("msg", "maintenance window active, not reopening", "key", issue.Key, "label", labels, "window", w)
+			suppressedTotal.WithLabelValues(r.conf.Name, "maintenance").Inc()
+			return false, nil
+		}
+		if ti := r.conf.MutingTimeInterval(r.timeNow()); ti != "" {
+			level.Info(r.logger).Log("msg", "mute time interval active, not reopening", "key", issue.Key, "label", labels, "timeInterval", ti)
+			suppressedTotal.WithLabelValues(r.conf.Name, "mute_time_interval").Inc()
+			return false, nil
+		}
+
+		if resolved := time.Time(issue.Fields.Resolutiondate); r.conf.ReopenDelay != nil && !resolved.IsZero() &&
+			r.timeNow().Before(resolved.Add(time.Duration(*r.conf.ReopenDelay))) {
+			level.Info(r.logger).Log("msg", "issue was resolved less than reopen_delay ago, not reopening yet", "key", issue.Key, "label", labels, "resolution_time", resolved.Format(time.RFC3339), "reopen_delay", *r.conf.ReopenDelay)
+			suppressedTotal.WithLabelValues(r.conf.Name, "reopen_delay").Inc()
+			return false, nil
+		}
+
 		level.Info(r.logger).Log("msg", "issue was recently resolved, reopening", "key", issue.Key, "label", labels)
-		return r.reopen(issue.Key)
+		retry, err := r.reopen(ctx, issue.Key)
+		if err != nil {
+			return retry, err
+		}
+		r.recordMapping(data, project, idLabel, issue.Key, MappingOpen)
+		return r.syncSubtasks(ctx, project, issue.Key, data)
 	}
 
 	if len(data.Alerts.Firing()) == 0 {
+		r.cancelDeferred(idLabel)
 		level.Debug(r.logger).Log("msg", "no firing alert; nothing to do.", "label", labels)
 		return false, nil
 	}
 
+	if r.tooOld(data) {
+		level.Info(r.logger).Log("msg", "all firing alerts started longer than max_alert_age ago, not creating issue", "label", labels)
+		suppressedTotal.WithLabelValues(r.conf.Name, "max_alert_age").Inc()
+		return false, nil
+	}
+
+	if w := r.maintenanceWindow(data); w != "" {
+		level.Info(r.logger).Log("msg", "maintenance window active, not creating issue", "label", labels, "window", w)
+		suppressedTotal.WithLabelValues(r.conf.Name, "maintenance").Inc()
+		return false, nil
+	}
+	if ti := r.conf.MutingTimeInterval(r.timeNow()); ti != "" {
+		level.Info(r.logger).Log("msg", "mute time interval active, not creating issue", "label", labels, "timeInterval", ti)
+		suppressedTotal.WithLabelValues(r.conf.Name, "mute_time_interval").Inc()
+		return false, nil
+	}
+
+	if key := r.inhibitingIssue(idLabel, data); key != "" {
+		suppressedTotal.WithLabelValues(r.conf.Name, "inhibited").Inc()
+		if r.state.listed(key, idLabel) {
+			level.Debug(r.logger).Log("msg", "alert group already listed in inhibiting issue", "key", key, "label", labels)
+			return false, nil
+		}
+		level.Info(r.logger).Log("msg", "alert group is inhibited by an open issue, adding it as comment", "key", key, "label", labels)
+		if retry, err := r.addComment(ctx, key, fmt.Sprintf("%s\n\n%s", issueSummary, issueDesc)); err != nil {
+			return retry, err
+		}
+		r.state.recordListed(key, idLabel)
+		return false, nil
+	}
+
+	if r.holdCreation(idLabel, data) {
+		level.Info(r.logger).Log("msg", "alert group firing for less than min_firing_duration, holding issue creation", "label", labels)
+		return false, nil
+	}
+
+	if r.deferCreation(idLabel, data) {
+		level.Info(r.logger).Log("msg", "outside of business hours, deferring issue creation", "label", labels)
+		return false, nil
+	}
+
+	if key, comment, ok := r.dedupedIssue(project, idLabel); ok {
+		level.Info(r.logger).Log("msg", "alert group is tracked by another receiver of the dedupe group, not creating issue", "key", key, "label", labels, "dedupeGroup", r.conf.DedupeGroup)
+		suppressedTotal.WithLabelValues(r.conf.Name, "dedupe").Inc()
+		if !comment {
+			return false, nil
+		}
+		// Adding watchers is idempotent, so failures retried after it do not add them twice, unlike the comment.
+		if retry, err := r.addDedupeWatchers(ctx, key); err != nil {
+			return retry, err
+		}
+		if retry, err := r.addComment(ctx, key, fmt.Sprintf("This alert group was also sent to receiver %q, which did not create a separate issue.", r.conf.Name)); err != nil {
+			return retry, err
+		}
+		r.dedupeNotified(idLabel)
+		return false, nil
+	}
+
 	level.Info(r.logger).Log("msg", "no recent matching issue found, creating new issue", "label", labels)
 
-	issueType, err := r.tmpl.Execute(r.conf.IssueType, data)
+	issue, err = r.newIssue(ctx, data, project, issueSummary, issueDesc, labels)
+	if err != nil {
+		return false, err
+	}
+
+	if r.aggregatesIncidents(data) {
+		return r.addToOutage(ctx, project, idLabel, issue, data)
+	}
+	if r.throttled(project, idLabel) {
+		return r.addToStorm(ctx, project, idLabel, issue, data)
+	}
+	if err := r.setEpic(ctx, project, issue, data); err != nil {
+		return false, err
+	}
+	// Create only returns the key of the new issue, so remember the assignee.
+	assignee := issue.Fields.Assignee
+	retry, err = r.create(ctx, issue)
+	if err != nil {
+		return retry, err
+	}
+	r.recordCreation(project, idLabel)
+	r.recordPoolAssignment(assignee)
+	r.recordMapping(data, project, idLabel, issue.Key, MappingOpen)
+	*issues = append(*issues, projectIssue{key: issue.Key, created: true})
+	r.linkToIncident(ctx, issue.Key)
+	return r.syncSubtasks(ctx, project, issue.Key, data)
+}
+
+// newIssue renders the issue to create for the given alert group.
+func (r *Receiver) newIssue(ctx context.Context, data *alertmanager.Data, project, issueSummary, issueDesc string, labels []string) (*jira.Issue, error) {
+	issue, err := r.renderIssue(data, project, issueSummary, issueDesc, labels)
 	if err != nil {
-		return false, errors.Wrap(err, "render issue type")
+		return nil, err
+	}
+	if user, ok := r.onCallAssignee(ctx, data); ok {
+		issue.Fields.Assignee = &jira.User{Name: user}
+	}
+	if err := r.setAssetsFields(ctx, issue, data); err != nil {
+		return nil, err
 	}
+	if err := r.setServiceDeskFields(ctx, issue, data); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
 
-	issue = &jira.Issue{
+// renderIssue returns the issue to create for the given alert group in project, rendered from the templates only: the
+// on-call assignee and the fields set from lookups in Assets and Jira Service Management are left to newIssue.
+func (r *Receiver) renderIssue(data *alertmanager.Data, project, issueSummary, issueDesc string, labels []string) (*jira.Issue, error) {
+	issueTypeTmpl := r.conf.IssueType
+	if mapped, ok := r.conf.IssueTypeMapping.Lookup(data.CommonLabels); ok {
+		issueTypeTmpl = mapped
+	}
+	issueType, err := r.execute("issue_type", issueTypeTmpl, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "render issue type")
+	}
+
+	if r.conf.ManagedDescription != nil && *r.conf.ManagedDescription {
+		issueDesc = managedDescription("", issueDesc)
+	}
+
+	issue := &jira.Issue{
 		Fields: &jira.IssueFields{
 			Project:     jira.Project{Key: project},
 			Type:        jira.IssueType{Name: issueType},
@@ -286,27 +620,61 @@ func (r *Receiver) notify(data *alertmanager.Data, hashJiraLabel bool) (bool, er
 			Unknowns:    tcontainer.NewMarshalMap(),
 		},
 	}
+	for field, value := range r.trackedFields(data) {
+		issue.Fields.Unknowns[field] = value
+	}
 	if r.conf.Priority != "" {
-		issuePrio, err := r.tmpl.Execute(r.conf.Priority, data)
+		issuePrio, err := r.execute("priority", r.conf.Priority, data)
 		if err != nil {
-			return false, errors.Wrap(err, "render issue priority")
+			return nil, errors.Wrap(err, "render issue priority")
+		}
+		if issuePrio != "" {
+			issue.Fields.Priority = &jira.Priority{Name: issuePrio}
 		}
+	}
 
-		issue.Fields.Priority = &jira.Priority{Name: issuePrio}
+	assignee, err := r.assignee(data)
+	if err != nil {
+		return nil, err
+	}
+	if assignee != "" {
+		issue.Fields.Assignee = &jira.User{Name: assignee}
+	}
+	if r.conf.Reporter != "" {
+		reporter, err := r.execute("reporter", r.conf.Reporter, data)
+		if err != nil {
+			return nil, errors.Wrap(err, "render issue reporter")
+		}
+		if reporter != "" {
+			issue.Fields.Reporter = &jira.User{Name: reporter}
+		}
 	}
 
 	if len(r.conf.Components) > 0 {
 		issue.Fields.Components = make([]*jira.Component, 0, len(r.conf.Components))
 		for _, component := range r.conf.Components {
-			issueComp, err := r.tmpl.Execute(component, data)
+			issueComp, err := r.execute("components", component, data)
 			if err != nil {
-				return false, errors.Wrap(err, "render issue component")
+				return nil, errors.Wrap(err, "render issue component")
+			}
+			if issueComp == "" {
+				continue
 			}
 
 			issue.Fields.Components = append(issue.Fields.Components, &jira.Component{Name: issueComp})
 		}
 	}
 
+	for _, mapped := range r.conf.ComponentMapping.Lookup(data.CommonLabels) {
+		duplicate := false
+		for _, c := range issue.Fields.Components {
+			duplicate = duplicate || c.Name == mapped
+		}
+		if !duplicate {
+			issue.Fields.Components = append(issue.Fields.Components, &jira.Component{Name: mapped})
+		}
+	}
+
 	if r.conf.AddGroupLabels {
 		for k, v := range data.GroupLabels {
 			issue.Fields.Labels = append(issue.Fields.Labels, fmt.Sprintf("%s=%q", k, v))
@@ -314,13 +682,117 @@ func (r *Receiver) notify(data *alertmanager.Data, hashJiraLabel bool) (bool, er
 	}
 
 	for key, value := range r.conf.Fields {
-		issue.Fields.Unknowns[key], err = deepCopyWithTemplate(value, r.tmpl, data)
+		v, err := r.fieldValue(key, value, data)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			issue.Fields.Unknowns[key] = v
+		}
+	}
+	if err := r.setCascadingFields(issue, data); err != nil {
+		return nil, err
+	}
+	return issue, nil
+}
+
+// projects returns the keys of the projects the issues of the given alert group belong to.
+func (r *Receiver) projects(data *alertmanager.Data) ([]string, error) {
+	projectTmpl := r.conf.Project
+	if mapped, ok := r.conf.ProjectMapping.Lookup(data.CommonLabels); ok {
+		projectTmpl = mapped
+	}
+	project, err := r.execute("project", projectTmpl, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate project from template")
+	}
+	projects := config.SplitProjects(project)
+	if len(projects) == 0 {
+		return nil, errors.Errorf("project template %q rendered no project", projectTmpl)
+	}
+	return projects, nil
+}
+
+// issueLabels returns the labels of the issue tracking the given alert group, including its identifier label.
+func (r *Receiver) issueLabels(data *alertmanager.Data, hashJiraLabel bool) ([]string, string, error) {
+	labels := make([]string, 0)
+
+	if r.conf.AddCommonLabels {
+		for _, pair := range data.CommonLabels.SortedPairs() {
+			labels = append(labels, fmt.Sprintf("%s=%q", pair.Name, pair.Value))
+		}
+	}
+
+	idLabel, err := r.toIssueIdentifierLabel(data, hashJiraLabel)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "build IssueIdentifierLabel")
+	}
+
+	return append(labels, idLabel), idLabel, nil
+}
+
+// Render returns the issues that would be created for the given notification, rendered from the templates only: it
+// neither talks to Jira nor looks up who is on call, so issues are assigned as if nobody was, and leaves the fields set
+// from lookups in Assets and Jira Service Management out. The assignee pool rotation is not advanced.
+func (r *Receiver) Render(data *alertmanager.Data, hashJiraLabel bool) ([]*jira.Issue, error) {
+	r = r.withGroupKey(data.GroupKey)
+	var issues []*jira.Issue
+	for _, d := range r.group(data) {
+		projects, err := r.projects(&d)
+		if err != nil {
+			return nil, err
+		}
+		labels, _, err := r.issueLabels(&d, hashJiraLabel)
 		if err != nil {
-			return false, err
+			return nil, err
+		}
+		issueSummary, err := r.execute("summary", r.conf.Summary, &d)
+		if err != nil {
+			return nil, errors.Wrap(err, "generate summary from template")
+		}
+		issueDesc, err := r.description(&d)
+		if err != nil {
+			return nil, errors.Wrap(err, "render issue description")
+		}
+		for _, project := range projects {
+			issue, err := r.renderIssue(&d, project, issueSummary, issueDesc, labels)
+			if err != nil {
+				return nil, err
+			}
+			issues = append(issues, issue)
 		}
 	}
+	return issues, nil
+}
 
-	return r.create(issue)
+// SearchQueries returns the JQL queries that would be run to find the existing issues of the given notification, one
+// per alert group and project, without talking to Jira.
+func (r *Receiver) SearchQueries(data *alertmanager.Data, hashJiraLabel bool) ([]string, error) {
+	var queries []string
+	for _, d := range r.group(data) {
+		projects, err := r.projects(&d)
+		if err != nil {
+			return nil, err
+		}
+		_, idLabel, err := r.issueLabels(&d, hashJiraLabel)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			queries = append(queries, searchQuery(project, idLabel).JQL())
+		}
+	}
+	return queries, nil
+}
+
+// maintenanceWindow returns the name of the first maintenance window active for data, if any.
+func (r *Receiver) maintenanceWindow(data *alertmanager.Data) string {
+	for _, w := range r.conf.MaintenanceWindows {
+		if w.Active(r.timeNow(), data.CommonLabels) {
+			return w.Name
+		}
+	}
+	return ""
 }
 
 // deepCopyWithTemplate returns a deep copy of a map/slice/array/string/int/bool or combination thereof, executing the
@@ -335,7 +807,7 @@ func deepCopyWithTemplate(value interface{}, tmpl *template.Template, data inter
 	switch valueMeta.Kind() {
 
 	case reflect.String:
-		return tmpl.Execute(value.(string), data)
+		return tmpl.ExecuteValue(value.(string), data)
 
 	case reflect.Array, reflect.Slice:
 		arrayLen := valueMeta.Len()
@@ -381,7 +853,7 @@ func (r *Receiver) toIssueIdentifierLabel(data *alertmanager.Data, hashJiraLabel
 		return toGroupTicketLabel(data.GroupLabels, hashJiraLabel), nil
 	}
 
-	label, err := r.tmpl.Execute(r.conf.IssueIdentifierLabel, data)
+	label, err := r.execute("issue_identifier_label", r.conf.IssueIdentifierLabel, data)
 	if err != nil {
 		return "", err
 	}
@@ -419,36 +891,47 @@ func toGroupTicketLabel(labels alertmanager.KV, hashJiraLabel bool) string {
 	return strings.Replace(buf.String(), " ", "", -1)
 }
 
-func (r *Receiver) search(project, issueLabel string) (*jira.Issue, bool, error) {
-	query := fmt.Sprintf("project=\"%s\" and labels=%q order by resolutiondate desc", project, issueLabel)
+func (r *Receiver) search(ctx context.Context, project, issueLabel string) (*jira.Issue, bool, error) {
+	query := searchQuery(project, issueLabel)
 	options := &jira.SearchOptions{
 		Fields:     []string{"summary", "status", "resolution", "resolutiondate"},
 		MaxResults: 2,
 	}
+	options.Fields = append(options.Fields, r.trackedFieldNames()...)
+	options.Fields = append(options.Fields, r.updatedFieldNames()...)
+	if r.conf.ManagedDescription != nil && *r.conf.ManagedDescription || r.conf.DiffIgnore != nil {
+		// The text outside of the managed section must be kept, and diff_ignore compares the whole description.
+		options.Fields = append(options.Fields, "description")
+	}
 
-	level.Debug(r.logger).Log("msg", "search", "query", query, "options", fmt.Sprintf("%+v", options))
-	issues, resp, err := r.client.Search(query, options)
+	level.Debug(r.logger).Log("msg", "search", "query", query.JQL(), "options", fmt.Sprintf("%+v", options))
+	issues, resp, err := r.client.SearchWithContext(ctx, query, options)
 	if err != nil {
 		retry, err := handleJiraErrResponse("Issue.Search", resp, err, r.logger)
 		return nil, retry, err
 	}
 
 	if len(issues) == 0 {
-		level.Debug(r.logger).Log("msg", "no results", "query", query)
+		level.Debug(r.logger).Log("msg", "no results", "query", query.JQL())
 		return nil, false, nil
 	}
 
 	issue := issues[0]
 	if len(issues) > 1 {
-		level.Warn(r.logger).Log("msg", "more than one issue matched, picking most recently resolved", "query", query, "issues", issues, "picked", issue)
+		level.Warn(r.logger).Log("msg", "more than one issue matched, picking most recently resolved", "query", query.JQL(), "issues", issues, "picked", issue)
 	}
 
-	level.Debug(r.logger).Log("msg", "found", "issue", issue, "query", query)
+	level.Debug(r.logger).Log("msg", "found", "issue", issue, "query", query.JQL())
 	return &issue, false, nil
 }
 
-func (r *Receiver) findIssueToReuse(project string, issueGroupLabel string) (*jira.Issue, bool, error) {
-	issue, retry, err := r.search(project, issueGroupLabel)
+// searchQuery returns the query finding the issues of an alert group in project, most recently resolved first.
+func searchQuery(project, issueLabel string) Query {
+	return Query{Project: project, Label: issueLabel, OrderByResolutionDate: true}
+}
+
+func (r *Receiver) findIssueToReuse(ctx context.Context, project string, issueGroupLabel string) (*jira.Issue, bool, error) {
+	issue, retry, err := r.search(ctx, project, issueGroupLabel)
 	if err != nil {
 		return nil, retry, err
 	}
@@ -463,11 +946,45 @@ func (r *Receiver) findIssueToReuse(project string, issueGroupLabel string) (*ji
 		return nil, false, nil
 	}
 
+	if resolutionTime != (time.Time{}) && r.conf.WontFixDuration != nil && *r.conf.WontFixDuration != 0 &&
+		issue.Fields.Resolution != nil && issue.Fields.Resolution.Name == r.conf.WontFixResolution &&
+		resolutionTime.Add(time.Duration(*r.conf.WontFixDuration)).Before(r.timeNow()) {
+		level.Debug(r.logger).Log("msg", "existing issue was resolved as won't fix too long ago, skipping", "key", issue.Key, "label", issueGroupLabel, "resolution_time", resolutionTime.Format(time.RFC3339), "wont_fix_duration", *r.conf.WontFixDuration)
+		return nil, false, nil
+	}
+
 	// Reuse issue.
 	return issue, false, nil
 }
 
-func (r *Receiver) updateSummary(issueKey string, summary string) (bool, error) {
+// updateOptions returns the query options of the issue edits JIRAlert makes, or nil for Jira's defaults. Options
+// that are set always carry NotifyUsers, so false means notifyUsers=false rather than Jira's default.
+func (r *Receiver) updateOptions() *jira.UpdateQueryOptions {
+	if r.conf.NotifyUsers == nil && r.conf.UpdateOptions == nil {
+		return nil
+	}
+	opts := &jira.UpdateQueryOptions{NotifyUsers: r.conf.NotifyUsers == nil || *r.conf.NotifyUsers}
+	if o := r.conf.UpdateOptions; o != nil {
+		opts.OverrideScreenSecurity = o.OverrideScreenSecurity
+		opts.OverrideEditableFlag = o.OverrideEditableFlag
+	}
+	return opts
+}
+
+// editFields sets the given fields of an existing issue, with the receiver's update options.
+func (r *Receiver) editFields(ctx context.Context, issueKey string, fields map[string]interface{}) (bool, error) {
+	update := &jira.Issue{
+		Key:    issueKey,
+		Fields: &jira.IssueFields{Unknowns: tcontainer.MarshalMap(fields)},
+	}
+	_, resp, err := r.client.UpdateWithOptionsWithContext(ctx, update, r.updateOptions())
+	if err != nil {
+		return handleJiraErrResponse("Issue.UpdateWithOptions", resp, err, r.logger)
+	}
+	return false, nil
+}
+
+func (r *Receiver) updateSummary(ctx context.Context, issueKey string, summary string) (bool, error) {
 	level.Debug(r.logger).Log("msg", "updating issue with new summary", "key", issueKey, "summary", summary)
 
 	issueUpdate := &jira.Issue{
@@ -476,7 +993,7 @@ func (r *Receiver) updateSummary(issueKey string, summary string) (bool, error)
 			Summary: summary,
 		},
 	}
-	issue, resp, err := r.client.UpdateWithOptions(issueUpdate, nil)
+	issue, resp, err := r.client.UpdateWithOptionsWithContext(ctx, issueUpdate, r.updateOptions())
 	if err != nil {
 		return handleJiraErrResponse("Issue.UpdateWithOptions", resp, err, r.logger)
 	}
@@ -484,7 +1001,7 @@ func (r *Receiver) updateSummary(issueKey string, summary string) (bool, error)
 	return false, nil
 }
 
-func (r *Receiver) updateDescription(issueKey string, description string) (bool, error) {
+func (r *Receiver) updateDescription(ctx context.Context, issueKey string, description string) (bool, error) {
 	level.Debug(r.logger).Log("msg", "updating issue with new description", "key", issueKey, "description", description)
 
 	issueUpdate := &jira.Issue{
@@ -493,7 +1010,7 @@ func (r *Receiver) updateDescription(issueKey string, description string) (bool,
 			Description: description,
 		},
 	}
-	issue, resp, err := r.client.UpdateWithOptions(issueUpdate, nil)
+	issue, resp, err := r.client.UpdateWithOptionsWithContext(ctx, issueUpdate, r.updateOptions())
 	if err != nil {
 		return handleJiraErrResponse("Issue.UpdateWithOptions", resp, err, r.logger)
 	}
@@ -501,13 +1018,36 @@ func (r *Receiver) updateDescription(issueKey string, description string) (bool,
 	return false, nil
 }
 
-func (r *Receiver) reopen(issueKey string) (bool, error) {
-	return r.doTransition(issueKey, r.conf.ReopenState)
+// Markers of the description section managed by JIRAlert when managed_description is enabled.
+const (
+	descriptionSectionStart = "---- JIRAlert status, updated automatically (edit outside of this section) ----"
+	descriptionSectionEnd   = "---- End of JIRAlert status ----"
+)
+
+// managedDescription returns the description with its managed section replaced by the rendered description, keeping
+// everything outside of the markers. The section is appended if the description has none, e.g. because a human
+// removed it.
+func managedDescription(description, rendered string) string {
+	section := descriptionSectionStart + "\n" + rendered + "\n" + descriptionSectionEnd
+	start := strings.Index(description, descriptionSectionStart)
+	end := strings.Index(description, descriptionSectionEnd)
+	if start < 0 || end < start {
+		if description == "" {
+			return section
+		}
+		return description + "\n\n" + section
+	}
+	return description[:start] + section + description[end+len(descriptionSectionEnd):]
+}
+
+func (r *Receiver) reopen(ctx context.Context, issueKey string) (bool, error) {
+	return r.doTransition(ctx, issueKey, r.conf.ReopenState)
 }
 
-func (r *Receiver) create(issue *jira.Issue) (bool, error) {
+func (r *Receiver) create(ctx context.Context, issue *jira.Issue) (bool, error) {
+	r.fillRequiredFields(ctx, issue)
 	level.Debug(r.logger).Log("msg", "create", "issue", fmt.Sprintf("%+v", *issue.Fields))
-	newIssue, resp, err := r.client.Create(issue)
+	newIssue, resp, err := r.client.CreateWithContext(ctx, issue)
 	if err != nil {
 		return handleJiraErrResponse("Issue.Create", resp, err, r.logger)
 	}
@@ -517,6 +1057,42 @@ func (r *Receiver) create(issue *jira.Issue) (bool, error) {
 	return false, nil
 }
 
+func (r *Receiver) addComment(ctx context.Context, issueKey string, body string) (bool, error) {
+	level.Debug(r.logger).Log("msg", "adding comment", "key", issueKey, "body", body)
+	comment, resp, err := r.client.AddCommentWithContext(ctx, issueKey, &jira.Comment{Body: body})
+	if err != nil {
+		return handleJiraErrResponse("Issue.AddComment", resp, err, r.logger)
+	}
+	level.Debug(r.logger).Log("msg", "comment added", "key", issueKey, "id", comment.ID)
+	return false, nil
+}
+
+// APIError is the error of an issue tracker request that returned an unsuccessful status. Callers can tell the
+// failed requests apart with errors.As, whereas transport errors, including the cancellation of the request's
+// context, are wrapped as is.
+type APIError struct {
+	// API is the request that failed, e.g. Issue.Create.
+	API        string
+	URL        string
+	StatusCode int
+	Status     string
+	// Message are the error messages of the response body, see jiraErrorMessage. If empty, Body is the raw body.
+	Message string
+	Body    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("JIRA request %s returned status %s: %s", e.URL, e.Status, e.Message)
+	}
+	return fmt.Sprintf("JIRA request %s returned status %s, body %q", e.URL, e.Status, e.Body)
+}
+
+// Retryable reports whether the request may succeed when retried, the tracker being unavailable.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusInternalServerError || e.StatusCode == http.StatusServiceUnavailable
+}
+
 func handleJiraErrResponse(api string, resp *jira.Response, err error, logger log.Logger) (bool, error) {
 	if resp == nil || resp.Request == nil {
 		level.Debug(logger).Log("msg", "handleJiraErrResponse", "api", api, "err", err)
@@ -525,20 +1101,90 @@ func handleJiraErrResponse(api string, resp *jira.Response, err error, logger lo
 	}
 
 	if resp != nil && resp.StatusCode/100 != 2 {
-		retry := resp.StatusCode == 500 || resp.StatusCode == 503
 		body, _ := io.ReadAll(resp.Body)
+		apiErr := &APIError{API: api, StatusCode: resp.StatusCode, Status: resp.Status}
+		if resp.Request != nil {
+			apiErr.URL = resp.Request.URL.String()
+		}
 		// go-jira error message is not particularly helpful, replace it
-		return retry, errors.Errorf("JIRA request %s returned status %s, body %q", resp.Request.URL, resp.Status, string(body))
+		if msg, ok := jiraErrorMessage(body); ok {
+			apiErr.Message = msg
+		} else {
+			apiErr.Body = string(body)
+		}
+		return apiErr.Retryable(), apiErr
 	}
 	return false, errors.Wrapf(err, "JIRA request %s failed", api)
 }
 
-func (r *Receiver) resolveIssue(issueKey string) (bool, error) {
-	return r.doTransition(issueKey, r.conf.AutoResolve.State)
+// jiraErrorMessage returns the messages of a Jira error response body, e.g.
+// `{"errorMessages": [], "errors": {"customfield_10010": "Field is required"}}`, as
+// "customfield_10010: Field is required". Field errors are sorted by field.
+func jiraErrorMessage(body []byte) (string, bool) {
+	var res struct {
+		ErrorMessages []string          `json:"errorMessages"`
+		Errors        map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", false
+	}
+	msgs := make([]string, 0, len(res.ErrorMessages)+len(res.Errors))
+	msgs = append(msgs, res.ErrorMessages...)
+	fields := make([]string, 0, len(res.Errors))
+	for f := range res.Errors {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f, res.Errors[f]))
+	}
+	if len(msgs) == 0 {
+		return "", false
+	}
+	return strings.Join(msgs, "; "), true
+}
+
+// addResolvedComment comments on an issue that was just resolved. The issue is resolved already, so failures are only
+// logged instead of having Alertmanager retry the notification.
+func (r *Receiver) addResolvedComment(ctx context.Context, issueKey string, data *alertmanager.Data) {
+	body, err := r.tmpl.Execute(r.conf.AutoResolve.Comment, data)
+	if err == nil {
+		_, err = r.addComment(ctx, issueKey, body)
+	}
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to comment on resolved issue", "key", issueKey, "err", err)
+	}
+}
+
+// autoResolve resolves the issue of the alert group with the given identifier label in project, as auto_resolve
+// does. The resolution fields, comment and epic are only updated if the issue was open.
+func (r *Receiver) autoResolve(ctx context.Context, data *alertmanager.Data, project, idLabel, issueKey string, open bool) (bool, error) {
+	if open {
+		// Before resolving, as workflows may not allow editing resolved issues.
+		if retry, err := r.updateResolutionFields(ctx, issueKey, data); err != nil {
+			return retry, err
+		}
+	}
+	retry, err := r.resolveIssue(ctx, issueKey)
+	if err != nil {
+		return retry, err
+	}
+	r.recordMapping(data, project, idLabel, issueKey, MappingResolved)
+	if open && r.conf.AutoResolve.Comment != "" {
+		r.addResolvedComment(ctx, issueKey, data)
+	}
+	if open {
+		r.resolveEpic(ctx, project, data)
+	}
+	return false, nil
+}
+
+func (r *Receiver) resolveIssue(ctx context.Context, issueKey string) (bool, error) {
+	return r.doTransition(ctx, issueKey, r.conf.AutoResolve.State)
 }
 
-func (r *Receiver) doTransition(issueKey string, transitionState string) (bool, error) {
-	transitions, resp, err := r.client.GetTransitions(issueKey)
+func (r *Receiver) doTransition(ctx context.Context, issueKey string, transitionState string) (bool, error) {
+	transitions, resp, err := r.client.GetTransitionsWithContext(ctx, issueKey)
 	if err != nil {
 		return handleJiraErrResponse("Issue.GetTransitions", resp, err, r.logger)
 	}
@@ -546,7 +1192,7 @@ func (r *Receiver) doTransition(issueKey string, transitionState string) (bool,
 	for _, t := range transitions {
 		if t.Name == transitionState {
 			level.Debug(r.logger).Log("msg", fmt.Sprintf("transition %s", transitionState), "key", issueKey, "transitionID", t.ID)
-			resp, err = r.client.DoTransition(issueKey, t.ID)
+			resp, err = r.client.DoTransitionWithContext(ctx, issueKey, t.ID)
 			if err != nil {
 				return handleJiraErrResponse("Issue.DoTransition", resp, err, r.logger)
 			}
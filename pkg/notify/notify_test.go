@@ -13,9 +13,18 @@
 package notify
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,7 +37,11 @@ import (
 	"github.com/prometheus-community/jiralert/pkg/alertmanager"
 	"github.com/prometheus-community/jiralert/pkg/config"
 	"github.com/prometheus-community/jiralert/pkg/template"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
 )
 
 func TestToGroupTicketLabel(t *testing.T) {
@@ -36,12 +49,36 @@ func TestToGroupTicketLabel(t *testing.T) {
 	require.Equal(t, `ALERT{C="d",a="B"}`, toGroupTicketLabel(alertmanager.KV{"a": "B", "C": "d"}, false))
 }
 
+func TestQueryJQL(t *testing.T) {
+	for _, tc := range []struct {
+		query Query
+		want  string
+	}{
+		{query: searchQuery("AB", "ALERT{a=\"b\"}"), want: `project="AB" and labels="ALERT{a=\"b\"}" order by resolutiondate desc`},
+		{query: Query{Project: "AB", Label: StormLabel, Unresolved: true}, want: `project="AB" and labels="` + StormLabel + `" and statusCategory != Done`},
+		{query: Query{Project: "AB", Unresolved: true, NotUpdatedFor: 2 * time.Hour}, want: `project="AB" and statusCategory != Done and updated <= "-120m"`},
+		{query: Query{Project: "AB", Unresolved: true, Parent: "AB-1"}, want: `project="AB" and statusCategory != Done and parent = "AB-1"`},
+		{query: Query{Project: "AB", Unresolved: true, Parent: "AB-1", ParentField: "customfield_10001"}, want: `project="AB" and statusCategory != Done and cf[10001] = "AB-1"`},
+	} {
+		require.Equal(t, tc.want, tc.query.JQL())
+	}
+}
+
 type fakeJira struct {
 	// Key = ID for simplification.
 	issuesByKey map[string]*jira.Issue
 	keysByQuery map[string][]string
 
 	transitionsByID map[string]jira.Transition
+	commentsByKey   map[string][]string
+	// Keys of the outward linked issues, by issue key.
+	linksByKey map[string][]string
+	// Users watching the issues, by issue key.
+	watchersByKey map[string][]string
+	// Create metadata of all projects, if any.
+	createMeta *jira.CreateMetaInfo
+	// Options of the last update made with UpdateWithOptions.
+	updateOptions *jira.UpdateQueryOptions
 }
 
 func newTestFakeJira() *fakeJira {
@@ -49,17 +86,69 @@ func newTestFakeJira() *fakeJira {
 		issuesByKey:     map[string]*jira.Issue{},
 		transitionsByID: map[string]jira.Transition{"1234": {ID: "1234", Name: "Done"}},
 		keysByQuery:     map[string][]string{},
+		commentsByKey:   map[string][]string{},
+		linksByKey:      map[string][]string{},
+		watchersByKey:   map[string][]string{},
+	}
+}
+
+func (f *fakeJira) AddWatcherWithContext(_ context.Context, issueID string, userName string) (*jira.Response, error) {
+	if _, ok := f.issuesByKey[issueID]; !ok {
+		return nil, errors.Errorf("no such issue %s", issueID)
+	}
+	for _, w := range f.watchersByKey[issueID] {
+		if w == userName {
+			return nil, nil
+		}
+	}
+	f.watchersByKey[issueID] = append(f.watchersByKey[issueID], userName)
+	return nil, nil
+}
+
+func (f *fakeJira) AddCommentWithContext(_ context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	if _, ok := f.issuesByKey[issueID]; !ok {
+		return nil, nil, errors.Errorf("no such issue %s", issueID)
+	}
+	f.commentsByKey[issueID] = append(f.commentsByKey[issueID], comment.Body)
+	return &jira.Comment{ID: fmt.Sprintf("%d", len(f.commentsByKey[issueID])), Body: comment.Body}, nil, nil
+}
+
+// openIssueKeys returns the keys of all unresolved issues when query is a query for open issues of a project. Any
+// additional conditions but a parent are ignored.
+func (f *fakeJira) openIssueKeys(query Query) ([]string, bool) {
+	if !query.Unresolved || query.Label != "" {
+		return nil, false
+	}
+	var keys []string
+	for key, issue := range f.issuesByKey {
+		if query.Parent != "" && (issue.Fields.Parent == nil || issue.Fields.Parent.Key != query.Parent) {
+			continue
+		}
+		if issue.Fields.Project.Key == query.Project && !strings.EqualFold(issue.Fields.Status.StatusCategory.Key, "done") {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
+	return keys, true
 }
 
-func (f *fakeJira) Search(jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+func (f *fakeJira) SearchWithContext(_ context.Context, query Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	keys, ok := f.openIssueKeys(query)
+	if !ok {
+		keys = f.keysByQuery[query.JQL()]
+	}
+
 	var issues []jira.Issue
-	for _, key := range f.keysByQuery[jql] {
+	for _, key := range keys {
 		issue := jira.Issue{Key: key, Fields: &jira.IssueFields{}}
 		for _, field := range options.Fields {
 			switch field {
 			case "summary":
 				issue.Fields.Summary = f.issuesByKey[key].Fields.Summary
+			case "labels":
+				issue.Fields.Labels = f.issuesByKey[key].Fields.Labels
+			case "description":
+				issue.Fields.Description = f.issuesByKey[key].Fields.Description
 			case "resolution":
 				if f.issuesByKey[key].Fields.Resolution == nil {
 					continue
@@ -73,6 +162,19 @@ func (f *fakeJira) Search(jql string, options *jira.SearchOptions) ([]jira.Issue
 				issue.Fields.Status = &jira.Status{
 					StatusCategory: f.issuesByKey[key].Fields.Status.StatusCategory,
 				}
+			default:
+				v, ok := f.issuesByKey[key].Fields.Unknowns[field]
+				if !ok {
+					continue
+				}
+				// Like Jira, return numbers as decoded from JSON.
+				if i, ok := v.(int); ok {
+					v = float64(i)
+				}
+				if issue.Fields.Unknowns == nil {
+					issue.Fields.Unknowns = tcontainer.MarshalMap{}
+				}
+				issue.Fields.Unknowns[field] = v
 			}
 		}
 		issues = append(issues, issue)
@@ -89,7 +191,7 @@ func (f *fakeJira) Search(jql string, options *jira.SearchOptions) ([]jira.Issue
 	return issues, nil, nil
 }
 
-func (f *fakeJira) GetTransitions(_ string) ([]jira.Transition, *jira.Response, error) {
+func (f *fakeJira) GetTransitionsWithContext(_ context.Context, _ string) ([]jira.Transition, *jira.Response, error) {
 	var trs []jira.Transition
 	for _, tr := range f.transitionsByID {
 		trs = append(trs, tr)
@@ -97,13 +199,16 @@ func (f *fakeJira) GetTransitions(_ string) ([]jira.Transition, *jira.Response,
 	return trs, nil, nil
 }
 
-func (f *fakeJira) Create(issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+func (f *fakeJira) CreateWithContext(_ context.Context, newIssue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	issue := *newIssue
+	fields := *newIssue.Fields
+	issue.Fields = &fields
 	issue.Key = fmt.Sprintf("%d", len(f.issuesByKey)+1)
 	issue.ID = issue.Key
 	issue.Fields.Status = &jira.Status{
 		StatusCategory: jira.StatusCategory{Key: "NotDone"},
 	}
-	f.issuesByKey[issue.Key] = issue
+	f.issuesByKey[issue.Key] = &issue
 
 	// Assuming single label.
 	query := fmt.Sprintf(
@@ -113,14 +218,20 @@ func (f *fakeJira) Create(issue *jira.Issue) (*jira.Issue, *jira.Response, error
 	)
 	f.keysByQuery[query] = append(f.keysByQuery[query], issue.Key)
 
-	return issue, nil, nil
+	// Like Jira, only return the identifiers of the created issue.
+	return &jira.Issue{ID: issue.ID, Key: issue.Key}, nil, nil
 }
 
-func (f *fakeJira) UpdateWithOptions(old *jira.Issue, _ *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+func (f *fakeJira) UpdateWithOptionsWithContext(_ context.Context, old *jira.Issue, opts *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
 	issue, ok := f.issuesByKey[old.Key]
 	if !ok {
 		return nil, nil, errors.Errorf("no such issue %s", old.Key)
 	}
+	f.updateOptions = opts
+
+	for k, v := range old.Fields.Unknowns {
+		issue.Fields.Unknowns[k] = v
+	}
 
 	if old.Fields.Summary != "" {
 		issue.Fields.Summary = old.Fields.Summary
@@ -134,7 +245,59 @@ func (f *fakeJira) UpdateWithOptions(old *jira.Issue, _ *jira.UpdateQueryOptions
 	return issue, nil, nil
 }
 
-func (f *fakeJira) DoTransition(ticketID, transitionID string) (*jira.Response, error) {
+func (f *fakeJira) GetCreateMetaWithOptionsWithContext(_ context.Context, _ *jira.GetQueryOptions) (*jira.CreateMetaInfo, *jira.Response, error) {
+	if f.createMeta == nil {
+		return nil, nil, errors.New("no create metadata")
+	}
+	return f.createMeta, nil, nil
+}
+
+func (f *fakeJira) UpdateIssueWithContext(_ context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	issue, ok := f.issuesByKey[jiraID]
+	if !ok {
+		return nil, errors.Errorf("no such issue %s", jiraID)
+	}
+
+	fields, _ := data["fields"].(map[string]interface{})
+	for k, v := range fields {
+		issue.Fields.Unknowns[k] = v
+	}
+
+	update, _ := data["update"].(map[string]interface{})
+	ops, _ := update["labels"].([]map[string]string)
+	for _, op := range ops {
+		if l, ok := op["add"]; ok {
+			issue.Fields.Labels = append(issue.Fields.Labels, l)
+			query := fmt.Sprintf("project=\"%s\" and labels=%q order by resolutiondate desc", issue.Fields.Project.Key, l)
+			f.keysByQuery[query] = append(f.keysByQuery[query], issue.Key)
+		}
+		if l, ok := op["remove"]; ok {
+			for i, existing := range issue.Fields.Labels {
+				if existing == l {
+					issue.Fields.Labels = append(issue.Fields.Labels[:i], issue.Fields.Labels[i+1:]...)
+					break
+				}
+			}
+			query := fmt.Sprintf("project=\"%s\" and labels=%q order by resolutiondate desc", issue.Fields.Project.Key, l)
+			for i, key := range f.keysByQuery[query] {
+				if key == issue.Key {
+					f.keysByQuery[query] = append(f.keysByQuery[query][:i], f.keysByQuery[query][i+1:]...)
+					break
+				}
+			}
+		}
+	}
+
+	links, _ := update["issuelinks"].([]map[string]interface{})
+	for _, op := range links {
+		add, _ := op["add"].(map[string]interface{})
+		outward, _ := add["outwardIssue"].(map[string]interface{})
+		f.linksByKey[issue.Key] = append(f.linksByKey[issue.Key], outward["key"].(string))
+	}
+	return nil, nil
+}
+
+func (f *fakeJira) DoTransitionWithContext(_ context.Context, ticketID, transitionID string) (*jira.Response, error) {
 	issue, ok := f.issuesByKey[ticketID]
 	if !ok {
 		return nil, errors.Errorf("no such issue %s", ticketID)
@@ -247,7 +410,7 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 			inputConfig: testReceiverConfig1(),
 			initJira: func(t *testing.T) *fakeJira {
 				f := newTestFakeJira()
-				_, _, err := f.Create(&jira.Issue{
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
 					ID:  "1",
 					Key: "1",
 					Fields: &jira.IssueFields{
@@ -289,7 +452,7 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 			inputConfig: testReceiverConfig2(),
 			initJira: func(t *testing.T) *fakeJira {
 				f := newTestFakeJira()
-				_, _, err := f.Create(&jira.Issue{
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
 					ID:  "1",
 					Key: "1",
 					Fields: &jira.IssueFields{
@@ -333,7 +496,7 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 			inputConfig: testReceiverConfig1(),
 			initJira: func(t *testing.T) *fakeJira {
 				f := newTestFakeJira()
-				_, _, err := f.Create(&jira.Issue{
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
 					ID:  "1",
 					Key: "1",
 					Fields: &jira.IssueFields{
@@ -388,7 +551,7 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 			inputConfig: testReceiverConfig1(),
 			initJira: func(t *testing.T) *fakeJira {
 				f := newTestFakeJira()
-				_, _, err := f.Create(&jira.Issue{
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
 					ID:  "1",
 					Key: "1",
 					Fields: &jira.IssueFields{
@@ -438,12 +601,72 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "closed ticket, resolved within reopen delay, update summary",
+			inputConfig: func() *config.ReceiverConfig {
+				c := testReceiverConfig1()
+				delay := config.Duration(45 * time.Minute)
+				c.ReopenDelay = &delay
+				return c
+			}(),
+			initJira: func(t *testing.T) *fakeJira {
+				f := newTestFakeJira()
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
+					ID:  "1",
+					Key: "1",
+					Fields: &jira.IssueFields{
+						Project:  jira.Project{Key: testReceiverConfig1().Project},
+						Labels:   []string{"JIRALERT{819ba5ecba4ea5946a8d17d285cb23f3bb6862e08bb602ab08fd231cd8e1a83a1d095b0208a661787e9035f0541817634df5a994d1b5d4200d6c68a7663c97f5}"},
+						Unknowns: tcontainer.MarshalMap{},
+						Summary:  "[FIRING:2] b d ",
+						Resolution: &jira.Resolution{
+							Name: "done",
+						},
+					},
+				})
+				// Close it.
+				f.issuesByKey["1"].Fields.Status.StatusCategory.Key = "done"
+				// Resolution time that fits into 1h reopen duration, but not past the 45m reopen delay.
+				f.issuesByKey["1"].Fields.Resolutiondate = jira.Time(testNowTime.Add(-30 * time.Minute))
+				f.transitionsByID["tr1"] = jira.Transition{ID: "tr1", Name: testReceiverConfig1().ReopenState}
+
+				require.NoError(t, err)
+				return f
+			},
+			inputAlert: &alertmanager.Data{
+				Alerts: alertmanager.Alerts{
+					{Status: "not firing"},
+					{Status: alertmanager.AlertFiring}, // Only one firing now.
+				},
+				Status:      alertmanager.AlertFiring,
+				GroupLabels: alertmanager.KV{"a": "b", "c": "d"},
+			},
+			expectedJiraIssues: map[string]*jira.Issue{
+				"1": {
+					ID:  "1",
+					Key: "1",
+					Fields: &jira.IssueFields{
+						Project: jira.Project{Key: testReceiverConfig1().Project},
+						Labels:  []string{"JIRALERT{819ba5ecba4ea5946a8d17d285cb23f3bb6862e08bb602ab08fd231cd8e1a83a1d095b0208a661787e9035f0541817634df5a994d1b5d4200d6c68a7663c97f5}"},
+						Status: &jira.Status{
+							StatusCategory: jira.StatusCategory{Key: "done"}, // Not reopened.
+						},
+						Unknowns: tcontainer.MarshalMap{},
+						Summary:  "[FIRING:1] b d ", // Title changed.
+						Resolution: &jira.Resolution{
+							Name: "done",
+						},
+						Resolutiondate: jira.Time(testNowTime.Add(-30 * time.Minute)),
+					},
+				},
+			},
+		},
 		{
 			name:        "closed ticket, reopen time exceeded, create and update summary",
 			inputConfig: testReceiverConfig1(),
 			initJira: func(t *testing.T) *fakeJira {
 				f := newTestFakeJira()
-				_, _, err := f.Create(&jira.Issue{
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
 					ID:  "1",
 					Key: "1",
 					Fields: &jira.IssueFields{
@@ -508,6 +731,77 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "closed won't fix ticket, won't fix duration exceeded, create",
+			inputConfig: func() *config.ReceiverConfig {
+				c := testReceiverConfig1()
+				wontFix := config.Duration(10 * time.Minute)
+				c.WontFixDuration = &wontFix
+				return c
+			}(),
+			initJira: func(t *testing.T) *fakeJira {
+				f := newTestFakeJira()
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
+					ID:  "1",
+					Key: "1",
+					Fields: &jira.IssueFields{
+						Project:  jira.Project{Key: testReceiverConfig1().Project},
+						Labels:   []string{"JIRALERT{819ba5ecba4ea5946a8d17d285cb23f3bb6862e08bb602ab08fd231cd8e1a83a1d095b0208a661787e9035f0541817634df5a994d1b5d4200d6c68a7663c97f5}"},
+						Unknowns: tcontainer.MarshalMap{},
+						Summary:  "[FIRING:2] b d ",
+						Resolution: &jira.Resolution{
+							Name: testReceiverConfig1().WontFixResolution,
+						},
+					},
+				})
+				// Close it.
+				f.issuesByKey["1"].Fields.Status.StatusCategory.Key = "done"
+				// Resolution time that fits into 1h reopen duration, but not into the 10m won't fix duration.
+				f.issuesByKey["1"].Fields.Resolutiondate = jira.Time(testNowTime.Add(-30 * time.Minute))
+
+				require.NoError(t, err)
+				return f
+			},
+			inputAlert: &alertmanager.Data{
+				Alerts: alertmanager.Alerts{
+					{Status: alertmanager.AlertFiring},
+				},
+				Status:      alertmanager.AlertFiring,
+				GroupLabels: alertmanager.KV{"a": "b", "c": "d"},
+			},
+			expectedJiraIssues: map[string]*jira.Issue{
+				"1": {
+					ID:  "1",
+					Key: "1",
+					Fields: &jira.IssueFields{
+						Project: jira.Project{Key: testReceiverConfig1().Project},
+						Labels:  []string{"JIRALERT{819ba5ecba4ea5946a8d17d285cb23f3bb6862e08bb602ab08fd231cd8e1a83a1d095b0208a661787e9035f0541817634df5a994d1b5d4200d6c68a7663c97f5}"},
+						Status: &jira.Status{
+							StatusCategory: jira.StatusCategory{Key: "done"},
+						},
+						Unknowns: tcontainer.MarshalMap{},
+						Summary:  "[FIRING:2] b d ",
+						Resolution: &jira.Resolution{
+							Name: testReceiverConfig1().WontFixResolution,
+						},
+						Resolutiondate: jira.Time(testNowTime.Add(-30 * time.Minute)),
+					},
+				},
+				"2": {
+					ID:  "2",
+					Key: "2",
+					Fields: &jira.IssueFields{
+						Project: jira.Project{Key: testReceiverConfig1().Project},
+						Labels:  []string{"JIRALERT{819ba5ecba4ea5946a8d17d285cb23f3bb6862e08bb602ab08fd231cd8e1a83a1d095b0208a661787e9035f0541817634df5a994d1b5d4200d6c68a7663c97f5}"},
+						Status: &jira.Status{
+							StatusCategory: jira.StatusCategory{Key: "NotDone"}, // Created
+						},
+						Unknowns: tcontainer.MarshalMap{},
+						Summary:  "[FIRING:1] b d ",
+					},
+				},
+			},
+		},
 		{
 			name:        "auto resolve alert",
 			inputConfig: testReceiverConfigAutoResolve(),
@@ -520,7 +814,7 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 			},
 			initJira: func(t *testing.T) *fakeJira {
 				f := newTestFakeJira()
-				_, _, err := f.Create(&jira.Issue{
+				_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
 					ID:  "1",
 					Key: "1",
 					Fields: &jira.IssueFields{
@@ -551,6 +845,25 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "maintenance window active, no issue created",
+			inputConfig: func() *config.ReceiverConfig {
+				c := testReceiverConfig1()
+				starts, ends := testNowTime.Add(-time.Hour), testNowTime.Add(time.Hour)
+				c.MaintenanceWindows = []*config.MaintenanceWindow{{Name: "upgrade", StartsAt: &starts, EndsAt: &ends, Matchers: map[string]string{"a": "b"}}}
+				return c
+			}(),
+			initJira: func(t *testing.T) *fakeJira { return newTestFakeJira() },
+			inputAlert: &alertmanager.Data{
+				Alerts: alertmanager.Alerts{
+					{Status: alertmanager.AlertFiring},
+				},
+				Status:       alertmanager.AlertFiring,
+				GroupLabels:  alertmanager.KV{"a": "b", "c": "d"},
+				CommonLabels: alertmanager.KV{"a": "b", "c": "d"},
+			},
+			expectedJiraIssues: map[string]*jira.Issue{},
+		},
 		{
 			name:        "group alerts by AlertRule",
 			inputConfig: testReceiverConfigAutoGroupByAlertRule(),
@@ -593,13 +906,14 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 				tcase.inputConfig,
 				template.SimpleTemplate(),
 				fakeJira,
+				nil,
 			)
 
 			receiver.timeNow = func() time.Time {
 				return testNowTime
 			}
 
-			_, err := receiver.Notify(tcase.inputAlert, true)
+			_, err := receiver.Notify(context.Background(), tcase.inputAlert, true)
 			require.NoError(t, err)
 			require.Equal(t, tcase.expectedJiraIssues, fakeJira.issuesByKey)
 		}); !ok {
@@ -607,3 +921,2510 @@ func TestNotify_JIRAInteraction(t *testing.T) {
 		}
 	}
 }
+
+func TestReconcile(t *testing.T) {
+	conf := testReceiverConfigAutoResolve()
+	conf.Name = "test"
+	conf.AlertmanagerReceiver = "team"
+
+	f := newTestFakeJira()
+	state := NewState()
+	// Issues 1 to 3 and 5 are tracked by this receiver, 4 by another one.
+	for i, labels := range []alertmanager.KV{{"a": "b"}, {"a": "c"}, {"a": "d"}, {"a": "e"}, {"a": "f"}} {
+		label := toGroupTicketLabel(labels, true)
+		issue, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
+			Fields: &jira.IssueFields{
+				Project:  jira.Project{Key: conf.Project},
+				Labels:   []string{label},
+				Unknowns: tcontainer.MarshalMap{},
+			},
+		})
+		require.NoError(t, err)
+		owner := *conf
+		if i == 3 {
+			owner.Name = "other"
+		}
+		NewReceiver(log.NewNopLogger(), &owner, template.SimpleTemplate(), f, state).
+			recordMapping(&alertmanager.Data{GroupLabels: labels}, conf.Project, label, issue.Key, MappingOpen)
+	}
+	// Not created by JIRAlert.
+	_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
+		Fields: &jira.IssueFields{
+			Project:  jira.Project{Key: conf.Project},
+			Labels:   []string{"manual"},
+			Unknowns: tcontainer.MarshalMap{},
+		},
+	})
+	require.NoError(t, err)
+
+	firing := func(receiver string, labels alertmanager.KV) alertmanager.Data {
+		return alertmanager.Data{
+			Receiver:    receiver,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: labels,
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		}
+	}
+	groups := []alertmanager.Data{
+		firing("test", alertmanager.KV{"a": "b"}),
+		// Same labels as the second issue, but for another receiver.
+		firing("other", alertmanager.KV{"a": "c"}),
+		// Routed to this receiver through its alertmanager_receiver.
+		firing("team", alertmanager.KV{"a": "d"}),
+	}
+
+	// Issues are resolved like by resolve notifications.
+	conf.AutoResolve.Comment = "Resolved."
+	conf.AutoResolve.EndedAtField = "customfield_10053"
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+	receiver.timeNow = func() time.Time { return time.Date(2022, 11, 5, 22, 0, 0, 0, time.UTC) }
+	require.NoError(t, receiver.Reconcile(context.Background(), groups, true))
+
+	require.Equal(t, "NotDone", f.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, "Done", f.issuesByKey["2"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, "NotDone", f.issuesByKey["3"].Fields.Status.StatusCategory.Key)
+	// Tracked by the other receiver.
+	require.Equal(t, "NotDone", f.issuesByKey["4"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, "Done", f.issuesByKey["5"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, "NotDone", f.issuesByKey["6"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, map[string][]string{"2": {"Resolved."}, "5": {"Resolved."}}, f.commentsByKey)
+	require.Equal(t, "2022-11-05T22:00:00.000+0000", f.issuesByKey["2"].Fields.Unknowns["customfield_10053"])
+	require.Nil(t, f.issuesByKey["1"].Fields.Unknowns["customfield_10053"])
+
+	m, ok := state.Mapping("test", conf.Project, toGroupTicketLabel(alertmanager.KV{"a": "c"}, true))
+	require.True(t, ok)
+	require.Equal(t, MappingResolved, m.Status)
+
+	// Without a state, no issue is known to be tracked by the receiver.
+	require.NoError(t, NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Reconcile(context.Background(), nil, true))
+	require.Equal(t, "NotDone", f.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+}
+
+func TestReconcile_AfterRestart(t *testing.T) {
+	conf := testReceiverConfigAutoResolve()
+	conf.Name = "test"
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	f := newTestFakeJira()
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state).Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+	require.NoError(t, state.Flush())
+
+	// The alert resolved while JIRAlert was down: a fresh state only knows the issue from the state file.
+	restarted, err := LoadState(path)
+	require.NoError(t, err)
+	require.Len(t, restarted.Mappings(), 1)
+	require.Equal(t, "1", restarted.Mappings()[0].IssueKey)
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, restarted)
+	require.NoError(t, receiver.Reconcile(context.Background(), nil, true))
+	require.Equal(t, "Done", f.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+	require.NoError(t, restarted.Flush())
+
+	restarted, err = LoadState(path)
+	require.NoError(t, err)
+	m, ok := restarted.Mapping("test", conf.Project, toGroupTicketLabel(alertmanager.KV{"a": "b"}, true))
+	require.True(t, ok)
+	require.Equal(t, MappingResolved, m.Status)
+
+	// Without the state file, the issues of the receiver are unknown.
+	f.issuesByKey["1"].Fields.Status.StatusCategory.Key = "NotDone"
+	require.NoError(t, NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, NewState()).Reconcile(context.Background(), nil, true))
+	require.Equal(t, "NotDone", f.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+}
+
+func TestLoadState_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err := LoadState(path)
+	require.Error(t, err)
+}
+
+func TestCloseStale(t *testing.T) {
+	after := config.Duration(30 * 24 * time.Hour)
+	conf := testReceiverConfig1()
+	conf.Name = "test"
+	conf.StaleIssues = &config.StaleIssues{After: &after, State: "Done", Comment: "No activity, closing."}
+
+	f := newTestFakeJira()
+	state := NewState()
+	// Issues 1 and 2 are tracked by this receiver, 3 by another one.
+	for i, labels := range []alertmanager.KV{{"a": "b"}, {"a": "c"}, {"a": "d"}} {
+		label := toGroupTicketLabel(labels, true)
+		issue, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
+			Fields: &jira.IssueFields{
+				Project:  jira.Project{Key: conf.Project},
+				Labels:   []string{label},
+				Unknowns: tcontainer.MarshalMap{},
+			},
+		})
+		require.NoError(t, err)
+		owner := *conf
+		if i == 2 {
+			owner.Name = "other"
+		}
+		NewReceiver(log.NewNopLogger(), &owner, template.SimpleTemplate(), f, state).
+			recordMapping(&alertmanager.Data{GroupLabels: labels}, conf.Project, label, issue.Key, MappingOpen)
+	}
+
+	groups := []alertmanager.Data{
+		{
+			Receiver:    "test",
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		},
+	}
+
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+	require.NoError(t, receiver.CloseStale(context.Background(), groups, true))
+
+	require.Equal(t, "NotDone", f.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, "Done", f.issuesByKey["2"].Fields.Status.StatusCategory.Key)
+	// Tracked by the other receiver.
+	require.Equal(t, "NotDone", f.issuesByKey["3"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, map[string][]string{"2": {"No activity, closing."}}, f.commentsByKey)
+
+	// Issues that cannot be transitioned are not commented on.
+	f.issuesByKey["2"].Fields.Status.StatusCategory.Key = "NotDone"
+	f.commentsByKey = map[string][]string{}
+	conf.StaleIssues.State = "Closed"
+	receiver = NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+	require.Error(t, receiver.CloseStale(context.Background(), groups, true))
+	require.Equal(t, "NotDone", f.issuesByKey["2"].Fields.Status.StatusCategory.Key)
+	require.Empty(t, f.commentsByKey)
+
+	// Without a state, no issue is known to be tracked by the receiver.
+	conf.StaleIssues.State = "Done"
+	require.NoError(t, NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).CloseStale(context.Background(), groups, true))
+	require.Equal(t, "NotDone", f.issuesByKey["2"].Fields.Status.StatusCategory.Key)
+}
+
+func TestNotify_CreationLimit(t *testing.T) {
+	window := config.Duration(time.Hour)
+	conf := testReceiverConfig1()
+	conf.CreationLimit = &config.CreationLimit{MaxIssues: 1, Window: &window, StormSummary: config.DefaultStormSummary}
+
+	f := newTestFakeJira()
+	state := NewState()
+	notify := func(labels alertmanager.KV) {
+		receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: labels,
+		}, true)
+		require.NoError(t, err)
+	}
+
+	notify(alertmanager.KV{"a": "b"})
+	require.Len(t, f.issuesByKey, 1)
+
+	// Limit exceeded, the umbrella issue is created.
+	notify(alertmanager.KV{"a": "c"})
+	require.Len(t, f.issuesByKey, 2)
+	require.Equal(t, []string{StormLabel}, f.issuesByKey["2"].Fields.Labels)
+	require.Equal(t, config.DefaultStormSummary, f.issuesByKey["2"].Fields.Summary)
+	f.keysByQuery[fmt.Sprintf("project=\"%s\" and labels=%q and statusCategory != Done", conf.Project, StormLabel)] = []string{"2"}
+
+	// Further groups are commented on the umbrella issue, once.
+	notify(alertmanager.KV{"a": "d"})
+	notify(alertmanager.KV{"a": "d"})
+	require.Len(t, f.issuesByKey, 2)
+	require.Equal(t, []string{"[FIRING:1] c \n\n", "[FIRING:1] d \n\n"}, f.commentsByKey["2"])
+}
+
+func TestState_Allow(t *testing.T) {
+	s := NewState()
+	now := time.Now()
+	require.True(t, s.allow("ABC", "a", 1, time.Hour, now))
+	// Only successful creations count.
+	require.True(t, s.allow("ABC", "b", 1, time.Hour, now))
+	s.recordCreation("ABC", "a", now)
+	require.False(t, s.allow("ABC", "b", 1, time.Hour, now))
+	require.True(t, s.allow("DEF", "b", 1, time.Hour, now))
+	// Groups already created within the window do not count twice.
+	require.True(t, s.allow("ABC", "a", 1, time.Hour, now))
+	// Creations expire after the window.
+	require.True(t, s.allow("ABC", "b", 1, time.Hour, now.Add(time.Hour)))
+}
+
+// failingCreateJira is a fake Jira failing to create issues while fail is set.
+type failingCreateJira struct {
+	*fakeJira
+	fail bool
+}
+
+func (f *failingCreateJira) CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	if f.fail {
+		return nil, nil, errors.New("jira is down")
+	}
+	return f.fakeJira.CreateWithContext(ctx, issue)
+}
+
+func TestNotify_CreationLimitCountsCreatedGroups(t *testing.T) {
+	window := config.Duration(time.Hour)
+	conf := testReceiverConfig1()
+	conf.CreationLimit = &config.CreationLimit{MaxIssues: 2, Window: &window, StormSummary: config.DefaultStormSummary}
+
+	f := &failingCreateJira{fakeJira: newTestFakeJira()}
+	state := NewState()
+	notify := func(labels alertmanager.KV) error {
+		receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: labels,
+		}, true)
+		return err
+	}
+
+	// Failed creations do not count against the limit.
+	f.fail = true
+	require.Error(t, notify(alertmanager.KV{"a": "b"}))
+	require.Error(t, notify(alertmanager.KV{"a": "b"}))
+	f.fail = false
+	require.NoError(t, notify(alertmanager.KV{"a": "b"}))
+	require.NoError(t, notify(alertmanager.KV{"a": "c"}))
+	require.Len(t, f.issuesByKey, 2)
+	for _, issue := range f.issuesByKey {
+		require.NotEqual(t, []string{StormLabel}, issue.Fields.Labels)
+	}
+
+	// Further groups exceed the limit.
+	require.NoError(t, notify(alertmanager.KV{"a": "d"}))
+	require.Len(t, f.issuesByKey, 3)
+	require.Equal(t, []string{StormLabel}, f.issuesByKey["3"].Fields.Labels)
+}
+
+// failingCommentJira is a fake Jira failing to add comments while fail is set.
+type failingCommentJira struct {
+	*fakeJira
+	fail bool
+}
+
+func (f *failingCommentJira) AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	if f.fail {
+		return nil, nil, errors.New("jira is down")
+	}
+	return f.fakeJira.AddCommentWithContext(ctx, issueID, comment)
+}
+
+func TestNotify_CreationLimitRetriesFailedComments(t *testing.T) {
+	window := config.Duration(time.Hour)
+	conf := testReceiverConfig1()
+	conf.CreationLimit = &config.CreationLimit{MaxIssues: 1, Window: &window, StormSummary: config.DefaultStormSummary}
+
+	f := &failingCommentJira{fakeJira: newTestFakeJira()}
+	state := NewState()
+	notify := func(labels alertmanager.KV) error {
+		receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: labels,
+		}, true)
+		return err
+	}
+
+	require.NoError(t, notify(alertmanager.KV{"a": "b"}))
+	require.NoError(t, notify(alertmanager.KV{"a": "c"}))
+	require.Equal(t, []string{StormLabel}, f.issuesByKey["2"].Fields.Labels)
+	f.keysByQuery[fmt.Sprintf("project=\"%s\" and labels=%q and statusCategory != Done", conf.Project, StormLabel)] = []string{"2"}
+
+	// A group whose comment failed is not listed, so the retry comments again.
+	f.fail = true
+	require.Error(t, notify(alertmanager.KV{"a": "d"}))
+	require.Equal(t, []string{"[FIRING:1] c \n\n"}, f.commentsByKey["2"])
+	f.fail = false
+	require.NoError(t, notify(alertmanager.KV{"a": "d"}))
+	require.NoError(t, notify(alertmanager.KV{"a": "d"}))
+	require.Equal(t, []string{"[FIRING:1] c \n\n", "[FIRING:1] d \n\n"}, f.commentsByKey["2"])
+}
+
+func TestNotify_BusinessHours(t *testing.T) {
+	var bh config.BusinessHours
+	require.NoError(t, yaml.Unmarshal([]byte(`
+intervals:
+- weekdays: ['monday:friday']
+  times: [{start_time: '09:00', end_time: '17:00'}]
+`), &bh))
+	conf := testReceiverConfig1()
+	conf.BusinessHours = &bh
+	conf.BusinessHours.SeverityLabel = "severity"
+	conf.BusinessHours.BypassSeverities = []string{"critical"}
+
+	f := newTestFakeJira()
+	state := NewState()
+	saturday := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	newReceiver := func(now time.Time) *Receiver {
+		r := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		return r
+	}
+	data := func(severity string) *alertmanager.Data {
+		return &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"severity": severity}}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"severity": severity},
+		}
+	}
+
+	// Critical alerts are never deferred.
+	_, err := newReceiver(saturday).Notify(context.Background(), data("critical"), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+
+	_, err = newReceiver(saturday).Notify(context.Background(), data("warning"), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+
+	// Still outside business hours.
+	_, err = newReceiver(saturday).NotifyDeferred(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+
+	_, err = newReceiver(monday).NotifyDeferred(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 2)
+	require.Equal(t, "[FIRING:1] warning ", f.issuesByKey["2"].Fields.Summary)
+
+	// Nothing left.
+	_, err = newReceiver(monday).NotifyDeferred(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 2)
+}
+
+func TestNotify_BusinessHoursAfterRestart(t *testing.T) {
+	var bh config.BusinessHours
+	require.NoError(t, yaml.Unmarshal([]byte(`
+intervals:
+- weekdays: ['monday:friday']
+  times: [{start_time: '09:00', end_time: '17:00'}]
+`), &bh))
+	conf := testReceiverConfig1()
+	conf.BusinessHours = &bh
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	f := newTestFakeJira()
+	saturday := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	newReceiver := func(now time.Time) *Receiver {
+		state, err := LoadState(path)
+		require.NoError(t, err)
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		return r
+	}
+
+	_, err := newReceiver(saturday).Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+	require.Empty(t, f.issuesByKey)
+
+	// The deferred group survives the restart and is processed once business hours start.
+	_, err = newReceiver(monday).NotifyDeferred(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+	require.Equal(t, "[FIRING:1] b ", f.issuesByKey["1"].Fields.Summary)
+
+	// Processed groups are removed from the state file.
+	_, err = newReceiver(monday).NotifyDeferred(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+}
+
+func TestNotify_MinFiringDuration(t *testing.T) {
+	minFiring := config.Duration(5 * time.Minute)
+	conf := testReceiverConfig1()
+	conf.MinFiringDuration = &minFiring
+
+	f := newTestFakeJira()
+	state := NewState()
+	start := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	newReceiver := func(now time.Time) *Receiver {
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		return r
+	}
+	data := func(group, status string) *alertmanager.Data {
+		return &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: status, StartsAt: start}},
+			Status:      status,
+			GroupLabels: alertmanager.KV{"a": group},
+		}
+	}
+
+	_, err := newReceiver(start.Add(time.Minute)).Notify(context.Background(), data("b", alertmanager.AlertFiring), true)
+	require.NoError(t, err)
+	_, err = newReceiver(start.Add(time.Minute)).Notify(context.Background(), data("c", alertmanager.AlertFiring), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 0)
+
+	// A blip resolving before min_firing_duration never becomes an issue.
+	_, err = newReceiver(start.Add(2*time.Minute)).Notify(context.Background(), data("c", alertmanager.AlertResolved), true)
+	require.NoError(t, err)
+
+	_, err = newReceiver(start.Add(4*time.Minute)).NotifyHeld(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 0)
+
+	_, err = newReceiver(start.Add(5*time.Minute)).NotifyHeld(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+	require.Equal(t, "[FIRING:1] b ", f.issuesByKey["1"].Fields.Summary)
+
+	// Nothing left.
+	_, err = newReceiver(start.Add(10*time.Minute)).NotifyHeld(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+}
+
+func TestNotify_MinFiringDurationAfterRestart(t *testing.T) {
+	minFiring := config.Duration(5 * time.Minute)
+	conf := testReceiverConfig1()
+	conf.MinFiringDuration = &minFiring
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	f := newTestFakeJira()
+	start := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	newReceiver := func(now time.Time) *Receiver {
+		state, err := LoadState(path)
+		require.NoError(t, err)
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		return r
+	}
+	data := func(group, status string) *alertmanager.Data {
+		return &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: status, StartsAt: start}},
+			Status:      status,
+			GroupLabels: alertmanager.KV{"a": group},
+		}
+	}
+
+	_, err := newReceiver(start.Add(time.Minute)).Notify(context.Background(), data("b", alertmanager.AlertFiring), true)
+	require.NoError(t, err)
+	_, err = newReceiver(start.Add(time.Minute)).Notify(context.Background(), data("c", alertmanager.AlertFiring), true)
+	require.NoError(t, err)
+	_, err = newReceiver(start.Add(2*time.Minute)).Notify(context.Background(), data("c", alertmanager.AlertResolved), true)
+	require.NoError(t, err)
+	require.Empty(t, f.issuesByKey)
+
+	// The held group survives the restart, the resolved one was dropped from the state file.
+	_, err = newReceiver(start.Add(5*time.Minute)).NotifyHeld(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+	require.Equal(t, "[FIRING:1] b ", f.issuesByKey["1"].Fields.Summary)
+
+	_, err = newReceiver(start.Add(10*time.Minute)).NotifyHeld(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+}
+
+func TestNotify_MinUpdateInterval(t *testing.T) {
+	interval := config.Duration(time.Hour)
+	conf := testReceiverConfig1()
+	conf.MinUpdateInterval = &interval
+
+	f := newTestFakeJira()
+	state := NewState()
+	start := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	notify := func(now time.Time, alerts ...string) {
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		data := &alertmanager.Data{Status: alertmanager.AlertResolved, GroupLabels: alertmanager.KV{"a": "b"}}
+		for _, status := range alerts {
+			data.Alerts = append(data.Alerts, alertmanager.Alert{Status: status})
+			if status == alertmanager.AlertFiring {
+				data.Status = alertmanager.AlertFiring
+			}
+		}
+		_, err := r.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+
+	notify(start, alertmanager.AlertFiring)
+	require.Len(t, f.issuesByKey, 1)
+	require.Equal(t, "[FIRING:1] b ", f.issuesByKey["1"].Fields.Summary)
+
+	// Updated less than min_update_interval ago, the issue is not even looked up.
+	f.issuesByKey["1"].Fields.Summary = "edited"
+	notify(start.Add(30*time.Minute), alertmanager.AlertFiring, alertmanager.AlertFiring)
+	require.Equal(t, "edited", f.issuesByKey["1"].Fields.Summary)
+
+	notify(start.Add(time.Hour), alertmanager.AlertFiring, alertmanager.AlertFiring)
+	require.Equal(t, "[FIRING:2] b ", f.issuesByKey["1"].Fields.Summary)
+
+	// Resolved groups are processed at once.
+	notify(start.Add(time.Hour+time.Minute), alertmanager.AlertResolved)
+	require.Equal(t, "[RESOLVED] b ", f.issuesByKey["1"].Fields.Summary)
+}
+
+func TestNotify_MinUpdateIntervalSeveralProjects(t *testing.T) {
+	interval := config.Duration(time.Hour)
+	conf := testReceiverConfig1()
+	conf.Project = "abc,def"
+	conf.MinUpdateInterval = &interval
+
+	f := newTestFakeJira()
+	state := NewState()
+	start := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	notify := func(now time.Time) {
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		data := &alertmanager.Data{
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		}
+		_, err := r.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+
+	// The alert group is mapped to an issue per project.
+	notify(start)
+	require.Len(t, f.issuesByKey, 2)
+	mappings := state.Mappings()
+	require.Len(t, mappings, 2)
+	require.Equal(t, []string{"abc", "def"}, []string{mappings[0].Project, mappings[1].Project})
+	m, ok := state.Mapping(conf.Name, "def", mappings[1].IssueLabel)
+	require.True(t, ok)
+	require.Equal(t, f.issuesByKey[m.IssueKey].Fields.Project.Key, "def")
+
+	// Both issues were updated less than min_update_interval ago.
+	for _, issue := range f.issuesByKey {
+		issue.Fields.Summary = "edited"
+	}
+	notify(start.Add(30 * time.Minute))
+	for _, issue := range f.issuesByKey {
+		require.Equal(t, "edited", issue.Fields.Summary)
+	}
+}
+
+func TestNotify_NumericFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Fields = map[string]interface{}{
+		"customfield_10001": `{{ .CommonAnnotations.cost | toNumber }}`,
+		"customfield_10002": `{{ .CommonAnnotations.cost }}`,
+		"customfield_10003": []interface{}{map[string]interface{}{"value": `{{ len .Alerts | toNumber }}`}},
+		"customfield_10004": `cost: {{ .CommonAnnotations.cost | toNumber }}`,
+	}
+
+	f := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:            alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:            alertmanager.AlertFiring,
+		GroupLabels:       alertmanager.KV{"a": "b"},
+		CommonAnnotations: alertmanager.KV{"cost": " 12.5"},
+	}, true)
+	require.NoError(t, err)
+	unknowns := f.issuesByKey["1"].Fields.Unknowns
+	require.Equal(t, 12.5, unknowns["customfield_10001"])
+	require.Equal(t, " 12.5", unknowns["customfield_10002"])
+	require.Equal(t, []interface{}{map[string]interface{}{"value": float64(1)}}, unknowns["customfield_10003"])
+	require.Equal(t, "cost: 12.5", unknowns["customfield_10004"])
+
+	// Values that are not numbers fail the notification rather than being rejected by Jira.
+	_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:            alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:            alertmanager.AlertFiring,
+		GroupLabels:       alertmanager.KV{"a": "c"},
+		CommonAnnotations: alertmanager.KV{"cost": "n/a"},
+	}, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `toNumber: "n/a" is not a number`)
+}
+
+func TestNotify_TypedFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Fields = map[string]interface{}{
+		"customfield_10001": `{{ .CommonLabels.team }}`,
+		"customfield_10002": []interface{}{"a", `{{ .CommonLabels.team }}`},
+		"customfield_10003": `a, {{ .CommonLabels.team }},`,
+		"customfield_10004": `{{ .CommonAnnotations.cost }}`,
+		"customfield_10005": `{{ .CommonLabels.owner }}`,
+		"customfield_10006": `{{ (index .Alerts 0).StartsAt }}`,
+		"customfield_10007": `{{ .CommonLabels.team }}/backend`,
+		"customfield_10008": []interface{}{"infra", "network"},
+		"customfield_10009": `{{ .CommonLabels.team }}`,
+	}
+	conf.FieldTypes = map[string]string{
+		"customfield_10001": config.FieldTypeOption,
+		"customfield_10002": config.FieldTypeOption,
+		"customfield_10003": config.FieldTypeArray,
+		"customfield_10004": config.FieldTypeNumber,
+		"customfield_10005": config.FieldTypeUser,
+		"customfield_10006": config.FieldTypeDateTime,
+		"customfield_10007": config.FieldTypeCascading,
+		"customfield_10008": config.FieldTypeCascading,
+	}
+
+	startsAt := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	f := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:            alertmanager.Alerts{{Status: alertmanager.AlertFiring, StartsAt: startsAt}},
+		Status:            alertmanager.AlertFiring,
+		GroupLabels:       alertmanager.KV{"a": "b"},
+		CommonLabels:      alertmanager.KV{"team": "storage", "owner": "jdoe"},
+		CommonAnnotations: alertmanager.KV{"cost": "12.5"},
+	}, true)
+	require.NoError(t, err)
+	unknowns := f.issuesByKey["1"].Fields.Unknowns
+	require.Equal(t, map[string]interface{}{"value": "storage"}, unknowns["customfield_10001"])
+	require.Equal(t, []interface{}{map[string]interface{}{"value": "a"}, map[string]interface{}{"value": "storage"}}, unknowns["customfield_10002"])
+	require.Equal(t, []interface{}{"a", "storage"}, unknowns["customfield_10003"])
+	require.Equal(t, 12.5, unknowns["customfield_10004"])
+	require.Equal(t, &FieldUser{Name: "jdoe"}, unknowns["customfield_10005"])
+	require.Equal(t, "2026-03-01T12:30:00.000+0000", unknowns["customfield_10006"])
+	require.Equal(t, map[string]interface{}{"value": "storage", "child": map[string]interface{}{"value": "backend"}}, unknowns["customfield_10007"])
+	require.Equal(t, map[string]interface{}{"value": "infra", "child": map[string]interface{}{"value": "network"}}, unknowns["customfield_10008"])
+	// Fields without a type are sent as rendered.
+	require.Equal(t, "storage", unknowns["customfield_10009"])
+
+	// Values that do not convert fail the notification rather than being rejected by Jira.
+	_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:            alertmanager.Alerts{{Status: alertmanager.AlertFiring, StartsAt: startsAt}},
+		Status:            alertmanager.AlertFiring,
+		GroupLabels:       alertmanager.KV{"a": "c"},
+		CommonLabels:      alertmanager.KV{"team": "storage", "owner": "jdoe"},
+		CommonAnnotations: alertmanager.KV{"cost": "n/a"},
+	}, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `field customfield_10004: "n/a" is not a number`)
+}
+
+func TestNotify_CascadingFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.CascadingFields = []*config.CascadingField{
+		{Field: "customfield_10001", Parent: `{{ .CommonLabels.region }}`, Child: `{{ .CommonLabels.zone }}`},
+		{Field: "customfield_10002", Parent: `{{ .CommonLabels.region }}`},
+		{Field: "customfield_10003", Parent: `{{ .CommonLabels.datacenter }}`, Child: `{{ .CommonLabels.zone }}`},
+	}
+
+	f := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"a": "b"},
+		CommonLabels: alertmanager.KV{"region": "eu-west", "zone": "eu-west-1a"},
+	}, true)
+	require.NoError(t, err)
+	unknowns := f.issuesByKey["1"].Fields.Unknowns
+	require.Equal(t, map[string]interface{}{"value": "eu-west", "child": map[string]interface{}{"value": "eu-west-1a"}}, unknowns["customfield_10001"])
+	require.Equal(t, map[string]interface{}{"value": "eu-west"}, unknowns["customfield_10002"])
+	// Fields whose parent renders empty are not set.
+	require.NotContains(t, unknowns, "customfield_10003")
+}
+
+func TestNotify_ListFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Fields = map[string]interface{}{
+		"customfield_10001": `{{ .Alerts | labelValues "instance" | toList }}`,
+		"customfield_10002": `{{ .Alerts.Firing | labelValues "instance" | toList }}`,
+		"customfield_10003": `{{ .CommonAnnotations.teams | toList }}`,
+		"customfield_10004": `instances: {{ .Alerts | labelValues "instance" | join "," }}`,
+	}
+	conf.FieldTypes = map[string]string{"customfield_10002": config.FieldTypeOption}
+
+	f := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts: alertmanager.Alerts{
+			{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"instance": "h1"}},
+			{Status: alertmanager.AlertResolved, Labels: alertmanager.KV{"instance": "h2"}},
+			{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"instance": "h1"}},
+			{Status: alertmanager.AlertFiring},
+		},
+		Status:            alertmanager.AlertFiring,
+		GroupLabels:       alertmanager.KV{"a": "b"},
+		CommonAnnotations: alertmanager.KV{"teams": "storage, network,"},
+	}, true)
+	require.NoError(t, err)
+	unknowns := f.issuesByKey["1"].Fields.Unknowns
+	require.Equal(t, []interface{}{"h1", "h2"}, unknowns["customfield_10001"])
+	require.Equal(t, []interface{}{map[string]interface{}{"value": "h1"}}, unknowns["customfield_10002"])
+	require.Equal(t, []interface{}{"storage", "network"}, unknowns["customfield_10003"])
+	require.Equal(t, "instances: h1,h2", unknowns["customfield_10004"])
+}
+
+func TestNotify_FieldUpdates(t *testing.T) {
+	update, noUpdate := true, false
+	conf := testReceiverConfig1()
+	conf.Fields = map[string]interface{}{
+		"customfield_10001": `{{ .CommonLabels.team }}`,
+		"customfield_10002": `{{ .CommonLabels.team }}`,
+		"customfield_10003": `{{ .CommonLabels.team }}`,
+		"customfield_10004": `{{ .CommonLabels.team }}`,
+	}
+	conf.FieldTypes = map[string]string{"customfield_10004": config.FieldTypeOption, "customfield_10005": config.FieldTypeNumber}
+	conf.FieldUpdates = map[string]*config.FieldUpdate{
+		"customfield_10001": {Update: &update},
+		"customfield_10002": {Update: &noUpdate},
+		"customfield_10004": {Update: &update},
+		"customfield_10005": {OnUpdate: `{{ len .Alerts.Firing }}`},
+	}
+	f := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil)
+
+	notify := func(team string, alerts int) {
+		data := &alertmanager.Data{
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  alertmanager.KV{"a": "b"},
+			CommonLabels: alertmanager.KV{"team": team},
+		}
+		for i := 0; i < alerts; i++ {
+			data.Alerts = append(data.Alerts, alertmanager.Alert{Status: alertmanager.AlertFiring})
+		}
+		_, err := receiver.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+		require.Len(t, f.issuesByKey, 1)
+	}
+
+	notify("storage", 1)
+	unknowns := f.issuesByKey["1"].Fields.Unknowns
+	// Fields only set on update are not set on creation.
+	require.NotContains(t, unknowns, "customfield_10005")
+
+	notify("network", 2)
+	require.Equal(t, "network", unknowns["customfield_10001"])
+	require.Equal(t, "storage", unknowns["customfield_10002"])
+	require.Equal(t, "storage", unknowns["customfield_10003"])
+	require.Equal(t, map[string]interface{}{"value": "network"}, unknowns["customfield_10004"])
+	require.Equal(t, float64(2), unknowns["customfield_10005"])
+
+	// Fields with the values already are not updated, including options Jira returns with more properties.
+	unknowns["customfield_10004"] = map[string]interface{}{"value": "network", "id": "10100"}
+	notify("network", 2)
+	require.Equal(t, map[string]interface{}{"value": "network", "id": "10100"}, unknowns["customfield_10004"])
+}
+
+func TestNotify_Reporter(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Reporter = `{{ with .CommonLabels.team }}svc-{{ . }}{{ end }}`
+	f := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil)
+
+	_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"a": "b"},
+		CommonLabels: alertmanager.KV{"team": "storage"},
+	}, true)
+	require.NoError(t, err)
+	require.Equal(t, &jira.User{Name: "svc-storage"}, f.issuesByKey["1"].Fields.Reporter)
+
+	// Reporters rendering empty leave it to Jira.
+	_, err = receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "c"},
+	}, true)
+	require.NoError(t, err)
+	require.Nil(t, f.issuesByKey["2"].Fields.Reporter)
+}
+
+func TestNotify_RequiredFields(t *testing.T) {
+	var meta jira.CreateMetaInfo
+	require.NoError(t, json.Unmarshal([]byte(`{"projects": [{"key": "abc", "issuetypes": [{"name": "Bug", "fields": {
+		"summary": {"required": true, "name": "Summary", "hasDefaultValue": false},
+		"customfield_10001": {"required": true, "name": "Team", "hasDefaultValue": false},
+		"customfield_10002": {"required": true, "name": "Severity", "hasDefaultValue": true, "defaultValue": {"id": "3", "value": "Low"}},
+		"customfield_10003": {"required": true, "name": "Area", "hasDefaultValue": false,
+			"schema": {"type": "array", "items": "option"},
+			"allowedValues": [{"self": "https://jira/option/20", "id": "20", "value": "Backend"}, {"id": "21", "value": "Frontend"}]},
+		"customfield_10004": {"required": true, "name": "Notes", "hasDefaultValue": false},
+		"customfield_10005": {"required": false, "name": "Optional", "hasDefaultValue": true, "defaultValue": "x"}
+	}}]}]}`), &meta))
+
+	for _, tc := range []struct {
+		fill     string
+		expected map[string]interface{}
+	}{
+		{
+			fill:     config.FillDefault,
+			expected: map[string]interface{}{"customfield_10001": "storage", "customfield_10002": map[string]interface{}{"id": "3", "value": "Low"}},
+		},
+		{
+			fill: config.FillFirstAllowed,
+			expected: map[string]interface{}{
+				"customfield_10001": "storage",
+				"customfield_10002": map[string]interface{}{"id": "3", "value": "Low"},
+				"customfield_10003": []interface{}{map[string]interface{}{"id": "20"}},
+			},
+		},
+	} {
+		t.Run(tc.fill, func(t *testing.T) {
+			conf := testReceiverConfig1()
+			conf.IssueType = "Bug"
+			conf.Fields = map[string]interface{}{"customfield_10001": "storage"}
+			conf.RequiredFields = &config.RequiredFields{Fill: tc.fill}
+			f := newTestFakeJira()
+			f.createMeta = &meta
+
+			_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+				Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+				Status:      alertmanager.AlertFiring,
+				GroupLabels: alertmanager.KV{"a": "b"},
+			}, true)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, map[string]interface{}(f.issuesByKey["1"].Fields.Unknowns))
+		})
+	}
+
+	// Failing lookups do not fail creating the issue.
+	conf := testReceiverConfig1()
+	conf.RequiredFields = &config.RequiredFields{Fill: config.FillDefault}
+	f := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 1)
+}
+
+func TestNotify_MaxAlertAge(t *testing.T) {
+	maxAge := config.Duration(24 * time.Hour)
+	conf := testReceiverConfig1()
+	conf.MaxAlertAge = &maxAge
+
+	f := newTestFakeJira()
+	now := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	notify := func(group string, startsAt ...time.Time) {
+		data := &alertmanager.Data{
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": group},
+		}
+		for _, s := range startsAt {
+			data.Alerts = append(data.Alerts, alertmanager.Alert{Status: alertmanager.AlertFiring, StartsAt: s})
+		}
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil)
+		r.timeNow = func() time.Time { return now }
+		_, err := r.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+
+	before := testutil.ToFloat64(oldAlertsSkippedTotal.WithLabelValues(conf.Name))
+	notify("b", now.Add(-48*time.Hour), now.Add(-25*time.Hour))
+	require.Len(t, f.issuesByKey, 0)
+	require.Equal(t, before+2, testutil.ToFloat64(oldAlertsSkippedTotal.WithLabelValues(conf.Name)))
+
+	// A single recent alert is enough.
+	notify("c", now.Add(-48*time.Hour), now.Add(-time.Hour))
+	require.Len(t, f.issuesByKey, 1)
+}
+
+func TestNotify_MinSeverity(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.MinSeverity = &config.MinSeverity{Severity: "warning", Label: "severity", Order: config.DefaultSeverityOrder}
+
+	f := newTestFakeJira()
+	notify := func(severities ...string) {
+		data := &alertmanager.Data{
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": strings.Join(severities, ",")},
+		}
+		for _, s := range severities {
+			data.Alerts = append(data.Alerts, alertmanager.Alert{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"severity": s}})
+		}
+		_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+
+	notify("info")
+	notify("unknown")
+	notify()
+	require.Len(t, f.issuesByKey, 0)
+
+	// The highest severity of the group counts.
+	notify("info", "critical")
+	require.Len(t, f.issuesByKey, 1)
+	notify("warning")
+	require.Len(t, f.issuesByKey, 2)
+}
+
+func TestNotify_Match(t *testing.T) {
+	teamRE, err := config.NewRegexp("db|storage")
+	require.NoError(t, err)
+	conf := testReceiverConfig1()
+	conf.Match = map[string]string{"env": "prod"}
+	conf.MatchRE = map[string]config.Regexp{"team": teamRE}
+
+	f := newTestFakeJira()
+	notify := func(alerts ...alertmanager.Alert) {
+		_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil).Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+
+	notify(alertmanager.Alert{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"env": "dev", "team": "db"}})
+	notify(alertmanager.Alert{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"env": "prod", "team": "dbx"}})
+	require.Len(t, f.issuesByKey, 0)
+
+	// Only the matching alerts are handled.
+	notify(
+		alertmanager.Alert{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"env": "prod", "team": "storage"}},
+		alertmanager.Alert{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"env": "dev", "team": "db"}},
+	)
+	require.Len(t, f.issuesByKey, 1)
+	require.Equal(t, "[FIRING:1] b ", f.issuesByKey["1"].Fields.Summary)
+}
+
+func TestNotify_MuteTimeIntervals(t *testing.T) {
+	cfg, err := config.Load(`
+defaults:
+  api_url: https://jiralert.atlassian.net
+  user: jiralert
+  password: 'JIRAlert'
+  project: abc
+  issue_type: Bug
+  summary: '{{ template "jira.summary" . }}'
+  reopen_state: reopened
+  reopen_duration: 0h
+template: jiralert.tmpl
+time_intervals:
+  - name: christmas
+    time_intervals:
+      - months: [december]
+        days_of_month: ['24:26']
+receivers:
+  - name: 'jira'
+    mute_time_intervals: [christmas]
+`)
+	require.NoError(t, err)
+	conf := cfg.Receivers[0]
+	conf.Summary = testReceiverConfig1().Summary
+
+	f := newTestFakeJira()
+	notify := func(now time.Time) {
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, nil)
+		r.timeNow = func() time.Time { return now }
+		_, err := r.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+
+	notify(time.Date(2022, 12, 25, 12, 0, 0, 0, time.UTC))
+	require.Len(t, f.issuesByKey, 0)
+	notify(time.Date(2022, 12, 27, 12, 0, 0, 0, time.UTC))
+	require.Len(t, f.issuesByKey, 1)
+}
+
+func TestNotify_RecordsMappings(t *testing.T) {
+	conf := testReceiverConfigAutoResolve()
+	conf.Name = "test"
+
+	f := newTestFakeJira()
+	state := NewState()
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+	now := time.Now()
+	receiver.timeNow = func() time.Time { return now }
+
+	data := &alertmanager.Data{
+		GroupKey:    "{}:{a=\"b\"}",
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}
+	_, err := receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+
+	expected := Mapping{
+		Receiver:   "test",
+		Project:    conf.Project,
+		GroupKey:   data.GroupKey,
+		IssueLabel: toGroupTicketLabel(data.GroupLabels, true),
+		IssueKey:   "1",
+		Status:     MappingOpen,
+		LastUpdate: now,
+	}
+	require.Equal(t, []Mapping{expected}, state.Mappings())
+
+	data.Alerts[0].Status = alertmanager.AlertResolved
+	data.Status = alertmanager.AlertResolved
+	_, err = receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+
+	expected.Status = MappingResolved
+	expiresAt := now.Add(time.Duration(*conf.ReopenDuration))
+	expected.ExpiresAt = &expiresAt
+	require.Equal(t, []Mapping{expected}, state.Mappings())
+
+	// Once the issue is too old to be reopened, the mapping is dropped by the next recorded mapping.
+	now = expiresAt.Add(-time.Second)
+	receiver.recordMapping(&alertmanager.Data{}, conf.Project, "other", "2", MappingOpen)
+	require.Len(t, state.Mappings(), 2)
+	now = expiresAt.Add(mappingExpiryInterval)
+	receiver.recordMapping(&alertmanager.Data{}, conf.Project, "other", "2", MappingOpen)
+	require.Len(t, state.Mappings(), 1)
+	require.Equal(t, "2", state.Mappings()[0].IssueKey)
+}
+
+func TestState_PersistLater(t *testing.T) {
+	defer func(d time.Duration) { persistDelay = d }(persistDelay)
+	persistDelay = time.Hour
+	conf := testReceiverConfigAutoResolve()
+	conf.Name = "test"
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), newTestFakeJira(), state)
+	for _, key := range []string{"1", "2", "3"} {
+		receiver.recordMapping(&alertmanager.Data{}, conf.Project, "label"+key, key, MappingOpen)
+	}
+	// The writes are deferred.
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, state.Flush())
+	restarted, err := LoadState(path)
+	require.NoError(t, err)
+	require.Len(t, restarted.Mappings(), 3)
+
+	// The deferred writes happen within persistDelay.
+	persistDelay = time.Millisecond
+	receiver.recordMapping(&alertmanager.Data{}, conf.Project, "label4", "4", MappingOpen)
+	require.Eventually(t, func() bool {
+		restarted, err := LoadState(path)
+		return err == nil && len(restarted.Mappings()) == 4
+	}, time.Second, time.Millisecond)
+}
+
+func TestReceiver_DetachRelink(t *testing.T) {
+	conf := testReceiverConfigAutoResolve()
+	conf.Name = "test"
+
+	f := newTestFakeJira()
+	state := NewState()
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, state)
+
+	data := &alertmanager.Data{
+		GroupKey:    "{}:{a=\"b\"}",
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}
+	idLabel := toGroupTicketLabel(data.GroupLabels, true)
+	_, err := receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+
+	// Detaching forgets the mapping and makes the next notification create a fresh issue.
+	require.NoError(t, receiver.Detach(context.Background(), conf.Project, idLabel))
+	require.Empty(t, state.Mappings())
+	require.Empty(t, f.issuesByKey["1"].Fields.Labels)
+
+	_, err = receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 2)
+	require.Equal(t, []string{idLabel}, f.issuesByKey["2"].Fields.Labels)
+
+	// Relinking moves the identifier label to the given issue.
+	require.NoError(t, receiver.Relink(context.Background(), conf.Project, idLabel, "1"))
+	require.Equal(t, []string{idLabel}, f.issuesByKey["1"].Fields.Labels)
+	require.Empty(t, f.issuesByKey["2"].Fields.Labels)
+	mappings := state.Mappings()
+	require.Len(t, mappings, 1)
+	require.Equal(t, "1", mappings[0].IssueKey)
+	require.Equal(t, data.GroupKey, mappings[0].GroupKey)
+
+	_, err = receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	require.Len(t, f.issuesByKey, 2)
+}
+
+func TestReceiver_Render(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.GroupIssueBy = config.Alert
+	conf.AddCommonLabels = true
+
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), nil, nil)
+	data := &alertmanager.Data{
+		Alerts: alertmanager.Alerts{
+			{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"a": "1"}},
+			{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"a": "2"}},
+		},
+		Status: alertmanager.AlertFiring,
+	}
+	issues, err := receiver.Render(data, true)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	for i, issue := range issues {
+		require.Equal(t, conf.Project, issue.Fields.Project.Key)
+		require.True(t, strings.HasPrefix(issue.Fields.Summary, "[FIRING:1]"), issue.Fields.Summary)
+		require.Len(t, issue.Fields.Labels, 2)
+		require.Equal(t, fmt.Sprintf("a=\"%d\"", i+1), issue.Fields.Labels[0])
+	}
+
+	// Nobody is looked up on call: issues are assigned to the fallback assignee.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected on-call request %s", r.URL)
+	}))
+	defer srv.Close()
+	timeout := config.Duration(time.Second)
+	conf.Assignee = "fallback"
+	conf.OnCall = &config.OnCall{Provider: config.OnCallOpsgenie, APIURL: srv.URL, Schedule: "ops", Timeout: &timeout}
+	issues, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), nil, nil).Render(data, true)
+	require.NoError(t, err)
+	require.Equal(t, "fallback", issues[0].Fields.Assignee.Name)
+}
+
+func TestNotify_IssueTypeMapping(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.IssueType = "Bug"
+	conf.IssueTypeMapping = &config.LabelMapping{
+		Label:  "severity",
+		Values: map[string]string{"critical": "Incident", "warning": "Task"},
+	}
+
+	f := newTestFakeJira()
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, nil)
+	for _, tc := range []struct {
+		severity, issueType string
+	}{
+		{severity: "critical", issueType: "Incident"},
+		{severity: "warning", issueType: "Task"},
+		{severity: "info", issueType: "Bug"},
+	} {
+		data := &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  alertmanager.KV{"severity": tc.severity},
+			CommonLabels: alertmanager.KV{"severity": tc.severity},
+		}
+		_, err := receiver.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+		require.Equal(t, tc.issueType, f.issuesByKey[fmt.Sprintf("%d", len(f.issuesByKey))].Fields.Type.Name, tc.severity)
+	}
+}
+
+func TestNotify_ProjectMapping(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.ProjectMapping = &config.LabelMapping{
+		Label:  "team",
+		Values: map[string]string{"db": "DBOPS", "web": "WEB"},
+	}
+
+	f := newTestFakeJira()
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, nil)
+	for _, tc := range []struct {
+		team, project string
+	}{
+		{team: "db", project: "DBOPS"},
+		{team: "web", project: "WEB"},
+		{team: "net", project: conf.Project},
+	} {
+		data := &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  alertmanager.KV{"team": tc.team},
+			CommonLabels: alertmanager.KV{"team": tc.team},
+		}
+		_, err := receiver.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+		require.Equal(t, tc.project, f.issuesByKey[fmt.Sprintf("%d", len(f.issuesByKey))].Fields.Project.Key, tc.team)
+	}
+	require.Equal(t, []string{"abc", "DBOPS", "WEB"}, conf.StaticProjects())
+}
+
+func TestNotify_ComponentMapping(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Components = []string{"Operations", "Payments"}
+	conf.ComponentMapping = &config.ComponentMapping{
+		Label:  "service",
+		Values: map[string][]string{"checkout": {"Checkout", "Payments"}},
+	}
+
+	f := newTestFakeJira()
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, nil)
+	data := &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"service": "checkout"},
+		CommonLabels: alertmanager.KV{"service": "checkout"},
+	}
+	_, err := receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+
+	var components []string
+	for _, c := range f.issuesByKey["1"].Fields.Components {
+		components = append(components, c.Name)
+	}
+	require.Equal(t, []string{"Operations", "Payments", "Checkout"}, components)
+	require.Equal(t, []string{"Checkout", "Payments"}, conf.ComponentMapping.Components())
+}
+
+func TestNotify_AssigneeMapping(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Assignee = "ops-lead"
+	conf.AssigneeMapping = &config.LabelMapping{
+		Label:  "team",
+		Values: map[string]string{"db": "jdoe"},
+	}
+
+	f := newTestFakeJira()
+	receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, nil)
+	for _, tc := range []struct {
+		team, assignee string
+	}{
+		{team: "db", assignee: "jdoe"},
+		{team: "web", assignee: "ops-lead"},
+	} {
+		data := &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  alertmanager.KV{"team": tc.team},
+			CommonLabels: alertmanager.KV{"team": tc.team},
+		}
+		_, err := receiver.Notify(context.Background(), data, true)
+		require.NoError(t, err)
+		require.Equal(t, tc.assignee, f.issuesByKey[fmt.Sprintf("%d", len(f.issuesByKey))].Fields.Assignee.Name, tc.team)
+	}
+}
+
+func TestNotify_AssigneePool(t *testing.T) {
+	for _, tc := range []struct {
+		strategy  string
+		assignees []string
+	}{
+		{strategy: config.AssigneePoolRoundRobin, assignees: []string{"a", "b", "c", "a"}},
+		{strategy: config.AssigneePoolLeastRecentlyAssigned, assignees: []string{"a", "b", "c", "a"}},
+	} {
+		t.Run(tc.strategy, func(t *testing.T) {
+			conf := testReceiverConfig1()
+			conf.Name = "test"
+			conf.AssigneePool = &config.AssigneePool{Users: []string{"a", "b", "c"}, Strategy: tc.strategy}
+
+			f := newTestFakeJira()
+			receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, NewState())
+			now := time.Now()
+			receiver.timeNow = func() time.Time { now = now.Add(time.Second); return now }
+
+			// Rendering alone must not advance the rotation.
+			_, err := receiver.Render(&alertmanager.Data{Alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring}}}, true)
+			require.NoError(t, err)
+
+			for i, expected := range tc.assignees {
+				data := &alertmanager.Data{
+					Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+					Status:      alertmanager.AlertFiring,
+					GroupLabels: alertmanager.KV{"i": fmt.Sprintf("%d", i)},
+				}
+				_, err := receiver.Notify(context.Background(), data, true)
+				require.NoError(t, err)
+				require.Equal(t, expected, f.issuesByKey[fmt.Sprintf("%d", i+1)].Fields.Assignee.Name)
+			}
+		})
+	}
+}
+
+func TestNotify_AssigneePoolAfterRestart(t *testing.T) {
+	for _, strategy := range []string{config.AssigneePoolRoundRobin, config.AssigneePoolLeastRecentlyAssigned} {
+		t.Run(strategy, func(t *testing.T) {
+			conf := testReceiverConfig1()
+			conf.Name = "test"
+			conf.AssigneePool = &config.AssigneePool{Users: []string{"a", "b", "c"}, Strategy: strategy}
+			path := filepath.Join(t.TempDir(), "state.json")
+
+			f := newTestFakeJira()
+			now := time.Now()
+			for i, expected := range []string{"a", "b", "c", "a"} {
+				// Every notification is handled by a restarted JIRAlert, only the state file carries the rotation.
+				state, err := LoadState(path)
+				require.NoError(t, err)
+				receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+				receiver.timeNow = func() time.Time { now = now.Add(time.Second); return now }
+
+				_, err = receiver.Notify(context.Background(), &alertmanager.Data{
+					Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+					Status:      alertmanager.AlertFiring,
+					GroupLabels: alertmanager.KV{"i": fmt.Sprintf("%d", i)},
+				}, true)
+				require.NoError(t, err)
+				require.Equal(t, expected, f.issuesByKey[fmt.Sprintf("%d", i+1)].Fields.Assignee.Name)
+			}
+		})
+	}
+}
+
+func TestNotify_OnCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/oncalls" && r.Header.Get("Authorization") == "Token token=pd" && r.URL.Query().Get("schedule_ids[]") == "PSCHED":
+			_, _ = w.Write([]byte(`{"oncalls": [{"user": {"email": "jdoe@example.com"}}]}`))
+		case r.URL.Path == "/v2/schedules/ops/on-calls" && r.Header.Get("Authorization") == "GenieKey og":
+			_, _ = w.Write([]byte(`{"data": {"onCallRecipients": ["asmith@example.com"]}}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	timeout := config.Duration(time.Second)
+	for _, tc := range []struct {
+		name     string
+		oncall   config.OnCall
+		assignee string
+	}{
+		{
+			name:     "pagerduty, mapped user",
+			oncall:   config.OnCall{Provider: config.OnCallPagerDuty, Token: "pd", Schedule: "PSCHED", Users: map[string]string{"jdoe@example.com": "jdoe"}},
+			assignee: "jdoe",
+		},
+		{
+			name:     "opsgenie, unmapped user",
+			oncall:   config.OnCall{Provider: config.OnCallOpsgenie, Token: "og", Schedule: "ops"},
+			assignee: "asmith@example.com",
+		},
+		{
+			name:     "lookup fails, fallback assignee",
+			oncall:   config.OnCall{Provider: config.OnCallOpsgenie, Token: "wrong", Schedule: "ops"},
+			assignee: "ops-lead",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := testReceiverConfig1()
+			conf.Assignee = "ops-lead"
+			conf.OnCall = &tc.oncall
+			conf.OnCall.APIURL = srv.URL
+			conf.OnCall.Timeout = &timeout
+
+			f := newTestFakeJira()
+			receiver := NewReceiver(log.NewLogfmtLogger(os.Stderr), conf, template.SimpleTemplate(), f, nil)
+			data := &alertmanager.Data{
+				Alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+				Status: alertmanager.AlertFiring,
+			}
+			_, err := receiver.Notify(context.Background(), data, true)
+			require.NoError(t, err)
+			require.Equal(t, tc.assignee, f.issuesByKey["1"].Fields.Assignee.Name)
+		})
+	}
+}
+
+func TestHandleJiraErrResponse(t *testing.T) {
+	newResp := func(status int, body string) *jira.Response {
+		u, _ := url.Parse("https://jira.example.com/rest/api/2/issue")
+		return &jira.Response{Response: &http.Response{
+			StatusCode: status,
+			Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    &http.Request{URL: u},
+		}}
+	}
+
+	retry, err := handleJiraErrResponse("Issue.Create", newResp(http.StatusBadRequest, `{"errorMessages":[],"errors":{"summary":"Field is required","customfield_10010":"Field is required"}}`), errors.New("failed"), log.NewNopLogger())
+	require.False(t, retry)
+	require.EqualError(t, err, "JIRA request https://jira.example.com/rest/api/2/issue returned status 400 Bad Request: customfield_10010: Field is required; summary: Field is required")
+
+	retry, err = handleJiraErrResponse("Issue.Create", newResp(http.StatusServiceUnavailable, `<html>down</html>`), errors.New("failed"), log.NewNopLogger())
+	require.True(t, retry)
+	require.EqualError(t, err, `JIRA request https://jira.example.com/rest/api/2/issue returned status 503 Service Unavailable, body "<html>down</html>"`)
+
+	// Failed requests are typed, also when wrapped, unlike transport errors.
+	var apiErr *APIError
+	require.True(t, errors.As(errors.Wrap(err, "create issue"), &apiErr))
+	require.Equal(t, "Issue.Create", apiErr.API)
+	require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	require.True(t, apiErr.Retryable())
+
+	retry, err = handleJiraErrResponse("Issue.Create", nil, context.DeadlineExceeded, log.NewNopLogger())
+	require.False(t, retry)
+	require.False(t, errors.As(err, &apiErr))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNotify_RequestMetrics(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Name = "metrics"
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), newTestFakeJira(), nil)
+	_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+
+	for _, op := range []string{opSearch, opCreate} {
+		var m dto.Metric
+		require.NoError(t, requestDuration.WithLabelValues("metrics", op).(prometheus.Histogram).Write(&m))
+		require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount(), op)
+	}
+	require.Equal(t, 0.0, testutil.ToFloat64(requestErrorsTotal.WithLabelValues("metrics", opCreate, "")))
+}
+
+func TestNotify_LogsGroupKeyHash(t *testing.T) {
+	var buf bytes.Buffer
+	groupKey := `{}:{alertname="foo"}`
+	receiver := NewReceiver(log.NewLogfmtLogger(&buf), testReceiverConfig1(), template.SimpleTemplate(), newTestFakeJira(), nil)
+	_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupKey:    groupKey,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.NotEmpty(t, lines)
+	for _, l := range lines {
+		require.Contains(t, l, "groupKeyHash="+GroupKeyHash(groupKey))
+	}
+	require.Len(t, GroupKeyHash(groupKey), 16)
+	require.Equal(t, GroupKeyHash(groupKey), GroupKeyHash(groupKey))
+}
+
+func TestNotify_Shadow(t *testing.T) {
+	var buf bytes.Buffer
+	conf := testReceiverConfig1()
+	conf.Shadow = true
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewLogfmtLogger(&buf), conf, template.SimpleTemplate(), fakeJira, nil)
+	_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+
+	require.Empty(t, fakeJira.issuesByKey)
+	require.Contains(t, buf.String(), `msg="shadow mode, not sending mutation" operation=create`)
+}
+
+func TestNotify_AutoResolveComment(t *testing.T) {
+	conf := testReceiverConfigAutoResolve()
+	conf.AutoResolve.State = "done"
+	conf.AutoResolve.Comment = `{{ range .Alerts.Resolved }}{{ .Labels.alertname }} resolved at {{ .EndsAt.Format "15:04" }}{{ end }}`
+	fakeJira := newTestFakeJira()
+	// The fake sets the status category to the transition name.
+	fakeJira.transitionsByID["1234"] = jira.Transition{ID: "1234", Name: "done"}
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	endsAt := time.Date(2022, 11, 5, 20, 30, 0, 0, time.UTC)
+	for _, status := range []string{alertmanager.AlertFiring, alertmanager.AlertResolved, alertmanager.AlertResolved} {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: status, Labels: alertmanager.KV{"alertname": "foo"}, EndsAt: endsAt}},
+			Status:      status,
+			GroupLabels: alertmanager.KV{"alertname": "foo"},
+		}, true)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "done", fakeJira.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+	// Not commented again when notified of the already resolved group.
+	require.Equal(t, []string{"foo resolved at 20:30"}, fakeJira.commentsByKey["1"])
+}
+
+func TestNotify_UpdateSummaryDisabled(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Description = `{{ .Alerts.Firing | len }} firing`
+	updateSummary := false
+	conf.UpdateSummary = &updateSummary
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	for _, alerts := range []alertmanager.Alerts{
+		{{Status: alertmanager.AlertFiring}},
+		{{Status: alertmanager.AlertFiring}, {Status: alertmanager.AlertFiring}},
+	} {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+		// Edited by hand.
+		fakeJira.issuesByKey["1"].Fields.Summary = "INC-42: b"
+	}
+	require.Len(t, fakeJira.issuesByKey, 1)
+	require.Equal(t, "INC-42: b", fakeJira.issuesByKey["1"].Fields.Summary)
+	require.Equal(t, "2 firing", fakeJira.issuesByKey["1"].Fields.Description)
+}
+
+func TestNotify_UpdateOptions(t *testing.T) {
+	notifyUsers := false
+	for _, tc := range []struct {
+		name     string
+		conf     func(*config.ReceiverConfig)
+		expected *jira.UpdateQueryOptions
+	}{
+		{name: "defaults", conf: func(*config.ReceiverConfig) {}},
+		{
+			name:     "notify users disabled",
+			conf:     func(c *config.ReceiverConfig) { c.NotifyUsers = &notifyUsers },
+			expected: &jira.UpdateQueryOptions{},
+		},
+		{
+			name:     "update options",
+			conf:     func(c *config.ReceiverConfig) { c.UpdateOptions = &config.UpdateOptions{OverrideScreenSecurity: true} },
+			expected: &jira.UpdateQueryOptions{NotifyUsers: true, OverrideScreenSecurity: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := testReceiverConfig1()
+			conf.Description = `{{ .Alerts.Firing | len }} firing`
+			tc.conf(conf)
+			fakeJira := newTestFakeJira()
+			receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+			for _, alerts := range []alertmanager.Alerts{
+				{{Status: alertmanager.AlertFiring}},
+				{{Status: alertmanager.AlertFiring}, {Status: alertmanager.AlertFiring}},
+			} {
+				_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+					Alerts:      alerts,
+					Status:      alertmanager.AlertFiring,
+					GroupLabels: alertmanager.KV{"a": "b"},
+				}, true)
+				require.NoError(t, err)
+			}
+			require.Equal(t, "2 firing", fakeJira.issuesByKey["1"].Fields.Description)
+			require.Equal(t, tc.expected, fakeJira.updateOptions)
+		})
+	}
+}
+
+func TestNotify_UpdateDescriptionDisabled(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Description = `{{ .Alerts.Firing | len }} firing`
+	updateDescription := false
+	conf.UpdateDescription = &updateDescription
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	for _, alerts := range []alertmanager.Alerts{
+		{{Status: alertmanager.AlertFiring}},
+		{{Status: alertmanager.AlertFiring}, {Status: alertmanager.AlertFiring}},
+	} {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+	require.Len(t, fakeJira.issuesByKey, 1)
+	require.Equal(t, "[FIRING:2] b ", fakeJira.issuesByKey["1"].Fields.Summary)
+	require.Equal(t, "1 firing", fakeJira.issuesByKey["1"].Fields.Description)
+}
+
+func TestNotify_ManagedDescription(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Description = `{{ .Alerts.Firing | len }} firing`
+	managed := true
+	conf.ManagedDescription = &managed
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	notify := func(alerts alertmanager.Alerts) {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+	notify(alertmanager.Alerts{{Status: alertmanager.AlertFiring}})
+	require.Equal(t, descriptionSectionStart+"\n1 firing\n"+descriptionSectionEnd, fakeJira.issuesByKey["1"].Fields.Description)
+
+	// Edited by hand around the section.
+	fakeJira.issuesByKey["1"].Fields.Description = "Runbook: see wiki.\n\n" + fakeJira.issuesByKey["1"].Fields.Description + "\n\nRoot cause: disk."
+	notify(alertmanager.Alerts{{Status: alertmanager.AlertFiring}, {Status: alertmanager.AlertFiring}})
+	require.Equal(t, "Runbook: see wiki.\n\n"+descriptionSectionStart+"\n2 firing\n"+descriptionSectionEnd+"\n\nRoot cause: disk.", fakeJira.issuesByKey["1"].Fields.Description)
+
+	// Section removed by hand.
+	fakeJira.issuesByKey["1"].Fields.Description = "Root cause: disk."
+	notify(alertmanager.Alerts{{Status: alertmanager.AlertFiring}})
+	require.Equal(t, "Root cause: disk.\n\n"+descriptionSectionStart+"\n1 firing\n"+descriptionSectionEnd, fakeJira.issuesByKey["1"].Fields.Description)
+}
+
+func TestNotify_DiffIgnore(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Summary = `{{ .CommonLabels.alertname }} on {{ .CommonLabels.instance }}`
+	conf.Description = `{{ .CommonAnnotations.summary }} (evaluated {{ .CommonAnnotations.timestamp }})`
+	conf.DiffIgnore = &config.DiffIgnore{Labels: []string{"instance"}, Annotations: []string{"timestamp"}}
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	notify := func(instance, summary, timestamp string) {
+		labels := alertmanager.KV{"alertname": "DiskFull", "instance": instance}
+		annotations := alertmanager.KV{"summary": summary, "timestamp": timestamp}
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:            alertmanager.Alerts{{Status: alertmanager.AlertFiring, Labels: labels, Annotations: annotations}},
+			Status:            alertmanager.AlertFiring,
+			GroupLabels:       alertmanager.KV{"alertname": "DiskFull"},
+			CommonLabels:      labels,
+			CommonAnnotations: annotations,
+		}, true)
+		require.NoError(t, err)
+	}
+	notify("a:9100", "Disk full", "12:00")
+	require.Equal(t, "DiskFull on a:9100", fakeJira.issuesByKey["1"].Fields.Summary)
+	require.Equal(t, "Disk full (evaluated 12:00)", fakeJira.issuesByKey["1"].Fields.Description)
+
+	// Only ignored values changed.
+	notify("b:9100", "Disk full", "12:05")
+	require.Equal(t, "DiskFull on a:9100", fakeJira.issuesByKey["1"].Fields.Summary)
+	require.Equal(t, "Disk full (evaluated 12:00)", fakeJira.issuesByKey["1"].Fields.Description)
+
+	notify("b:9100", "Disk almost full", "12:10")
+	require.Equal(t, "DiskFull on a:9100", fakeJira.issuesByKey["1"].Fields.Summary)
+	require.Equal(t, "Disk almost full (evaluated 12:10)", fakeJira.issuesByKey["1"].Fields.Description)
+}
+
+func TestNotify_DescriptionHeaderFooter(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Description = `{{ .CommonAnnotations.summary }}`
+	conf.DescriptionHeader = `{{ if .CommonLabels.critical }}Page the on-call engineer.{{ end }}`
+	conf.DescriptionFooter = `Dashboard: https://grafana/d/{{ .CommonLabels.alertname }}`
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	notify := func(labels alertmanager.KV) {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:            alertmanager.Alerts{{Status: alertmanager.AlertFiring, Labels: labels}},
+			Status:            alertmanager.AlertFiring,
+			GroupLabels:       alertmanager.KV{"alertname": "DiskFull"},
+			CommonLabels:      labels,
+			CommonAnnotations: alertmanager.KV{"summary": "Disk full"},
+		}, true)
+		require.NoError(t, err)
+	}
+	// Empty header is left out.
+	notify(alertmanager.KV{"alertname": "DiskFull"})
+	require.Equal(t, "Disk full\n\nDashboard: https://grafana/d/DiskFull", fakeJira.issuesByKey["1"].Fields.Description)
+
+	notify(alertmanager.KV{"alertname": "DiskFull", "critical": "true"})
+	require.Equal(t, "Page the on-call engineer.\n\nDisk full\n\nDashboard: https://grafana/d/DiskFull", fakeJira.issuesByKey["1"].Fields.Description)
+}
+
+func TestNotifyGroups_ContinuesAfterFailure(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.GroupIssueBy = config.Alert
+	conf.IssueIdentifierLabel = `disk-full-{{ .CommonLabels.instance }}`
+	conf.Summary = `{{ .CommonLabels.instance }}{{ if .CommonLabels.broken }}{{ index .CommonLabels.broken 99 }}{{ end }}`
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	labels := func(instance string, extra ...string) alertmanager.KV {
+		kv := alertmanager.KV{"alertname": "DiskFull", "instance": instance}
+		if len(extra) > 0 {
+			kv["broken"] = extra[0]
+		}
+		return kv
+	}
+	results, retry, err := receiver.NotifyGroups(context.Background(), &alertmanager.Data{
+		Alerts: alertmanager.Alerts{
+			{Status: alertmanager.AlertFiring, Labels: labels("a")},
+			{Status: alertmanager.AlertFiring, Labels: labels("b", "x")},
+			{Status: alertmanager.AlertFiring, Labels: labels("c")},
+		},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"alertname": "DiskFull"},
+	}, true)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1 of 3 alert groups failed")
+	require.False(t, retry)
+
+	// The groups after the failing one are still notified.
+	require.Len(t, fakeJira.issuesByKey, 2)
+	require.Len(t, results, 3)
+	require.Equal(t, GroupResult{Labels: labels("a"), IssueKeys: []string{"1"}}, results[0])
+	require.Equal(t, labels("b", "x"), results[1].Labels)
+	require.Empty(t, results[1].IssueKeys)
+	require.NotEmpty(t, results[1].Error)
+	require.Equal(t, GroupResult{Labels: labels("c"), IssueKeys: []string{"2"}}, results[2])
+}
+
+func TestNotify_PartialResolutionComment(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.PartialResolutionComment = `Resolved:{{ range .Alerts.Resolved }} {{ .Labels.instance }}{{ end }}`
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, NewState())
+
+	end := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	notify := func(alerts ...alertmanager.Alert) {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+	alert := func(instance, status string) alertmanager.Alert {
+		a := alertmanager.Alert{Status: status, Labels: alertmanager.KV{"a": "b", "instance": instance}}
+		if status == alertmanager.AlertResolved {
+			a.EndsAt = end
+		}
+		return a
+	}
+
+	notify(alert("x", alertmanager.AlertFiring), alert("y", alertmanager.AlertFiring), alert("z", alertmanager.AlertFiring))
+	require.Len(t, fakeJira.issuesByKey, 1)
+	require.Empty(t, fakeJira.commentsByKey["1"])
+
+	notify(alert("x", alertmanager.AlertResolved), alert("y", alertmanager.AlertResolved), alert("z", alertmanager.AlertFiring))
+	require.Equal(t, []string{"Resolved: x y"}, fakeJira.commentsByKey["1"])
+
+	// Resolutions already listed are not listed again.
+	notify(alert("x", alertmanager.AlertResolved), alert("z", alertmanager.AlertFiring))
+	require.Equal(t, []string{"Resolved: x y"}, fakeJira.commentsByKey["1"])
+
+	end = end.Add(time.Hour)
+	notify(alert("x", alertmanager.AlertResolved), alert("z", alertmanager.AlertFiring))
+	require.Equal(t, []string{"Resolved: x y", "Resolved: x"}, fakeJira.commentsByKey["1"])
+}
+
+func TestNotify_TemplateErrors(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Description = `{{ template "missing" . }}`
+	conf.Priority = `{{ template "missing" . }}`
+	conf.Fields = map[string]interface{}{"customfield_10001": `{{ template "missing" . }}`, "customfield_10002": "ok"}
+	data := func() *alertmanager.Data {
+		return &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring, Labels: alertmanager.KV{"a": "b", "instance": "x"}}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}
+	}
+	before := testutil.ToFloat64(templateErrorsTotal.WithLabelValues(conf.Name, "description"))
+	beforeField := testutil.ToFloat64(templateErrorsTotal.WithLabelValues(conf.Name, "fields.customfield_10001"))
+
+	// By default, the notification fails.
+	fakeJira := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil).Notify(context.Background(), data(), true)
+	require.Error(t, err)
+	require.Len(t, fakeJira.issuesByKey, 0)
+	require.Equal(t, before+1, testutil.ToFloat64(templateErrorsTotal.WithLabelValues(conf.Name, "description")))
+
+	conf.TemplateErrors = config.TemplateErrorsFallback
+	_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil).Notify(context.Background(), data(), true)
+	require.NoError(t, err)
+	require.Len(t, fakeJira.issuesByKey, 1)
+	issue := fakeJira.issuesByKey["1"]
+	require.Equal(t, "[FIRING:1] b ", issue.Fields.Summary)
+	require.Contains(t, issue.Fields.Description, "The description template failed to render: ")
+	require.Contains(t, issue.Fields.Description, `Labels: a="b", instance="x"`)
+	require.Nil(t, issue.Fields.Priority)
+	require.NotContains(t, issue.Fields.Unknowns, "customfield_10001")
+	require.Equal(t, "ok", issue.Fields.Unknowns["customfield_10002"])
+	require.Equal(t, before+2, testutil.ToFloat64(templateErrorsTotal.WithLabelValues(conf.Name, "description")))
+	require.Equal(t, beforeField+1, testutil.ToFloat64(templateErrorsTotal.WithLabelValues(conf.Name, "fields.customfield_10001")))
+
+	// The project never falls back.
+	conf.Project = `{{ template "missing" . }}`
+	_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil).Notify(context.Background(), data(), true)
+	require.Error(t, err)
+}
+
+func TestNotify_TemplateDefaults(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Priority = `{{ .CommonLabels.priority }}`
+	conf.Description = `{{ template "missing" . }}`
+	conf.Fields = map[string]interface{}{"customfield_10001": `{{ .CommonLabels.team }}`, "customfield_10002": `{{ .CommonLabels.team }}`}
+	conf.TemplateDefaults = map[string]interface{}{
+		"priority":                 "Medium",
+		"description":              "No description.",
+		"fields.customfield_10001": map[string]interface{}{"value": "unassigned"},
+	}
+	fakeJira := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}, true)
+	require.NoError(t, err)
+	issue := fakeJira.issuesByKey["1"]
+	require.Equal(t, "Medium", issue.Fields.Priority.Name)
+	require.Equal(t, "No description.", issue.Fields.Description)
+	require.Equal(t, map[string]interface{}{"value": "unassigned"}, issue.Fields.Unknowns["customfield_10001"])
+	require.Equal(t, "", issue.Fields.Unknowns["customfield_10002"])
+
+	_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"a": "c"},
+		CommonLabels: alertmanager.KV{"a": "c", "priority": "High", "team": "storage"},
+	}, true)
+	require.NoError(t, err)
+	issue = fakeJira.issuesByKey["2"]
+	require.Equal(t, "High", issue.Fields.Priority.Name)
+	require.Equal(t, "storage", issue.Fields.Unknowns["customfield_10001"])
+}
+
+func TestNotify_JiraBlocks(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Description = `{{ .CommonAnnotations.log | jiraCode "java" }}
+{{ .CommonAnnotations.query | jiraNoformat }}`
+	fakeJira := newTestFakeJira()
+	_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil).Notify(context.Background(), &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+		CommonAnnotations: alertmanager.KV{
+			"log":   "Exception in {main} | at Foo.bar()\n",
+			"query": "sum(rate(x[5m])) {noformat} |",
+		},
+	}, true)
+	require.NoError(t, err)
+	require.Equal(t, "{code:java}\nException in {main} | at Foo.bar()\n{code}\n{noformat}\nsum(rate(x[5m])) {\u200bnoformat} |\n{noformat}", fakeJira.issuesByKey["1"].Fields.Description)
+}
+
+func TestNotify_AlertCountField(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.AlertCountField = "customfield_10050"
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	for _, tc := range []struct {
+		alerts   alertmanager.Alerts
+		expected int
+	}{
+		{alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring}}, expected: 1},
+		{alerts: alertmanager.Alerts{{Status: alertmanager.AlertFiring}, {Status: alertmanager.AlertFiring}, {Status: alertmanager.AlertResolved}}, expected: 2},
+		{alerts: alertmanager.Alerts{{Status: alertmanager.AlertResolved}}, expected: 0},
+	} {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      tc.alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+		require.Len(t, fakeJira.issuesByKey, 1)
+		require.Equal(t, tc.expected, fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10050"])
+	}
+}
+
+func TestNotify_DateTimeFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.FiringSinceField = "customfield_10051"
+	conf.LastSeenField = "customfield_10052"
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	startsAt := time.Date(2022, 11, 5, 20, 0, 0, 0, time.UTC)
+	for i, alerts := range []alertmanager.Alerts{
+		{{Status: alertmanager.AlertFiring, StartsAt: startsAt.Add(time.Minute)}},
+		{{Status: alertmanager.AlertFiring, StartsAt: startsAt.Add(time.Minute)}, {Status: alertmanager.AlertFiring, StartsAt: startsAt}},
+	} {
+		now := startsAt.Add(time.Duration(i+1) * time.Hour)
+		receiver.timeNow = func() time.Time { return now }
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alerts,
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+	require.Len(t, fakeJira.issuesByKey, 1)
+	require.Equal(t, "2022-11-05T20:00:00.000+0000", fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10051"])
+	require.Equal(t, "2022-11-05T22:00:00.000+0000", fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10052"])
+}
+
+func TestNotify_ResolutionFields(t *testing.T) {
+	conf := testReceiverConfigAutoResolve()
+	conf.AutoResolve.State = "done"
+	conf.AutoResolve.EndedAtField = "customfield_10053"
+	conf.AutoResolve.DurationField = "customfield_10054"
+	fakeJira := newTestFakeJira()
+	fakeJira.transitionsByID["1234"] = jira.Transition{ID: "1234", Name: "done"}
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	startsAt := time.Date(2022, 11, 5, 20, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(90 * time.Minute)
+	for _, status := range []string{alertmanager.AlertFiring, alertmanager.AlertResolved} {
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts: alertmanager.Alerts{
+				{Status: status, StartsAt: startsAt, EndsAt: endsAt.Add(-time.Hour)},
+				{Status: status, StartsAt: startsAt.Add(time.Minute), EndsAt: endsAt},
+			},
+			Status:      status,
+			GroupLabels: alertmanager.KV{"a": "b"},
+		}, true)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "done", fakeJira.issuesByKey["1"].Fields.Status.StatusCategory.Key)
+	require.Equal(t, "2022-11-05T21:30:00.000+0000", fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10053"])
+	require.Equal(t, 5400, fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10054"])
+}
+
+// fakeAssets is a fake Jira with Assets objects, by AQL query.
+type fakeAssets struct {
+	*fakeJira
+	objects map[string][]interface{}
+}
+
+func (f *fakeAssets) SearchAssetsWithContext(_ context.Context, _, aql string, limit int) ([]interface{}, error) {
+	objects := f.objects[aql]
+	if len(objects) > limit {
+		objects = objects[:limit]
+	}
+	return objects, nil
+}
+
+func TestNotify_AssetsFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.AssetsFields = []*config.AssetsField{
+		{Field: "customfield_10100", Query: `Name = "{{ .CommonLabels.instance }}"`, MaxObjects: 1},
+		{Field: "customfield_10101", Query: `Name = "unknown"`, MaxObjects: 1},
+	}
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), &fakeAssets{
+		fakeJira: fakeJira,
+		objects: map[string][]interface{}{
+			`Name = "db-1"`: {map[string]interface{}{"key": "CMDB-1"}, map[string]interface{}{"key": "CMDB-2"}},
+		},
+	}, nil)
+
+	_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"a": "b"},
+		CommonLabels: alertmanager.KV{"a": "b", "instance": "db-1"},
+	}, true)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{map[string]interface{}{"key": "CMDB-1"}}, fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10100"])
+	require.NotContains(t, fakeJira.issuesByKey["1"].Fields.Unknowns, "customfield_10101")
+}
+
+// fakeServiceDesk is a fake Jira Service Management with organizations, by name, and SLAs, by request key.
+type fakeServiceDesk struct {
+	*fakeJira
+	organizations map[string]int
+	slas          map[string][]SLA
+}
+
+func (f *fakeServiceDesk) OrganizationIDsWithContext(_ context.Context, organizations []string) ([]int, error) {
+	var ids []int
+	for _, o := range organizations {
+		id, ok := f.organizations[o]
+		if !ok {
+			return nil, errors.Errorf("unknown organization %q", o)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (f *fakeServiceDesk) ParticipantsWithContext(_ context.Context, users []string) ([]interface{}, error) {
+	var values []interface{}
+	for _, u := range users {
+		values = append(values, map[string]interface{}{"name": u})
+	}
+	return values, nil
+}
+
+func (f *fakeServiceDesk) SLAsWithContext(_ context.Context, issueKey string) ([]SLA, error) {
+	return f.slas[issueKey], nil
+}
+
+func TestNotify_ServiceDeskFields(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.ServiceDesk = &config.ServiceDesk{
+		OrganizationsField: "customfield_10002",
+		Organizations:      []string{"{{ .CommonLabels.customer }}", "Operations", "{{ .CommonLabels.missing }}"},
+		ParticipantsField:  "customfield_10001",
+		Participants:       []string{"{{ .CommonLabels.owner }}", "{{ .CommonLabels.owner }}"},
+	}
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), &fakeServiceDesk{
+		fakeJira:      fakeJira,
+		organizations: map[string]int{"Acme": 1, "Operations": 2},
+	}, nil)
+
+	_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"a": "b"},
+		CommonLabels: alertmanager.KV{"a": "b", "customer": "Acme", "owner": "jdoe"},
+	}, true)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10002"])
+	require.Equal(t, []interface{}{map[string]interface{}{"name": "jdoe"}}, fakeJira.issuesByKey["1"].Fields.Unknowns["customfield_10001"])
+}
+
+func TestWarnSLABreaches(t *testing.T) {
+	before := config.Duration(30 * time.Minute)
+	conf := testReceiverConfig1()
+	conf.Name = "test"
+	conf.ServiceDesk = &config.ServiceDesk{SLAWarning: &config.SLAWarning{
+		Before:   &before,
+		SLAs:     []string{"Time to resolution"},
+		Comment:  config.DefaultSLAWarningComment,
+		Priority: "Highest",
+	}}
+
+	f := newTestFakeJira()
+	for _, labels := range []alertmanager.KV{{"a": "b"}, {"a": "c"}} {
+		_, _, err := f.CreateWithContext(context.Background(), &jira.Issue{
+			Fields: &jira.IssueFields{
+				Project:  jira.Project{Key: conf.Project},
+				Labels:   []string{toGroupTicketLabel(labels, true)},
+				Unknowns: tcontainer.MarshalMap{},
+			},
+		})
+		require.NoError(t, err)
+	}
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	sd := &fakeServiceDesk{fakeJira: f, slas: map[string][]SLA{
+		"1": {
+			{Name: "Time to resolution", StartTime: start, Remaining: 20 * time.Minute},
+			{Name: "Time to first response", StartTime: start, Remaining: 5 * time.Minute},
+		},
+		// Not firing anymore.
+		"2": {{Name: "Time to resolution", StartTime: start, Remaining: 20 * time.Minute}},
+	}}
+
+	groups := []alertmanager.Data{
+		{
+			Receiver:    "test",
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": "b"},
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		},
+	}
+
+	state := NewState()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), sd, state)
+	require.NoError(t, receiver.WarnSLABreaches(context.Background(), groups, true))
+	require.Equal(t, []string{`SLA "Time to resolution" breaches in 20m0s while the alerts of this request are still firing.`}, f.commentsByKey["1"])
+	require.Equal(t, map[string]interface{}{"name": "Highest"}, f.issuesByKey["1"].Fields.Unknowns["priority"])
+	require.Empty(t, f.commentsByKey["2"])
+
+	// Every SLA cycle is only warned about once.
+	require.NoError(t, receiver.WarnSLABreaches(context.Background(), groups, true))
+	require.Len(t, f.commentsByKey["1"], 1)
+
+	sd.slas["1"][0].StartTime = start.Add(time.Hour)
+	require.NoError(t, receiver.WarnSLABreaches(context.Background(), groups, true))
+	require.Len(t, f.commentsByKey["1"], 2)
+}
+
+func TestNotify_MultipleProjects(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Backend = config.BackendJira
+	conf.Project = "abc, {{ .CommonLabels.team }}, abc"
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	data := &alertmanager.Data{
+		Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:       alertmanager.AlertFiring,
+		GroupLabels:  alertmanager.KV{"a": "b"},
+		CommonLabels: alertmanager.KV{"a": "b", "team": "INC"},
+	}
+	_, err := receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	require.Len(t, fakeJira.issuesByKey, 2)
+	require.Equal(t, "abc", fakeJira.issuesByKey["1"].Fields.Project.Key)
+	require.Equal(t, "INC", fakeJira.issuesByKey["2"].Fields.Project.Key)
+	require.Equal(t, map[string][]string{"1": {"2"}}, fakeJira.linksByKey)
+
+	// Existing issues are not linked again, new ones are linked to them.
+	data.CommonLabels["team"] = "INC, WEB"
+	_, err = receiver.Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	require.Len(t, fakeJira.issuesByKey, 3)
+	require.Equal(t, map[string][]string{"1": {"2", "3"}, "2": {"3"}}, fakeJira.linksByKey)
+}
+
+func TestNotify_IncidentMode(t *testing.T) {
+	window := config.Duration(10 * time.Minute)
+	conf := testReceiverConfig1()
+	conf.IncidentMode = &config.IncidentMode{Window: &window, Matchers: map[string]string{"cluster": "eu"}, Summary: config.DefaultIncidentSummary}
+
+	f := newTestFakeJira()
+	state := NewState()
+	now := time.Now()
+	notify := func(group, cluster string) {
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		_, err := r.Notify(context.Background(), &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  alertmanager.KV{"a": group},
+			CommonLabels: alertmanager.KV{"cluster": cluster},
+		}, true)
+		require.NoError(t, err)
+	}
+
+	// The first group creates the umbrella issue, further ones within the rolling window are commented on it, once.
+	notify("b", "eu")
+	require.Len(t, f.issuesByKey, 1)
+	require.Equal(t, []string{IncidentLabel}, f.issuesByKey["1"].Fields.Labels)
+	require.Equal(t, config.DefaultIncidentSummary, f.issuesByKey["1"].Fields.Summary)
+	now = now.Add(8 * time.Minute)
+	notify("c", "eu")
+	notify("c", "eu")
+	now = now.Add(8 * time.Minute)
+	notify("d", "eu")
+	require.Len(t, f.issuesByKey, 1)
+	require.Len(t, f.commentsByKey["1"], 3)
+
+	// Groups not matching get their own issues.
+	notify("e", "us")
+	require.Len(t, f.issuesByKey, 2)
+
+	// Once the window passed without new groups, the next outage gets a new umbrella issue.
+	now = now.Add(10 * time.Minute)
+	notify("f", "eu")
+	require.Len(t, f.issuesByKey, 3)
+	require.Equal(t, []string{IncidentLabel}, f.issuesByKey["3"].Fields.Labels)
+	require.Len(t, f.commentsByKey["3"], 1)
+}
+
+func TestNotify_IncidentModeRetriesFailedComments(t *testing.T) {
+	window := config.Duration(10 * time.Minute)
+	conf := testReceiverConfig1()
+	conf.IncidentMode = &config.IncidentMode{Window: &window, Summary: config.DefaultIncidentSummary}
+
+	f := &failingCommentJira{fakeJira: newTestFakeJira()}
+	state := NewState()
+	notify := func(group string) error {
+		_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state).Notify(context.Background(), &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": group},
+		}, true)
+		return err
+	}
+
+	require.NoError(t, notify("b"))
+	f.fail = true
+	require.Error(t, notify("c"))
+	require.Len(t, f.commentsByKey["1"], 1)
+
+	// The failed comment is retried once, not skipped as already listed.
+	f.fail = false
+	require.NoError(t, notify("c"))
+	require.NoError(t, notify("c"))
+	require.Len(t, f.issuesByKey, 1)
+	require.Len(t, f.commentsByKey["1"], 2)
+}
+
+func TestNotify_CurrentIncident(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Backend = config.BackendJira
+	conf.CurrentIncident = &config.CurrentIncident{IssueKey: "OPS-1", LinkType: "Relates"}
+	fakeJira := newTestFakeJira()
+	state := NewState()
+	notify := func(group string) {
+		data := &alertmanager.Data{
+			Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:      alertmanager.AlertFiring,
+			GroupLabels: alertmanager.KV{"a": group},
+		}
+		_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+
+	notify("b")
+	notify("b")
+	state.SetIncident(conf.Name, "OPS-2")
+	notify("c")
+	state.SetIncident(conf.Name, "")
+	notify("d")
+	require.Len(t, fakeJira.issuesByKey, 3)
+	require.Equal(t, map[string][]string{"1": {"OPS-1"}, "2": {"OPS-2"}}, fakeJira.linksByKey)
+	require.Equal(t, "", state.Incident(conf))
+}
+
+func TestNotify_DedupeGroup(t *testing.T) {
+	first := testReceiverConfig1()
+	first.Name = "first"
+	first.DedupeGroup = "incidents"
+	second := testReceiverConfig1()
+	second.Name = "second"
+	second.Project = "xyz"
+	second.DedupeGroup = "incidents"
+	second.DedupeWatchers = []string{"jane", "john"}
+
+	fakeJira := newTestFakeJira()
+	state := NewState()
+	data := &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}
+
+	_, err := NewReceiver(log.NewNopLogger(), first, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = NewReceiver(log.NewNopLogger(), second, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+	require.Len(t, fakeJira.issuesByKey, 1)
+	require.Equal(t, []string{`This alert group was also sent to receiver "second", which did not create a separate issue.`}, fakeJira.commentsByKey["1"])
+	require.Equal(t, []string{"jane", "john"}, fakeJira.watchersByKey["1"])
+
+	// Once the issue is resolved, the next receiver creates its own.
+	data.Status = alertmanager.AlertResolved
+	data.Alerts[0].Status = alertmanager.AlertResolved
+	first.AutoResolve = &config.AutoResolve{State: "Done"}
+	_, err = NewReceiver(log.NewNopLogger(), first, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	data.Status = alertmanager.AlertFiring
+	data.Alerts[0].Status = alertmanager.AlertFiring
+	_, err = NewReceiver(log.NewNopLogger(), second, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	require.Len(t, fakeJira.issuesByKey, 2)
+	require.Equal(t, "xyz", fakeJira.issuesByKey["2"].Fields.Project.Key)
+}
+
+func TestNotify_DedupeGroupAfterRestart(t *testing.T) {
+	first := testReceiverConfig1()
+	first.Name = "first"
+	first.DedupeGroup = "incidents"
+	second := testReceiverConfig1()
+	second.Name = "second"
+	second.Project = "xyz"
+	second.DedupeGroup = "incidents"
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	fakeJira := newTestFakeJira()
+	data := &alertmanager.Data{
+		Alerts:      alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+		Status:      alertmanager.AlertFiring,
+		GroupLabels: alertmanager.KV{"a": "b"},
+	}
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	_, err = NewReceiver(log.NewNopLogger(), first, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	_, err = NewReceiver(log.NewNopLogger(), second, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), data, true)
+	require.NoError(t, err)
+	require.NoError(t, state.Flush())
+
+	// After a restart, the owner of the alert group is still known: neither receiver creates another issue, and the
+	// second one does not comment again.
+	restarted, err := LoadState(path)
+	require.NoError(t, err)
+	for _, conf := range []*config.ReceiverConfig{second, first} {
+		_, err = NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, restarted).Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+	require.Len(t, fakeJira.issuesByKey, 1)
+	require.Len(t, fakeJira.commentsByKey["1"], 1)
+}
+
+func TestNotify_InhibitRules(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Name = "test"
+	conf.InhibitRules = []*config.InhibitRule{{
+		SourceMatchers: map[string]string{"alertname": "ClusterDown"},
+		Equal:          []string{"cluster"},
+	}}
+	fakeJira := newTestFakeJira()
+	state := NewState()
+	notify := func(labels alertmanager.KV) {
+		_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, state).Notify(context.Background(), &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  labels,
+			CommonLabels: labels,
+		}, true)
+		require.NoError(t, err)
+	}
+
+	notify(alertmanager.KV{"alertname": "ClusterDown", "cluster": "a"})
+	notify(alertmanager.KV{"alertname": "PodCrashLooping", "cluster": "a"})
+	notify(alertmanager.KV{"alertname": "PodCrashLooping", "cluster": "a"})
+	notify(alertmanager.KV{"alertname": "PodCrashLooping", "cluster": "b"})
+
+	require.Len(t, fakeJira.issuesByKey, 2)
+	require.Contains(t, fakeJira.issuesByKey["2"].Fields.Summary, "PodCrashLooping b")
+	require.Len(t, fakeJira.commentsByKey["1"], 1)
+	require.Contains(t, fakeJira.commentsByKey["1"][0], "PodCrashLooping a")
+}
+
+func TestNotify_InhibitRulesRetriesFailedComments(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Name = "test"
+	conf.InhibitRules = []*config.InhibitRule{{
+		SourceMatchers: map[string]string{"alertname": "ClusterDown"},
+		Equal:          []string{"cluster"},
+	}}
+	f := &failingCommentJira{fakeJira: newTestFakeJira()}
+	state := NewState()
+	notify := func(labels alertmanager.KV) error {
+		_, err := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state).Notify(context.Background(), &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  labels,
+			CommonLabels: labels,
+		}, true)
+		return err
+	}
+
+	require.NoError(t, notify(alertmanager.KV{"alertname": "ClusterDown", "cluster": "a"}))
+	f.fail = true
+	require.Error(t, notify(alertmanager.KV{"alertname": "PodCrashLooping", "cluster": "a"}))
+	require.Empty(t, f.commentsByKey["1"])
+
+	// The failed comment is retried once, not skipped as already listed.
+	f.fail = false
+	require.NoError(t, notify(alertmanager.KV{"alertname": "PodCrashLooping", "cluster": "a"}))
+	require.NoError(t, notify(alertmanager.KV{"alertname": "PodCrashLooping", "cluster": "a"}))
+	require.Len(t, f.issuesByKey, 1)
+	require.Len(t, f.commentsByKey["1"], 1)
+	require.Contains(t, f.commentsByKey["1"][0], "PodCrashLooping a")
+}
+
+func TestNotify_Epics(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Epics = &config.Epics{Labels: []string{"alertname"}, IssueType: "Epic", Summary: "{{ .CommonLabels.alertname }}"}
+	fakeJira := newTestFakeJira()
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	for _, instance := range []string{"a", "b"} {
+		labels := alertmanager.KV{"alertname": "HighLatency", "instance": instance}
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts:       alertmanager.Alerts{{Status: alertmanager.AlertFiring}},
+			Status:       alertmanager.AlertFiring,
+			GroupLabels:  labels,
+			CommonLabels: labels,
+		}, true)
+		require.NoError(t, err)
+	}
+
+	require.Len(t, fakeJira.issuesByKey, 3)
+	epic := fakeJira.issuesByKey["1"]
+	require.Equal(t, "Epic", epic.Fields.Type.Name)
+	require.Equal(t, "HighLatency", epic.Fields.Summary)
+	require.Equal(t, []string{`JIRALERT_EPIC{alertname="HighLatency"}`}, epic.Fields.Labels)
+	require.Equal(t, &jira.Parent{Key: "1"}, fakeJira.issuesByKey["2"].Fields.Parent)
+	require.Equal(t, &jira.Parent{Key: "1"}, fakeJira.issuesByKey["3"].Fields.Parent)
+}
+
+func TestNotify_Hierarchy(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Epics = &config.Epics{Labels: []string{"alertname"}, IssueType: "Epic", Summary: "{{ .CommonLabels.alertname }}"}
+	conf.Subtasks = &config.Subtasks{Labels: []string{"instance"}, IssueType: "Sub-task", Summary: "{{ .CommonLabels.instance }}"}
+	conf.AutoResolve = &config.AutoResolve{State: "done"}
+	fakeJira := newTestFakeJira()
+	fakeJira.transitionsByID = map[string]jira.Transition{"1": {ID: "1", Name: "done"}}
+	receiver := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), fakeJira, nil)
+
+	notify := func(x, y string) {
+		status := alertmanager.AlertResolved
+		if x == alertmanager.AlertFiring || y == alertmanager.AlertFiring {
+			status = alertmanager.AlertFiring
+		}
+		_, err := receiver.Notify(context.Background(), &alertmanager.Data{
+			Alerts: alertmanager.Alerts{
+				{Status: x, Labels: alertmanager.KV{"alertname": "A", "instance": "x"}},
+				{Status: y, Labels: alertmanager.KV{"alertname": "A", "instance": "y"}},
+			},
+			Status:       status,
+			GroupLabels:  alertmanager.KV{"alertname": "A"},
+			CommonLabels: alertmanager.KV{"alertname": "A"},
+		}, true)
+		require.NoError(t, err)
+	}
+	statuses := func() []string {
+		var res []string
+		for _, key := range []string{"1", "2", "3", "4"} {
+			res = append(res, fakeJira.issuesByKey[key].Fields.Status.StatusCategory.Key)
+		}
+		return res
+	}
+
+	notify(alertmanager.AlertFiring, alertmanager.AlertFiring)
+	require.Len(t, fakeJira.issuesByKey, 4)
+	require.Equal(t, "Epic", fakeJira.issuesByKey["1"].Fields.Type.Name)
+	require.Equal(t, &jira.Parent{Key: "1"}, fakeJira.issuesByKey["2"].Fields.Parent)
+	for _, key := range []string{"3", "4"} {
+		require.Equal(t, "Sub-task", fakeJira.issuesByKey[key].Fields.Type.Name)
+		require.Equal(t, &jira.Parent{Key: "2"}, fakeJira.issuesByKey[key].Fields.Parent)
+	}
+	require.Equal(t, "x", fakeJira.issuesByKey["3"].Fields.Summary)
+
+	notify(alertmanager.AlertResolved, alertmanager.AlertFiring)
+	require.Equal(t, []string{"NotDone", "NotDone", "done", "NotDone"}, statuses())
+
+	notify(alertmanager.AlertResolved, alertmanager.AlertResolved)
+	require.Equal(t, []string{"done", "done", "done", "done"}, statuses())
+}
+
+func TestNotify_Pause(t *testing.T) {
+	conf := testReceiverConfig1()
+	conf.Name = "paused"
+	f := newTestFakeJira()
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	start := time.Date(2022, 10, 17, 12, 0, 0, 0, time.UTC)
+	receiver := func(now time.Time) *Receiver {
+		r := NewReceiver(log.NewNopLogger(), conf, template.SimpleTemplate(), f, state)
+		r.timeNow = func() time.Time { return now }
+		return r
+	}
+	notify := func(now time.Time, groupKey, label string, alerts int) {
+		data := &alertmanager.Data{Status: alertmanager.AlertFiring, GroupKey: groupKey, GroupLabels: alertmanager.KV{"a": label}}
+		for i := 0; i < alerts; i++ {
+			data.Alerts = append(data.Alerts, alertmanager.Alert{Status: alertmanager.AlertFiring})
+		}
+		_, err := receiver(now).Notify(context.Background(), data, true)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, state.Pause(""))
+	require.True(t, state.Paused(conf.Name))
+	notify(start, "g1", "b", 1)
+	notify(start.Add(time.Minute), "g2", "c", 1)
+	// Only the latest notification of a group is kept.
+	notify(start.Add(2*time.Minute), "g1", "b", 2)
+	require.Empty(t, f.issuesByKey)
+	require.Equal(t, 2, state.Queued(conf.Name))
+
+	// Still paused, nothing is processed.
+	n, _, err := receiver(start).NotifyQueued(context.Background(), true)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// A receiver stays paused while all receivers are.
+	require.NoError(t, state.Pause(conf.Name))
+	require.NoError(t, state.Resume(conf.Name))
+	require.True(t, state.Paused(conf.Name))
+	all, receivers := state.PausedReceivers()
+	require.True(t, all)
+	require.Empty(t, receivers)
+
+	// The pause and the queue survive a restart.
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	require.True(t, state.Paused(conf.Name))
+	require.Equal(t, 2, state.Queued(conf.Name))
+
+	require.NoError(t, state.Resume(""))
+	n, _, err = receiver(start).NotifyQueued(context.Background(), true)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 0, state.Queued(conf.Name))
+	require.Len(t, f.issuesByKey, 2)
+	// The oldest queued notification is processed first.
+	require.Equal(t, "[FIRING:1] c ", f.issuesByKey["1"].Fields.Summary)
+	require.Equal(t, "[FIRING:2] b ", f.issuesByKey["2"].Fields.Summary)
+
+	// Processed notifications are removed from the state file.
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	require.False(t, state.Paused(conf.Name))
+	require.Equal(t, 0, state.Queued(conf.Name))
+}
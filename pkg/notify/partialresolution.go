@@ -0,0 +1,90 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// partialResolutionRetention is how long resolved alerts listed in a partial resolution comment are remembered.
+// Alertmanager stops sending resolved alerts long before.
+const partialResolutionRetention = 24 * time.Hour
+
+// addPartialResolutionComment comments on the open issue with the given key if alerts of the still firing group
+// resolved since the last comment, as configured by partial_resolution_comment. Failures are logged only.
+func (r *Receiver) addPartialResolutionComment(ctx context.Context, issueKey string, data *alertmanager.Data) {
+	if r.state == nil || r.conf.PartialResolutionComment == "" {
+		return
+	}
+
+	now := r.timeNow()
+	r.state.mtx.Lock()
+	commented, ok := r.state.partialResolutions[r.conf.Name]
+	if !ok {
+		commented = map[string]time.Time{}
+		r.state.partialResolutions[r.conf.Name] = commented
+	}
+	for k, t := range commented {
+		if now.Sub(t) > partialResolutionRetention {
+			delete(commented, k)
+		}
+	}
+	d := *data
+	d.Alerts = data.Alerts.Firing()
+	var keys []string
+	for _, a := range data.Alerts.Resolved() {
+		key := issueKey + "/" + resolvedAlertKey(a)
+		if _, ok := commented[key]; ok {
+			continue
+		}
+		keys = append(keys, key)
+		d.Alerts = append(d.Alerts, a)
+	}
+	r.state.mtx.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+
+	body, err := r.tmpl.Execute(r.conf.PartialResolutionComment, &d)
+	if err == nil {
+		_, err = r.addComment(ctx, issueKey, body)
+	}
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to comment on partially resolved issue", "key", issueKey, "err", err)
+		return
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	for _, key := range keys {
+		commented[key] = now
+	}
+}
+
+// resolvedAlertKey identifies a resolution of an alert: its fingerprint, or its labels if it has none, and end time.
+func resolvedAlertKey(a alertmanager.Alert) string {
+	id := a.Fingerprint
+	if id == "" {
+		var pairs []string
+		for _, p := range a.Labels.SortedPairs() {
+			pairs = append(pairs, p.Name+"="+p.Value)
+		}
+		id = strings.Join(pairs, ",")
+	}
+	return id + "@" + a.EndsAt.UTC().Format(time.RFC3339Nano)
+}
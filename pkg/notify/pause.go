@@ -0,0 +1,162 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// queuedNotification is a notification received while its receiver was paused.
+type queuedNotification struct {
+	Data     alertmanager.Data `json:"data"`
+	Received time.Time         `json:"received"`
+}
+
+// Pause makes the receiver, or all receivers if empty, queue notifications instead of processing them until resumed.
+// The error tells the pause could not be persisted, the receiver is paused anyway.
+func (s *State) Pause(receiver string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.paused[receiver] = struct{}{}
+	return s.persistLocked()
+}
+
+// Resume ends the pause of the receiver. An empty receiver resumes all receivers, including those paused one by one.
+// A receiver stays paused while all receivers are. Queued notifications are processed by NotifyQueued. The error tells
+// the resumption could not be persisted, the receiver is resumed anyway.
+func (s *State) Resume(receiver string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if receiver == "" {
+		s.paused = map[string]struct{}{}
+	} else {
+		delete(s.paused, receiver)
+	}
+	return s.persistLocked()
+}
+
+// Paused reports whether the receiver is paused.
+func (s *State) Paused(receiver string) bool {
+	if s == nil {
+		return false
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.pausedLocked(receiver)
+}
+
+func (s *State) pausedLocked(receiver string) bool {
+	_, all := s.paused[""]
+	_, ok := s.paused[receiver]
+	return all || ok
+}
+
+// PausedReceivers returns whether all receivers are paused and the receivers paused one by one, sorted.
+func (s *State) PausedReceivers() (bool, []string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, all := s.paused[""]
+	receivers := []string{}
+	for r := range s.paused {
+		if r != "" {
+			receivers = append(receivers, r)
+		}
+	}
+	sort.Strings(receivers)
+	return all, receivers
+}
+
+// Queued returns the number of alert groups with a notification queued for the receiver.
+func (s *State) Queued(receiver string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.queued[receiver])
+}
+
+// queueIfPaused reports whether the receiver is paused, queuing data if so. Only the latest notification of each alert
+// group is kept, as it carries the group's current state. The error tells the queue could not be persisted, so the
+// notification must be retried.
+func (r *Receiver) queueIfPaused(data *alertmanager.Data) (bool, error) {
+	if r.state == nil {
+		return false, nil
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	if !r.state.pausedLocked(r.conf.Name) {
+		return false, nil
+	}
+	if _, ok := r.state.queued[r.conf.Name]; !ok {
+		r.state.queued[r.conf.Name] = map[string]queuedNotification{}
+	}
+	r.state.queued[r.conf.Name][data.GroupKey] = queuedNotification{Data: *data, Received: r.timeNow()}
+	queuedNotifications.WithLabelValues(r.conf.Name).Set(float64(len(r.state.queued[r.conf.Name])))
+	return true, errors.Wrap(r.state.persistLocked(), "queue notification")
+}
+
+// NotifyQueued processes the notifications queued while the receiver was paused, oldest first, unless it is still
+// paused. It returns the number of notifications processed. On failure, the remaining notifications stay queued.
+func (r *Receiver) NotifyQueued(ctx context.Context, hashJiraLabel bool) (int, bool, error) {
+	if r.state == nil {
+		return 0, false, nil
+	}
+	r.state.mtx.Lock()
+	if r.state.pausedLocked(r.conf.Name) {
+		r.state.mtx.Unlock()
+		return 0, false, nil
+	}
+	queued := make([]queuedNotification, 0, len(r.state.queued[r.conf.Name]))
+	for _, q := range r.state.queued[r.conf.Name] {
+		queued = append(queued, q)
+	}
+	delete(r.state.queued, r.conf.Name)
+	queuedNotifications.WithLabelValues(r.conf.Name).Set(0)
+	r.state.mtx.Unlock()
+	if len(queued) == 0 {
+		return 0, false, nil
+	}
+	// The notifications stay in the state file until they were processed, so a restart meanwhile processes them again.
+	defer func() {
+		r.state.mtx.Lock()
+		defer r.state.mtx.Unlock()
+		r.state.persistLockedOrWarn(r.logger)
+	}()
+
+	sort.Slice(queued, func(i, j int) bool { return queued[i].Received.Before(queued[j].Received) })
+	for i, q := range queued {
+		level.Info(r.logger).Log("msg", "processing notification queued while paused", "groupKeyHash", GroupKeyHash(q.Data.GroupKey))
+		data := q.Data
+		if retry, err := r.Notify(ctx, &data, hashJiraLabel); err != nil {
+			// Re-queue what is left unless newer notifications of the same groups arrived meanwhile.
+			r.state.mtx.Lock()
+			if _, ok := r.state.queued[r.conf.Name]; !ok {
+				r.state.queued[r.conf.Name] = map[string]queuedNotification{}
+			}
+			for _, q := range queued[i:] {
+				if _, ok := r.state.queued[r.conf.Name][q.Data.GroupKey]; !ok {
+					r.state.queued[r.conf.Name][q.Data.GroupKey] = q
+				}
+			}
+			queuedNotifications.WithLabelValues(r.conf.Name).Set(float64(len(r.state.queued[r.conf.Name])))
+			r.state.mtx.Unlock()
+			return i, retry, err
+		}
+	}
+	return len(queued), false, nil
+}
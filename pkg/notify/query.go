@@ -0,0 +1,65 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Query selects issues of a project. Jira runs it as JQL, see JQL; other backends translate it to their search APIs.
+type Query struct {
+	// Project is the project, or its equivalent in other backends, of the issues.
+	Project string
+	// Label, if set, only matches the issues carrying this label.
+	Label string
+	// Unresolved only matches the issues whose status is not in the done category.
+	Unresolved bool
+	// NotUpdatedFor, if set, only matches the issues not updated for this long, in whole minutes.
+	NotUpdatedFor time.Duration
+	// Parent, if set, only matches the children of the issue with this key.
+	Parent string
+	// ParentField, if set, is the custom field holding the key of the parent instead, e.g. the Epic Link field, as
+	// Jira Server and Data Center only find the children of an epic by it.
+	ParentField string
+	// OrderByResolutionDate returns the unresolved issues first, then the resolved ones, most recently resolved first.
+	OrderByResolutionDate bool
+}
+
+// JQL returns the query as JQL.
+func (q Query) JQL() string {
+	jql := fmt.Sprintf("project=\"%s\"", q.Project)
+	if q.Label != "" {
+		jql += fmt.Sprintf(" and labels=%q", q.Label)
+	}
+	if q.Unresolved {
+		jql += " and statusCategory != Done"
+	}
+	if q.NotUpdatedFor > 0 {
+		// JQL relative dates do not support years, so always use minutes.
+		jql += fmt.Sprintf(" and updated <= \"-%dm\"", int64(q.NotUpdatedFor/time.Minute))
+	}
+	if q.Parent != "" {
+		if q.ParentField != "" {
+			jql += fmt.Sprintf(" and cf[%s] = %q", strings.TrimPrefix(q.ParentField, "customfield_"), q.Parent)
+		} else {
+			jql += fmt.Sprintf(" and parent = %q", q.Parent)
+		}
+	}
+	if q.OrderByResolutionDate {
+		jql += " order by resolutiondate desc"
+	}
+	return jql
+}
@@ -0,0 +1,210 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// reconcilePageSize is the number of open issues fetched per search request while reconciling.
+const reconcilePageSize = 100
+
+// Reconcile resolves open issues of this receiver whose alerts are no longer firing according to groups, the alert
+// groups currently known to Alertmanager, silenced and inhibited alerts included. It covers resolve notifications that
+// were missed, e.g. while Alertmanager was down.
+//
+// Only issues this receiver is known to track, through the mappings of the state, are resolved: labels are shared by
+// the issues of all receivers and JIRAlert instances in a project. The state must be persisted, see LoadState, for
+// issues whose alerts disappeared while JIRAlert was down to be known. Only receivers with auto_resolve and the default
+// issue identifier labels are reconciled, as otherwise JIRAlert cannot tell which alerts an issue tracks. Templated
+// projects are skipped for the same reason.
+func (r *Receiver) Reconcile(ctx context.Context, groups []alertmanager.Data, hashJiraLabel bool) error {
+	if r.conf.AutoResolve == nil || r.state == nil {
+		return nil
+	}
+	if r.conf.IssueIdentifierLabel != "" {
+		level.Debug(r.logger).Log("msg", "receiver uses custom issue identifier label; skipping reconciliation")
+		return nil
+	}
+
+	firing, err := r.firingLabels(groups, hashJiraLabel)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range r.conf.StaticProjects() {
+		issues, err := r.searchOpen(ctx, Query{Project: project})
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			idLabel, ok := ownedIssueLabel(issue.Fields.Labels)
+			if !ok {
+				continue
+			}
+			if _, ok := firing[idLabel]; ok {
+				continue
+			}
+			m, ok := r.owns(project, idLabel, issue.Key)
+			if !ok {
+				continue
+			}
+			level.Info(r.logger).Log("msg", "open issue has no firing alerts left; resolving", "key", issue.Key, "label", idLabel)
+			// The alerts are gone, the resolution is handled like a resolve notification of the mapped alert group.
+			data := &alertmanager.Data{Status: alertmanager.AlertResolved, GroupKey: m.GroupKey, CommonLabels: m.Labels}
+			if _, err := r.autoResolve(ctx, data, project, idLabel, issue.Key, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CloseStale transitions open issues of this receiver that have not been updated for the configured stale_issues
+// duration. Issues whose alerts are still firing according to groups, the alert groups in Alertmanager, are left alone.
+// The comment is only added once the issue was transitioned. Like for Reconcile, only issues this receiver is known to
+// track are transitioned.
+func (r *Receiver) CloseStale(ctx context.Context, groups []alertmanager.Data, hashJiraLabel bool) error {
+	if r.conf.StaleIssues == nil || r.state == nil {
+		return nil
+	}
+	if r.conf.IssueIdentifierLabel != "" {
+		level.Debug(r.logger).Log("msg", "receiver uses custom issue identifier label; skipping stale issue cleanup")
+		return nil
+	}
+
+	firing, err := r.firingLabels(groups, hashJiraLabel)
+	if err != nil {
+		return err
+	}
+
+	after := time.Duration(*r.conf.StaleIssues.After)
+	for _, project := range r.conf.StaticProjects() {
+		issues, err := r.searchOpen(ctx, Query{Project: project, NotUpdatedFor: after})
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			idLabel, ok := ownedIssueLabel(issue.Fields.Labels)
+			if !ok {
+				continue
+			}
+			if _, ok := firing[idLabel]; ok {
+				continue
+			}
+			if _, ok := r.owns(project, idLabel, issue.Key); !ok {
+				continue
+			}
+			level.Info(r.logger).Log("msg", "issue is stale; transitioning", "key", issue.Key, "label", idLabel, "state", r.conf.StaleIssues.State)
+			if _, err := r.doTransition(ctx, issue.Key, r.conf.StaleIssues.State); err != nil {
+				return err
+			}
+			if r.conf.StaleIssues.Comment != "" {
+				if _, err := r.addComment(ctx, issue.Key, r.conf.StaleIssues.Comment); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// owns returns the mapping of the open issue with the given key and identifier label in project, if this receiver
+// tracks it. Labels are shared by the issues of all receivers and JIRAlert instances in a project, so issues are only
+// ever reconciled or cleaned up by the receiver whose mapping records them.
+func (r *Receiver) owns(project, idLabel, issueKey string) (Mapping, bool) {
+	m, ok := r.state.Mapping(r.conf.Name, project, idLabel)
+	if !ok || m.IssueKey != issueKey || m.Status != MappingOpen {
+		return Mapping{}, false
+	}
+	return m, true
+}
+
+// firingLabels returns the set of issue identifier labels of the firing alerts in groups routed to this receiver,
+// directly or through its alertmanager_receiver.
+func (r *Receiver) firingLabels(groups []alertmanager.Data, hashJiraLabel bool) (map[string]struct{}, error) {
+	firing := map[string]struct{}{}
+	for i := range groups {
+		if !r.conf.Receives(groups[i].Receiver) {
+			continue
+		}
+		for _, d := range r.group(&groups[i]) {
+			if len(d.Alerts.Firing()) == 0 {
+				continue
+			}
+			idLabel, err := r.toIssueIdentifierLabel(&d, hashJiraLabel)
+			if err != nil {
+				return nil, errors.Wrap(err, "build IssueIdentifierLabel")
+			}
+			firing[idLabel] = struct{}{}
+		}
+	}
+	return firing, nil
+}
+
+// ownedIssueLabel returns the JIRAlert issue identifier label out of the given issue labels, if any.
+func ownedIssueLabel(labels []string) (string, bool) {
+	for _, l := range labels {
+		if strings.HasPrefix(l, "JIRALERT{") || strings.HasPrefix(l, "ALERT{") {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// searchOpenInProjects returns the unresolved issues matching query in all static projects of this receiver.
+func (r *Receiver) searchOpenInProjects(ctx context.Context, query Query) ([]jira.Issue, error) {
+	var res []jira.Issue
+	for _, project := range r.conf.StaticProjects() {
+		query.Project = project
+		issues, err := r.searchOpen(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, issues...)
+	}
+	return res, nil
+}
+
+// searchOpen returns all unresolved issues matching query.
+func (r *Receiver) searchOpen(ctx context.Context, query Query) ([]jira.Issue, error) {
+	query.Unresolved = true
+
+	var res []jira.Issue
+	for {
+		options := &jira.SearchOptions{
+			Fields:     []string{"labels", "status"},
+			StartAt:    len(res),
+			MaxResults: reconcilePageSize,
+		}
+		level.Debug(r.logger).Log("msg", "search", "query", query.JQL(), "options", fmt.Sprintf("%+v", options))
+		issues, resp, err := r.client.SearchWithContext(ctx, query, options)
+		if err != nil {
+			_, err := handleJiraErrResponse("Issue.Search", resp, err, r.logger)
+			return nil, err
+		}
+		res = append(res, issues...)
+		if len(issues) < reconcilePageSize {
+			return res, nil
+		}
+	}
+}
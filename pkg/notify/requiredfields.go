@@ -0,0 +1,132 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// CreateMetaGetter is implemented by Ticketers that can look up the create metadata of Jira projects, i.e. which
+// fields issues of each type have, e.g. *jira.IssueService.
+type CreateMetaGetter interface {
+	GetCreateMetaWithOptionsWithContext(ctx context.Context, options *jira.GetQueryOptions) (*jira.CreateMetaInfo, *jira.Response, error)
+}
+
+// fillRequiredFields sets the fields the create metadata of the issue's project and type reports as required but the
+// issue does not set, as configured in required_fields. Failing lookups and fields that cannot be filled are logged
+// rather than failing the notification, Jira rejecting the issue tells what is missing.
+func (r *Receiver) fillRequiredFields(ctx context.Context, issue *jira.Issue) {
+	if r.conf.RequiredFields == nil {
+		return
+	}
+	if r.createMeta == nil {
+		level.Debug(r.logger).Log("msg", "no create metadata support, not filling required fields")
+		return
+	}
+
+	meta, _, err := r.createMeta.GetCreateMetaWithOptionsWithContext(ctx, &jira.GetQueryOptions{
+		ProjectKeys: issue.Fields.Project.Key,
+		Expand:      "projects.issuetypes.fields",
+	})
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to look up create metadata, not filling required fields", "project", issue.Fields.Project.Key, "err", err)
+		return
+	}
+	var issueType *jira.MetaIssueType
+	if project := meta.GetProjectWithKey(issue.Fields.Project.Key); project != nil {
+		issueType = project.GetIssueTypeWithName(issue.Fields.Type.Name)
+	}
+	if issueType == nil {
+		level.Warn(r.logger).Log("msg", "issue type not found in create metadata, not filling required fields", "project", issue.Fields.Project.Key, "issueType", issue.Fields.Type.Name)
+		return
+	}
+
+	set, err := setFields(issue)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to determine the fields set, not filling required fields", "err", err)
+		return
+	}
+	keys := make([]string, 0, len(issueType.Fields))
+	for key := range issueType.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if required, _ := issueType.Fields.Bool(key + "/required"); !required || set[key] {
+			continue
+		}
+		field, _ := issueType.Fields[key].(map[string]interface{})
+		value, ok := requiredFieldValue(field, r.conf.RequiredFields.Fill)
+		if !ok {
+			level.Warn(r.logger).Log("msg", "required field not set and cannot be filled", "field", key, "name", field["name"])
+			continue
+		}
+		level.Info(r.logger).Log("msg", "filling required field", "field", key, "name", field["name"])
+		if issue.Fields.Unknowns == nil {
+			issue.Fields.Unknowns = tcontainer.NewMarshalMap()
+		}
+		issue.Fields.Unknowns[key] = value
+	}
+}
+
+// setFields returns the names of the fields an issue to create sets, e.g. "summary" or "customfield_10001".
+func setFields(issue *jira.Issue) (map[string]bool, error) {
+	b, err := json.Marshal(issue.Fields)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(fields))
+	for key, value := range fields {
+		if value != nil {
+			set[key] = true
+		}
+	}
+	return set, nil
+}
+
+// requiredFieldValue returns the value a required field is filled with according to the fill policy: its default
+// value or, with config.FillFirstAllowed, its first allowed value.
+func requiredFieldValue(field map[string]interface{}, fill string) (interface{}, bool) {
+	if hasDefault, _ := field["hasDefaultValue"].(bool); hasDefault && field["defaultValue"] != nil {
+		return field["defaultValue"], true
+	}
+	if fill != config.FillFirstAllowed {
+		return nil, false
+	}
+	allowed, _ := field["allowedValues"].([]interface{})
+	if len(allowed) == 0 {
+		return nil, false
+	}
+	value := allowed[0]
+	// Allowed values are objects with more properties than Jira accepts, e.g. "self", but references by ID work
+	// for all of them.
+	if v, ok := value.(map[string]interface{}); ok && v["id"] != nil {
+		value = map[string]interface{}{"id": v["id"]}
+	}
+	if schema, _ := field["schema"].(map[string]interface{}); schema["type"] == "array" {
+		return []interface{}{value}, true
+	}
+	return value, true
+}
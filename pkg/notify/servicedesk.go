@@ -0,0 +1,94 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// ServiceDeskResolver is implemented by Ticketers that can resolve the organizations, participants and SLAs of Jira
+// Service Management requests.
+type ServiceDeskResolver interface {
+	// OrganizationIDsWithContext returns the IDs of the organizations with the given names or IDs.
+	OrganizationIDsWithContext(ctx context.Context, organizations []string) ([]int, error)
+	// ParticipantsWithContext returns the values referencing the given users in a request participants field.
+	ParticipantsWithContext(ctx context.Context, users []string) ([]interface{}, error)
+	// SLAsWithContext returns the ongoing SLA cycles of the given request.
+	SLAsWithContext(ctx context.Context, issueKey string) ([]SLA, error)
+}
+
+// setServiceDeskFields sets the organizations and participants fields of a new request. Failing lookups are only
+// logged, as the request is more important than its routing.
+func (r *Receiver) setServiceDeskFields(ctx context.Context, issue *jira.Issue, data *alertmanager.Data) error {
+	sd := r.conf.ServiceDesk
+	if sd == nil {
+		return nil
+	}
+	organizations, err := r.executeAll(sd.Organizations, data)
+	if err != nil {
+		return errors.Wrap(err, "render organizations")
+	}
+	participants, err := r.executeAll(sd.Participants, data)
+	if err != nil {
+		return errors.Wrap(err, "render participants")
+	}
+	if len(organizations) == 0 && len(participants) == 0 {
+		return nil
+	}
+	if r.serviceDesk == nil {
+		level.Debug(r.logger).Log("msg", "no Jira Service Management support, not setting organizations and participants")
+		return nil
+	}
+
+	if len(organizations) > 0 {
+		ids, err := r.serviceDesk.OrganizationIDsWithContext(ctx, organizations)
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "failed to look up organizations", "organizations", len(organizations), "err", err)
+		} else if len(ids) > 0 {
+			issue.Fields.Unknowns[sd.OrganizationsField] = ids
+		}
+	}
+	if len(participants) > 0 {
+		values, err := r.serviceDesk.ParticipantsWithContext(ctx, participants)
+		if err != nil {
+			level.Warn(r.logger).Log("msg", "failed to look up participants", "participants", len(participants), "err", err)
+		} else if len(values) > 0 {
+			issue.Fields.Unknowns[sd.ParticipantsField] = values
+		}
+	}
+	return nil
+}
+
+// executeAll renders the given templates, dropping empty and duplicate results.
+func (r *Receiver) executeAll(texts []string, data *alertmanager.Data) ([]string, error) {
+	var res []string
+	seen := map[string]struct{}{}
+	for _, text := range texts {
+		v, err := r.tmpl.Execute(text, data)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[v]; ok || v == "" {
+			continue
+		}
+		seen[v] = struct{}{}
+		res = append(res, v)
+	}
+	return res, nil
+}
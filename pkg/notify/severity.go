@@ -0,0 +1,34 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// severeEnough reports whether the highest severity of the alerts of data reaches min_severity. Resolved alerts count
+// too, so groups keep being handled until their issues are resolved. Missing or unknown severities rank lowest.
+func (r *Receiver) severeEnough(data *alertmanager.Data) bool {
+	ms := r.conf.MinSeverity
+	if ms == nil {
+		return true
+	}
+	threshold := ms.Rank(ms.Severity)
+	for _, a := range data.Alerts {
+		if ms.Rank(a.Labels[ms.Label]) >= threshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// shadowTicketer sends the read requests of a shadow receiver, but only logs its mutations instead of sending them.
+type shadowTicketer struct {
+	Ticketer
+	receiver string
+	logger   log.Logger
+}
+
+// skip logs and counts a mutation that is not performed.
+func (t *shadowTicketer) skip(op, key string, data interface{}) {
+	shadowMutationsTotal.WithLabelValues(t.receiver, op).Inc()
+	b, err := json.Marshal(data)
+	if err != nil {
+		b = []byte(err.Error())
+	}
+	level.Info(t.logger).Log("msg", "shadow mode, not sending mutation", "operation", op, "key", key, "data", string(b))
+}
+
+func (t *shadowTicketer) CreateWithContext(_ context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	t.skip(opCreate, "", issue)
+	created := *issue
+	if issue.Fields != nil {
+		// Jira numbers issues starting at 1, so this never refers to an existing issue.
+		created.Key = issue.Fields.Project.Key + "-0"
+	}
+	return &created, nil, nil
+}
+
+func (t *shadowTicketer) UpdateWithOptionsWithContext(_ context.Context, issue *jira.Issue, _ *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	t.skip(opUpdate, issue.Key, issue.Fields)
+	return issue, nil, nil
+}
+
+func (t *shadowTicketer) DoTransitionWithContext(_ context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	t.skip(opTransition, ticketID, map[string]string{"transition": transitionID})
+	return nil, nil
+}
+
+func (t *shadowTicketer) AddCommentWithContext(_ context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	t.skip(opComment, issueID, comment)
+	return comment, nil, nil
+}
+
+func (t *shadowTicketer) AddWatcherWithContext(_ context.Context, issueID string, userName string) (*jira.Response, error) {
+	t.skip(opWatch, issueID, map[string]string{"watcher": userName})
+	return nil, nil
+}
+
+func (t *shadowTicketer) UpdateIssueWithContext(_ context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	t.skip(opUpdate, jiraID, data)
+	return nil, nil
+}
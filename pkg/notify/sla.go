@@ -0,0 +1,164 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// SLA is the ongoing cycle of a Jira Service Management SLA of a request.
+type SLA struct {
+	Name      string
+	StartTime time.Time
+	Remaining time.Duration
+	Breached  bool
+	Paused    bool
+}
+
+// slaWarningData is the data the sla_warning comment is executed with.
+type slaWarningData struct {
+	Key       string
+	SLA       string
+	Remaining time.Duration
+}
+
+// WarnSLABreaches comments on the open requests of this receiver whose alerts are still firing according to groups and
+// whose SLA breaches within the configured sla_warning duration, and optionally raises their priority. With a State,
+// every SLA cycle is only warned about once.
+//
+// Like Reconcile, only receivers with the default issue identifier labels and static projects are checked.
+func (r *Receiver) WarnSLABreaches(ctx context.Context, groups []alertmanager.Data, hashJiraLabel bool) error {
+	if r.conf.ServiceDesk == nil || r.conf.ServiceDesk.SLAWarning == nil {
+		return nil
+	}
+	if r.conf.IssueIdentifierLabel != "" {
+		level.Debug(r.logger).Log("msg", "receiver uses custom issue identifier label; skipping SLA warnings")
+		return nil
+	}
+	if r.serviceDesk == nil {
+		level.Debug(r.logger).Log("msg", "no Jira Service Management support, skipping SLA warnings")
+		return nil
+	}
+	warning := r.conf.ServiceDesk.SLAWarning
+
+	firing, err := r.firingLabels(groups, hashJiraLabel)
+	if err != nil {
+		return err
+	}
+
+	issues, err := r.searchOpenInProjects(ctx, Query{})
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]struct{}{}
+	defer r.pruneSLAWarnings(seen)
+	for _, issue := range issues {
+		idLabel, ok := ownedIssueLabel(issue.Fields.Labels)
+		if !ok {
+			continue
+		}
+		if _, ok := firing[idLabel]; !ok {
+			continue
+		}
+		slas, err := r.serviceDesk.SLAsWithContext(ctx, issue.Key)
+		if err != nil {
+			return errors.Wrapf(err, "get SLAs of %s", issue.Key)
+		}
+		for _, sla := range slas {
+			if !watchedSLA(warning.SLAs, sla.Name) {
+				continue
+			}
+			id := issue.Key + "/" + sla.Name
+			seen[id] = struct{}{}
+			if sla.Breached || sla.Paused || sla.Remaining > time.Duration(*warning.Before) || r.slaWarned(id, sla.StartTime) {
+				continue
+			}
+
+			remaining := sla.Remaining.Round(time.Minute)
+			level.Info(r.logger).Log("msg", "SLA about to breach while alerts are firing; warning", "key", issue.Key, "sla", sla.Name, "remaining", remaining)
+			comment, err := r.tmpl.Execute(warning.Comment, &slaWarningData{Key: issue.Key, SLA: sla.Name, Remaining: remaining})
+			if err != nil {
+				return errors.Wrap(err, "render SLA warning comment")
+			}
+			if _, err := r.addComment(ctx, issue.Key, comment); err != nil {
+				return err
+			}
+			if warning.Priority != "" {
+				fields := map[string]interface{}{"priority": map[string]interface{}{"name": warning.Priority}}
+				if _, err := r.editFields(ctx, issue.Key, fields); err != nil {
+					return err
+				}
+			}
+			r.recordSLAWarning(id, sla.StartTime)
+		}
+	}
+	return nil
+}
+
+// watchedSLA reports whether the SLA with the given name is one of names, or names is empty.
+func watchedSLA(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// slaWarned reports whether the SLA cycle starting at start was already warned about.
+func (r *Receiver) slaWarned(id string, start time.Time) bool {
+	if r.state == nil {
+		return false
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	warned, ok := r.state.slaWarnings[r.conf.Name][id]
+	return ok && warned.Equal(start)
+}
+
+// recordSLAWarning remembers that the SLA cycle starting at start was warned about.
+func (r *Receiver) recordSLAWarning(id string, start time.Time) {
+	if r.state == nil {
+		return
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	if _, ok := r.state.slaWarnings[r.conf.Name]; !ok {
+		r.state.slaWarnings[r.conf.Name] = map[string]time.Time{}
+	}
+	r.state.slaWarnings[r.conf.Name][id] = start
+}
+
+// pruneSLAWarnings forgets the warnings about SLAs not in seen, e.g. of resolved requests.
+func (r *Receiver) pruneSLAWarnings(seen map[string]struct{}) {
+	if r.state == nil {
+		return
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	for id := range r.state.slaWarnings[r.conf.Name] {
+		if _, ok := seen[id]; !ok {
+			delete(r.state.slaWarnings[r.conf.Name], id)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// State holds everything that must outlive a single notification. It is shared by all receivers, as receivers are
+// created per notification. The alert group mappings are also persisted to the state file, if any, so they outlive
+// restarts.
+type State struct {
+	mtx sync.Mutex
+	// path is the file the state is persisted to, if any, see LoadState.
+	path string
+	// persistTimer is set while a deferred write of the state file is pending, see persistLaterLocked.
+	persistTimer *time.Timer
+	// dirty is set while the state file lags behind the state.
+	dirty bool
+	// nextExpiry is when expired mappings are dropped next, see expireMappingsLocked.
+	nextExpiry time.Time
+
+	// Times of the successful issue creations, by project and identifier label, for creation limits.
+	created map[string]map[string]time.Time
+	// Identifier labels already listed in an alert storm issue, by umbrella issue key.
+	aggregated map[string]map[string]struct{}
+	// Groups waiting for business hours, by receiver and identifier label.
+	deferred map[string]map[string]alertmanager.Data
+	// Groups firing for less than min_firing_duration, by receiver and identifier label.
+	held map[string]map[string]alertmanager.Data
+	// Known alert group to issue mappings.
+	mappings map[mappingKey]*Mapping
+	// Assignee pool rotation: number of assignments and last assignment per user, by receiver.
+	poolAssignments  map[string]int
+	poolLastAssigned map[string]map[string]time.Time
+	// Start of the SLA cycles already warned about, by receiver and request key and SLA name.
+	slaWarnings map[string]map[string]time.Time
+	// Receivers owning the issues of alert groups, by dedupe group and identifier label.
+	dedupeOwners map[string]map[string]*dedupeOwner
+	// Umbrella issues of incident_mode, by receiver and project.
+	outages map[string]*outage
+	// Current incident issues set through the API, by receiver. Empty keys clear the configured issue.
+	incidents map[string]string
+	// When resolved alerts were listed in a partial resolution comment, by receiver and issue key and alert.
+	partialResolutions map[string]map[string]time.Time
+	// Paused receivers, the empty name pausing all, and the notifications queued meanwhile, by receiver and group key.
+	paused map[string]struct{}
+	queued map[string]map[string]queuedNotification
+	// Notifications held while further deliveries are coalesced into them, by key, see Coalesce.
+	coalesced map[string]alertmanager.Data
+}
+
+const (
+	// MappingOpen is the status of a mapping whose issue is unresolved.
+	MappingOpen = "open"
+	// MappingResolved is the status of a mapping whose issue is resolved.
+	MappingResolved = "resolved"
+)
+
+// mappingKey identifies the mapping of an alert group: a receiver tracks an alert group by one issue per project.
+type mappingKey struct {
+	receiver, project, idLabel string
+}
+
+// Mapping links an alert group to the issue tracking it.
+type Mapping struct {
+	Receiver   string    `json:"receiver"`
+	Project    string    `json:"project"`
+	GroupKey   string    `json:"groupKey"`
+	IssueLabel string    `json:"issueLabel"`
+	IssueKey   string    `json:"issueKey"`
+	Status     string    `json:"status"`
+	LastUpdate time.Time `json:"lastUpdate"`
+	// Common labels of the alert group, for inhibit rules.
+	Labels alertmanager.KV `json:"labels,omitempty"`
+	// ExpiresAt is when the mapping of a resolved issue is dropped: once the issue is too old to be reopened, the next
+	// notification of the alert group creates a new issue anyway.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// persistDelay is how long writes of the state file are deferred to batch the mapping updates of notifications.
+var persistDelay = time.Second
+
+// mappingExpiryInterval is how often expired mappings are dropped.
+const mappingExpiryInterval = time.Minute
+
+// NewState returns an empty State, which is not persisted.
+func NewState() *State {
+	return &State{
+		created:    map[string]map[string]time.Time{},
+		aggregated: map[string]map[string]struct{}{},
+		deferred:   map[string]map[string]alertmanager.Data{},
+		held:       map[string]map[string]alertmanager.Data{},
+		mappings:   map[mappingKey]*Mapping{},
+
+		poolAssignments:  map[string]int{},
+		poolLastAssigned: map[string]map[string]time.Time{},
+		slaWarnings:      map[string]map[string]time.Time{},
+		dedupeOwners:     map[string]map[string]*dedupeOwner{},
+		outages:          map[string]*outage{},
+		incidents:        map[string]string{},
+
+		partialResolutions: map[string]map[string]time.Time{},
+		paused:             map[string]struct{}{},
+		queued:             map[string]map[string]queuedNotification{},
+		coalesced:          map[string]alertmanager.Data{},
+	}
+}
+
+// Mappings returns a copy of all known mappings, sorted by receiver and issue label.
+func (s *State) Mappings() []Mapping {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var res []Mapping
+	for _, m := range s.mappings {
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Receiver != res[j].Receiver {
+			return res[i].Receiver < res[j].Receiver
+		}
+		if res[i].IssueLabel != res[j].IssueLabel {
+			return res[i].IssueLabel < res[j].IssueLabel
+		}
+		return res[i].Project < res[j].Project
+	})
+	return res
+}
+
+// Mapping returns the mapping of the given receiver, project and identifier label, if known.
+func (s *State) Mapping(receiver, project, idLabel string) (Mapping, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	m, ok := s.mappings[mappingKey{receiver: receiver, project: project, idLabel: idLabel}]
+	if !ok {
+		return Mapping{}, false
+	}
+	return *m, true
+}
+
+// recordMapping remembers that the alert group with the given identifier label is tracked by issueKey in project.
+func (r *Receiver) recordMapping(data *alertmanager.Data, project, idLabel, issueKey, status string) {
+	if r.state == nil {
+		return
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+
+	now := r.timeNow()
+	m := &Mapping{
+		Receiver:   r.conf.Name,
+		Project:    project,
+		GroupKey:   data.GroupKey,
+		IssueLabel: idLabel,
+		IssueKey:   issueKey,
+		Status:     status,
+		LastUpdate: now,
+		Labels:     data.CommonLabels,
+	}
+	// A zero reopen_duration reopens issues however old they are.
+	if status == MappingResolved && r.conf.ReopenDuration != nil && *r.conf.ReopenDuration != 0 {
+		expiresAt := now.Add(time.Duration(*r.conf.ReopenDuration))
+		m.ExpiresAt = &expiresAt
+	}
+	r.state.mappings[mappingKey{receiver: r.conf.Name, project: project, idLabel: idLabel}] = m
+	r.state.expireMappingsLocked(now)
+	r.state.persistLaterLocked(r.logger)
+}
+
+// expireMappingsLocked drops the mappings which expired by now, at most once per mappingExpiryInterval. s.mtx must be
+// held.
+func (s *State) expireMappingsLocked(now time.Time) {
+	if now.Before(s.nextExpiry) {
+		return
+	}
+	s.nextExpiry = now.Add(mappingExpiryInterval)
+	expired := map[mappingKey]struct{}{}
+	for k, m := range s.mappings {
+		if m.ExpiresAt != nil && !now.Before(*m.ExpiresAt) {
+			delete(s.mappings, k)
+			expired[k] = struct{}{}
+			s.dirty = true
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	// The ownerships of alert groups in dedupe groups end with the mapping of the owner's issue.
+	for group, owners := range s.dedupeOwners {
+		for idLabel, o := range owners {
+			if _, ok := expired[mappingKey{receiver: o.Receiver, project: o.Project, idLabel: idLabel}]; ok {
+				delete(owners, idLabel)
+			}
+		}
+		if len(owners) == 0 {
+			delete(s.dedupeOwners, group)
+		}
+	}
+}
+
+// forgetMapping drops the mapping of the given identifier label in project.
+func (r *Receiver) forgetMapping(project, idLabel string) {
+	if r.state == nil {
+		return
+	}
+	r.state.mtx.Lock()
+	defer r.state.mtx.Unlock()
+	delete(r.state.mappings, mappingKey{receiver: r.conf.Name, project: project, idLabel: idLabel})
+	r.state.persistLaterLocked(r.logger)
+}
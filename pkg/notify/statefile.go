@@ -0,0 +1,180 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// stateFile is the part of the state written to the state file, as JSON.
+type stateFile struct {
+	Mappings []Mapping `json:"mappings,omitempty"`
+	// Deferred are the groups waiting for business hours, by receiver and identifier label.
+	Deferred map[string]map[string]alertmanager.Data `json:"deferred,omitempty"`
+	// Held are the groups waiting for min_firing_duration, by receiver and identifier label.
+	Held map[string]map[string]alertmanager.Data `json:"held,omitempty"`
+	// PoolAssignments and PoolLastAssigned are the rotation of the assignee pools, by receiver.
+	PoolAssignments  map[string]int                  `json:"pool_assignments,omitempty"`
+	PoolLastAssigned map[string]map[string]time.Time `json:"pool_last_assigned,omitempty"`
+	// DedupeOwners are the receivers owning the issues of alert groups, by dedupe group and identifier label.
+	DedupeOwners map[string]map[string]*dedupeOwner `json:"dedupe_owners,omitempty"`
+	// Paused are the paused receivers, the empty name pausing all, and Queued the notifications queued meanwhile, by
+	// receiver and group key.
+	Paused []string                                 `json:"paused,omitempty"`
+	Queued map[string]map[string]queuedNotification `json:"queued,omitempty"`
+	// Coalesced are the notifications held while further deliveries are coalesced into them, by key.
+	Coalesced map[string]alertmanager.Data `json:"coalesced,omitempty"`
+}
+
+// LoadState returns a State persisted to the file at path, restoring the state written there before, if any. The
+// file is rewritten whenever the persisted part of the state changes.
+func LoadState(path string) (*State, error) {
+	s := NewState()
+	s.path = path
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f stateFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, errors.Wrapf(err, "parse state file %s", path)
+	}
+	for i := range f.Mappings {
+		m := f.Mappings[i]
+		s.mappings[mappingKey{receiver: m.Receiver, project: m.Project, idLabel: m.IssueLabel}] = &m
+	}
+	for receiver, groups := range f.Deferred {
+		s.deferred[receiver] = groups
+	}
+	for receiver, groups := range f.Held {
+		s.held[receiver] = groups
+	}
+	for receiver, n := range f.PoolAssignments {
+		s.poolAssignments[receiver] = n
+	}
+	for receiver, users := range f.PoolLastAssigned {
+		s.poolLastAssigned[receiver] = users
+	}
+	for group, owners := range f.DedupeOwners {
+		s.dedupeOwners[group] = owners
+	}
+	for _, receiver := range f.Paused {
+		s.paused[receiver] = struct{}{}
+	}
+	for receiver, queued := range f.Queued {
+		s.queued[receiver] = queued
+		queuedNotifications.WithLabelValues(receiver).Set(float64(len(queued)))
+	}
+	for key, data := range f.Coalesced {
+		s.coalesced[key] = data
+	}
+	return s, nil
+}
+
+// Persisted reports whether the state is persisted to a file, see LoadState.
+func (s *State) Persisted() bool {
+	return s.path != ""
+}
+
+// persistLocked writes the persisted part of the state to the state file, if any. The file is replaced atomically,
+// so a crash leaves the previous state behind. s.mtx must be held.
+func (s *State) persistLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	f := stateFile{
+		Deferred:         s.deferred,
+		Held:             s.held,
+		PoolAssignments:  s.poolAssignments,
+		PoolLastAssigned: s.poolLastAssigned,
+		DedupeOwners:     s.dedupeOwners,
+		Queued:           s.queued,
+		Coalesced:        s.coalesced,
+	}
+	for receiver := range s.paused {
+		f.Paused = append(f.Paused, receiver)
+	}
+	sort.Strings(f.Paused)
+	for _, m := range s.mappings {
+		f.Mappings = append(f.Mappings, *m)
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return errors.Wrap(err, "write state file")
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return errors.Wrap(err, "write state file")
+	}
+	s.dirty = false
+	return nil
+}
+
+// persistLaterLocked persists the state within persistDelay, like persistLockedOrWarn, so the many changes of
+// concurrent notifications are written at once rather than each rewriting the whole file. s.mtx must be held.
+func (s *State) persistLaterLocked(logger log.Logger) {
+	if s.path == "" {
+		return
+	}
+	s.dirty = true
+	if s.persistTimer != nil {
+		return
+	}
+	s.persistTimer = time.AfterFunc(persistDelay, func() {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		s.persistTimer = nil
+		if s.dirty {
+			s.persistLockedOrWarn(logger)
+		}
+	})
+}
+
+// Flush writes the changes of the state not yet written to the state file, if any. It is called on shutdown.
+func (s *State) Flush() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.persistTimer != nil {
+		s.persistTimer.Stop()
+		s.persistTimer = nil
+	}
+	if !s.dirty {
+		return nil
+	}
+	return s.persistLocked()
+}
+
+// persistLockedOrWarn persists the state, only logging failures: the state file lags behind until the next change.
+// s.mtx must be held.
+func (s *State) persistLockedOrWarn(logger log.Logger) {
+	if err := s.persistLocked(); err != nil {
+		level.Warn(logger).Log("msg", "failed to persist state", "err", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// syncSubtasks maintains a sub-task of the given issue per value of the sub-task labels among the alerts of the group:
+// sub-tasks of firing alerts are created or reopened, those of resolved alerts are resolved with auto_resolve.
+func (r *Receiver) syncSubtasks(ctx context.Context, project, parentKey string, data *alertmanager.Data) (bool, error) {
+	st := r.conf.Subtasks
+	if st == nil {
+		return false, nil
+	}
+
+	var labels []string
+	groups := map[string]*alertmanager.Data{}
+	for _, a := range data.Alerts {
+		values := alertmanager.KV{}
+		for _, n := range st.Labels {
+			if v := a.Labels[n]; v != "" {
+				values[n] = v
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		label := hierarchyLabel("JIRALERT_SUBTASK_"+parentKey, st.Labels, values)
+		d, ok := groups[label]
+		if !ok {
+			// The sub-task's alerts share its label values, on top of the group's common labels.
+			d = &alertmanager.Data{
+				Receiver:          data.Receiver,
+				GroupKey:          data.GroupKey,
+				Status:            alertmanager.AlertResolved,
+				GroupLabels:       data.GroupLabels,
+				CommonLabels:      alertmanager.KV{},
+				CommonAnnotations: data.CommonAnnotations,
+				ExternalURL:       data.ExternalURL,
+			}
+			for k, v := range data.CommonLabels {
+				d.CommonLabels[k] = v
+			}
+			for k, v := range values {
+				d.CommonLabels[k] = v
+			}
+			groups[label] = d
+			labels = append(labels, label)
+		}
+		d.Alerts = append(d.Alerts, a)
+		if a.Status == alertmanager.AlertFiring {
+			d.Status = alertmanager.AlertFiring
+		}
+	}
+
+	for _, label := range labels {
+		d := groups[label]
+		subtask, retry, err := r.search(ctx, project, label)
+		if err != nil {
+			return retry, err
+		}
+		resolved := subtask != nil && subtask.Fields.Status != nil && subtask.Fields.Status.StatusCategory.Key == "done"
+
+		switch {
+		case d.Status == alertmanager.AlertFiring && subtask == nil:
+			summary, err := r.tmpl.Execute(st.Summary, d)
+			if err != nil {
+				return false, errors.Wrap(err, "render sub-task summary")
+			}
+			subtask = &jira.Issue{
+				Fields: &jira.IssueFields{
+					Project:  jira.Project{Key: project},
+					Type:     jira.IssueType{Name: st.IssueType},
+					Summary:  summary,
+					Parent:   &jira.Parent{Key: parentKey},
+					Labels:   []string{label},
+					Unknowns: tcontainer.NewMarshalMap(),
+				},
+			}
+			level.Info(r.logger).Log("msg", "creating sub-task", "parent", parentKey, "label", label)
+			if retry, err := r.create(ctx, subtask); err != nil {
+				return retry, err
+			}
+		case d.Status == alertmanager.AlertFiring && resolved:
+			level.Info(r.logger).Log("msg", "reopening sub-task", "key", subtask.Key, "parent", parentKey)
+			if retry, err := r.reopen(ctx, subtask.Key); err != nil {
+				return retry, err
+			}
+		case d.Status == alertmanager.AlertResolved && subtask != nil && !resolved && r.conf.AutoResolve != nil:
+			level.Info(r.logger).Log("msg", "alerts of sub-task resolved, resolving sub-task", "key", subtask.Key, "parent", parentKey)
+			if retry, err := r.resolveIssue(ctx, subtask.Key); err != nil {
+				return retry, err
+			}
+		}
+	}
+	return false, nil
+}
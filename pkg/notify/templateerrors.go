@@ -0,0 +1,86 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// Settings whose templates cannot fall back, as issues would end up in the wrong place or could not be found again.
+var requiredTemplates = map[string]bool{"project": true, "issue_type": true, "issue_identifier_label": true}
+
+// execute renders the template text of the given setting, e.g. "summary", counting failures. Its value in
+// template_defaults is returned if the template renders empty or fails. Otherwise, with template_errors set to
+// fallback, failures are logged and the fallback value of the setting is returned instead, see templateFallback.
+func (r *Receiver) execute(setting, text string, data *alertmanager.Data) (string, error) {
+	s, err := r.tmpl.Execute(text, data)
+	if err == nil && strings.TrimSpace(s) != "" {
+		return s, nil
+	}
+	if err != nil && !r.templateFailed(setting, err) {
+		return "", err
+	}
+	if d, ok := r.conf.TemplateDefaults[setting].(string); ok {
+		return d, nil
+	}
+	if err != nil {
+		return templateFallback(setting, data, err), nil
+	}
+	return s, nil
+}
+
+// templateFailed counts a failure to render the template of the given setting and reports whether a default or
+// fallback value is used instead, logging it.
+func (r *Receiver) templateFailed(setting string, err error) bool {
+	templateErrorsTotal.WithLabelValues(r.conf.Name, setting).Inc()
+	if _, ok := r.conf.TemplateDefaults[setting]; ok {
+		level.Warn(r.logger).Log("msg", "failed to render template, using default value", "setting", setting, "err", err)
+		return true
+	}
+	if r.conf.TemplateErrors != config.TemplateErrorsFallback || requiredTemplates[setting] {
+		return false
+	}
+	level.Warn(r.logger).Log("msg", "failed to render template, using fallback value", "setting", setting, "err", err)
+	return true
+}
+
+// templateFallback returns the value of a setting whose template failed to render: the status and group labels for
+// the summary, the error and the labels and annotations of all alerts for the description and nothing otherwise.
+func templateFallback(setting string, data *alertmanager.Data, err error) string {
+	switch setting {
+	case "summary":
+		return fmt.Sprintf("[%s] %s", strings.ToUpper(data.Status), labelDump(data.GroupLabels, " "))
+	case "description":
+		var b strings.Builder
+		fmt.Fprintf(&b, "The description template failed to render: %s\n", err)
+		for _, a := range data.Alerts {
+			fmt.Fprintf(&b, "\nAlert (%s):\nLabels: %s\nAnnotations: %s\n", a.Status, labelDump(a.Labels, ", "), labelDump(a.Annotations, ", "))
+		}
+		return b.String()
+	}
+	return ""
+}
+
+func labelDump(kv alertmanager.KV, sep string) string {
+	pairs := make([]string, 0, len(kv))
+	for _, p := range kv.SortedPairs() {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", p.Name, p.Value))
+	}
+	return strings.Join(pairs, sep)
+}
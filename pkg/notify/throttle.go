@@ -0,0 +1,142 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+// StormLabel is the label of the umbrella issues created once a project exceeds its creation limit.
+const StormLabel = "JIRALERT_STORM"
+
+// allow reports whether an issue may be created in project for the alert group with the given identifier label, given
+// at most max alert groups with issues created per window. Groups whose issue was already created within the window do
+// not count twice. Creations are only counted once they succeeded, see recordCreation.
+func (s *State) allow(project, idLabel string, max int, window time.Duration, now time.Time) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for l, c := range s.created[project] {
+		if now.Sub(c) >= window {
+			delete(s.created[project], l)
+		}
+	}
+	if _, ok := s.created[project][idLabel]; ok {
+		return true
+	}
+	return len(s.created[project]) < max
+}
+
+// recordCreation records that an issue was created in project for the alert group with the given identifier label.
+func (s *State) recordCreation(project, idLabel string, now time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.created[project] == nil {
+		s.created[project] = map[string]time.Time{}
+	}
+	s.created[project][idLabel] = now
+}
+
+// listed reports whether the group with the given identifier label is already listed in the umbrella issue.
+func (s *State) listed(umbrellaKey, idLabel string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	_, ok := s.aggregated[umbrellaKey][idLabel]
+	return ok
+}
+
+// recordListed records that the group with the given identifier label was listed in the umbrella issue. It is
+// only called once the comment listing the group was added, so failed comments are retried.
+func (s *State) recordListed(umbrellaKey, idLabel string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.aggregated[umbrellaKey]; !ok {
+		s.aggregated[umbrellaKey] = map[string]struct{}{}
+	}
+	s.aggregated[umbrellaKey][idLabel] = struct{}{}
+}
+
+// throttled reports whether an issue must not be created in project for the alert group because the project exceeded
+// the creation limit.
+func (r *Receiver) throttled(project, idLabel string) bool {
+	if r.state == nil || r.conf.CreationLimit == nil {
+		return false
+	}
+	return !r.state.allow(project, idLabel, r.conf.CreationLimit.MaxIssues, time.Duration(*r.conf.CreationLimit.Window), r.timeNow())
+}
+
+// recordCreation counts the issue created in project for the alert group against the creation limit.
+func (r *Receiver) recordCreation(project, idLabel string) {
+	if r.state == nil || r.conf.CreationLimit == nil {
+		return
+	}
+	r.state.recordCreation(project, idLabel, r.timeNow())
+}
+
+// addToStorm comments on the project's open umbrella issue (creating it if needed) instead of creating issue.
+func (r *Receiver) addToStorm(ctx context.Context, project, idLabel string, issue *jira.Issue, data *alertmanager.Data) (bool, error) {
+	query := Query{Project: project, Label: StormLabel, Unresolved: true}
+	options := &jira.SearchOptions{Fields: []string{"summary"}, MaxResults: 1}
+	issues, resp, err := r.client.SearchWithContext(ctx, query, options)
+	if err != nil {
+		return handleJiraErrResponse("Issue.Search", resp, err, r.logger)
+	}
+
+	var umbrellaKey string
+	if len(issues) > 0 {
+		umbrellaKey = issues[0].Key
+	} else {
+		summary, err := r.tmpl.Execute(r.conf.CreationLimit.StormSummary, data)
+		if err != nil {
+			return false, errors.Wrap(err, "render storm summary")
+		}
+		umbrella := &jira.Issue{
+			Fields: &jira.IssueFields{
+				Project:     jira.Project{Key: project},
+				Type:        issue.Fields.Type,
+				Summary:     summary,
+				Description: fmt.Sprintf("More than %d issues were created in %s, further alert groups are listed in the comments.", r.conf.CreationLimit.MaxIssues, *r.conf.CreationLimit.Window),
+				Labels:      []string{StormLabel},
+				Unknowns:    tcontainer.NewMarshalMap(),
+			},
+		}
+		level.Warn(r.logger).Log("msg", "creation limit exceeded, creating alert storm issue", "project", project)
+		if retry, err := r.create(ctx, umbrella); err != nil {
+			return retry, err
+		}
+		umbrellaKey = umbrella.Key
+	}
+
+	if r.state.listed(umbrellaKey, idLabel) {
+		level.Debug(r.logger).Log("msg", "alert group already listed in alert storm issue", "key", umbrellaKey, "label", idLabel)
+		return false, nil
+	}
+	level.Info(r.logger).Log("msg", "creation limit exceeded, adding alert group to alert storm issue", "key", umbrellaKey, "label", idLabel)
+	if retry, err := r.addComment(ctx, umbrellaKey, fmt.Sprintf("%s\n\n%s", issue.Fields.Summary, issue.Fields.Description)); err != nil {
+		return retry, err
+	}
+	r.state.recordListed(umbrellaKey, idLabel)
+	return false, nil
+}
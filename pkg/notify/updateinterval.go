@@ -0,0 +1,34 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"time"
+
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
+)
+
+// recentlyUpdated returns the key of the open issue of the alert group with the given identifier label in project if
+// it was updated less than min_update_interval ago and the group is still firing, in which case the notification is
+// skipped. Issues are known from earlier notifications only, so the first notification after a restart is processed.
+func (r *Receiver) recentlyUpdated(project, idLabel string, data *alertmanager.Data) (string, bool) {
+	if r.state == nil || r.conf.MinUpdateInterval == nil || *r.conf.MinUpdateInterval == 0 || len(data.Alerts.Firing()) == 0 {
+		return "", false
+	}
+	m, ok := r.state.Mapping(r.conf.Name, project, idLabel)
+	if !ok || m.Status != MappingOpen {
+		return "", false
+	}
+	return m.IssueKey, r.timeNow().Sub(m.LastUpdate) < time.Duration(*r.conf.MinUpdateInterval)
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oncall looks up who is currently on call in PagerDuty or Opsgenie schedules.
+package oncall
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/config"
+)
+
+// Lookup returns the email address of the user currently on call for the configured schedule.
+func Lookup(ctx context.Context, conf *config.OnCall, client *http.Client) (string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch conf.Provider {
+	case config.OnCallPagerDuty:
+		return lookupPagerDuty(ctx, conf, client)
+	case config.OnCallOpsgenie:
+		return lookupOpsgenie(ctx, conf, client)
+	}
+	return "", errors.Errorf("unknown on-call provider %q", conf.Provider)
+}
+
+func lookupPagerDuty(ctx context.Context, conf *config.OnCall, client *http.Client) (string, error) {
+	q := url.Values{}
+	q.Set("schedule_ids[]", conf.Schedule)
+	q.Set("include[]", "users")
+	q.Set("earliest", "true")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(conf.APIURL, "/")+"/oncalls?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	req.Header.Set("Authorization", "Token token="+string(conf.Token))
+
+	var res struct {
+		OnCalls []struct {
+			User struct {
+				Email string `json:"email"`
+			} `json:"user"`
+		} `json:"oncalls"`
+	}
+	if err := do(client, req, &res); err != nil {
+		return "", err
+	}
+	for _, oc := range res.OnCalls {
+		if oc.User.Email != "" {
+			return oc.User.Email, nil
+		}
+	}
+	return "", errors.Errorf("nobody is on call for PagerDuty schedule %q", conf.Schedule)
+}
+
+func lookupOpsgenie(ctx context.Context, conf *config.OnCall, client *http.Client) (string, error) {
+	u := strings.TrimSuffix(conf.APIURL, "/") + "/v2/schedules/" + url.PathEscape(conf.Schedule) + "/on-calls?flat=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "GenieKey "+string(conf.Token))
+
+	var res struct {
+		Data struct {
+			OnCallRecipients []string `json:"onCallRecipients"`
+		} `json:"data"`
+	}
+	if err := do(client, req, &res); err != nil {
+		return "", err
+	}
+	if len(res.Data.OnCallRecipients) == 0 {
+		return "", errors.Errorf("nobody is on call for Opsgenie schedule %q", conf.Schedule)
+	}
+	return res.Data.OnCallRecipients[0], nil
+}
+
+func do(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "query on-call schedule")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("on-call request %s returned status %s, body %q", req.URL.Path, resp.Status, string(body))
+	}
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(v), "decode on-call response")
+}
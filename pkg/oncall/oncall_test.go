@@ -0,0 +1,138 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oncall
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus-community/jiralert/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		provider  string
+		schedule  string
+		wantPath  string
+		wantQuery string
+		wantAuth  string
+		status    int
+		body      string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "PagerDuty",
+			provider:  config.OnCallPagerDuty,
+			schedule:  "PSCHED1",
+			wantPath:  "/oncalls",
+			wantQuery: "earliest=true&include%5B%5D=users&schedule_ids%5B%5D=PSCHED1",
+			wantAuth:  "Token token=secret",
+			status:    http.StatusOK,
+			body:      `{"oncalls":[{"user":{}},{"user":{"email":"alice@example.com"}}]}`,
+			want:      "alice@example.com",
+		},
+		{
+			name:     "PagerDuty nobody on call",
+			provider: config.OnCallPagerDuty,
+			schedule: "PSCHED1",
+			wantPath: "/oncalls",
+			wantAuth: "Token token=secret",
+			status:   http.StatusOK,
+			body:     `{"oncalls":[]}`,
+			wantErr:  `nobody is on call for PagerDuty schedule "PSCHED1"`,
+		},
+		{
+			name:     "PagerDuty error status",
+			provider: config.OnCallPagerDuty,
+			schedule: "PSCHED1",
+			wantPath: "/oncalls",
+			wantAuth: "Token token=secret",
+			status:   http.StatusUnauthorized,
+			body:     `{"error":{"message":"Unauthorized"}}`,
+			wantErr:  `on-call request /oncalls returned status 401 Unauthorized, body "{\"error\":{\"message\":\"Unauthorized\"}}"`,
+		},
+		{
+			name:      "Opsgenie",
+			provider:  config.OnCallOpsgenie,
+			schedule:  "ops team",
+			wantPath:  "/v2/schedules/ops team/on-calls",
+			wantQuery: "flat=true",
+			wantAuth:  "GenieKey secret",
+			status:    http.StatusOK,
+			body:      `{"data":{"onCallRecipients":["bob@example.com","carol@example.com"]}}`,
+			want:      "bob@example.com",
+		},
+		{
+			name:     "Opsgenie nobody on call",
+			provider: config.OnCallOpsgenie,
+			schedule: "ops",
+			wantPath: "/v2/schedules/ops/on-calls",
+			wantAuth: "GenieKey secret",
+			status:   http.StatusOK,
+			body:     `{"data":{"onCallRecipients":[]}}`,
+			wantErr:  `nobody is on call for Opsgenie schedule "ops"`,
+		},
+		{
+			name:     "Opsgenie invalid response",
+			provider: config.OnCallOpsgenie,
+			schedule: "ops",
+			wantPath: "/v2/schedules/ops/on-calls",
+			wantAuth: "GenieKey secret",
+			status:   http.StatusOK,
+			body:     `not json`,
+			wantErr:  "decode on-call response",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodGet, r.Method)
+				require.Equal(t, tc.wantPath, r.URL.Path)
+				if tc.wantQuery != "" {
+					require.Equal(t, tc.wantQuery, r.URL.RawQuery)
+				}
+				require.Equal(t, tc.wantAuth, r.Header.Get("Authorization"))
+				w.WriteHeader(tc.status)
+				_, _ = io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			conf := &config.OnCall{Provider: tc.provider, APIURL: srv.URL + "/", Token: "secret", Schedule: tc.schedule}
+			got, err := Lookup(context.Background(), conf, srv.Client())
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	_, err := Lookup(context.Background(), &config.OnCall{Provider: "victorops"}, nil)
+	require.EqualError(t, err, `unknown on-call provider "victorops"`)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	_, err = Lookup(context.Background(), &config.OnCall{Provider: config.OnCallPagerDuty, APIURL: srv.URL, Schedule: "PSCHED1"}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "query on-call schedule")
+}
@@ -0,0 +1,384 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package servicenow implements notify.Ticketer on top of the ServiceNow incident table. Projects are assignment
+// groups, the issue identifier label is stored in the incident's correlation_id and the incident states are exposed
+// as transitions.
+package servicenow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+)
+
+// timeLayout is the layout of date/time values returned by the Table API.
+const timeLayout = "2006-01-02 15:04:05"
+
+// Incident states of a default ServiceNow instance.
+const (
+	StateNew        = "1"
+	StateInProgress = "2"
+	StateOnHold     = "3"
+	StateResolved   = "6"
+	StateClosed     = "7"
+	StateCanceled   = "8"
+)
+
+var (
+	stateNames = map[string]string{
+		StateNew:        "New",
+		StateInProgress: "In Progress",
+		StateOnHold:     "On Hold",
+		StateResolved:   "Resolved",
+		StateClosed:     "Closed",
+		StateCanceled:   "Canceled",
+	}
+	stateOrder = []string{StateNew, StateInProgress, StateOnHold, StateResolved, StateClosed, StateCanceled}
+
+	fields = "sys_id,number,short_description,description,state,correlation_id,close_code,resolved_at"
+)
+
+// Client talks to the ServiceNow Table API.
+type Client struct {
+	url        string
+	user       string
+	password   string
+	token      string
+	closeCode  string
+	closeNotes string
+	client     *http.Client
+	now        func() time.Time
+}
+
+// NewClient returns a Client for the ServiceNow instance at baseURL, authenticated with basic auth if user is set or
+// else with the token. Incidents are resolved with the given close code and notes.
+func NewClient(baseURL, user, password, token, closeCode, closeNotes string, client *http.Client) (*Client, error) {
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid ServiceNow URL %q", baseURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{
+		url:        strings.TrimSuffix(baseURL, "/"),
+		user:       user,
+		password:   password,
+		token:      token,
+		closeCode:  closeCode,
+		closeNotes: closeNotes,
+		client:     client,
+		now:        time.Now,
+	}, nil
+}
+
+type incident struct {
+	SysID            string `json:"sys_id"`
+	Number           string `json:"number"`
+	ShortDescription string `json:"short_description"`
+	Description      string `json:"description"`
+	State            string `json:"state"`
+	CorrelationID    string `json:"correlation_id"`
+	CloseCode        string `json:"close_code"`
+	ResolvedAt       string `json:"resolved_at"`
+}
+
+// do sends a request and decodes the "result" of the response into out, if not nil. Error responses are returned as
+// *jira.Response with a readable body, so callers can handle them like Jira errors.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) (*jira.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	jresp := &jira.Response{Response: resp}
+	if err != nil {
+		return jresp, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return jresp, errors.Errorf("ServiceNow request %s %s returned status %s", method, req.URL.Path, resp.Status)
+	}
+	if out != nil {
+		res := struct {
+			Result interface{} `json:"result"`
+		}{Result: out}
+		if err := json.Unmarshal(b, &res); err != nil {
+			return jresp, errors.Wrap(err, "decode ServiceNow response")
+		}
+	}
+	return jresp, nil
+}
+
+// escape escapes a value of an encoded query.
+func escape(v string) string {
+	return strings.Replace(v, "^", "^^", -1)
+}
+
+func (c *Client) toJira(project string, in *incident) jira.Issue {
+	category := "new"
+	switch in.State {
+	case StateInProgress, StateOnHold:
+		category = "indeterminate"
+	case StateResolved, StateClosed, StateCanceled:
+		category = "done"
+	}
+	name, ok := stateNames[in.State]
+	if !ok {
+		name = in.State
+	}
+	fields := &jira.IssueFields{
+		Project:     jira.Project{Key: project},
+		Summary:     in.ShortDescription,
+		Description: in.Description,
+		Status:      &jira.Status{ID: in.State, Name: name, StatusCategory: jira.StatusCategory{Key: category}},
+	}
+	if in.CorrelationID != "" {
+		fields.Labels = []string{in.CorrelationID}
+	}
+	if category == "done" {
+		fields.Resolution = &jira.Resolution{Name: in.CloseCode}
+		if t, err := time.Parse(timeLayout, in.ResolvedAt); err == nil {
+			fields.Resolutiondate = jira.Time(t)
+		}
+	}
+	return jira.Issue{ID: in.SysID, Key: in.Number, Fields: fields}
+}
+
+// get returns the incident with the given number or sys_id.
+func (c *Client) get(ctx context.Context, id string) (*incident, *jira.Response, error) {
+	q := url.Values{}
+	q.Set("sysparm_query", "number="+escape(id)+"^ORsys_id="+escape(id))
+	q.Set("sysparm_fields", fields)
+	q.Set("sysparm_limit", "1")
+	var res []incident
+	resp, err := c.do(ctx, http.MethodGet, "/api/now/table/incident?"+q.Encode(), nil, &res)
+	if err != nil {
+		return nil, resp, err
+	}
+	if len(res) == 0 {
+		return nil, resp, errors.Errorf("incident %q not found", id)
+	}
+	return &res[0], resp, nil
+}
+
+// patch updates the incident with the given number or sys_id.
+func (c *Client) patch(ctx context.Context, id string, in map[string]interface{}) (*incident, *jira.Response, error) {
+	current, resp, err := c.get(ctx, id)
+	if err != nil {
+		return nil, resp, err
+	}
+	var res incident
+	resp, err = c.do(ctx, http.MethodPatch, "/api/now/table/incident/"+current.SysID+"?sysparm_fields="+fields, in, &res)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &res, resp, nil
+}
+
+// SearchWithContext returns the incidents of the assignment group given as project, the label matching their
+// correlation ID. Children of a parent incident are not supported.
+func (c *Client) SearchWithContext(ctx context.Context, query notify.Query, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	if query.Parent != "" {
+		return nil, nil, errors.Errorf("unsupported query %q: incidents have no parent", query.JQL())
+	}
+	project := query.Project
+
+	// ^OR binds tighter than ^, so this matches the group by sys_id or name.
+	conditions := []string{"assignment_group=" + escape(project) + "^ORassignment_group.name=" + escape(project)}
+	if query.Label != "" {
+		conditions = append(conditions, "correlation_id="+escape(query.Label))
+	}
+	if query.Unresolved {
+		conditions = append(conditions, "stateNOT IN"+strings.Join([]string{StateResolved, StateClosed, StateCanceled}, ","))
+	}
+	if query.NotUpdatedFor > 0 {
+		conditions = append(conditions, "sys_updated_on<="+c.now().UTC().Add(-query.NotUpdatedFor).Format(timeLayout))
+	}
+	if query.OrderByResolutionDate {
+		conditions = append(conditions, "ORDERBYDESCresolved_at")
+	}
+
+	q := url.Values{}
+	q.Set("sysparm_query", strings.Join(conditions, "^"))
+	q.Set("sysparm_fields", fields)
+	if options != nil {
+		q.Set("sysparm_offset", strconv.Itoa(options.StartAt))
+		if options.MaxResults > 0 {
+			q.Set("sysparm_limit", strconv.Itoa(options.MaxResults))
+		}
+	}
+
+	var incidents []incident
+	resp, err := c.do(ctx, http.MethodGet, "/api/now/table/incident?"+q.Encode(), nil, &incidents)
+	if err != nil {
+		return nil, resp, err
+	}
+	res := make([]jira.Issue, 0, len(incidents))
+	for i := range incidents {
+		res = append(res, c.toJira(project, &incidents[i]))
+	}
+	if query.OrderByResolutionDate {
+		// Unresolved incidents first, like Jira.
+		sort.SliceStable(res, func(i, j int) bool {
+			return res[i].Fields.Resolution == nil && res[j].Fields.Resolution != nil
+		})
+	}
+	return res, resp, nil
+}
+
+// GetTransitionsWithContext returns the incident states.
+func (c *Client) GetTransitionsWithContext(_ context.Context, _ string) ([]jira.Transition, *jira.Response, error) {
+	transitions := make([]jira.Transition, 0, len(stateOrder))
+	for _, s := range stateOrder {
+		transitions = append(transitions, jira.Transition{ID: s, Name: stateNames[s]})
+	}
+	return transitions, nil, nil
+}
+
+// DoTransitionWithContext sets the incident state. Resolving or closing sets the configured close code and notes, reopening
+// clears them.
+func (c *Client) DoTransitionWithContext(ctx context.Context, ticketID, transitionID string) (*jira.Response, error) {
+	req := map[string]interface{}{"state": transitionID}
+	switch transitionID {
+	case StateResolved, StateClosed, StateCanceled:
+		req["close_code"] = c.closeCode
+		req["close_notes"] = c.closeNotes
+	default:
+		req["close_code"] = ""
+		req["close_notes"] = ""
+	}
+	_, resp, err := c.patch(ctx, ticketID, req)
+	return resp, err
+}
+
+// CreateWithContext creates an incident assigned to the group given as project. The first label is used as correlation ID and
+// fields are set as incident columns of the same name.
+func (c *Client) CreateWithContext(ctx context.Context, issue *jira.Issue) (*jira.Issue, *jira.Response, error) {
+	req := map[string]interface{}{}
+	for k, v := range issue.Fields.Unknowns {
+		req[k] = v
+	}
+	req["assignment_group"] = issue.Fields.Project.Key
+	req["short_description"] = issue.Fields.Summary
+	req["description"] = issue.Fields.Description
+	if n := len(issue.Fields.Labels); n > 0 {
+		// The issue identifier label comes last.
+		req["correlation_id"] = issue.Fields.Labels[n-1]
+	}
+	if issue.Fields.Assignee != nil && issue.Fields.Assignee.Name != "" {
+		req["assigned_to"] = issue.Fields.Assignee.Name
+	}
+
+	var res incident
+	resp, err := c.do(ctx, http.MethodPost, "/api/now/table/incident?sysparm_fields="+fields, req, &res)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &jira.Issue{ID: res.SysID, Key: res.Number}, resp, nil
+}
+
+// UpdateWithOptionsWithContext updates the short description and description of the incident, if set.
+func (c *Client) UpdateWithOptionsWithContext(ctx context.Context, issue *jira.Issue, _ *jira.UpdateQueryOptions) (*jira.Issue, *jira.Response, error) {
+	req := map[string]interface{}{}
+	if issue.Fields.Summary != "" {
+		req["short_description"] = issue.Fields.Summary
+	}
+	if issue.Fields.Description != "" {
+		req["description"] = issue.Fields.Description
+	}
+	res, resp, err := c.patch(ctx, issue.Key, req)
+	if err != nil {
+		return nil, resp, err
+	}
+	updated := c.toJira(issue.Fields.Project.Key, res)
+	return &updated, resp, nil
+}
+
+// UpdateIssueWithContext supports adding and removing labels, i.e. `{"update": {"labels": [{"add": "..."}, {"remove": "..."}]}}`,
+// by setting or clearing the correlation ID.
+func (c *Client) UpdateIssueWithContext(ctx context.Context, jiraID string, data map[string]interface{}) (*jira.Response, error) {
+	// Round-trip through JSON, so the operations can be given as any slice of maps.
+	var update struct {
+		Update struct {
+			Labels []map[string]string `json:"labels"`
+		} `json:"update"`
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &update); err != nil {
+		return nil, errors.Wrap(err, "unsupported issue update")
+	}
+
+	current, resp, err := c.get(ctx, jiraID)
+	if err != nil {
+		return resp, err
+	}
+	correlationID := current.CorrelationID
+	for _, op := range update.Update.Labels {
+		if l, ok := op["remove"]; ok && l == correlationID {
+			correlationID = ""
+		}
+		if l, ok := op["add"]; ok {
+			correlationID = l
+		}
+	}
+	resp, err = c.do(ctx, http.MethodPatch, "/api/now/table/incident/"+current.SysID, map[string]interface{}{"correlation_id": correlationID}, nil)
+	return resp, err
+}
+
+// AddCommentWithContext adds the comment to the incident's work notes.
+func (c *Client) AddCommentWithContext(ctx context.Context, issueID string, comment *jira.Comment) (*jira.Comment, *jira.Response, error) {
+	_, resp, err := c.patch(ctx, issueID, map[string]interface{}{"work_notes": comment.Body})
+	if err != nil {
+		return nil, resp, err
+	}
+	return &jira.Comment{Body: comment.Body}, resp, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/prometheus-community/jiralert/pkg/notify"
+	"github.com/stretchr/testify/require"
+)
+
+// request is a request received by the test server.
+type request struct {
+	method, path, query string
+	body                map[string]interface{}
+}
+
+// newTestClient returns a Client of a server listing the given incidents, and responding with the first one to
+// writes.
+func newTestClient(t *testing.T, incidents []incident) (*Client, *[]request) {
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "user", user)
+		require.Equal(t, "password", password)
+		req := request{method: r.Method, path: r.URL.Path, query: r.URL.Query().Get("sysparm_query")}
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+		requests = append(requests, req)
+		var res interface{} = incidents
+		if r.Method != http.MethodGet {
+			res = incidents[0]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": res})
+	}))
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, "user", "password", "", "Solved", "Resolved by JIRAlert", srv.Client())
+	require.NoError(t, err)
+	c.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return c, &requests
+}
+
+func TestSearchQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		query notify.Query
+		want  string
+	}{
+		{
+			name:  "alert group",
+			query: notify.Query{Project: "Ops^Team", Label: "ALERT{a=\"b\"}", OrderByResolutionDate: true},
+			want:  `assignment_group=Ops^^Team^ORassignment_group.name=Ops^^Team^correlation_id=ALERT{a="b"}^ORDERBYDESCresolved_at`,
+		},
+		{
+			name:  "stale incidents",
+			query: notify.Query{Project: "Ops", Unresolved: true, NotUpdatedFor: time.Hour},
+			want:  `assignment_group=Ops^ORassignment_group.name=Ops^stateNOT IN6,7,8^sys_updated_on<=2024-01-02 02:04:05`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, requests := newTestClient(t, nil)
+			_, _, err := c.SearchWithContext(context.Background(), tc.query, &jira.SearchOptions{MaxResults: 2})
+			require.NoError(t, err)
+			require.Len(t, *requests, 1)
+			require.Equal(t, tc.want, (*requests)[0].query)
+		})
+	}
+
+	c, _ := newTestClient(t, nil)
+	_, _, err := c.SearchWithContext(context.Background(), notify.Query{Project: "Ops", Parent: "INC0001"}, nil)
+	require.EqualError(t, err, `unsupported query "project=\"Ops\" and parent = \"INC0001\"": incidents have no parent`)
+}
+
+func TestSearchOrderByResolutionDate(t *testing.T) {
+	c, _ := newTestClient(t, []incident{
+		{SysID: "1", Number: "INC0001", State: StateResolved, CloseCode: "Solved", ResolvedAt: "2024-01-02 01:00:00"},
+		{SysID: "2", Number: "INC0002", State: StateClosed, CloseCode: "Solved", ResolvedAt: "2024-01-01 01:00:00"},
+		{SysID: "3", Number: "INC0003", State: StateInProgress, CorrelationID: "ALERT{a=\"b\"}"},
+	})
+
+	issues, _, err := c.SearchWithContext(context.Background(), notify.Query{Project: "Ops", OrderByResolutionDate: true}, nil)
+	require.NoError(t, err)
+	var keys []string
+	for _, i := range issues {
+		keys = append(keys, i.Key)
+	}
+	require.Equal(t, []string{"INC0003", "INC0001", "INC0002"}, keys)
+	require.Equal(t, "indeterminate", issues[0].Fields.Status.StatusCategory.Key)
+	require.Equal(t, []string{"ALERT{a=\"b\"}"}, issues[0].Fields.Labels)
+	require.Nil(t, issues[0].Fields.Resolution)
+	require.Equal(t, "Solved", issues[1].Fields.Resolution.Name)
+	require.Equal(t, time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC), time.Time(issues[1].Fields.Resolutiondate))
+}
+
+func TestDoTransition(t *testing.T) {
+	c, requests := newTestClient(t, []incident{{SysID: "1", Number: "INC0001", State: StateNew}})
+
+	_, err := c.DoTransitionWithContext(context.Background(), "INC0001", StateResolved)
+	require.NoError(t, err)
+	require.Len(t, *requests, 2)
+	require.Equal(t, "number=INC0001^ORsys_id=INC0001", (*requests)[0].query)
+	require.Equal(t, http.MethodPatch, (*requests)[1].method)
+	require.Equal(t, "/api/now/table/incident/1", (*requests)[1].path)
+	require.Equal(t, map[string]interface{}{"state": StateResolved, "close_code": "Solved", "close_notes": "Resolved by JIRAlert"}, (*requests)[1].body)
+
+	_, err = c.DoTransitionWithContext(context.Background(), "INC0001", StateInProgress)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"state": StateInProgress, "close_code": "", "close_notes": ""}, (*requests)[3].body)
+}
+
+func TestUpdateLabels(t *testing.T) {
+	c, requests := newTestClient(t, []incident{{SysID: "1", Number: "INC0001", CorrelationID: "old"}})
+
+	_, err := c.UpdateIssueWithContext(context.Background(), "INC0001", map[string]interface{}{
+		"update": map[string]interface{}{"labels": []map[string]string{{"remove": "old"}, {"add": "new"}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"correlation_id": "new"}, (*requests)[1].body)
+}
@@ -15,16 +15,120 @@ package template
 
 import (
 	"bytes"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/pkg/errors"
+	"github.com/prometheus-community/jiralert/pkg/alertmanager"
 	"golang.org/x/text/cases"
 )
 
+// Number is the result of toNumber. Templates consisting of a single action resulting in a Number are executed into
+// a number by ExecuteValue, e.g. for numeric custom fields.
+type Number float64
+
+// toNumber converts numbers and strings holding a number, e.g. a label or annotation value, into a Number.
+func toNumber(v interface{}) (Number, error) {
+	switch n := v.(type) {
+	case Number:
+		return n, nil
+	case float64:
+		return Number(n), nil
+	case int:
+		return Number(n), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("toNumber: %q is not a number", n)
+		}
+		return Number(f), nil
+	}
+	return 0, fmt.Errorf("toNumber: cannot convert %T to a number", v)
+}
+
+// List is the result of toList. Templates consisting of a single action resulting in a List are executed into a list
+// of strings by ExecuteValue, e.g. for multi-select and label custom fields.
+type List []string
+
+// toList converts string lists and strings holding comma separated values, e.g. rendered by a range, into a List.
+// Values are trimmed and empty values dropped.
+func toList(v interface{}) (List, error) {
+	var values []string
+	switch l := v.(type) {
+	case List:
+		return l, nil
+	case []string:
+		values = l
+	case []interface{}:
+		for _, e := range l {
+			values = append(values, fmt.Sprint(e))
+		}
+	case string:
+		values = strings.Split(l, ",")
+	default:
+		return nil, fmt.Errorf("toList: cannot convert %T to a list", v)
+	}
+	list := List{}
+	for _, e := range values {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+// labelValues returns the distinct values of the given label of alerts, in the order of the alerts. Alerts without the
+// label are skipped.
+func labelValues(name string, alerts []alertmanager.Alert) []string {
+	values := []string{}
+	seen := map[string]bool{}
+	for _, a := range alerts {
+		v, ok := a.Labels[name]
+		if !ok || seen[v] {
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+	return values
+}
+
+// jiraMacroRE matches the start of Jira code and noformat macros, which would end a block in the middle of a value.
+var jiraMacroRE = regexp.MustCompile(`(?i)\{(code|noformat)`)
+
+// jiraBlock wraps value in the given Jira wiki markup macro, e.g. noformat, so markup characters like braces and
+// pipes are rendered as they are. Macros in value are broken up with a zero-width space so they do not end the block.
+func jiraBlock(macro, value string) string {
+	value = jiraMacroRE.ReplaceAllString(strings.TrimRight(value, "\n"), "{\u200b$1")
+	return "{" + macro + "}\n" + value + "\n{" + strings.SplitN(macro, ":", 2)[0] + "}"
+}
+
+// jiraCode wraps the last argument, e.g. a log excerpt from an annotation, in a Jira code block, with the language
+// given as first argument, if any.
+func jiraCode(args ...string) (string, error) {
+	switch len(args) {
+	case 1:
+		return jiraBlock("code", args[0]), nil
+	case 2:
+		return jiraBlock("code:"+args[0], args[1]), nil
+	}
+	return "", fmt.Errorf("jiraCode: expected a value and optionally a language, got %d arguments", len(args))
+}
+
+// jiraNoformat wraps value in a Jira noformat block.
+func jiraNoformat(value string) string {
+	return jiraBlock("noformat", value)
+}
+
+// valueFunc is the function appended to the pipeline executed by ExecuteValue, to capture its result.
+const valueFunc = "jiralertValue"
+
 type Template struct {
 	tmpl   *template.Template
 	logger log.Logger
@@ -47,6 +151,15 @@ var funcs = template.FuncMap{
 	"stringSlice": func(s ...string) []string {
 		return s
 	},
+	"toNumber":     toNumber,
+	"toList":       toList,
+	"labelValues":  labelValues,
+	"jiraCode":     jiraCode,
+	"jiraNoformat": jiraNoformat,
+	// Replaced by ExecuteValue, defined so templates referencing it parse.
+	valueFunc: func(v interface{}) interface{} {
+		return v
+	},
 }
 
 // LoadTemplate reads and parses all templates defined in the given file and constructs a jiralert.Template.
@@ -59,10 +172,42 @@ func LoadTemplate(path string, logger log.Logger) (*Template, error) {
 	return &Template{tmpl: tmpl, logger: logger}, nil
 }
 
+// ParseTemplate parses all templates defined in text like LoadTemplate does for a file.
+func ParseTemplate(text string, logger log.Logger) (*Template, error) {
+	tmpl, err := template.New("").Option("missingkey=zero").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl, logger: logger}, nil
+}
+
 func SimpleTemplate() *Template {
 	return &Template{logger: log.NewNopLogger(), tmpl: template.New("").Option("missingkey=zero").Funcs(funcs)}
 }
 
+// Strict returns a copy of t failing on references to missing map keys, e.g. labels an alert does not have, instead
+// of rendering them as zero values.
+func (t *Template) Strict() (*Template, error) {
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return nil, errors.Wrap(err, "clone tmpl")
+	}
+	return &Template{tmpl: tmpl.Option("missingkey=error"), logger: t.logger}, nil
+}
+
+// Check parses the provided text like Execute does, without executing it, and returns the parse error, if any.
+func (t *Template) Check(text string) error {
+	if !strings.Contains(text, "{{") {
+		return nil
+	}
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return errors.Wrap(err, "parse clone tmpl")
+	}
+	_, err = tmpl.New("").Parse(text)
+	return err
+}
+
 // Execute parses the provided text (or returns it unchanged if not a Go template), associates it with the templates
 // defined in t.tmpl (so they may be referenced and used) and applies the resulting template to the specified data
 // object, returning the output as a string .
@@ -91,3 +236,68 @@ func (t *Template) Execute(text string, data interface{}) (string, error) {
 	level.Debug(t.logger).Log("msg", "template output", "output", ret)
 	return ret, nil
 }
+
+// ExecuteValue executes text like Execute, but returns a float64 instead of a string if text is a single action whose
+// result is a Number, e.g. `{{ .CommonAnnotations.cost | toNumber }}`, and a []interface{} of strings if it is a List,
+// e.g. `{{ .Alerts | labelValues "instance" | toList }}`.
+func (t *Template) ExecuteValue(text string, data interface{}) (interface{}, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", errors.Wrap(err, "parse clone tmpl")
+	}
+	tmpl, err = tmpl.New("").Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse template %s", text)
+	}
+	action, ok := singleAction(tmpl.Tree)
+	if !ok {
+		return t.Execute(text, data)
+	}
+	var value interface{}
+	tmpl.Funcs(template.FuncMap{valueFunc: func(v interface{}) interface{} {
+		value = v
+		return v
+	}})
+	action.Pipe.Cmds = append(action.Pipe.Cmds, &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      action.Pos,
+		Args:     []parse.Node{parse.NewIdentifier(valueFunc).SetTree(tmpl.Tree).SetPos(action.Pos)},
+	})
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "execute template %s", text)
+	}
+	if n, ok := value.(Number); ok {
+		level.Debug(t.logger).Log("msg", "template output", "output", float64(n))
+		return float64(n), nil
+	}
+	if l, ok := value.(List); ok {
+		level.Debug(t.logger).Log("msg", "template output", "output", strings.Join(l, ","))
+		list := make([]interface{}, 0, len(l))
+		for _, e := range l {
+			list = append(list, e)
+		}
+		return list, nil
+	}
+	ret := buf.String()
+	level.Debug(t.logger).Log("msg", "template output", "output", ret)
+	return ret, nil
+}
+
+// singleAction returns the action of a template consisting of nothing but a single action without variable
+// declarations.
+func singleAction(tree *parse.Tree) (*parse.ActionNode, bool) {
+	if tree == nil || tree.Root == nil || len(tree.Root.Nodes) != 1 {
+		return nil, false
+	}
+	action, ok := tree.Root.Nodes[0].(*parse.ActionNode)
+	if !ok || len(action.Pipe.Decl) > 0 {
+		return nil, false
+	}
+	return action, true
+}